@@ -3,10 +3,12 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -18,7 +20,12 @@ import (
 	appauth "github.com/sqszy/TaskTracker/internal/auth"
 	appdb "github.com/sqszy/TaskTracker/internal/db"
 	handlers "github.com/sqszy/TaskTracker/internal/handlers"
+	appmailer "github.com/sqszy/TaskTracker/internal/mailer"
 	appmw "github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/oidc"
+	"github.com/sqszy/TaskTracker/internal/ratelimit"
+	"github.com/sqszy/TaskTracker/internal/realtime"
+	"github.com/sqszy/TaskTracker/internal/webhook"
 )
 
 func main() {
@@ -27,13 +34,45 @@ func main() {
 	port := env("PORT", "8080")
 	dbURL := env("DB_URL", "")
 	redisAddr := env("REDIS_ADDR", "localhost:6379")
+	appURL := env("APP_URL", "http://localhost:5173")
+	mailLogFile := env("MAIL_LOG_FILE", "") // пусто — письма пишутся в лог
+	// true — вход только с подтверждённым email
+	requireVerifiedEmail := env("REQUIRE_EMAIL_VERIFICATION", "false") == "true"
 
 	accessSecret := env("JWT_ACCESS_SECRET", "")
 	refreshSecret := env("JWT_REFRESH_SECRET", "")
 	accessTTLstr := env("JWT_ACCESS_TTL", "15m")
 	refreshTTLstr := env("JWT_REFRESH_TTL", "168h") // 7 дней
-
-	if dbURL == "" || accessSecret == "" || refreshSecret == "" {
+	// HS256 — подпись секретами; RS256 или EdDSA — ключами из Redis с ротацией и /.well-known/jwks.json
+	signingAlg := env("JWT_SIGNING_ALG", "HS256")
+	keyRotationStr := env("JWT_KEY_ROTATION", "720h") // 30 дней
+
+	// параметры Argon2id для новых хешей паролей; хеши со старыми параметрами пересчитываются при входе
+	argonMemoryStr := env("PASSWORD_ARGON2_MEMORY", "65536") // KiB
+	argonIterationsStr := env("PASSWORD_ARGON2_ITERATIONS", "3")
+	argonParallelismStr := env("PASSWORD_ARGON2_PARALLELISM", "4")
+
+	// парольная политика для регистрации, смены и сброса пароля
+	passwordMinLengthStr := env("PASSWORD_MIN_LENGTH", "8")
+	passwordMinScoreStr := env("PASSWORD_MIN_SCORE", "2") // 0–4, 0 — не проверять стойкость
+	// SHA-1 утёкших паролей, по строке на хеш (формат pwnedpasswords); пусто — не проверять
+	passwordBreachedFile := env("PASSWORD_BREACHED_FILE", "")
+
+	// вход через провайдера OpenID Connect; пусто — выключен
+	oidcIssuer := env("OIDC_ISSUER", "")
+	oidcProvider := env("OIDC_PROVIDER", "sso")
+	oidcClientID := env("OIDC_CLIENT_ID", "")
+	oidcClientSecret := env("OIDC_CLIENT_SECRET", "")
+	oidcRedirectURL := env("OIDC_REDIRECT_URL", appURL+"/oidc/callback")
+
+	// true — refresh-токен выдаётся браузеру в HttpOnly cookie, а не в теле ответа
+	refreshCookie := env("REFRESH_TOKEN_COOKIE", "false") == "true"
+	cookieSecure := env("COOKIE_SECURE", "true") == "true"
+	cookieSameSiteStr := env("COOKIE_SAMESITE", "strict") // strict, lax или none
+	cookieDomain := env("COOKIE_DOMAIN", "")
+
+	// при подписи ключами секреты нужны только для токенов, выданных до переключения
+	if dbURL == "" || (signingAlg == "HS256" && (accessSecret == "" || refreshSecret == "")) {
 		log.Fatal("DB_URL, JWT_ACCESS_SECRET или JWT_REFRESH_SECRET не заданы")
 	}
 
@@ -45,6 +84,52 @@ func main() {
 	if err != nil {
 		log.Fatalf("parse JWT_REFRESH_TTL: %v", err)
 	}
+	keyRotation, err := time.ParseDuration(keyRotationStr)
+	if err != nil {
+		log.Fatalf("parse JWT_KEY_ROTATION: %v", err)
+	}
+	argonParams := appauth.DefaultArgon2idParams
+	if argonParams.Memory, err = parseUint32(argonMemoryStr); err != nil || argonParams.Memory < 8*1024 {
+		log.Fatalf("PASSWORD_ARGON2_MEMORY: нужно целое число KiB, не меньше 8192")
+	}
+	if argonParams.Iterations, err = parseUint32(argonIterationsStr); err != nil || argonParams.Iterations == 0 {
+		log.Fatalf("PASSWORD_ARGON2_ITERATIONS: нужно целое положительное число")
+	}
+	parallelism, err := parseUint32(argonParallelismStr)
+	if err != nil || parallelism == 0 || parallelism > 255 {
+		log.Fatalf("PASSWORD_ARGON2_PARALLELISM: нужно целое число от 1 до 255")
+	}
+	argonParams.Parallelism = uint8(parallelism)
+	policy := appauth.DefaultPasswordPolicy
+	if policy.MinLength, err = strconv.Atoi(passwordMinLengthStr); err != nil || policy.MinLength < 1 || policy.MinLength > policy.MaxLength {
+		log.Fatalf("PASSWORD_MIN_LENGTH: нужно целое число от 1 до %d", policy.MaxLength)
+	}
+	if policy.MinScore, err = strconv.Atoi(passwordMinScoreStr); err != nil || policy.MinScore < 0 || policy.MinScore > 4 {
+		log.Fatalf("PASSWORD_MIN_SCORE: нужно целое число от 0 до 4")
+	}
+	if passwordBreachedFile != "" {
+		if policy.Breached, err = appauth.LoadBreachedCorpus(passwordBreachedFile); err != nil {
+			log.Fatalf("PASSWORD_BREACHED_FILE: %v", err)
+		}
+		log.Printf("breached passwords loaded: %d", policy.Breached.Len())
+	}
+	var cookies *handlers.RefreshCookies
+	if refreshCookie {
+		sameSite, err := parseSameSite(cookieSameSiteStr)
+		if err != nil {
+			log.Fatalf("parse COOKIE_SAMESITE: %v", err)
+		}
+		// браузеры отбрасывают SameSite=None без Secure
+		if sameSite == http.SameSiteNoneMode && !cookieSecure {
+			log.Fatal("COOKIE_SAMESITE=none требует COOKIE_SECURE=true")
+		}
+		cookies = &handlers.RefreshCookies{
+			Secure:   cookieSecure,
+			SameSite: sameSite,
+			Domain:   cookieDomain,
+			MaxAge:   refreshTTL,
+		}
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
@@ -67,14 +152,83 @@ func main() {
 
 	// sqlc queries
 	queries := appdb.New(dbpool)
+	store := appdb.NewStore(dbpool)
 
 	// auth service
-	authSvc := appauth.NewService(rdb, accessSecret, refreshSecret, accessTTL, refreshTTL)
+	authSvc := appauth.NewService(rdb, accessSecret, refreshSecret, accessTTL, refreshTTL).WithPersonalTokens(queries)
+	var keys *appauth.KeyManager
+	if signingAlg != "HS256" {
+		keys, err = appauth.NewKeyManager(rdb, signingAlg, keyRotation, max(accessTTL, refreshTTL))
+		if err != nil {
+			log.Fatalf("JWT_SIGNING_ALG: %v", err)
+		}
+		if _, err := keys.Rotate(ctx); err != nil {
+			log.Fatalf("jwt keys: %v", err)
+		}
+		authSvc.WithKeys(keys)
+		go keys.Run(ctx, time.Minute)
+	}
+
+	// mailer
+	// письма уходят в фоне: время ответа не выдаёт, отправлялось ли письмо
+	mail := appmailer.NewAsync(appmailer.NewLogMailer(mailLogFile), 1000)
+	go mail.Run(ctx)
+
+	// события досок расходятся между экземплярами API через Redis pub/sub
+	broker := realtime.NewBroker(rdb)
+	if err := broker.Start(ctx); err != nil {
+		log.Fatalf("redis subscribe error: %v", err)
+	}
+
+	// вебхуки отправляются в фоне из очереди в Postgres
+	go webhook.NewWorker(store).Run(ctx, 5*time.Second)
+
+	// защита входа от подбора: лимиты запросов по IP и email, блокировка аккаунта после неверных паролей
+	loginByIP := ratelimit.NewLimiter(rdb, "login_ip", ratelimit.Limit{Requests: 20, Window: time.Minute})
+	loginByEmail := ratelimit.NewLimiter(rdb, "login_email", ratelimit.Limit{Requests: 10, Window: time.Minute})
+	signupByIP := ratelimit.NewLimiter(rdb, "signup_ip", ratelimit.Limit{Requests: 10, Window: time.Hour})
+	refreshByIP := ratelimit.NewLimiter(rdb, "refresh_ip", ratelimit.Limit{Requests: 60, Window: time.Minute})
+	// письма со ссылками: не даёт засыпать чужой ящик и перебирать адреса
+	emailByIP := ratelimit.NewLimiter(rdb, "email_ip", ratelimit.Limit{Requests: 10, Window: time.Hour})
+	loginLockout := ratelimit.NewLockout(rdb, "login", ratelimit.DefaultLockoutPolicy)
+	mfaLockout := ratelimit.NewLockout(rdb, "mfa", ratelimit.DefaultLockoutPolicy)
 
 	// handlers
-	authHandler := handlers.NewAuthHandler(queries, authSvc)
-	boardHandler := handlers.NewBoardHandler(queries)
-	taskHandler := handlers.NewTaskHandler(queries)
+	authHandler := handlers.NewAuthHandler(queries, authSvc, mail, appURL).
+		RequireVerifiedEmail(requireVerifiedEmail).
+		WithLoginLockout(loginLockout).
+		WithPasswordHasher(appauth.NewArgon2idHasher(argonParams)).
+		WithPasswordPolicy(policy).
+		WithRefreshCookies(cookies)
+	boardHandler := handlers.NewBoardHandler(store)
+	taskHandler := handlers.NewTaskHandler(store, broker)
+	memberHandler := handlers.NewMemberHandler(store)
+	commentHandler := handlers.NewCommentHandler(store)
+	columnHandler := handlers.NewColumnHandler(store)
+	activityHandler := handlers.NewActivityHandler(queries)
+	invitationHandler := handlers.NewInvitationHandler(store, mail, appURL)
+	eventsHandler := handlers.NewEventsHandler(queries, authSvc, broker)
+	webhookHandler := handlers.NewWebhookHandler(store)
+	sessionHandler := handlers.NewSessionHandler(authSvc)
+	mfaHandler := handlers.NewMFAHandler(store, authSvc).
+		WithCodeLockout(mfaLockout).
+		WithRefreshCookies(cookies)
+	tokenHandler := handlers.NewTokenHandler(queries)
+	jwksHandler := handlers.NewJWKSHandler(keys)
+	profileHandler := handlers.NewProfileHandler(store)
+	var providers []*oidc.Provider
+	if oidcIssuer != "" {
+		providers = append(providers, oidc.NewProvider(oidc.Config{
+			Name:         oidcProvider,
+			Issuer:       oidcIssuer,
+			ClientID:     oidcClientID,
+			ClientSecret: oidcClientSecret,
+			RedirectURL:  oidcRedirectURL,
+		}, nil))
+	}
+	oidcHandler := handlers.NewOIDCHandler(store, authSvc, providers...).
+		RequireVerifiedEmail(requireVerifiedEmail).
+		WithRefreshCookies(cookies)
 
 	r := chi.NewRouter()
 
@@ -95,27 +249,113 @@ func main() {
 	})
 
 	// public routes
-	r.Post("/signup", authHandler.Signup)
-	r.Post("/login", authHandler.Login)
-	r.Post("/refresh", authHandler.Refresh)
-	r.Post("/logout", authHandler.Logout)
-
-	// protected routes
+	r.Get("/.well-known/jwks.json", jwksHandler.JWKS)
+	r.With(appmw.RateLimit(signupByIP, appmw.ByIP)).Post("/signup", authHandler.Signup)
+	r.With(appmw.RateLimit(loginByIP, appmw.ByIP), appmw.RateLimit(loginByEmail, appmw.ByEmail)).Post("/login", authHandler.Login)
+	r.With(appmw.RateLimit(loginByIP, appmw.ByIP)).Post("/login/mfa", mfaHandler.Login)
+	r.With(appmw.RateLimit(refreshByIP, appmw.ByIP), appmw.CSRF).Post("/refresh", authHandler.Refresh)
+	r.With(appmw.RateLimit(loginByIP, appmw.ByIP)).Get("/oidc/{provider}/start", oidcHandler.Start)
+	r.With(appmw.RateLimit(loginByIP, appmw.ByIP)).Post("/oidc/callback", oidcHandler.Callback)
+	r.With(appmw.CSRF).Post("/logout", authHandler.Logout)
+	r.With(appmw.RateLimit(emailByIP, appmw.ByIP)).Post("/password/forgot", authHandler.ForgotPassword)
+	r.With(appmw.RateLimit(loginByIP, appmw.ByIP)).Post("/password/reset", authHandler.ResetPassword)
+	r.Post("/email/verify", authHandler.VerifyEmail)
+	r.With(appmw.RateLimit(emailByIP, appmw.ByIP)).Post("/email/verify/resend", authHandler.ResendVerification)
+	r.Get("/users/{userID}/avatar", profileHandler.Avatar)
+
+	// SSE: токен принимается и из query, т.к. EventSource не передаёт заголовки
+	r.With(appmw.StreamAuthMiddleware(authSvc)).Get("/boards/{boardID}/events", eventsHandler.Stream)
+
+	// protected routes: токен из /login или personal access token с нужной областью доступа
 	r.Group(func(r chi.Router) {
 		r.Use(appmw.AuthMiddleware(authSvc))
 
-		r.Get("/GetBoards", boardHandler.GetBoards)
-		r.Post("/CreateBoard", boardHandler.CreateBoard)
+		// управление аккаунтом — только с токеном из /login
+		r.Group(func(r chi.Router) {
+			r.Use(appmw.SessionOnly)
+
+			r.Post("/password/change", authHandler.ChangePassword)
+
+			r.Patch("/me", profileHandler.UpdateMe)
+			r.Put("/me/avatar", profileHandler.UploadAvatar)
+			r.Delete("/me/avatar", profileHandler.DeleteAvatar)
+
+			r.Get("/sessions", sessionHandler.ListSessions)
+			r.Delete("/sessions", sessionHandler.RevokeAllSessions)
+			r.Delete("/sessions/{sessionID}", sessionHandler.RevokeSession)
+
+			r.Get("/mfa", mfaHandler.Status)
+			r.Post("/mfa/totp/enroll", mfaHandler.EnrollTOTP)
+			r.Post("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+			r.Post("/mfa/totp/disable", mfaHandler.DisableTOTP)
+			r.Post("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+
+			r.Get("/tokens", tokenHandler.ListTokens)
+			r.Post("/tokens", tokenHandler.CreateToken)
+			r.Delete("/tokens/{tokenID}", tokenHandler.RevokeToken)
+
+			r.Get("/identities", oidcHandler.ListIdentities)
+			r.Post("/oidc/{provider}/link", oidcHandler.Link)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(appmw.RequireScope("boards"))
+
+			r.Get("/GetBoards", boardHandler.GetBoards)
+			r.Post("/CreateBoard", boardHandler.CreateBoard)
+
+			r.Patch("/boards/{boardID}", boardHandler.PatchBoard)
+			r.Delete("/boards/{boardID}", boardHandler.DeleteBoard)
+
+			r.Get("/boards/{boardID}/members", memberHandler.ListMembers)
+			r.Post("/boards/{boardID}/members", memberHandler.AddMember)
+			r.Patch("/boards/{boardID}/members/{userID}", memberHandler.UpdateMember)
+			r.Delete("/boards/{boardID}/members/{userID}", memberHandler.RemoveMember)
+
+			r.Get("/boards/{boardID}/columns", columnHandler.ListColumns)
+			r.Post("/boards/{boardID}/columns", columnHandler.CreateColumn)
+			r.Put("/boards/{boardID}/columns/order", columnHandler.ReorderColumns)
+			r.Patch("/boards/{boardID}/columns/{columnID}", columnHandler.UpdateColumn)
+			r.Delete("/boards/{boardID}/columns/{columnID}", columnHandler.DeleteColumn)
+
+			r.Get("/boards/{boardID}/webhooks", webhookHandler.ListWebhooks)
+			r.Post("/boards/{boardID}/webhooks", webhookHandler.CreateWebhook)
+			r.Patch("/boards/{boardID}/webhooks/{webhookID}", webhookHandler.UpdateWebhook)
+			r.Delete("/boards/{boardID}/webhooks/{webhookID}", webhookHandler.DeleteWebhook)
+			r.Get("/boards/{boardID}/webhooks/{webhookID}/deliveries", webhookHandler.ListDeliveries)
+			r.Get("/boards/{boardID}/webhooks/{webhookID}/deliveries/{deliveryID}", webhookHandler.GetDelivery)
+			r.Post("/boards/{boardID}/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", webhookHandler.Redeliver)
+
+			r.Get("/boards/{boardID}/invitations", invitationHandler.ListInvitations)
+			r.Post("/boards/{boardID}/invitations", invitationHandler.CreateInvitation)
+			r.Delete("/boards/{boardID}/invitations/{invitationID}", invitationHandler.RevokeInvitation)
+			r.Post("/invitations/{token}/accept", invitationHandler.AcceptInvitation)
+			r.Post("/invitations/{token}/decline", invitationHandler.DeclineInvitation)
+
+			r.Get("/boards/{boardID}/activity", activityHandler.BoardActivity)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(appmw.RequireScope("tasks"))
 
-		r.Patch("/boards/{boardID}", boardHandler.PatchBoard)
-		r.Delete("/boards/{boardID}", boardHandler.DeleteBoard)
+			r.Get("/boards/{boardID}/GetTasks", taskHandler.GetTasks)
+			r.Get("/tasks/assigned", taskHandler.GetAssignedTasks)
+			r.Post("/boards/{boardID}/CreateTask", taskHandler.CreateTask)
 
-		r.Get("/boards/{boardID}/GetTasks", taskHandler.GetTasks)
-		r.Post("/boards/{boardID}/CreateTask", taskHandler.CreateTask)
+			r.Patch("/boards/{boardID}/tasks/{taskID}", taskHandler.PatchTask)
+			r.Post("/boards/{boardID}/tasks/{taskID}/move", taskHandler.MoveTask)
+			r.Delete("/boards/{boardID}/tasks/{taskID}", taskHandler.DeleteTask)
 
-		r.Patch("/boards/{boardID}/tasks/{taskID}", taskHandler.PatchTask)
-		r.Delete("/boards/{boardID}/tasks/{taskID}", taskHandler.DeleteTask)
+			r.Get("/boards/{boardID}/tasks/{taskID}/history", activityHandler.TaskHistory)
+
+			r.Get("/boards/{boardID}/tasks/{taskID}/comments", commentHandler.ListComments)
+			r.Post("/boards/{boardID}/tasks/{taskID}/comments", commentHandler.CreateComment)
+			r.Patch("/boards/{boardID}/tasks/{taskID}/comments/{commentID}", commentHandler.UpdateComment)
+			r.Delete("/boards/{boardID}/tasks/{taskID}/comments/{commentID}", commentHandler.DeleteComment)
+			r.Get("/boards/{boardID}/tasks/{taskID}/comments/{commentID}/history", commentHandler.CommentHistory)
+		})
 
+		r.Get("/me", profileHandler.GetMe)
 		r.Get("/protected/me", func(w http.ResponseWriter, r *http.Request) {
 			uid, _ := appmw.GetUserID(r)
 			_ = json.NewEncoder(w).Encode(map[string]interface{}{"user_id": uid})
@@ -146,6 +386,23 @@ func env(key, def string) string {
 	return def
 }
 
+func parseUint32(v string) (uint32, error) {
+	n, err := strconv.ParseUint(v, 10, 32)
+	return uint32(n), err
+}
+
+func parseSameSite(v string) (http.SameSite, error) {
+	switch v {
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("unknown value %q", v)
+}
+
 // jsonContentType гарантирует JSON по умолчанию
 func jsonContentType(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
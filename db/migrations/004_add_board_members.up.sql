@@ -0,0 +1,12 @@
+CREATE TABLE board_members (
+    board_id INT NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at TIMESTAMP DEFAULT now(),
+    PRIMARY KEY (board_id, user_id)
+);
+
+CREATE INDEX board_members_user_id_idx ON board_members(user_id);
+
+INSERT INTO board_members (board_id, user_id, role)
+SELECT id, user_id, 'owner' FROM boards;
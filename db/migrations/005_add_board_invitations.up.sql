@@ -0,0 +1,17 @@
+CREATE TABLE board_invitations (
+    id SERIAL PRIMARY KEY,
+    board_id INT NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    token_hash TEXT NOT NULL UNIQUE,
+    inviter_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'revoked')),
+    expires_at TIMESTAMP NOT NULL,
+    responded_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+-- не больше одного активного приглашения на email в рамках доски
+CREATE UNIQUE INDEX board_invitations_pending_idx
+    ON board_invitations(board_id, email)
+    WHERE status = 'pending';
@@ -0,0 +1,8 @@
+CREATE TABLE task_assignees (
+    task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT now(),
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX task_assignees_user_id_idx ON task_assignees(user_id);
@@ -0,0 +1,25 @@
+CREATE TABLE task_comments (
+    id SERIAL PRIMARY KEY,
+    task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- ответы только на комментарии верхнего уровня, глубже одного уровня не вкладываются
+    parent_id INT NULL REFERENCES task_comments(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    edited_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL
+);
+
+CREATE INDEX task_comments_task_idx ON task_comments(task_id, created_at);
+CREATE INDEX task_comments_parent_idx ON task_comments(parent_id);
+
+-- предыдущие версии текста комментария
+CREATE TABLE task_comment_edits (
+    id SERIAL PRIMARY KEY,
+    comment_id INT NOT NULL REFERENCES task_comments(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    edited_by INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    edited_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX task_comment_edits_comment_idx ON task_comment_edits(comment_id);
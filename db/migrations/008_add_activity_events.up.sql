@@ -0,0 +1,16 @@
+-- журнал изменений досок, задач и комментариев.
+-- board_id и task_id без внешних ключей: история переживает удаление самих объектов
+CREATE TABLE activity_events (
+    id SERIAL PRIMARY KEY,
+    board_id INT NOT NULL,
+    task_id INT NULL,
+    actor_id INT NULL REFERENCES users(id) ON DELETE SET NULL,
+    entity TEXT NOT NULL CHECK (entity IN ('board', 'task', 'comment')),
+    entity_id INT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('created', 'updated', 'deleted')),
+    changes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX activity_events_board_idx ON activity_events(board_id, id DESC);
+CREATE INDEX activity_events_task_idx ON activity_events(task_id, id DESC) WHERE task_id IS NOT NULL;
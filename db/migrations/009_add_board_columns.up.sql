@@ -0,0 +1,44 @@
+CREATE TABLE board_columns (
+    id SERIAL PRIMARY KEY,
+    board_id INT NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    color TEXT NOT NULL DEFAULT '#94a3b8',
+    is_done BOOLEAN NOT NULL DEFAULT false,
+    position INT NOT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    UNIQUE (board_id, name)
+);
+
+CREATE INDEX board_columns_board_idx ON board_columns(board_id, position);
+
+-- прежний фиксированный набор статусов становится колонками по умолчанию каждой доски
+INSERT INTO board_columns (board_id, name, color, is_done, position)
+SELECT b.id, d.name, d.color, d.is_done, d.position
+FROM boards b
+CROSS JOIN (VALUES
+    ('todo', '#94a3b8', false, 0),
+    ('in_progress', '#3b82f6', false, 1),
+    ('need_review', '#f59e0b', false, 2),
+    ('done', '#22c55e', true, 3)
+) AS d(name, color, is_done, position);
+
+UPDATE tasks SET status = 'todo' WHERE status IS NULL OR status = '';
+
+-- нестандартные статусы, которые уже встречаются в задачах, добавляются отдельными колонками
+INSERT INTO board_columns (board_id, name, position)
+SELECT s.board_id, s.status, 3 + row_number() OVER (PARTITION BY s.board_id ORDER BY s.status)
+FROM (SELECT DISTINCT board_id, status FROM tasks WHERE board_id IS NOT NULL) s
+WHERE NOT EXISTS (
+    SELECT 1 FROM board_columns c WHERE c.board_id = s.board_id AND c.name = s.status
+);
+
+-- status остаётся денормализованной копией имени колонки, чтобы не ломать фильтры и клиентов
+ALTER TABLE tasks
+    ADD COLUMN column_id INT NULL REFERENCES board_columns(id) ON DELETE RESTRICT;
+
+UPDATE tasks t
+SET column_id = c.id
+FROM board_columns c
+WHERE c.board_id = t.board_id AND c.name = t.status;
+
+CREATE INDEX tasks_column_idx ON tasks(column_id);
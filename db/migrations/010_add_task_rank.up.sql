@@ -0,0 +1,19 @@
+-- ключ ручного порядка задачи внутри колонки (fractional indexing, см. internal/rank).
+-- Ключи сравниваются побайтно, поэтому колонка использует collation "C".
+ALTER TABLE tasks ADD COLUMN rank TEXT COLLATE "C";
+
+-- существующие задачи выстраиваются в прежнем порядке создания с равными промежутками;
+-- шестнадцатеричные цифры входят в алфавит ключей, нули в конце отбрасываются.
+-- Ширина 16 цифр вмещает n * 65536 для n < 2^48: при меньшей ширине lpad обрезал бы
+-- длинные значения и порядок ключей разошёлся бы с порядком задач
+UPDATE tasks t
+SET rank = rtrim(lpad(to_hex(r.n * 65536), 16, '0'), '0')
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY column_id ORDER BY created_at, id) AS n
+    FROM tasks
+) r
+WHERE r.id = t.id;
+
+ALTER TABLE tasks ALTER COLUMN rank SET NOT NULL;
+
+CREATE INDEX tasks_column_rank_idx ON tasks(column_id, rank);
@@ -0,0 +1,7 @@
+-- WIP-лимит колонки; NULL — без ограничения
+ALTER TABLE board_columns
+    ADD COLUMN wip_limit INT NULL CHECK (wip_limit > 0);
+
+-- strict — перенос сверх лимита отклоняется, soft — принимается с предупреждением
+ALTER TABLE boards
+    ADD COLUMN wip_mode TEXT NOT NULL DEFAULT 'soft' CHECK (wip_mode IN ('strict', 'soft'));
@@ -0,0 +1,45 @@
+CREATE TABLE webhooks (
+    id SERIAL PRIMARY KEY,
+    board_id INT NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    -- секрет нужен в открытом виде: им подписывается каждая доставка
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_by INT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX webhooks_board_idx ON webhooks(board_id);
+
+-- очередь доставок; записи пишутся в той же транзакции, что и событие журнала
+CREATE TABLE webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    webhook_id INT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT now(),
+    last_status_code INT NULL,
+    last_error TEXT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_webhook_idx ON webhook_deliveries(webhook_id, id DESC);
+
+-- каждая попытка доставки
+CREATE TABLE webhook_attempts (
+    id SERIAL PRIMARY KEY,
+    delivery_id INT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    status_code INT NULL,
+    error TEXT NULL,
+    duration_ms INT NOT NULL,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX webhook_attempts_delivery_idx ON webhook_attempts(delivery_id, attempt);
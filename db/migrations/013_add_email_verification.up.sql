@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL;
+
+-- существующие аккаунты считаются подтверждёнными, иначе включение проверки их заблокирует
+UPDATE users SET email_verified_at = COALESCE(created_at, now());
@@ -0,0 +1,12 @@
+-- подозрительные события аккаунта (повторное использование refresh-токена и т.п.)
+CREATE TABLE security_events (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    ip TEXT NULL,
+    user_agent TEXT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX security_events_user_idx ON security_events(user_id, id DESC);
@@ -0,0 +1,13 @@
+-- секрет TOTP записывается при начале подключения, totp_enabled_at — после подтверждения первым кодом
+ALTER TABLE users ADD COLUMN totp_secret TEXT NULL;
+ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMP NULL;
+
+-- одноразовые коды восстановления; хранится только SHA-256
+CREATE TABLE user_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,14 @@
+-- токены для скриптов и CI; хранится только SHA-256, token_prefix — для узнавания в списке
+CREATE TABLE personal_access_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    token_prefix TEXT NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP NULL,
+    last_used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX personal_access_tokens_user_idx ON personal_access_tokens(user_id);
@@ -0,0 +1,12 @@
+-- внешние учётные записи (OpenID Connect), привязанные к пользователю;
+-- subject — неизменный идентификатор пользователя у провайдера, email — на момент привязки
+CREATE TABLE user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
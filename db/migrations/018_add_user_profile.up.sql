@@ -0,0 +1,14 @@
+-- профиль пользователя: пустые display_name и avatar_url хранятся как NULL;
+-- timezone — имя из базы IANA, locale — тег BCP 47
+ALTER TABLE users ADD COLUMN display_name TEXT NULL;
+ALTER TABLE users ADD COLUMN avatar_url TEXT NULL;
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT 'en';
+
+-- загруженные аватары; avatar_url владельца указывает на /users/{id}/avatar
+CREATE TABLE user_avatars (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    content_type TEXT NOT NULL,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMP DEFAULT now()
+);
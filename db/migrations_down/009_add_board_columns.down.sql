@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS column_id;
+DROP TABLE IF EXISTS board_columns;
@@ -0,0 +1,15 @@
+-- name: CreateActivityEvent :exec
+INSERT INTO activity_events (board_id, task_id, actor_id, entity, entity_id, action, changes)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListTaskActivity :many
+SELECT * FROM activity_events
+WHERE board_id = $1 AND task_id = $2
+ORDER BY id DESC
+LIMIT $3 OFFSET $4;
+
+-- name: ListBoardActivity :many
+SELECT * FROM activity_events
+WHERE board_id = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3;
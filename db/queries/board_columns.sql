@@ -0,0 +1,72 @@
+-- name: CreateDefaultColumns :many
+INSERT INTO board_columns (board_id, name, color, is_done, position)
+SELECT @board_id::int, d.name, d.color, d.is_done, d.position
+FROM (VALUES
+    ('todo', '#94a3b8', false, 0),
+    ('in_progress', '#3b82f6', false, 1),
+    ('need_review', '#f59e0b', false, 2),
+    ('done', '#22c55e', true, 3)
+) AS d(name, color, is_done, position)
+RETURNING *;
+
+-- name: ListBoardColumns :many
+SELECT * FROM board_columns
+WHERE board_id = $1
+ORDER BY position, id;
+
+-- name: GetBoardColumn :one
+SELECT * FROM board_columns
+WHERE id = @id AND board_id = @board_id;
+
+-- name: GetBoardColumnByName :one
+SELECT * FROM board_columns
+WHERE board_id = @board_id AND name = @name;
+
+-- name: GetFirstBoardColumn :one
+SELECT * FROM board_columns
+WHERE board_id = $1
+ORDER BY position, id
+LIMIT 1;
+
+-- name: CreateBoardColumn :one
+INSERT INTO board_columns (board_id, name, color, is_done, wip_limit, position)
+VALUES (
+    @board_id, @name, @color, @is_done, sqlc.narg('wip_limit'),
+    (SELECT COALESCE(MAX(position) + 1, 0) FROM board_columns WHERE board_id = @board_id)
+)
+RETURNING *;
+
+-- name: UpdateBoardColumn :one
+UPDATE board_columns
+SET
+    name = COALESCE(sqlc.narg('name'), name),
+    color = COALESCE(sqlc.narg('color'), color),
+    is_done = COALESCE(sqlc.narg('is_done'), is_done),
+    -- 0 снимает лимит
+    wip_limit = CASE
+        WHEN sqlc.narg('wip_limit')::int IS NULL THEN wip_limit
+        ELSE NULLIF(sqlc.narg('wip_limit')::int, 0)
+    END
+WHERE id = @id AND board_id = @board_id
+RETURNING *;
+
+-- name: ReorderBoardColumns :execrows
+UPDATE board_columns c
+SET position = o.ord - 1
+FROM unnest(@column_ids::int[]) WITH ORDINALITY AS o(id, ord)
+WHERE c.id = o.id AND c.board_id = @board_id;
+
+-- name: DeleteBoardColumn :execrows
+DELETE FROM board_columns
+WHERE id = @id AND board_id = @board_id;
+
+-- name: GetColumnWIP :one
+-- блокирует колонку, чтобы конкурентные переносы не превысили лимит
+SELECT
+  c.wip_limit,
+  b.wip_mode,
+  (SELECT count(*) FROM tasks t WHERE t.column_id = c.id AND t.id <> @task_id) AS task_count
+FROM board_columns c
+JOIN boards b ON b.id = c.board_id
+WHERE c.id = @column_id
+FOR UPDATE OF c;
@@ -0,0 +1,36 @@
+-- name: CreateInvitation :one
+INSERT INTO board_invitations (board_id, email, role, token_hash, inviter_id, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListBoardInvitations :many
+SELECT * FROM board_invitations
+WHERE board_id = $1 AND status = 'pending'
+ORDER BY created_at DESC;
+
+-- name: GetInvitationByTokenHash :one
+SELECT * FROM board_invitations
+WHERE token_hash = $1
+FOR UPDATE;
+
+-- name: UpdateInvitationStatus :execrows
+UPDATE board_invitations
+SET status = @status, responded_at = now()
+WHERE id = @id AND status = 'pending';
+
+-- name: RevokeInvitation :execrows
+UPDATE board_invitations
+SET status = 'revoked', responded_at = now()
+WHERE id = $1 AND board_id = $2 AND status = 'pending';
+
+-- name: AcceptPendingInvitations :many
+WITH accepted AS (
+    UPDATE board_invitations
+    SET status = 'accepted', responded_at = now()
+    WHERE email = @email AND status = 'pending' AND expires_at > now()
+    RETURNING board_id, role
+)
+INSERT INTO board_members (board_id, user_id, role)
+SELECT board_id, @user_id, role FROM accepted
+ON CONFLICT (board_id, user_id) DO NOTHING
+RETURNING board_id, user_id, role, created_at;
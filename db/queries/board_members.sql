@@ -0,0 +1,37 @@
+-- name: GetBoardMemberRole :one
+SELECT role FROM board_members
+WHERE board_id = $1 AND user_id = $2;
+
+-- name: ListBoardMembers :many
+SELECT bm.board_id, bm.user_id, u.email, bm.role, bm.created_at
+FROM board_members bm
+JOIN users u ON u.id = bm.user_id
+WHERE bm.board_id = $1
+ORDER BY bm.created_at, bm.user_id;
+
+-- name: AddBoardMember :one
+INSERT INTO board_members (board_id, user_id, role)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: UpdateBoardMemberRole :one
+UPDATE board_members
+SET role = $3
+WHERE board_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: RemoveBoardMember :execrows
+DELETE FROM board_members
+WHERE board_id = $1 AND user_id = $2;
+
+-- name: LockBoardOwners :many
+SELECT user_id FROM board_members
+WHERE board_id = $1 AND role = 'owner'
+ORDER BY user_id
+FOR UPDATE;
+
+-- name: GetTaskMemberRole :one
+SELECT bm.role
+FROM tasks t
+JOIN board_members bm ON bm.board_id = t.board_id
+WHERE t.id = @task_id AND bm.board_id = @board_id AND bm.user_id = @user_id;
@@ -4,17 +4,26 @@ VALUES ($1, $2)
 RETURNING *;
 
 -- name: GetBoards :many
-SELECT * FROM boards
-WHERE user_id = $1;
+SELECT b.id, b.user_id, b.name, b.created_at, b.updated_at, b.wip_mode, bm.role
+FROM boards b
+JOIN board_members bm ON bm.board_id = b.id
+WHERE bm.user_id = $1
+ORDER BY b.id;
 
 -- name: UpdateBoard :one
 UPDATE boards
 SET
     name = COALESCE(sqlc.narg('name'), name),
+    wip_mode = COALESCE(sqlc.narg('wip_mode'), wip_mode),
     updated_at = now()
-WHERE id = @id AND user_id = @user_id
-RETURNING id, user_id, name, created_at, updated_at;
+WHERE id = @id
+RETURNING *;
 
 -- name: DeleteBoard :execrows
 DELETE FROM boards
-WHERE id = @id AND user_id = @user_id;
\ No newline at end of file
+WHERE id = @id;
+
+-- name: GetBoardForUpdate :one
+SELECT * FROM boards
+WHERE id = @id
+FOR UPDATE;
@@ -0,0 +1,38 @@
+-- name: GetUserTOTP :one
+SELECT id, email, totp_secret, totp_enabled_at
+FROM users
+WHERE id = $1
+LIMIT 1;
+
+-- name: SetPendingTOTPSecret :execrows
+UPDATE users
+SET totp_secret = $2
+WHERE id = $1 AND totp_enabled_at IS NULL;
+
+-- name: EnableTOTP :execrows
+UPDATE users
+SET totp_enabled_at = now()
+WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL;
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL
+WHERE id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO user_recovery_codes (user_id, code_hash)
+SELECT @user_id::int, unnest(@code_hashes::text[]);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM user_recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE user_recovery_codes
+SET used_at = now()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT count(*)
+FROM user_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
@@ -0,0 +1,27 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+VALUES (@user_id, @name, @token_hash, @token_prefix, @scopes, now() + make_interval(days => @expires_in_days::int))
+RETURNING *;
+
+-- name: ListPersonalAccessTokens :many
+SELECT *
+FROM personal_access_tokens
+WHERE user_id = $1
+ORDER BY id DESC;
+
+-- name: GetPersonalAccessTokenByHash :one
+-- истёкшие токены не находятся, как и несуществующие
+SELECT *
+FROM personal_access_tokens
+WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > now())
+LIMIT 1;
+
+-- name: TouchPersonalAccessToken :exec
+-- last_used_at обновляется не чаще раза в минуту, чтобы не писать на каждый запрос
+UPDATE personal_access_tokens
+SET last_used_at = now()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
+
+-- name: DeletePersonalAccessToken :execrows
+DELETE FROM personal_access_tokens
+WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,3 @@
+-- name: CreateSecurityEvent :exec
+INSERT INTO security_events (user_id, event, ip, user_agent, details)
+VALUES ($1, $2, $3, $4, $5);
@@ -0,0 +1,22 @@
+-- name: SetTaskAssignees :exec
+WITH removed AS (
+    DELETE FROM task_assignees
+    WHERE task_id = @task_id AND NOT (user_id = ANY(@user_ids::int[]))
+)
+INSERT INTO task_assignees (task_id, user_id)
+SELECT @task_id, unnest(@user_ids::int[])
+ON CONFLICT (task_id, user_id) DO NOTHING;
+
+-- name: ListTaskAssignees :many
+SELECT user_id FROM task_assignees
+WHERE task_id = $1
+ORDER BY user_id;
+
+-- name: CountBoardMembersByIDs :one
+SELECT count(*) FROM board_members
+WHERE board_id = @board_id AND user_id = ANY(@user_ids::int[]);
+
+-- name: UnassignUserFromBoard :exec
+DELETE FROM task_assignees ta
+USING tasks t
+WHERE ta.task_id = t.id AND t.board_id = @board_id AND ta.user_id = @user_id;
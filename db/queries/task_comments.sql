@@ -0,0 +1,48 @@
+-- name: CreateComment :one
+INSERT INTO task_comments (task_id, user_id, parent_id, body)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetComment :one
+SELECT * FROM task_comments
+WHERE id = @id AND task_id = @task_id;
+
+-- name: ListComments :many
+SELECT * FROM task_comments
+WHERE task_id = $1 AND parent_id IS NULL
+ORDER BY created_at, id
+LIMIT $2 OFFSET $3;
+
+-- name: CountComments :one
+SELECT count(*) FROM task_comments
+WHERE task_id = $1 AND parent_id IS NULL;
+
+-- name: ListCommentReplies :many
+SELECT * FROM task_comments
+WHERE parent_id = ANY(@parent_ids::int[])
+ORDER BY created_at, id;
+
+-- name: UpdateComment :one
+WITH prev AS (
+    SELECT id, body FROM task_comments
+    WHERE id = @id AND task_id = @task_id AND deleted_at IS NULL
+    FOR UPDATE
+), history AS (
+    INSERT INTO task_comment_edits (comment_id, body, edited_by)
+    SELECT prev.id, prev.body, @edited_by FROM prev
+)
+UPDATE task_comments c
+SET body = @body, edited_at = now()
+FROM prev
+WHERE c.id = prev.id
+RETURNING c.*;
+
+-- name: SoftDeleteComment :execrows
+UPDATE task_comments
+SET deleted_at = now()
+WHERE id = @id AND task_id = @task_id AND deleted_at IS NULL;
+
+-- name: ListCommentEdits :many
+SELECT * FROM task_comment_edits
+WHERE comment_id = $1
+ORDER BY edited_at DESC, id DESC;
@@ -1,11 +1,14 @@
 -- name: CreateTask :one
-INSERT INTO tasks (board_id, user_id, title, description, status, priority, deadline)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, user_id, title, description, status, priority, deadline, created_at, updated_at, board_id;
+INSERT INTO tasks (board_id, user_id, title, description, status, priority, deadline, column_id, rank)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, user_id, title, description, status, priority, deadline, created_at, updated_at, board_id, column_id, rank;
 
 -- name: GetTasks :many
 SELECT 
-  id, user_id, title, description, status, priority, deadline, created_at, updated_at, board_id
+  id, user_id, title, description, status, priority, deadline, created_at, updated_at, board_id, column_id, rank,
+  ARRAY(
+    SELECT ta.user_id FROM task_assignees ta WHERE ta.task_id = tasks.id ORDER BY ta.user_id
+  )::int[] AS assignee_ids
 FROM tasks
 WHERE board_id = sqlc.arg(board_id)
   AND (
@@ -37,8 +40,50 @@ ORDER BY
   CASE WHEN sqlc.arg(sort_code)::int = 1 THEN created_at END ASC,
   CASE WHEN sqlc.arg(sort_code)::int = 2 THEN deadline END ASC NULLS LAST,
   CASE WHEN sqlc.arg(sort_code)::int = 3 THEN deadline END DESC NULLS LAST,
+  CASE WHEN sqlc.arg(sort_code)::int = 4 THEN rank END ASC,
   created_at DESC;
 
+-- name: GetAssignedTasks :many
+SELECT
+  t.id, t.user_id, t.title, t.description, t.status, t.priority, t.deadline, t.created_at, t.updated_at, t.board_id, t.column_id, t.rank,
+  ARRAY(
+    SELECT ta.user_id FROM task_assignees ta WHERE ta.task_id = t.id ORDER BY ta.user_id
+  )::int[] AS assignee_ids
+FROM tasks t
+JOIN task_assignees a ON a.task_id = t.id AND a.user_id = sqlc.arg(user_id)
+JOIN board_members bm ON bm.board_id = t.board_id AND bm.user_id = sqlc.arg(user_id)
+WHERE (
+    COALESCE(sqlc.arg(search)::text, '') = '' OR
+    t.title ILIKE '%' || sqlc.arg(search)::text || '%' OR
+    t.description ILIKE '%' || sqlc.arg(search)::text || '%'
+  )
+  AND (
+    COALESCE(sqlc.arg(status)::text, '') = '' OR
+    t.status = sqlc.arg(status)
+  )
+  AND (
+    COALESCE(sqlc.arg(priority)::text, '') = '' OR
+    t.priority = sqlc.arg(priority)
+  )
+  AND (
+    sqlc.arg(has_deadline_set)::bool = false
+    OR (
+      sqlc.arg(has_deadline_set)::bool = true AND
+      (
+        (sqlc.arg(has_deadline)::bool = true AND t.deadline IS NOT NULL)
+        OR
+        (sqlc.arg(has_deadline)::bool = false AND t.deadline IS NULL)
+      )
+    )
+  )
+ORDER BY
+  CASE WHEN sqlc.arg(sort_code)::int = 0 THEN t.created_at END DESC,
+  CASE WHEN sqlc.arg(sort_code)::int = 1 THEN t.created_at END ASC,
+  CASE WHEN sqlc.arg(sort_code)::int = 2 THEN t.deadline END ASC NULLS LAST,
+  CASE WHEN sqlc.arg(sort_code)::int = 3 THEN t.deadline END DESC NULLS LAST,
+  CASE WHEN sqlc.arg(sort_code)::int = 4 THEN t.rank END ASC,
+  t.created_at DESC;
+
 -- name: UpdateTask :one
 UPDATE tasks
 SET
@@ -47,10 +92,65 @@ SET
     status = COALESCE(sqlc.narg('status'), status),
     priority = COALESCE(sqlc.narg('priority'), priority),
     deadline = COALESCE(sqlc.narg('deadline'), deadline),
+    column_id = COALESCE(sqlc.narg('column_id'), column_id),
+    rank = COALESCE(sqlc.narg('rank'), rank),
     updated_at = now()
-WHERE id = @id AND board_id = @board_id AND user_id = @user_id
+WHERE id = @id AND board_id = @board_id
 RETURNING *;
 
 -- name: DeleteTask :execrows
 DELETE FROM tasks
-WHERE id = @id AND board_id = @board_id AND user_id = @user_id;
\ No newline at end of file
+WHERE id = @id AND board_id = @board_id;
+
+-- name: CountColumnTasks :one
+SELECT count(*) FROM tasks
+WHERE column_id = $1;
+
+-- name: SyncColumnStatus :exec
+UPDATE tasks
+SET status = @name
+WHERE column_id = @column_id;
+
+-- name: GetTaskForUpdate :one
+SELECT * FROM tasks
+WHERE id = @id AND board_id = @board_id
+FOR UPDATE;
+
+-- name: MoveTask :one
+UPDATE tasks
+SET column_id = @column_id, status = @status, rank = @rank, updated_at = now()
+WHERE id = @id AND board_id = @board_id
+RETURNING *;
+
+-- name: GetTaskRank :one
+SELECT column_id, rank FROM tasks
+WHERE id = @id AND board_id = @board_id;
+
+-- name: GetNextRank :one
+SELECT rank FROM tasks
+WHERE column_id = @column_id AND id <> @task_id AND rank > @rank
+ORDER BY rank, id
+LIMIT 1;
+
+-- name: GetPrevRank :one
+SELECT rank FROM tasks
+WHERE column_id = @column_id AND id <> @task_id AND rank < @rank
+ORDER BY rank DESC, id DESC
+LIMIT 1;
+
+-- name: GetLastRank :one
+SELECT rank FROM tasks
+WHERE column_id = @column_id AND id <> @task_id
+ORDER BY rank DESC, id DESC
+LIMIT 1;
+
+-- name: ListColumnRanks :many
+SELECT id, rank FROM tasks
+WHERE column_id = @column_id AND id <> @task_id
+ORDER BY rank, id;
+
+-- name: UpdateTaskRanks :exec
+UPDATE tasks t
+SET rank = u.rank
+FROM unnest(@ids::int[], @ranks::text[]) AS u(id, rank)
+WHERE t.id = u.id;
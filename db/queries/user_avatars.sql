@@ -0,0 +1,13 @@
+-- name: UpsertUserAvatar :exec
+INSERT INTO user_avatars (user_id, content_type, data)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET content_type = EXCLUDED.content_type, data = EXCLUDED.data, updated_at = now();
+
+-- name: GetUserAvatar :one
+SELECT * FROM user_avatars
+WHERE user_id = $1;
+
+-- name: DeleteUserAvatar :execrows
+DELETE FROM user_avatars
+WHERE user_id = $1;
@@ -4,7 +4,58 @@ VALUES ($1, $2)
 RETURNING id, email, password;
 
 -- name: GetUserByEmail :one
-SELECT id, email, password
+SELECT id, email, password, email_verified_at, totp_enabled_at
 FROM users
 WHERE email = $1
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: GetUserByID :one
+SELECT id, email, email_verified_at, totp_enabled_at
+FROM users
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetUserPassword :one
+SELECT email, password
+FROM users
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetUserProfile :one
+SELECT id, email, email_verified_at, totp_enabled_at, display_name, avatar_url, timezone, locale, created_at
+FROM users
+WHERE id = $1
+LIMIT 1;
+
+-- name: UpdateUserProfile :one
+-- Пустая строка в display_name или avatar_url очищает поле
+UPDATE users
+SET
+    display_name = NULLIF(COALESCE(sqlc.narg('display_name'), display_name), ''),
+    avatar_url = NULLIF(COALESCE(sqlc.narg('avatar_url'), avatar_url), ''),
+    timezone = COALESCE(sqlc.narg('timezone'), timezone),
+    locale = COALESCE(sqlc.narg('locale'), locale)
+WHERE id = sqlc.arg(id)
+RETURNING id, email, email_verified_at, totp_enabled_at, display_name, avatar_url, timezone, locale, created_at;
+
+-- name: ListUserSummaries :many
+SELECT id, email, display_name, avatar_url
+FROM users
+WHERE id = ANY(@ids::int[])
+ORDER BY id;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2
+WHERE id = $1;
+
+-- name: RehashUserPassword :execrows
+-- Заменяет хеш, только если пароль не сменили с момента проверки
+UPDATE users
+SET password = sqlc.arg(new_password)
+WHERE id = sqlc.arg(id) AND password = sqlc.arg(old_password);
+
+-- name: MarkEmailVerified :execrows
+UPDATE users
+SET email_verified_at = now()
+WHERE id = $1 AND email_verified_at IS NULL;
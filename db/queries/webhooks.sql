@@ -0,0 +1,91 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (board_id, url, secret, events, created_by)
+VALUES (@board_id, @url, @secret, @events, @created_by)
+RETURNING *;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhooks
+WHERE board_id = $1
+ORDER BY id;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = @id AND board_id = @board_id;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET
+    url = COALESCE(sqlc.narg('url'), url),
+    secret = COALESCE(sqlc.narg('secret'), secret),
+    events = COALESCE(sqlc.narg('events')::text[], events),
+    active = COALESCE(sqlc.narg('active'), active),
+    updated_at = now()
+WHERE id = @id AND board_id = @board_id
+RETURNING *;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = @id AND board_id = @board_id;
+
+-- name: EnqueueWebhookDeliveries :exec
+INSERT INTO webhook_deliveries (webhook_id, event, payload)
+SELECT id, @event::text, @payload
+FROM webhooks
+WHERE board_id = @board_id AND active AND @event::text = ANY(events);
+
+-- name: ClaimDueWebhookDeliveries :many
+-- Забирает пачку созревших доставок активных вебхуков. Аренда (next_attempt_at в будущем)
+-- не даёт другому экземпляру отправить ту же доставку, а после падения воркера она вернётся в очередь.
+UPDATE webhook_deliveries d
+SET next_attempt_at = now() + make_interval(secs => @lease_seconds::int), updated_at = now()
+FROM webhooks w
+WHERE w.id = d.webhook_id
+  AND d.id IN (
+    SELECT dd.id FROM webhook_deliveries dd
+    JOIN webhooks ww ON ww.id = dd.webhook_id
+    WHERE dd.status = 'pending' AND dd.next_attempt_at <= now() AND ww.active
+    ORDER BY dd.next_attempt_at
+    LIMIT @batch_size
+    FOR UPDATE OF dd SKIP LOCKED
+  )
+RETURNING d.id, d.webhook_id, d.event, d.payload, d.attempts, w.url, w.secret;
+
+-- name: CreateWebhookAttempt :exec
+INSERT INTO webhook_attempts (delivery_id, attempt, status_code, error, duration_ms)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: UpdateWebhookDeliveryResult :exec
+UPDATE webhook_deliveries
+SET
+    status = @status,
+    attempts = @attempts,
+    next_attempt_at = now() + make_interval(secs => @retry_in::int),
+    last_status_code = @last_status_code,
+    last_error = @last_error,
+    updated_at = now()
+WHERE id = @id;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = @webhook_id
+ORDER BY id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: GetWebhookDelivery :one
+SELECT d.* FROM webhook_deliveries d
+JOIN webhooks w ON w.id = d.webhook_id
+WHERE d.id = @id AND d.webhook_id = @webhook_id AND w.board_id = @board_id;
+
+-- name: ListWebhookAttempts :many
+SELECT * FROM webhook_attempts
+WHERE delivery_id = $1
+ORDER BY attempt;
+
+-- name: RedeliverWebhookDelivery :one
+-- повторная доставка — новая запись с тем же событием; история исходной не меняется
+INSERT INTO webhook_deliveries (webhook_id, event, payload)
+SELECT d.webhook_id, d.event, d.payload
+FROM webhook_deliveries d
+JOIN webhooks w ON w.id = d.webhook_id
+WHERE d.id = @id AND d.webhook_id = @webhook_id AND w.board_id = @board_id
+RETURNING *;
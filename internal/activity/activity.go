@@ -0,0 +1,163 @@
+package activity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/db"
+)
+
+// Сущности журнала
+const (
+	EntityBoard   = "board"
+	EntityTask    = "task"
+	EntityComment = "comment"
+)
+
+// Действия над сущностями
+const (
+	Created = "created"
+	Updated = "updated"
+	Deleted = "deleted"
+)
+
+// Change — значение поля до и после изменения
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Fields — снимок отслеживаемых полей сущности
+type Fields map[string]any
+
+// Event — запись журнала. TaskID = 0 для событий уровня доски.
+type Event struct {
+	BoardID  int32
+	TaskID   int32
+	ActorID  int32
+	Entity   string
+	EntityID int32
+	Action   string
+	Changes  map[string]Change
+}
+
+// Name — имя события для подписчиков, например "task.updated"
+func (e Event) Name() string {
+	return e.Entity + "." + e.Action
+}
+
+// webhookPayload — тело, которое получают вебхуки доски
+type webhookPayload struct {
+	Event      string            `json:"event"`
+	BoardID    int32             `json:"board_id"`
+	TaskID     int32             `json:"task_id,omitempty"`
+	EntityID   int32             `json:"entity_id"`
+	ActorID    int32             `json:"actor_id,omitempty"`
+	Changes    map[string]Change `json:"changes"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// Record пишет событие через q и ставит его в очередь вебхуков доски;
+// вызывается внутри той же транзакции, что и само изменение
+func Record(ctx context.Context, q db.Querier, e Event) error {
+	changes := e.Changes
+	if changes == nil {
+		changes = map[string]Change{}
+	}
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	err = q.CreateActivityEvent(ctx, db.CreateActivityEventParams{
+		BoardID:  e.BoardID,
+		TaskID:   pgtype.Int4{Int32: e.TaskID, Valid: e.TaskID != 0},
+		ActorID:  pgtype.Int4{Int32: e.ActorID, Valid: e.ActorID != 0},
+		Entity:   e.Entity,
+		EntityID: e.EntityID,
+		Action:   e.Action,
+		Changes:  raw,
+	})
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(webhookPayload{
+		Event:      e.Name(),
+		BoardID:    e.BoardID,
+		TaskID:     e.TaskID,
+		EntityID:   e.EntityID,
+		ActorID:    e.ActorID,
+		Changes:    changes,
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return q.EnqueueWebhookDeliveries(ctx, db.EnqueueWebhookDeliveriesParams{
+		Event:   e.Name(),
+		Payload: payload,
+		BoardID: e.BoardID,
+	})
+}
+
+// Diff возвращает поля, значения которых отличаются. Значения сравниваются
+// в JSON-представлении, чтобы время и указатели сравнивались по содержимому.
+// nil в before означает создание, nil в after — удаление.
+func Diff(before, after Fields) map[string]Change {
+	changes := map[string]Change{}
+	for k, v := range after {
+		old, ok := before[k]
+		if !ok || !sameJSON(old, v) {
+			changes[k] = Change{Before: old, After: v}
+		}
+	}
+	for k, v := range before {
+		if _, ok := after[k]; !ok {
+			changes[k] = Change{Before: v, After: nil}
+		}
+	}
+	return changes
+}
+
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// TaskFields — отслеживаемые поля задачи
+func TaskFields(t db.Task, assignees []int32) Fields {
+	f := Fields{
+		"title":       t.Title,
+		"description": text(t.Description),
+		"status":      text(t.Status),
+		"priority":    t.Priority,
+		"deadline":    timestamp(t.Deadline),
+	}
+	if assignees != nil {
+		f["assignee_ids"] = assignees
+	}
+	return f
+}
+
+// BoardFields — отслеживаемые поля доски
+func BoardFields(b db.Board) Fields {
+	return Fields{"name": b.Name, "wip_mode": b.WipMode}
+}
+
+func text(t pgtype.Text) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.String
+}
+
+func timestamp(t pgtype.Timestamp) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.UTC().Format(time.RFC3339)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/sqszy/TaskTracker/internal/db"
 )
 
 type TokenPair struct {
@@ -19,15 +20,57 @@ type TokenPair struct {
 
 type Claims struct {
 	UserID int32 `json:"user_id"`
+	// SessionID связывает токены одного входа: при обновлении jti меняется, sid — нет
+	SessionID string `json:"sid,omitempty"`
+	// TokenType — "access" или "refresh": при подписи общими ключами токены одного
+	// вида нельзя выдать за другой, как это обеспечивали разные секреты HS256
+	TokenType string `json:"typ,omitempty"`
 	jwt.RegisteredClaims
 }
 
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+var ErrRefreshRevoked = errors.New("refresh token revoked or not found")
+
+// DefaultRefreshGrace — сколько после обмена прежний refresh-токен считается не кражей,
+// а параллельным запросом того же клиента (например, двух вкладок)
+const DefaultRefreshGrace = 10 * time.Second
+
+// ErrRefreshTokenReused — предъявлен уже обменянный refresh-токен; сессия отозвана
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// ReuseError описывает обнаруженное повторное использование для журнала безопасности
+type ReuseError struct {
+	UserID    int32
+	SessionID string
+	TokenID   string
+}
+
+func (e *ReuseError) Error() string {
+	return ErrRefreshTokenReused.Error()
+}
+
+func (e *ReuseError) Unwrap() error {
+	return ErrRefreshTokenReused
+}
+
 type Service struct {
 	redis         *redis.Client
 	accessSecret  []byte
 	refreshSecret []byte
 	accessTTL     time.Duration
 	refreshTTL    time.Duration
+	// tokens — хранилище personal access token; nil, если они не включены
+	tokens db.Querier
+	// keys — ключи RS256/EdDSA; nil — подпись секретами HS256
+	keys *KeyManager
+	// revoked — локальный кеш проверки отзыва access-токенов
+	revoked *revocationCache
+	// refreshGrace — окно для параллельных обменов одного refresh-токена
+	refreshGrace time.Duration
 }
 
 func NewService(r *redis.Client, accessSecret, refreshSecret string, accessTTL, refreshTTL time.Duration) *Service {
@@ -37,44 +80,86 @@ func NewService(r *redis.Client, accessSecret, refreshSecret string, accessTTL,
 		refreshSecret: []byte(refreshSecret),
 		accessTTL:     accessTTL,
 		refreshTTL:    refreshTTL,
+		revoked:       newRevocationCache(DefaultRevocationCacheTTL),
+		refreshGrace:  DefaultRefreshGrace,
 	}
 }
 
+// WithKeys включает подпись токенов ключами KeyManager с kid в заголовке.
+// Токены HS256, выданные до переключения, продолжают приниматься до истечения срока.
+func (s *Service) WithKeys(km *KeyManager) *Service {
+	s.keys = km
+	return s
+}
+
+// WithRefreshGrace задаёт окно, в которое повтор только что обменянного refresh-токена
+// отклоняется без отзыва сессии; 0 — любой повтор считается кражей
+func (s *Service) WithRefreshGrace(d time.Duration) *Service {
+	s.refreshGrace = d
+	return s
+}
+
+// GenerateTokenPair начинает новую сессию без сведений об устройстве
 func (s *Service) GenerateTokenPair(ctx context.Context, userID int32) (*TokenPair, error) {
+	return s.StartSession(ctx, userID, SessionMeta{})
+}
+
+// StartSession выдаёт пару токенов для нового входа и записывает сессию в индекс пользователя
+func (s *Service) StartSession(ctx context.Context, userID int32, meta SessionMeta) (*TokenPair, error) {
 	now := time.Now()
+	return s.issue(ctx, userID, Session{
+		ID:         uuid.NewString(),
+		UserAgent:  meta.UserAgent,
+		IP:         meta.IP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}, "")
+}
+
+// issue выдаёт пару токенов сессии; prevJTI — обменянный refresh-токен, "" для нового входа
+func (s *Service) issue(ctx context.Context, userID int32, sess Session, prevJTI string) (*TokenPair, error) {
+	now, err := s.issueTime(ctx, userID, sess.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
 	accessExp := now.Add(s.accessTTL)
 	refreshExp := now.Add(s.refreshTTL)
 
 	accessClaims := &Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sess.ID,
+		TokenType: tokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(accessExp),
 			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	accessTok := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessStr, err := accessTok.SignedString(s.accessSecret)
+	accessStr, err := s.sign(accessClaims, s.accessSecret)
 	if err != nil {
 		return nil, err
 	}
 
 	jti := uuid.NewString()
 	refreshClaims := &Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sess.ID,
+		TokenType: tokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(refreshExp),
 			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	refreshTok := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshStr, err := refreshTok.SignedString(s.refreshSecret)
+	refreshStr, err := s.sign(refreshClaims, s.refreshSecret)
 	if err != nil {
 		return nil, err
 	}
 
-	key := "refresh:" + jti
-	if err := s.redis.Set(ctx, key, strconv.Itoa(int(userID)), s.refreshTTL).Err(); err != nil {
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, refreshKey(jti), strconv.Itoa(int(userID)), s.refreshTTL)
+	s.saveSession(ctx, pipe, userID, sess, jti, prevJTI)
+	if _, err := pipe.Exec(ctx); err != nil {
 		return nil, err
 	}
 
@@ -85,64 +170,182 @@ func (s *Service) GenerateTokenPair(ctx context.Context, userID int32) (*TokenPa
 	}, nil
 }
 
-func (s *Service) ValidateAccessToken(tokenStr string) (int32, error) {
-	tok, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return s.accessSecret, nil
-	})
+func (s *Service) ValidateAccessToken(ctx context.Context, tokenStr string) (int32, error) {
+	claims, err := s.ParseAccessToken(ctx, tokenStr)
 	if err != nil {
 		return 0, err
 	}
-	claims, ok := tok.Claims.(*Claims)
-	if !ok || !tok.Valid {
-		return 0, errors.New("invalid token")
-	}
 	return claims.UserID, nil
 }
 
+// ParseAccessToken проверяет access-токен, в том числе не отозван ли он, и возвращает его claims
+func (s *Service) ParseAccessToken(ctx context.Context, tokenStr string) (*Claims, error) {
+	claims, err := s.parse(tokenStr, s.accessSecret, tokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// CheckAccessToken повторяет проверку уже принятого access-токена — для долгих соединений
+// вроде потока событий: не истёк ли он и не отозван ли с тех пор
+func (s *Service) CheckAccessToken(ctx context.Context, claims *Claims) error {
+	if claims.ExpiresAt != nil && !time.Now().Before(claims.ExpiresAt.Time) {
+		return jwt.ErrTokenExpired
+	}
+	return s.checkRevoked(ctx, claims)
+}
+
+// Refresh обновляет пару токенов без сведений об устройстве
 func (s *Service) Refresh(ctx context.Context, refreshStr string) (*TokenPair, error) {
-	tok, err := jwt.ParseWithClaims(refreshStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return s.refreshSecret, nil
-	})
+	return s.RefreshSession(ctx, refreshStr, SessionMeta{})
+}
+
+// RefreshSession меняет refresh-токен на новую пару в рамках той же сессии
+// и обновляет время последнего использования; пустые поля meta не перезаписывают сохранённые.
+func (s *Service) RefreshSession(ctx context.Context, refreshStr string, meta SessionMeta) (*TokenPair, error) {
+	claims, err := s.parse(refreshStr, s.refreshSecret, tokenTypeRefresh)
 	if err != nil {
 		return nil, err
 	}
-	claims, ok := tok.Claims.(*Claims)
-	if !ok || !tok.Valid || claims.ID == "" {
+	if claims.ID == "" {
 		return nil, errors.New("invalid refresh token")
 	}
+	// выход везде и сброс пароля отзывают и refresh-токены без sid, которых нет в индексе сессий
+	if err := s.checkRefreshRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
 
-	key := "refresh:" + claims.ID
-	val, err := s.redis.Get(ctx, key).Result()
+	// GETDEL: из двух одновременных обменов одного токена успешен только один
+	val, err := s.redis.GetDel(ctx, refreshKey(claims.ID)).Result()
 	if err == redis.Nil {
-		return nil, errors.New("refresh token revoked or not found")
+		return nil, s.checkReuse(ctx, claims)
 	}
 	if err != nil {
 		return nil, err
 	}
 
 	if strconv.Itoa(int(claims.UserID)) != val {
-		_ = s.redis.Del(ctx, key)
 		return nil, errors.New("refresh token mismatch")
 	}
 
-	if err := s.redis.Del(ctx, key).Err(); err != nil {
+	now := time.Now()
+	sess := Session{ID: claims.SessionID, CreatedAt: now}
+	if sess.ID == "" {
+		// токены, выданные до появления сессий
+		sess.ID = uuid.NewString()
+	} else if stored, err := s.getSession(ctx, claims.UserID, sess.ID); err == nil {
+		sess = stored
+	} else if !errors.Is(err, ErrSessionNotFound) {
 		return nil, err
 	}
-
-	return s.GenerateTokenPair(ctx, claims.UserID)
+	sess.LastUsedAt = now
+	if meta.UserAgent != "" {
+		sess.UserAgent = meta.UserAgent
+	}
+	if meta.IP != "" {
+		sess.IP = meta.IP
+	}
+	return s.issue(ctx, claims.UserID, sess, claims.ID)
 }
 
+// Revoke отзывает refresh-токен и завершает его сессию
 func (s *Service) Revoke(ctx context.Context, refreshStr string) error {
-	tok, err := jwt.ParseWithClaims(refreshStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return s.refreshSecret, nil
-	})
+	claims, err := s.parse(refreshStr, s.refreshSecret, tokenTypeRefresh)
 	if err != nil {
 		return err
 	}
-	claims, ok := tok.Claims.(*Claims)
-	if !ok || claims.ID == "" {
+	if claims.ID == "" {
 		return errors.New("invalid refresh token")
 	}
-	key := "refresh:" + claims.ID
-	return s.redis.Del(ctx, key).Err()
+	if err := s.redis.Del(ctx, refreshKey(claims.ID)).Err(); err != nil {
+		return err
+	}
+	if claims.SessionID == "" {
+		return nil
+	}
+	err = s.RevokeSession(ctx, claims.UserID, claims.SessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	return err
+}
+
+// checkReuse разбирает предъявление уже недействительного refresh-токена.
+// Все токены одной сессии образуют семейство: действует только последний выданный.
+// Если сессия жива, а предъявлен один из прежних токенов, значит, токен украден
+// и обменян кем-то ещё — сессия отзывается целиком вместе с токенами «наследников».
+// Исключение — токен, обменянный не раньше refreshGrace назад: так выглядят два
+// одновременных обновления одного клиента, проигравшему просто отказываем.
+func (s *Service) checkReuse(ctx context.Context, claims *Claims) error {
+	if claims.SessionID == "" {
+		return ErrRefreshRevoked
+	}
+	fields, err := s.redis.HMGet(ctx, sessionKey(claims.SessionID), "jti", "prev_jti", "rotated_at").Result()
+	if err != nil {
+		return err
+	}
+	current, _ := fields[0].(string)
+	if current == "" {
+		return ErrRefreshRevoked
+	}
+	if current == claims.ID {
+		// токен только что обменян параллельным запросом и ещё не заменён в сессии
+		return ErrRefreshRevoked
+	}
+	if prev, _ := fields[1].(string); prev == claims.ID {
+		rotatedAt, _ := fields[2].(string)
+		ms, _ := strconv.ParseInt(rotatedAt, 10, 64)
+		if time.Since(time.UnixMilli(ms)) < s.refreshGrace {
+			return ErrRefreshRevoked
+		}
+	}
+	if err := s.RevokeSession(ctx, claims.UserID, claims.SessionID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+	return &ReuseError{UserID: claims.UserID, SessionID: claims.SessionID, TokenID: claims.ID}
+}
+
+// sign подписывает токен текущим ключом KeyManager или, если он не настроен, секретом HS256
+func (s *Service) sign(claims *Claims, secret []byte) (string, error) {
+	if s.keys != nil {
+		return s.keys.sign(claims)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// parse проверяет подпись и вид токена. Токены с kid проверяются ключами KeyManager,
+// токены HS256 без kid — секретом (в том числе выданные до перехода на ключи).
+func (s *Service) parse(tokenStr string, secret []byte, typ string) (*Claims, error) {
+	tok, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Header["kid"]; ok {
+			if s.keys == nil {
+				return nil, ErrUnknownKey
+			}
+			return s.keys.verificationKey(t)
+		}
+		if t.Method != jwt.SigningMethodHS256 || len(secret) == 0 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), AlgRS256, AlgEdDSA}))
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := tok.Claims.(*Claims)
+	if !ok || !tok.Valid {
+		return nil, errors.New("invalid token")
+	}
+	// у старых токенов HS256 вида нет — их различают секреты
+	if claims.TokenType != typ && (claims.TokenType != "" || tok.Method != jwt.SigningMethodHS256) {
+		return nil, errors.New("invalid token type")
+	}
+	return claims, nil
+}
+
+func refreshKey(jti string) string {
+	return "refresh:" + jti
 }
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// breachedPrefixLen — длина префикса SHA-1, как в range API Have I Been Pwned
+const breachedPrefixLen = 5
+
+// BreachedCorpus — локальный список утёкших паролей в виде SHA-1, разложенный по префиксам.
+// Файл — по строке на хеш: 40 hex-символов SHA-1, после двоеточия может идти число утечек
+// (формат выгрузки pwnedpasswords). Пустые строки и строки с # пропускаются.
+// Сеть не используется: файл готовится заранее и кладётся рядом с сервисом.
+type BreachedCorpus struct {
+	// префикс -> множество оставшихся 35 символов хеша
+	ranges map[string]map[string]struct{}
+	size   int
+}
+
+func LoadBreachedCorpus(path string) (*BreachedCorpus, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &BreachedCorpus{ranges: map[string]map[string]struct{}{}}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("%s:%d: expected SHA-1 hex", path, n)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("%s:%d: expected SHA-1 hex", path, n)
+		}
+		c.add(hash)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *BreachedCorpus) add(hash string) {
+	prefix, suffix := hash[:breachedPrefixLen], hash[breachedPrefixLen:]
+	set, ok := c.ranges[prefix]
+	if !ok {
+		set = map[string]struct{}{}
+		c.ranges[prefix] = set
+	}
+	if _, ok := set[suffix]; !ok {
+		set[suffix] = struct{}{}
+		c.size++
+	}
+}
+
+// Len — число хешей в списке
+func (c *BreachedCorpus) Len() int {
+	return c.size
+}
+
+// Contains сообщает, есть ли пароль в списке утёкших
+func (c *BreachedCorpus) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, ok := c.ranges[hash[:breachedPrefixLen]][hash[breachedPrefixLen:]]
+	return ok
+}
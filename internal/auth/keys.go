@@ -0,0 +1,437 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Алгоритмы подписи с открытым ключом
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+const (
+	// keysKey — hash kid → закрытый ключ; общий для всех экземпляров API
+	keysKey = "jwt_keys"
+	// rotateLockKey не даёт двум экземплярам одновременно выпустить новый ключ
+	rotateLockKey = "jwt_keys:rotate"
+	rotateLockTTL = 30 * time.Second
+	rsaKeyBits    = 2048
+	// reloadOnMissEvery — как часто можно перечитывать ключи, встретив незнакомый kid
+	reloadOnMissEvery = 5 * time.Second
+	// JWKSMaxAge — сколько клиенты кешируют опубликованный набор ключей
+	JWKSMaxAge = 5 * time.Minute
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type signingKey struct {
+	ID        string
+	Alg       string
+	Private   crypto.Signer
+	CreatedAt time.Time
+	// ActivatesAt — с этого момента ключ подписывает токены; до того он только опубликован
+	ActivatesAt time.Time
+}
+
+func (k *signingKey) active(now time.Time) bool {
+	return !k.ActivatesAt.After(now)
+}
+
+func (k *signingKey) method() jwt.SigningMethod {
+	if k.Alg == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// storedKey — формат ключа в Redis
+type storedKey struct {
+	Alg         string `json:"alg"`
+	Private     []byte `json:"private"`                // PKCS#8 DER
+	CreatedAt   int64  `json:"created_at"`             // unix, мс
+	ActivatesAt int64  `json:"activates_at,omitempty"` // unix, мс; у ключей до её появления — CreatedAt
+}
+
+// KeyManager хранит ключи подписи JWT в Redis и по расписанию выпускает новые.
+// Новый ключ публикуется заранее и начинает подписывать только через publishAhead:
+// к этому времени он есть в любой закешированной копии JWKS. Подписывает самый новый
+// из вступивших в силу ключей, проверка принимает все ключи, пока токены,
+// подписанные ими, ещё могут быть действительны.
+type KeyManager struct {
+	redis       *redis.Client
+	alg         string
+	rotateEvery time.Duration
+	// retainFor — сколько ключ нужен для проверки после выхода из обращения (срок жизни токенов)
+	retainFor time.Duration
+	// publishAhead — за сколько до начала подписи ключ появляется в JWKS
+	publishAhead time.Duration
+
+	mu       sync.RWMutex
+	keys     []*signingKey // от новых к старым по ActivatesAt
+	loadedAt time.Time
+}
+
+// NewKeyManager создаёт менеджер ключей для алгоритма RS256 или EdDSA
+func NewKeyManager(r *redis.Client, alg string, rotateEvery, retainFor time.Duration) (*KeyManager, error) {
+	if alg != AlgRS256 && alg != AlgEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if rotateEvery <= 0 {
+		return nil, errors.New("key rotation interval must be positive")
+	}
+	return &KeyManager{redis: r, alg: alg, rotateEvery: rotateEvery, retainFor: retainFor, publishAhead: JWKSMaxAge}, nil
+}
+
+// WithPublishAhead меняет, за сколько до начала подписи публикуется новый ключ
+// (по умолчанию JWKSMaxAge); должно быть не меньше срока кеширования JWKS у клиентов
+func (m *KeyManager) WithPublishAhead(d time.Duration) *KeyManager {
+	m.publishAhead = d
+	return m
+}
+
+// Run проверяет расписание ротации и перечитывает ключи каждые interval, пока не отменён ctx.
+// Другие экземпляры узнают о новом ключе при следующем перечитывании.
+func (m *KeyManager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := m.Rotate(ctx); err != nil {
+			log.Println("[keys] rotate:", err)
+		}
+	}
+}
+
+// Rotate выпускает следующий ключ, если текущему скоро на смену (или ключей ещё нет),
+// удаляет ключи, которые больше не нужны для проверки, и перечитывает набор.
+// Возвращает true, если ключ был выпущен этим вызовом.
+func (m *KeyManager) Rotate(ctx context.Context) (bool, error) {
+	if err := m.Load(ctx); err != nil {
+		return false, err
+	}
+	if _, ok := m.nextActivation(time.Now()); !ok {
+		return false, nil
+	}
+
+	locked, err := m.redis.SetNX(ctx, rotateLockKey, 1, rotateLockTTL).Result()
+	if err != nil || !locked {
+		// ключ выпускает другой экземпляр
+		return false, err
+	}
+	defer m.redis.Del(ctx, rotateLockKey)
+
+	// пока ждали блокировку, ключ мог выпустить другой экземпляр
+	if err := m.Load(ctx); err != nil {
+		return false, err
+	}
+	activatesAt, ok := m.nextActivation(time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	key, err := generateKey(m.alg)
+	if err != nil {
+		return false, err
+	}
+	key.ActivatesAt = activatesAt
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return false, err
+	}
+	raw, err := json.Marshal(storedKey{
+		Alg:         key.Alg,
+		Private:     der,
+		CreatedAt:   key.CreatedAt.UnixMilli(),
+		ActivatesAt: key.ActivatesAt.UnixMilli(),
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := m.redis.HSet(ctx, keysKey, key.ID, raw).Err(); err != nil {
+		return false, err
+	}
+	log.Println("[keys] new signing key", key.ID, key.Alg, "signs from", key.ActivatesAt.Format(time.RFC3339))
+
+	if err := m.prune(ctx); err != nil {
+		log.Println("[keys] prune:", err)
+	}
+	return true, m.Load(ctx)
+}
+
+// Load перечитывает набор ключей из Redis
+func (m *KeyManager) Load(ctx context.Context) error {
+	all, err := m.redis.HGetAll(ctx, keysKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]*signingKey, 0, len(all))
+	for kid, raw := range all {
+		key, err := decodeKey(kid, raw)
+		if err != nil {
+			log.Println("[keys] skip key", kid+":", err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	keys, _ = m.split(keys, time.Now())
+
+	m.mu.Lock()
+	m.keys = keys
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// nextActivation решает, нужен ли новый ключ, и возвращает, когда он начнёт подписывать.
+// Ключ нужен, если ключей нет (первый подписывает сразу), текущий выпущен для другого
+// алгоритма или до его смены осталось меньше publishAhead, а следующий ещё не выпущен.
+func (m *KeyManager) nextActivation(now time.Time) (time.Time, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.keys) == 0 {
+		return now, true
+	}
+	if newest := m.keys[0]; !newest.active(now) && newest.Alg == m.alg {
+		return time.Time{}, false
+	}
+	cur := m.signingKeyAt(now)
+	earliest := now.Add(m.publishAhead)
+	if cur == nil || cur.Alg != m.alg {
+		return earliest, true
+	}
+	due := cur.ActivatesAt.Add(m.rotateEvery)
+	if due.Sub(now) > m.publishAhead {
+		return time.Time{}, false
+	}
+	if due.Before(earliest) {
+		due = earliest
+	}
+	return due, true
+}
+
+// split делит ключи на нужные и устаревшие и сортирует нужные от новых к старым.
+// Ключ устарел, когда его сменил следующий и токены, подписанные им до смены, истекли.
+func (m *KeyManager) split(keys []*signingKey, now time.Time) (live, stale []*signingKey) {
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ActivatesAt.After(keys[j].ActivatesAt) })
+	var replacedAt time.Time // когда вступил в силу ключ, сменивший текущий
+	for _, k := range keys {
+		if !replacedAt.IsZero() && now.Sub(replacedAt) > m.retainFor {
+			stale = append(stale, k)
+		} else {
+			live = append(live, k)
+		}
+		if k.active(now) {
+			replacedAt = k.ActivatesAt
+		}
+	}
+	return live, stale
+}
+
+// prune удаляет из Redis ключи, которыми уже не может быть подписан ни один действующий токен
+func (m *KeyManager) prune(ctx context.Context) error {
+	all, err := m.redis.HGetAll(ctx, keysKey).Result()
+	if err != nil {
+		return err
+	}
+	var stale []string
+	var keys []*signingKey
+	for kid, raw := range all {
+		key, err := decodeKey(kid, raw)
+		if err != nil {
+			stale = append(stale, kid)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	_, expired := m.split(keys, time.Now())
+	for _, k := range expired {
+		stale = append(stale, k.ID)
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return m.redis.HDel(ctx, keysKey, stale...).Err()
+}
+
+// current — ключ, которым подписываются токены сейчас
+func (m *KeyManager) current() *signingKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.signingKeyAt(time.Now())
+}
+
+// signingKeyAt — самый новый из вступивших в силу ключей; вызывается под m.mu
+func (m *KeyManager) signingKeyAt(now time.Time) *signingKey {
+	for _, k := range m.keys {
+		if k.active(now) {
+			return k
+		}
+	}
+	return nil
+}
+
+func (m *KeyManager) canReload() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return time.Since(m.loadedAt) >= reloadOnMissEvery
+}
+
+func (m *KeyManager) lookup(kid string) *signingKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, k := range m.keys {
+		if k.ID == kid {
+			return k
+		}
+	}
+	return nil
+}
+
+// sign подписывает claims текущим ключом и проставляет kid в заголовок
+func (m *KeyManager) sign(claims jwt.Claims) (string, error) {
+	key := m.current()
+	if key == nil {
+		return "", ErrUnknownKey
+	}
+	tok := jwt.NewWithClaims(key.method(), claims)
+	tok.Header["kid"] = key.ID
+	return tok.SignedString(key.Private)
+}
+
+// verificationKey возвращает открытый ключ для kid; алгоритм токена должен совпадать с алгоритмом ключа.
+// Незнакомый kid может означать, что другой экземпляр только что выпустил ключ, — тогда набор перечитывается.
+func (m *KeyManager) verificationKey(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key := m.lookup(kid)
+	if key == nil && m.canReload() {
+		if err := m.Load(context.Background()); err != nil {
+			log.Println("[keys] reload:", err)
+		}
+		key = m.lookup(kid)
+	}
+	if key == nil || t.Method.Alg() != key.Alg {
+		return nil, ErrUnknownKey
+	}
+	return key.Private.Public(), nil
+}
+
+// JWK — открытый ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые части всех ключей, которыми могут быть подписаны действующие токены,
+// и следующего ключа, который ещё только начнёт подписывать
+func (m *KeyManager) JWKS() JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range m.keys {
+		jwk := JWK{Kid: k.ID, Alg: k.Alg, Use: "sig"}
+		switch pub := k.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func generateKey(alg string) (*signingKey, error) {
+	key := &signingKey{ID: uuid.NewString(), Alg: alg, CreatedAt: time.Now()}
+	var err error
+	switch alg {
+	case AlgRS256:
+		key.Private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, key.Private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func decodeKey(kid, raw string) (*signingKey, error) {
+	var stored storedKey
+	if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+		return nil, err
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(stored.Private)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("key is not a signer")
+	}
+	switch signer.(type) {
+	case *rsa.PrivateKey:
+		if stored.Alg != AlgRS256 {
+			return nil, fmt.Errorf("rsa key with alg %q", stored.Alg)
+		}
+	case ed25519.PrivateKey:
+		if stored.Alg != AlgEdDSA {
+			return nil, fmt.Errorf("ed25519 key with alg %q", stored.Alg)
+		}
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+	key := &signingKey{
+		ID:          kid,
+		Alg:         stored.Alg,
+		Private:     signer,
+		CreatedAt:   time.UnixMilli(stored.CreatedAt),
+		ActivatesAt: time.UnixMilli(stored.CreatedAt),
+	}
+	if stored.ActivatesAt != 0 {
+		key.ActivatesAt = time.UnixMilli(stored.ActivatesAt)
+	}
+	return key, nil
+}
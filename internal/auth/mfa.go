@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// MFAChallengeTTL — сколько действует токен второго шага входа
+	MFAChallengeTTL = 5 * time.Minute
+	// MaxMFAAttempts — после стольких неверных кодов токен второго шага сгорает.
+	// Это ограничение одного токена; общий предел на пользователя — блокировка
+	// в MFAHandler.WithCodeLockout, ведь новый токен выдаётся за верный пароль
+	MaxMFAAttempts = 5
+)
+
+var ErrInvalidMFAChallenge = errors.New("invalid or expired mfa token")
+
+// IssueMFAChallenge выдаётся после верного пароля, когда у пользователя включена 2FA.
+// Токены выдаются только в обмен на него и код из приложения.
+func (s *Service) IssueMFAChallenge(ctx context.Context, userID int32) (string, error) {
+	token, hash, err := NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	key := mfaKey(hash)
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", strconv.Itoa(int(userID)), "attempts", 0)
+	pipe.Expire(ctx, key, MFAChallengeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// MFAChallengeUser возвращает пользователя, которому выдан токен второго шага, не погашая его
+func (s *Service) MFAChallengeUser(ctx context.Context, token string) (int32, error) {
+	val, err := s.redis.HGet(ctx, mfaKey(HashToken(token)), "user_id").Result()
+	if err == redis.Nil {
+		return 0, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return 0, err
+	}
+	userID, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, ErrInvalidMFAChallenge
+	}
+	return int32(userID), nil
+}
+
+// FailMFAChallenge учитывает неверный код; после MaxMFAAttempts токен удаляется,
+// чтобы код нельзя было подобрать перебором
+func (s *Service) FailMFAChallenge(ctx context.Context, token string) error {
+	key := mfaKey(HashToken(token))
+	n, err := s.redis.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return err
+	}
+	if n >= MaxMFAAttempts {
+		return s.redis.Del(ctx, key).Err()
+	}
+	return nil
+}
+
+// ConsumeMFAChallenge погашает токен второго шага; из двух одновременных запросов успешен один
+func (s *Service) ConsumeMFAChallenge(ctx context.Context, token string) error {
+	n, err := s.redis.Del(ctx, mfaKey(HashToken(token))).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidMFAChallenge
+	}
+	return nil
+}
+
+// VerifyTOTP проверяет код и запоминает использованный интервал:
+// один и тот же код нельзя предъявить дважды, даже пока он ещё действует
+func (s *Service) VerifyTOTP(ctx context.Context, userID int32, secret, code string) (bool, error) {
+	step, ok := MatchTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	ttl := TOTPPeriod * (2*totpSkew + 1)
+	return s.redis.SetNX(ctx, totpUsedKey(userID, step), 1, ttl).Result()
+}
+
+func mfaKey(hash string) string {
+	return "mfa:" + hash
+}
+
+func totpUsedKey(userID int32, step int64) string {
+	return "totp_used:" + strconv.Itoa(int(userID)) + ":" + strconv.FormatInt(step, 10)
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OIDCStateTTL — сколько ждём возвращения пользователя от провайдера входа
+const OIDCStateTTL = 10 * time.Minute
+
+var ErrInvalidOIDCState = errors.New("invalid or expired login state")
+
+// OIDCState — то, что нужно запомнить между уходом к провайдеру и возвратом с code
+type OIDCState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// LinkUserID — пользователь, который привязывает внешний аккаунт; 0 — обычный вход
+	LinkUserID int32 `json:"link_user_id,omitempty"`
+}
+
+// IssueOIDCState сохраняет состояние входа и возвращает значение параметра state.
+// В Redis хранится только хеш state.
+func (s *Service) IssueOIDCState(ctx context.Context, st OIDCState) (string, error) {
+	token, hash, err := NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	if err := s.redis.Set(ctx, oidcStateKey(hash), raw, OIDCStateTTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumeOIDCState погашает state: ответ провайдера принимается только один раз
+func (s *Service) ConsumeOIDCState(ctx context.Context, state string) (OIDCState, error) {
+	raw, err := s.redis.GetDel(ctx, oidcStateKey(HashToken(state))).Result()
+	if err == redis.Nil {
+		return OIDCState{}, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return OIDCState{}, err
+	}
+	var st OIDCState
+	if err := json.Unmarshal([]byte(raw), &st); err != nil {
+		return OIDCState{}, ErrInvalidOIDCState
+	}
+	return st, nil
+}
+
+func oidcStateKey(hash string) string {
+	return "oidc_state:" + hash
+}
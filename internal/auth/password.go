@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher хеширует пароли для хранения в users.password
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify сравнивает пароль с хешем. rehash — хеш посчитан устаревшим алгоритмом
+	// или с другими параметрами, и после успешной проверки его стоит пересчитать.
+	Verify(encoded, password string) (ok, rehash bool, err error)
+}
+
+// Argon2idParams — параметры Argon2id; Memory в KiB
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams — рекомендация RFC 9106 для систем с ограниченной памятью
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2idHasher хранит хеши в формате PHC: $argon2id$v=19$m=65536,t=3,p=4$<соль>$<хеш>.
+// Хеши bcrypt, которыми пароли хранились раньше, тоже проверяются и помечаются на пересчёт.
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(p Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: p}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(encoded, password string) (bool, bool, error) {
+	switch {
+	case encoded == "":
+		// пароль не задан (аккаунт создан через провайдера OIDC)
+		return false, false, nil
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return h.verifyArgon2id(encoded, password)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+	}
+	return false, false, ErrUnknownPasswordHash
+}
+
+func (h *Argon2idHasher) verifyArgon2id(encoded, password string) (bool, bool, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", соль, хеш
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false, ErrUnknownPasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, ErrUnknownPasswordHash
+	}
+	var p Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return false, false, ErrUnknownPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrUnknownPasswordHash
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return false, false, ErrUnknownPasswordHash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(want))
+
+	got := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return false, false, nil
+	}
+	cur := h.params
+	rehash := p.Memory != cur.Memory || p.Iterations != cur.Iterations || p.Parallelism != cur.Parallelism ||
+		p.SaltLength != cur.SaltLength || p.KeyLength != cur.KeyLength
+	return true, rehash, nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sqszy/TaskTracker/internal/db"
+)
+
+// PersonalTokenPrefix отличает personal access token от JWT в заголовке Authorization
+const PersonalTokenPrefix = "ttp_"
+
+// Области доступа personal access token. Запись включает чтение.
+var Scopes = []string{
+	"boards:read",
+	"boards:write",
+	"tasks:read",
+	"tasks:write",
+}
+
+var (
+	ErrInvalidPersonalToken = errors.New("invalid or expired personal access token")
+	ErrPersonalTokensOff    = errors.New("personal access tokens are not enabled")
+)
+
+// PersonalToken — владелец и права токена, предъявленного в запросе
+type PersonalToken struct {
+	ID     int32
+	UserID int32
+	Scopes []string
+}
+
+// HasScope сообщает, разрешено ли токену действие: "<resource>:write" покрывает и чтение
+func (t *PersonalToken) HasScope(resource string, write bool) bool {
+	if slices.Contains(t.Scopes, resource+":write") {
+		return true
+	}
+	return !write && slices.Contains(t.Scopes, resource+":read")
+}
+
+// ValidScope сообщает, существует ли область доступа
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// IsPersonalToken сообщает, похожа ли строка на personal access token
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
+
+// NewPersonalToken генерирует токен; пользователю он показывается один раз, в БД хранится hash
+func NewPersonalToken() (token, hash string, err error) {
+	raw, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = PersonalTokenPrefix + raw
+	return token, HashToken(token), nil
+}
+
+// WithPersonalTokens включает проверку personal access token по таблице в Postgres
+func (s *Service) WithPersonalTokens(q db.Querier) *Service {
+	s.tokens = q
+	return s
+}
+
+// AuthenticatePersonalToken находит действующий токен и отмечает его использование
+func (s *Service) AuthenticatePersonalToken(ctx context.Context, token string) (*PersonalToken, error) {
+	if s.tokens == nil {
+		return nil, ErrPersonalTokensOff
+	}
+	pat, err := s.tokens.GetPersonalAccessTokenByHash(ctx, HashToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidPersonalToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	// время последнего использования — справочное, его ошибка не мешает запросу
+	if err := s.tokens.TouchPersonalAccessToken(ctx, pat.ID); err != nil {
+		log.Println("cannot touch personal access token:", err)
+	}
+	return &PersonalToken{ID: pat.ID, UserID: pat.UserID, Scopes: pat.Scopes}, nil
+}
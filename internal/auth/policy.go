@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Коды нарушений парольной политики — их видит клиент в ответе 400
+const (
+	PasswordTooShort      = "too_short"
+	PasswordTooLong       = "too_long"
+	PasswordTooWeak       = "too_weak"
+	PasswordContainsEmail = "contains_email"
+	PasswordBreached      = "breached"
+)
+
+// PasswordPolicy — требования к новому паролю при регистрации, смене и сбросе
+type PasswordPolicy struct {
+	MinLength int
+	// MaxLength ограничивает работу хешера на запрос
+	MaxLength int
+	// MinScore — минимальная оценка PasswordStrength, 0 — не проверяется
+	MinScore int
+	// Breached — список утёкших паролей; nil — проверка выключена
+	Breached *BreachedCorpus
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8, MaxLength: 128, MinScore: 2}
+
+// PasswordViolation — одно нарушение политики
+type PasswordViolation struct {
+	Code    string
+	Message string
+}
+
+// PasswordPolicyError перечисляет все нарушения, чтобы клиент показал их разом
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return "password does not meet policy: " + strings.Join(msgs, "; ")
+}
+
+// Check проверяет пароль пользователя с данным email; возвращает *PasswordPolicyError или nil
+func (p PasswordPolicy) Check(password, email string) error {
+	var vs []PasswordViolation
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		vs = append(vs, PasswordViolation{PasswordTooShort, fmt.Sprintf("password must be at least %d characters", p.MinLength)})
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		// слишком длинный пароль дальше не разбирается
+		vs = append(vs, PasswordViolation{PasswordTooLong, fmt.Sprintf("password must be at most %d characters", p.MaxLength)})
+		return &PasswordPolicyError{Violations: vs}
+	}
+
+	inputs := emailWords(email)
+	if derivedFromEmail(password, inputs) {
+		vs = append(vs, PasswordViolation{PasswordContainsEmail, "password must not contain your email address"})
+	}
+	if p.MinScore > 0 {
+		if score := PasswordStrength(password, inputs...); score < p.MinScore {
+			vs = append(vs, PasswordViolation{PasswordTooWeak,
+				fmt.Sprintf("password is too easy to guess (strength %d of 4, at least %d required)", score, p.MinScore)})
+		}
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		vs = append(vs, PasswordViolation{PasswordBreached, "password has appeared in a data breach"})
+	}
+
+	if len(vs) > 0 {
+		return &PasswordPolicyError{Violations: vs}
+	}
+	return nil
+}
+
+// emailWords — части адреса, из которых часто собирают пароль: локальная часть целиком,
+// её слова и имя домена без зоны
+func emailWords(email string) []string {
+	local, domain, _ := strings.Cut(strings.ToLower(email), "@")
+	local, _, _ = strings.Cut(local, "+")
+	words := []string{local}
+	words = append(words, strings.FieldsFunc(local, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})...)
+	if labels := strings.Split(domain, "."); len(labels) > 1 {
+		words = append(words, labels[:len(labels)-1]...)
+	}
+	return words
+}
+
+// derivedFromEmail — пароль содержит часть email (от 4 символов) или сам является её частью
+func derivedFromEmail(password string, words []string) bool {
+	pw := alnum(deleet([]rune(strings.ToLower(password))))
+	if pw == "" {
+		return false
+	}
+	for _, w := range words {
+		w = alnum(w)
+		if len(w) < 4 {
+			continue
+		}
+		if strings.Contains(pw, w) || strings.Contains(pw, reverse(w)) || (len(pw) >= 4 && strings.Contains(w, pw)) {
+			return true
+		}
+	}
+	return false
+}
+
+func alnum(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// PasswordResetTTL — срок жизни ссылки для сброса пароля
+	PasswordResetTTL = 30 * time.Minute
+	// PasswordResetCooldown — минимальный интервал между письмами сброса одному пользователю
+	PasswordResetCooldown = time.Minute
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	ErrResetCooldown     = errors.New("password reset email was sent recently")
+)
+
+// IssuePasswordReset выдаёт одноразовый токен сброса пароля.
+// В Redis хранится только хеш токена; предыдущий токен пользователя перестаёт действовать.
+// Чаще раза в PasswordResetCooldown токен не выдаётся (ErrResetCooldown): иначе чужой ящик
+// можно было бы засыпать письмами, а каждое новое письмо гасило бы ссылку из предыдущего.
+func (s *Service) IssuePasswordReset(ctx context.Context, userID int32) (string, error) {
+	ok, err := s.redis.SetNX(ctx, resetCooldownKey(userID), 1, PasswordResetCooldown).Result()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrResetCooldown
+	}
+
+	token, hash, err := NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	userKey := resetUserKey(userID)
+	if old, err := s.redis.Get(ctx, userKey).Result(); err == nil {
+		_ = s.redis.Del(ctx, resetKey(old))
+	} else if err != redis.Nil {
+		return "", err
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, resetKey(hash), strconv.Itoa(int(userID)), PasswordResetTTL)
+	pipe.Set(ctx, userKey, hash, PasswordResetTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// PasswordResetUser возвращает владельца токена, не погашая его: новый пароль проверяется
+// до погашения, чтобы отклонённый пароль не сжигал ссылку
+func (s *Service) PasswordResetUser(ctx context.Context, token string) (int32, error) {
+	val, err := s.redis.Get(ctx, resetKey(HashToken(token))).Result()
+	if err == redis.Nil {
+		return 0, ErrInvalidResetToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	userID, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, ErrInvalidResetToken
+	}
+	return int32(userID), nil
+}
+
+// ConsumePasswordReset погашает токен и возвращает ID пользователя.
+// GETDEL гарантирует, что токен сработает только один раз даже при параллельных запросах.
+func (s *Service) ConsumePasswordReset(ctx context.Context, token string) (int32, error) {
+	val, err := s.redis.GetDel(ctx, resetKey(HashToken(token))).Result()
+	if err == redis.Nil {
+		return 0, ErrInvalidResetToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	userID, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, ErrInvalidResetToken
+	}
+	_ = s.redis.Del(ctx, resetUserKey(int32(userID)))
+	return int32(userID), nil
+}
+
+func resetKey(hash string) string {
+	return "pwreset:" + hash
+}
+
+func resetUserKey(userID int32) string {
+	return "pwreset_user:" + strconv.Itoa(int(userID))
+}
+
+func resetCooldownKey(userID int32) string {
+	return "pwreset_cooldown:" + strconv.Itoa(int(userID))
+}
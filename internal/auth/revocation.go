@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRevocationCacheTTL — сколько экземпляр API помнит результат проверки отзыва.
+// Отзыв, сделанный на другом экземпляре, вступает в силу здесь не позже чем через это время.
+const DefaultRevocationCacheTTL = 5 * time.Second
+
+// revocationCacheMax — после стольких записей устаревшие вычищаются при вставке
+const revocationCacheMax = 10000
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+// Access-токен отзывается двумя способами:
+//   - revoked_session:<sid> — сессия завершена (выход, отзыв сессии, повтор refresh-токена),
+//     недействительны все access-токены этой сессии;
+//   - tokens_valid_after:<userID> — unix-время, раньше которого выданные токены пользователя
+//     недействительны (выход везде, сброс пароля); нужен для токенов без sid.
+//     Ставится на следующую секунду после отзыва, новые токены получают iat не раньше него.
+//
+// revoked_session живёт accessTTL: к этому времени отозванные access-токены истекают сами.
+// tokens_valid_after проверяется и при обмене refresh-токена, поэтому живёт refreshTTL:
+// refresh-токены без sid не попадают в индекс сессий, и отозвать их можно только так.
+
+type revocationState struct {
+	sessionRevoked bool
+	validAfter     int64
+	expires        time.Time
+}
+
+type revocationCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]revocationState
+}
+
+func newRevocationCache(ttl time.Duration) *revocationCache {
+	return &revocationCache{ttl: ttl, entries: map[string]revocationState{}}
+}
+
+func (c *revocationCache) get(key string) (revocationState, bool) {
+	if c.ttl <= 0 {
+		return revocationState{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st, ok := c.entries[key]
+	if !ok || time.Now().After(st.expires) {
+		return revocationState{}, false
+	}
+	return st, true
+}
+
+func (c *revocationCache) put(key string, st revocationState) {
+	if c.ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	st.expires = now.Add(c.ttl)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= revocationCacheMax {
+		for k, v := range c.entries {
+			if now.After(v.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = st
+}
+
+// forgetUser сбрасывает записи пользователя, чтобы отзыв на этом экземпляре действовал сразу
+func (c *revocationCache) forgetUser(userID int32) {
+	prefix := strconv.Itoa(int(userID)) + ":"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.entries {
+		if len(k) >= len(prefix) && k[:len(prefix)] == prefix {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// WithRevocationCache задаёт время жизни локального кеша проверки отзыва; 0 — без кеша
+func (s *Service) WithRevocationCache(ttl time.Duration) *Service {
+	s.revoked = newRevocationCache(ttl)
+	return s
+}
+
+// checkRevoked сверяет access-токен с отзывами в Redis; результат кешируется на экземпляре
+func (s *Service) checkRevoked(ctx context.Context, claims *Claims) error {
+	cacheKey := strconv.Itoa(int(claims.UserID)) + ":" + claims.SessionID
+	st, ok := s.revoked.get(cacheKey)
+	if !ok {
+		var err error
+		st, err = s.loadRevocation(ctx, claims)
+		if err != nil {
+			return err
+		}
+		s.revoked.put(cacheKey, st)
+	}
+
+	if st.sessionRevoked {
+		return ErrTokenRevoked
+	}
+	if issuedBefore(claims, st.validAfter) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// checkRefreshRevoked сверяет refresh-токен с tokens_valid_after, без кеша: обмен редкий
+func (s *Service) checkRefreshRevoked(ctx context.Context, claims *Claims) error {
+	v, err := s.redis.Get(ctx, validAfterKey(claims.UserID)).Int64()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if issuedBefore(claims, v) {
+		return ErrRefreshRevoked
+	}
+	return nil
+}
+
+// issueTime возвращает время выдачи нового токена: now, но не раньше tokens_valid_after,
+// который RevokeAll ставит на начало следующей секунды
+func (s *Service) issueTime(ctx context.Context, userID int32, now time.Time) (time.Time, error) {
+	mark, err := s.redis.Get(ctx, validAfterKey(userID)).Int64()
+	if err == redis.Nil {
+		return now, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if mark > now.Unix() {
+		return time.Unix(mark, 0), nil
+	}
+	return now, nil
+}
+
+// issuedBefore: токен выдан раньше отметки validAfter (0 — отметки нет)
+func issuedBefore(claims *Claims, validAfter int64) bool {
+	return validAfter > 0 && (claims.IssuedAt == nil || claims.IssuedAt.Unix() < validAfter)
+}
+
+func (s *Service) loadRevocation(ctx context.Context, claims *Claims) (revocationState, error) {
+	pipe := s.redis.Pipeline()
+	validAfter := pipe.Get(ctx, validAfterKey(claims.UserID))
+	var sessionRevoked *redis.IntCmd
+	if claims.SessionID != "" {
+		sessionRevoked = pipe.Exists(ctx, revokedSessionKey(claims.SessionID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return revocationState{}, err
+	}
+
+	var st revocationState
+	if v, err := validAfter.Int64(); err == nil {
+		st.validAfter = v
+	}
+	if sessionRevoked != nil {
+		st.sessionRevoked = sessionRevoked.Val() > 0
+	}
+	return st, nil
+}
+
+func revokedSessionKey(sid string) string {
+	return "revoked_session:" + sid
+}
+
+func validAfterKey(userID int32) string {
+	return "tokens_valid_after:" + strconv.Itoa(int(userID))
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionMeta — сведения об устройстве, с которого выполнен вход
+type SessionMeta struct {
+	UserAgent string
+	IP        string
+}
+
+// Session — один вход пользователя (устройство). Живёт, пока обновляется её refresh-токен.
+type Session struct {
+	ID         string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// Сессия хранится хешем session:<sid>, рядом — текущий jti, чтобы её можно было отозвать.
+// user_sessions:<userID> — множество sid пользователя.
+// При обмене запоминаются прежний jti и время обмена (unix ms) — см. checkReuse.
+func (s *Service) saveSession(ctx context.Context, pipe redis.Pipeliner, userID int32, sess Session, jti, prevJTI string) {
+	key := sessionKey(sess.ID)
+	fields := map[string]any{
+		"user_id":      strconv.Itoa(int(userID)),
+		"jti":          jti,
+		"user_agent":   sess.UserAgent,
+		"ip":           sess.IP,
+		"created_at":   sess.CreatedAt.Unix(),
+		"last_used_at": sess.LastUsedAt.Unix(),
+	}
+	if prevJTI != "" {
+		fields["prev_jti"] = prevJTI
+		fields["rotated_at"] = time.Now().UnixMilli()
+	}
+	pipe.HSet(ctx, key, fields)
+	pipe.Expire(ctx, key, s.refreshTTL)
+	pipe.SAdd(ctx, userSessionsKey(userID), sess.ID)
+	pipe.Expire(ctx, userSessionsKey(userID), s.refreshTTL)
+}
+
+func (s *Service) getSession(ctx context.Context, userID int32, sid string) (Session, error) {
+	fields, err := s.redis.HGetAll(ctx, sessionKey(sid)).Result()
+	if err != nil {
+		return Session{}, err
+	}
+	if len(fields) == 0 || fields["user_id"] != strconv.Itoa(int(userID)) {
+		return Session{}, ErrSessionNotFound
+	}
+	return Session{
+		ID:         sid,
+		UserAgent:  fields["user_agent"],
+		IP:         fields["ip"],
+		CreatedAt:  unixField(fields["created_at"]),
+		LastUsedAt: unixField(fields["last_used_at"]),
+	}, nil
+}
+
+// ListSessions возвращает активные сессии пользователя, последние использованные — первыми
+func (s *Service) ListSessions(ctx context.Context, userID int32) ([]Session, error) {
+	sids, err := s.redis.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sessions := []Session{}
+	for _, sid := range sids {
+		sess, err := s.getSession(ctx, userID, sid)
+		if errors.Is(err, ErrSessionNotFound) {
+			// сессия истекла сама: убираем её из индекса
+			_ = s.redis.SRem(ctx, userSessionsKey(userID), sid)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию пользователя: её refresh-токен перестаёт действовать,
+// а выданные в ней access-токены отклоняются, не дожидаясь истечения срока
+func (s *Service) RevokeSession(ctx context.Context, userID int32, sid string) error {
+	key := sessionKey(sid)
+	fields, err := s.redis.HMGet(ctx, key, "user_id", "jti").Result()
+	if err != nil {
+		return err
+	}
+	owner, _ := fields[0].(string)
+	if owner != strconv.Itoa(int(userID)) {
+		return ErrSessionNotFound
+	}
+
+	pipe := s.redis.TxPipeline()
+	if jti, _ := fields[1].(string); jti != "" {
+		pipe.Del(ctx, refreshKey(jti))
+	}
+	pipe.Del(ctx, key)
+	pipe.SRem(ctx, userSessionsKey(userID), sid)
+	pipe.Set(ctx, revokedSessionKey(sid), 1, s.accessTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	s.revoked.forgetUser(userID)
+	return nil
+}
+
+// RevokeAll завершает все сессии пользователя и отзывает все выданные ему access-токены
+func (s *Service) RevokeAll(ctx context.Context, userID int32) error {
+	// токены без sid отзываются только отметкой времени. iat хранится с точностью до секунды,
+	// поэтому отметка — начало следующей секунды: отзываются и токены, выданные в эту же
+	// секунду, а новые выдаются уже с iat не раньше отметки (см. issueTime)
+	mark := time.Now().Unix() + 1
+	if err := s.redis.Set(ctx, validAfterKey(userID), mark, s.refreshTTL).Err(); err != nil {
+		return err
+	}
+	s.revoked.forgetUser(userID)
+
+	sids, err := s.redis.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, sid := range sids {
+		if err := s.RevokeSession(ctx, userID, sid); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	}
+	return s.redis.Del(ctx, userSessionsKey(userID)).Err()
+}
+
+func unixField(v string) time.Time {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(n, 0).UTC()
+}
+
+func sessionKey(sid string) string {
+	return "session:" + sid
+}
+
+func userSessionsKey(userID int32) string {
+	return "user_sessions:" + strconv.Itoa(int(userID))
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Оценка стойкости пароля в духе zxcvbn: пароль разбивается на куски — словарные слова,
+// повторы, последовательности, ряды клавиатуры, годы — и случайные символы. Для каждого
+// куска оценивается число попыток перебора, берётся разбиение с наименьшей суммой log10,
+// и она переводится в шкалу 0–4.
+
+// commonPasswordWords — частые пароли и их основы, по убыванию популярности
+var commonPasswordWords = []string{
+	"password", "123456", "qwerty", "111111", "iloveyou", "admin", "welcome", "letmein",
+	"monkey", "dragon", "master", "login", "abc123", "football", "baseball", "sunshine",
+	"princess", "shadow", "superman", "trustno1", "hello", "freedom", "whatever", "secret",
+	"starwars", "computer", "michael", "charlie", "jordan", "hunter", "ranger", "buster",
+	"soccer", "hockey", "killer", "pepper", "summer", "winter", "spring", "autumn", "love",
+	"changeme", "default", "guest", "root", "access", "test", "user", "pass", "tasktracker",
+	"flower", "cookie", "banana", "orange", "purple", "silver", "golden", "tigger", "maggie",
+	"ginger", "batman", "thomas", "robert", "daniel", "andrew", "jessica", "ashley", "matrix",
+	"internet", "service", "server", "mustang", "harley", "yankees", "cheese", "coffee",
+}
+
+var commonPasswordRanks = func() map[string]int {
+	m := make(map[string]int, len(commonPasswordWords))
+	for i, w := range commonPasswordWords {
+		m[w] = i + 1
+	}
+	return m
+}()
+
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// leetSubstitutions — замены символов, которыми «усиливают» словарные слова
+var leetSubstitutions = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's', '!': 'i',
+}
+
+// strengthMaxRunes — дальше пароль не разбирается: оценка и так максимальная, а разбор квадратичный
+const strengthMaxRunes = 64
+
+// PasswordStrength оценивает пароль от 0 (угадывается сразу) до 4 (очень стойкий).
+// userInputs — слова, связанные с пользователем (части email), они считаются словарными.
+func PasswordStrength(password string, userInputs ...string) int {
+	guesses := passwordGuessesLog10(password, userInputs)
+	switch {
+	case guesses < 3:
+		return 0
+	case guesses < 6:
+		return 1
+	case guesses < 8:
+		return 2
+	case guesses < 10:
+		return 3
+	}
+	return 4
+}
+
+// passwordGuessesLog10 — log10 числа попыток, нужного для подбора пароля
+func passwordGuessesLog10(password string, userInputs []string) float64 {
+	raw := []rune(password)
+	if len(raw) > strengthMaxRunes {
+		raw = raw[:strengthMaxRunes]
+	}
+	lower := make([]rune, len(raw))
+	for i, r := range raw {
+		lower[i] = unicode.ToLower(r)
+	}
+	user := map[string]bool{}
+	for _, w := range userInputs {
+		if w = strings.ToLower(w); len([]rune(w)) >= 3 {
+			user[w] = true
+		}
+	}
+
+	best := make([]float64, len(raw)+1)
+	for end := 1; end <= len(raw); end++ {
+		// случайный символ — как в zxcvbn, 10 попыток
+		best[end] = best[end-1] + 1
+		for start := 0; start <= end-3; start++ {
+			if cost, ok := chunkGuessesLog10(raw[start:end], lower[start:end], user); ok {
+				best[end] = math.Min(best[end], best[start]+cost)
+			}
+		}
+	}
+	return best[len(raw)]
+}
+
+// chunkGuessesLog10 оценивает кусок пароля, если он подходит под один из шаблонов
+func chunkGuessesLog10(raw, lower []rune, user map[string]bool) (float64, bool) {
+	n := len(raw)
+	best, found := math.Inf(1), false
+	try := func(cost float64) {
+		// любой шаблон не дешевле одного случайного символа
+		best, found = math.Min(best, math.Max(cost, 1)), true
+	}
+
+	if isRepeat(lower) {
+		try(1 + math.Log10(float64(n)))
+	}
+	for k := 2; k <= n/2; k++ {
+		if n%k == 0 && isRepeat(blocks(lower, k)) {
+			try(float64(k) + math.Log10(float64(n/k)))
+			break
+		}
+	}
+	if base, desc, ok := sequence(raw); ok {
+		cost := math.Log10(base * float64(n))
+		if desc {
+			cost += math.Log10(2)
+		}
+		try(cost)
+	}
+	s := string(lower)
+	for _, row := range keyboardRows {
+		if strings.Contains(row, s) || strings.Contains(reverse(row), s) {
+			try(math.Log10(float64(len(keyboardRows) * 10 * n)))
+			break
+		}
+	}
+	if n == 4 && isDigits(lower) && s >= "1900" && s <= "2039" {
+		try(math.Log10(140))
+	}
+
+	word := deleet(lower)
+	for _, w := range []string{s, word, reverse(s), reverse(word)} {
+		rank, ok := commonPasswordRanks[w]
+		if user[w] {
+			rank, ok = 1, true
+		}
+		if !ok {
+			continue
+		}
+		cost := math.Log10(float64(rank)) + caseGuessesLog10(raw)
+		if w != s {
+			// замены символов или запись задом наперёд
+			cost += 1
+		}
+		try(cost)
+	}
+	return best, found
+}
+
+func isRepeat(rs []rune) bool {
+	for _, r := range rs[1:] {
+		if r != rs[0] {
+			return false
+		}
+	}
+	return len(rs) > 1
+}
+
+// blocks превращает кусок в последовательность блоков длины k, закодированных одним символом,
+// чтобы проверить повтор вида abcabc тем же isRepeat
+func blocks(rs []rune, k int) []rune {
+	ids := map[string]rune{}
+	res := make([]rune, 0, len(rs)/k)
+	for i := 0; i < len(rs); i += k {
+		b := string(rs[i : i+k])
+		if _, ok := ids[b]; !ok {
+			ids[b] = rune(len(ids))
+		}
+		res = append(res, ids[b])
+	}
+	return res
+}
+
+// sequence узнаёт abc, 9876 и т.п.; base — число возможных начал такой последовательности
+func sequence(rs []rune) (base float64, desc bool, ok bool) {
+	d := rs[1] - rs[0]
+	if d != 1 && d != -1 {
+		return 0, false, false
+	}
+	for i := 1; i < len(rs); i++ {
+		if rs[i]-rs[i-1] != d || charClass(rs[i]) != charClass(rs[0]) {
+			return 0, false, false
+		}
+	}
+	switch {
+	case strings.ContainsRune("aAzZ019", rs[0]):
+		base = 4
+	case charClass(rs[0]) == 'd':
+		base = 10
+	default:
+		base = 26
+	}
+	return base, d < 0, charClass(rs[0]) != 0
+}
+
+func charClass(r rune) byte {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return 'l'
+	case r >= 'A' && r <= 'Z':
+		return 'u'
+	case r >= '0' && r <= '9':
+		return 'd'
+	}
+	return 0
+}
+
+// caseGuessesLog10 — цена заглавных букв в словарном слове: первая или все — почти бесплатно
+func caseGuessesLog10(rs []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range rs {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0 || (upper == 1 && unicode.IsUpper(rs[0])):
+		return math.Log10(2)
+	}
+	return 1
+}
+
+func isDigits(rs []rune) bool {
+	for _, r := range rs {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func deleet(rs []rune) string {
+	var b strings.Builder
+	for _, r := range rs {
+		if sub, ok := leetSubstitutions[r]; ok {
+			r = sub
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func reverse(s string) string {
+	rs := []rune(s)
+	for i, j := 0, len(rs)-1; i < j; i, j = i+1, j-1 {
+		rs[i], rs[j] = rs[j], rs[i]
+	}
+	return string(rs)
+}
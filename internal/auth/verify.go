@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// EmailVerificationTTL — срок жизни ссылки подтверждения email
+	EmailVerificationTTL = 24 * time.Hour
+	// VerificationCooldown — минимальный интервал между письмами одному пользователю
+	VerificationCooldown = time.Minute
+)
+
+var (
+	ErrInvalidVerifyToken   = errors.New("invalid or expired verification token")
+	ErrVerificationCooldown = errors.New("verification email was sent recently")
+)
+
+// IssueEmailVerification выдаёт токен подтверждения email. Если письмо уже
+// отправлялось недавно, возвращает ErrVerificationCooldown и оставшееся время ожидания.
+func (s *Service) IssueEmailVerification(ctx context.Context, userID int32) (string, time.Duration, error) {
+	cooldownKey := verifyCooldownKey(userID)
+	ok, err := s.redis.SetNX(ctx, cooldownKey, 1, VerificationCooldown).Result()
+	if err != nil {
+		return "", 0, err
+	}
+	if !ok {
+		wait, err := s.redis.TTL(ctx, cooldownKey).Result()
+		if err != nil {
+			return "", 0, err
+		}
+		return "", max(wait, time.Second), ErrVerificationCooldown
+	}
+
+	token, hash, err := NewOpaqueToken()
+	if err != nil {
+		return "", 0, err
+	}
+	if err := s.redis.Set(ctx, verifyKey(hash), strconv.Itoa(int(userID)), EmailVerificationTTL).Err(); err != nil {
+		return "", 0, err
+	}
+	return token, 0, nil
+}
+
+// ConsumeEmailVerification погашает токен подтверждения и возвращает ID пользователя
+func (s *Service) ConsumeEmailVerification(ctx context.Context, token string) (int32, error) {
+	val, err := s.redis.GetDel(ctx, verifyKey(HashToken(token))).Result()
+	if err == redis.Nil {
+		return 0, ErrInvalidVerifyToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	userID, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, ErrInvalidVerifyToken
+	}
+	return int32(userID), nil
+}
+
+func verifyKey(hash string) string {
+	return "verify:" + hash
+}
+
+func verifyCooldownKey(userID int32) string {
+	return "verify_cooldown:" + strconv.Itoa(int(userID))
+}
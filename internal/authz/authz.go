@@ -0,0 +1,151 @@
+package authz
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sqszy/TaskTracker/internal/db"
+)
+
+// Роли участников доски
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var roleRank = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+type Action string
+
+const (
+	ListBoards     Action = "boards:list"
+	CreateBoard    Action = "boards:create"
+	ViewBoard      Action = "board:view"
+	UpdateBoard    Action = "board:update"
+	DeleteBoard    Action = "board:delete"
+	ManageMembers  Action = "board:members"
+	LeaveBoard     Action = "board:leave"
+	ListColumns    Action = "board:columns:list"
+	ManageColumns  Action = "board:columns:manage"
+	ManageWebhooks Action = "board:webhooks"
+
+	RespondInvitation Action = "invitation:respond"
+
+	ListTasks         Action = "tasks:list"
+	ListAssignedTasks Action = "tasks:assigned"
+	CreateTask        Action = "tasks:create"
+	UpdateTask        Action = "task:update"
+	DeleteTask        Action = "task:delete"
+
+	ListComments     Action = "comments:list"
+	CreateComment    Action = "comments:create"
+	UpdateComment    Action = "comment:update"
+	DeleteComment    Action = "comment:delete"
+	ModerateComments Action = "comments:moderate"
+)
+
+// policy — минимальная роль на доске для каждого действия.
+// Пустая роль означает действие без привязки к доске: достаточно быть авторизованным.
+var policy = map[Action]string{
+	ListBoards:     "",
+	CreateBoard:    "",
+	ViewBoard:      RoleViewer,
+	UpdateBoard:    RoleOwner,
+	DeleteBoard:    RoleOwner,
+	ManageMembers:  RoleOwner,
+	LeaveBoard:     RoleViewer,
+	ListColumns:    RoleViewer,
+	ManageColumns:  RoleOwner,
+	ManageWebhooks: RoleOwner,
+
+	RespondInvitation: "",
+
+	ListTasks:         RoleViewer,
+	ListAssignedTasks: "",
+	CreateTask:        RoleEditor,
+	UpdateTask:        RoleEditor,
+	DeleteTask:        RoleEditor,
+
+	ListComments:     RoleViewer,
+	CreateComment:    RoleEditor,
+	UpdateComment:    RoleEditor,
+	DeleteComment:    RoleEditor,
+	ModerateComments: RoleOwner,
+}
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrNotFound        = errors.New("resource not found")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// Resource — объект, к которому запрашивается доступ.
+// TaskID = 0 означает ресурс уровня доски.
+type Resource struct {
+	BoardID int32
+	TaskID  int32
+}
+
+func Board(boardID int32) Resource {
+	return Resource{BoardID: boardID}
+}
+
+func Task(boardID, taskID int32) Resource {
+	return Resource{BoardID: boardID, TaskID: taskID}
+}
+
+type Authorizer struct {
+	queries db.Querier
+}
+
+func New(q db.Querier) *Authorizer {
+	return &Authorizer{queries: q}
+}
+
+// Can проверяет, может ли пользователь выполнить action над res.
+// Ресурсы, в которых пользователь не участвует, отдаются как ErrNotFound,
+// чтобы по ID нельзя было узнать о существовании чужих досок и задач.
+func (a *Authorizer) Can(ctx context.Context, userID int32, action Action, res Resource) error {
+	if userID == 0 {
+		return ErrUnauthenticated
+	}
+	minRole, ok := policy[action]
+	if !ok {
+		return ErrForbidden
+	}
+	if minRole == "" {
+		return nil
+	}
+
+	role, err := a.role(ctx, userID, res)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if roleRank[role] < roleRank[minRole] {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (a *Authorizer) role(ctx context.Context, userID int32, res Resource) (string, error) {
+	if res.TaskID != 0 {
+		return a.queries.GetTaskMemberRole(ctx, db.GetTaskMemberRoleParams{
+			TaskID:  res.TaskID,
+			BoardID: res.BoardID,
+			UserID:  userID,
+		})
+	}
+	return a.queries.GetBoardMemberRole(ctx, db.GetBoardMemberRoleParams{
+		BoardID: res.BoardID,
+		UserID:  userID,
+	})
+}
@@ -14,7 +14,7 @@ import (
 const createBoard = `-- name: CreateBoard :one
 INSERT INTO boards (name, user_id)
 VALUES ($1, $2)
-RETURNING id, user_id, name, created_at, updated_at
+RETURNING id, user_id, name, created_at, updated_at, wip_mode
 `
 
 type CreateBoardParams struct {
@@ -31,48 +31,79 @@ func (q *Queries) CreateBoard(ctx context.Context, arg CreateBoardParams) (Board
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.WipMode,
 	)
 	return i, err
 }
 
 const deleteBoard = `-- name: DeleteBoard :execrows
 DELETE FROM boards
-WHERE id = $1 AND user_id = $2
+WHERE id = $1
 `
 
-type DeleteBoardParams struct {
-	ID     int32
-	UserID int32
-}
-
-func (q *Queries) DeleteBoard(ctx context.Context, arg DeleteBoardParams) (int64, error) {
-	result, err := q.db.Exec(ctx, deleteBoard, arg.ID, arg.UserID)
+func (q *Queries) DeleteBoard(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteBoard, id)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected(), nil
 }
 
+const getBoardForUpdate = `-- name: GetBoardForUpdate :one
+SELECT id, user_id, name, created_at, updated_at, wip_mode FROM boards
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetBoardForUpdate(ctx context.Context, id int32) (Board, error) {
+	row := q.db.QueryRow(ctx, getBoardForUpdate, id)
+	var i Board
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.WipMode,
+	)
+	return i, err
+}
+
 const getBoards = `-- name: GetBoards :many
-SELECT id, user_id, name, created_at, updated_at FROM boards
-WHERE user_id = $1
+SELECT b.id, b.user_id, b.name, b.created_at, b.updated_at, b.wip_mode, bm.role
+FROM boards b
+JOIN board_members bm ON bm.board_id = b.id
+WHERE bm.user_id = $1
+ORDER BY b.id
 `
 
-func (q *Queries) GetBoards(ctx context.Context, userID int32) ([]Board, error) {
+type GetBoardsRow struct {
+	ID        int32
+	UserID    int32
+	Name      string
+	CreatedAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
+	WipMode   string
+	Role      string
+}
+
+func (q *Queries) GetBoards(ctx context.Context, userID int32) ([]GetBoardsRow, error) {
 	rows, err := q.db.Query(ctx, getBoards, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Board
+	var items []GetBoardsRow
 	for rows.Next() {
-		var i Board
+		var i GetBoardsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
 			&i.Name,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.WipMode,
+			&i.Role,
 		); err != nil {
 			return nil, err
 		}
@@ -88,19 +119,20 @@ const updateBoard = `-- name: UpdateBoard :one
 UPDATE boards
 SET
     name = COALESCE($1, name),
+    wip_mode = COALESCE($2, wip_mode),
     updated_at = now()
-WHERE id = $2 AND user_id = $3
-RETURNING id, user_id, name, created_at, updated_at
+WHERE id = $3
+RETURNING id, user_id, name, created_at, updated_at, wip_mode
 `
 
 type UpdateBoardParams struct {
-	Name   pgtype.Text
-	ID     int32
-	UserID int32
+	Name    pgtype.Text
+	WipMode pgtype.Text
+	ID      int32
 }
 
 func (q *Queries) UpdateBoard(ctx context.Context, arg UpdateBoardParams) (Board, error) {
-	row := q.db.QueryRow(ctx, updateBoard, arg.Name, arg.ID, arg.UserID)
+	row := q.db.QueryRow(ctx, updateBoard, arg.Name, arg.WipMode, arg.ID)
 	var i Board
 	err := row.Scan(
 		&i.ID,
@@ -108,6 +140,7 @@ func (q *Queries) UpdateBoard(ctx context.Context, arg UpdateBoardParams) (Board
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.WipMode,
 	)
 	return i, err
 }
@@ -8,12 +8,78 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ActivityEvent struct {
+	ID        int32
+	BoardID   int32
+	TaskID    pgtype.Int4
+	ActorID   pgtype.Int4
+	Entity    string
+	EntityID  int32
+	Action    string
+	Changes   []byte
+	CreatedAt pgtype.Timestamp
+}
+
 type Board struct {
 	ID        int32
 	UserID    int32
 	Name      string
 	CreatedAt pgtype.Timestamp
 	UpdatedAt pgtype.Timestamp
+	WipMode   string
+}
+
+type BoardColumn struct {
+	ID        int32
+	BoardID   int32
+	Name      string
+	Color     string
+	IsDone    bool
+	Position  int32
+	CreatedAt pgtype.Timestamp
+	WipLimit  pgtype.Int4
+}
+
+type BoardInvitation struct {
+	ID          int32
+	BoardID     int32
+	Email       string
+	Role        string
+	TokenHash   string
+	InviterID   int32
+	Status      string
+	ExpiresAt   pgtype.Timestamp
+	RespondedAt pgtype.Timestamp
+	CreatedAt   pgtype.Timestamp
+}
+
+type BoardMember struct {
+	BoardID   int32
+	UserID    int32
+	Role      string
+	CreatedAt pgtype.Timestamp
+}
+
+type PersonalAccessToken struct {
+	ID          int32
+	UserID      int32
+	Name        string
+	TokenHash   string
+	TokenPrefix string
+	Scopes      []string
+	ExpiresAt   pgtype.Timestamp
+	LastUsedAt  pgtype.Timestamp
+	CreatedAt   pgtype.Timestamp
+}
+
+type SecurityEvent struct {
+	ID        int32
+	UserID    int32
+	Event     string
+	Ip        pgtype.Text
+	UserAgent pgtype.Text
+	Details   []byte
+	CreatedAt pgtype.Timestamp
 }
 
 type Task struct {
@@ -27,11 +93,105 @@ type Task struct {
 	BoardID     pgtype.Int4
 	Priority    string
 	Deadline    pgtype.Timestamp
+	ColumnID    pgtype.Int4
+	Rank        string
+}
+
+type TaskAssignee struct {
+	TaskID    int32
+	UserID    int32
+	CreatedAt pgtype.Timestamp
+}
+
+type TaskComment struct {
+	ID        int32
+	TaskID    int32
+	UserID    int32
+	ParentID  pgtype.Int4
+	Body      string
+	CreatedAt pgtype.Timestamp
+	EditedAt  pgtype.Timestamp
+	DeletedAt pgtype.Timestamp
+}
+
+type TaskCommentEdit struct {
+	ID        int32
+	CommentID int32
+	Body      string
+	EditedBy  int32
+	EditedAt  pgtype.Timestamp
 }
 
 type User struct {
+	ID              int32
+	Email           string
+	Password        string
+	CreatedAt       pgtype.Timestamp
+	EmailVerifiedAt pgtype.Timestamp
+	TotpSecret      pgtype.Text
+	TotpEnabledAt   pgtype.Timestamp
+	DisplayName     pgtype.Text
+	AvatarUrl       pgtype.Text
+	Timezone        string
+	Locale          string
+}
+
+type UserAvatar struct {
+	UserID      int32
+	ContentType string
+	Data        []byte
+	UpdatedAt   pgtype.Timestamp
+}
+
+type UserIdentity struct {
 	ID        int32
-	Email     string
-	Password  string
+	UserID    int32
+	Provider  string
+	Subject   string
+	Email     pgtype.Text
+	CreatedAt pgtype.Timestamp
+}
+
+type UserRecoveryCode struct {
+	ID        int32
+	UserID    int32
+	CodeHash  string
+	UsedAt    pgtype.Timestamp
 	CreatedAt pgtype.Timestamp
 }
+
+type Webhook struct {
+	ID        int32
+	BoardID   int32
+	Url       string
+	Secret    string
+	Events    []string
+	Active    bool
+	CreatedBy pgtype.Int4
+	CreatedAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
+}
+
+type WebhookAttempt struct {
+	ID         int32
+	DeliveryID int32
+	Attempt    int32
+	StatusCode pgtype.Int4
+	Error      pgtype.Text
+	DurationMs int32
+	CreatedAt  pgtype.Timestamp
+}
+
+type WebhookDelivery struct {
+	ID             int32
+	WebhookID      int32
+	Event          string
+	Payload        []byte
+	Status         string
+	Attempts       int32
+	NextAttemptAt  pgtype.Timestamp
+	LastStatusCode pgtype.Int4
+	LastError      pgtype.Text
+	CreatedAt      pgtype.Timestamp
+	UpdatedAt      pgtype.Timestamp
+}
@@ -2,21 +2,128 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
 	CreateBoard(ctx context.Context, arg CreateBoardParams) (Board, error)
-	GetBoards(ctx context.Context, userID int32) ([]Board, error)
+	GetBoards(ctx context.Context, userID int32) ([]GetBoardsRow, error)
+	GetBoardForUpdate(ctx context.Context, id int32) (Board, error)
 	UpdateBoard(ctx context.Context, arg UpdateBoardParams) (Board, error)
-	DeleteBoard(ctx context.Context, arg DeleteBoardParams) (int64, error)
+	DeleteBoard(ctx context.Context, id int32) (int64, error)
+
+	GetBoardMemberRole(ctx context.Context, arg GetBoardMemberRoleParams) (string, error)
+	GetTaskMemberRole(ctx context.Context, arg GetTaskMemberRoleParams) (string, error)
+	ListBoardMembers(ctx context.Context, boardID int32) ([]ListBoardMembersRow, error)
+	AddBoardMember(ctx context.Context, arg AddBoardMemberParams) (BoardMember, error)
+	UpdateBoardMemberRole(ctx context.Context, arg UpdateBoardMemberRoleParams) (BoardMember, error)
+	RemoveBoardMember(ctx context.Context, arg RemoveBoardMemberParams) (int64, error)
+	LockBoardOwners(ctx context.Context, boardID int32) ([]int32, error)
+
+	CreateDefaultColumns(ctx context.Context, boardID int32) ([]BoardColumn, error)
+	ListBoardColumns(ctx context.Context, boardID int32) ([]BoardColumn, error)
+	GetBoardColumn(ctx context.Context, arg GetBoardColumnParams) (BoardColumn, error)
+	GetBoardColumnByName(ctx context.Context, arg GetBoardColumnByNameParams) (BoardColumn, error)
+	GetFirstBoardColumn(ctx context.Context, boardID int32) (BoardColumn, error)
+	GetColumnWIP(ctx context.Context, arg GetColumnWIPParams) (GetColumnWIPRow, error)
+	CreateBoardColumn(ctx context.Context, arg CreateBoardColumnParams) (BoardColumn, error)
+	UpdateBoardColumn(ctx context.Context, arg UpdateBoardColumnParams) (BoardColumn, error)
+	ReorderBoardColumns(ctx context.Context, arg ReorderBoardColumnsParams) (int64, error)
+	DeleteBoardColumn(ctx context.Context, arg DeleteBoardColumnParams) (int64, error)
+
+	CreateInvitation(ctx context.Context, arg CreateInvitationParams) (BoardInvitation, error)
+	ListBoardInvitations(ctx context.Context, boardID int32) ([]BoardInvitation, error)
+	GetInvitationByTokenHash(ctx context.Context, tokenHash string) (BoardInvitation, error)
+	UpdateInvitationStatus(ctx context.Context, arg UpdateInvitationStatusParams) (int64, error)
+	RevokeInvitation(ctx context.Context, arg RevokeInvitationParams) (int64, error)
+	AcceptPendingInvitations(ctx context.Context, arg AcceptPendingInvitationsParams) ([]BoardMember, error)
 
 	CreateTask(ctx context.Context, arg CreateTaskParams) (CreateTaskRow, error)
 	GetTasks(ctx context.Context, arg GetTasksParams) ([]GetTasksRow, error)
+	GetAssignedTasks(ctx context.Context, arg GetAssignedTasksParams) ([]GetAssignedTasksRow, error)
+	GetTaskForUpdate(ctx context.Context, arg GetTaskForUpdateParams) (Task, error)
 	UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error)
 	DeleteTask(ctx context.Context, arg DeleteTaskParams) (int64, error)
+	CountColumnTasks(ctx context.Context, columnID pgtype.Int4) (int64, error)
+	SyncColumnStatus(ctx context.Context, arg SyncColumnStatusParams) error
+	MoveTask(ctx context.Context, arg MoveTaskParams) (Task, error)
+	GetTaskRank(ctx context.Context, arg GetTaskRankParams) (GetTaskRankRow, error)
+	GetNextRank(ctx context.Context, arg GetNextRankParams) (string, error)
+	GetPrevRank(ctx context.Context, arg GetPrevRankParams) (string, error)
+	GetLastRank(ctx context.Context, arg GetLastRankParams) (string, error)
+	ListColumnRanks(ctx context.Context, arg ListColumnRanksParams) ([]ListColumnRanksRow, error)
+	UpdateTaskRanks(ctx context.Context, arg UpdateTaskRanksParams) error
+
+	SetTaskAssignees(ctx context.Context, arg SetTaskAssigneesParams) error
+	ListTaskAssignees(ctx context.Context, taskID int32) ([]int32, error)
+	CountBoardMembersByIDs(ctx context.Context, arg CountBoardMembersByIDsParams) (int64, error)
+	UnassignUserFromBoard(ctx context.Context, arg UnassignUserFromBoardParams) error
+
+	CreateComment(ctx context.Context, arg CreateCommentParams) (TaskComment, error)
+	GetComment(ctx context.Context, arg GetCommentParams) (TaskComment, error)
+	ListComments(ctx context.Context, arg ListCommentsParams) ([]TaskComment, error)
+	CountComments(ctx context.Context, taskID int32) (int64, error)
+	ListCommentReplies(ctx context.Context, parentIds []int32) ([]TaskComment, error)
+	UpdateComment(ctx context.Context, arg UpdateCommentParams) (TaskComment, error)
+	SoftDeleteComment(ctx context.Context, arg SoftDeleteCommentParams) (int64, error)
+	ListCommentEdits(ctx context.Context, commentID int32) ([]TaskCommentEdit, error)
+
+	CreateActivityEvent(ctx context.Context, arg CreateActivityEventParams) error
+	ListTaskActivity(ctx context.Context, arg ListTaskActivityParams) ([]ActivityEvent, error)
+	ListBoardActivity(ctx context.Context, arg ListBoardActivityParams) ([]ActivityEvent, error)
+
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	ListWebhooks(ctx context.Context, boardID int32) ([]Webhook, error)
+	GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook, error)
+	UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error)
+	DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error)
+	EnqueueWebhookDeliveries(ctx context.Context, arg EnqueueWebhookDeliveriesParams) error
+	ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]ClaimDueWebhookDeliveriesRow, error)
+	CreateWebhookAttempt(ctx context.Context, arg CreateWebhookAttemptParams) error
+	UpdateWebhookDeliveryResult(ctx context.Context, arg UpdateWebhookDeliveryResultParams) error
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, arg GetWebhookDeliveryParams) (WebhookDelivery, error)
+	ListWebhookAttempts(ctx context.Context, deliveryID int32) ([]WebhookAttempt, error)
+	RedeliverWebhookDelivery(ctx context.Context, arg RedeliverWebhookDeliveryParams) (WebhookDelivery, error)
 
 	CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error)
 	GetUserByEmail(ctx context.Context, email string) (GetUserByEmailRow, error)
+	GetUserByID(ctx context.Context, id int32) (GetUserByIDRow, error)
+	GetUserPassword(ctx context.Context, id int32) (GetUserPasswordRow, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	// Заменяет хеш, только если пароль не сменили с момента проверки
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error)
+	MarkEmailVerified(ctx context.Context, id int32) (int64, error)
+	GetUserProfile(ctx context.Context, id int32) (GetUserProfileRow, error)
+	// Пустая строка в display_name или avatar_url очищает поле
+	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error)
+	ListUserSummaries(ctx context.Context, ids []int32) ([]ListUserSummariesRow, error)
+
+	UpsertUserAvatar(ctx context.Context, arg UpsertUserAvatarParams) error
+	GetUserAvatar(ctx context.Context, userID int32) (UserAvatar, error)
+	DeleteUserAvatar(ctx context.Context, userID int32) (int64, error)
+
+	CreateSecurityEvent(ctx context.Context, arg CreateSecurityEventParams) error
+
+	GetUserTOTP(ctx context.Context, id int32) (GetUserTOTPRow, error)
+	SetPendingTOTPSecret(ctx context.Context, arg SetPendingTOTPSecretParams) (int64, error)
+	EnableTOTP(ctx context.Context, id int32) (int64, error)
+	DisableTOTP(ctx context.Context, id int32) error
+	CreateRecoveryCodes(ctx context.Context, arg CreateRecoveryCodesParams) error
+	DeleteRecoveryCodes(ctx context.Context, userID int32) error
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID int32) (int64, error)
+
+	CreatePersonalAccessToken(ctx context.Context, arg CreatePersonalAccessTokenParams) (PersonalAccessToken, error)
+	ListPersonalAccessTokens(ctx context.Context, userID int32) ([]PersonalAccessToken, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (PersonalAccessToken, error)
+	TouchPersonalAccessToken(ctx context.Context, id int32) error
+	DeletePersonalAccessToken(ctx context.Context, arg DeletePersonalAccessTokenParams) (int64, error)
+
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error)
+	GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error)
+	ListUserIdentities(ctx context.Context, userID int32) ([]UserIdentity, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store — Querier с поддержкой транзакций
+type Store interface {
+	Querier
+	ExecTx(ctx context.Context, fn func(Querier) error) error
+}
+
+type SQLStore struct {
+	*Queries
+	pool *pgxpool.Pool
+}
+
+func NewStore(pool *pgxpool.Pool) *SQLStore {
+	return &SQLStore{Queries: New(pool), pool: pool}
+}
+
+// ExecTx выполняет fn в одной транзакции: коммит при nil, откат при ошибке
+func (s *SQLStore) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(s.WithTx(tx))
+	})
+}
+
+var _ Store = (*SQLStore)(nil)
@@ -11,10 +11,22 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countColumnTasks = `-- name: CountColumnTasks :one
+SELECT count(*) FROM tasks
+WHERE column_id = $1
+`
+
+func (q *Queries) CountColumnTasks(ctx context.Context, columnID pgtype.Int4) (int64, error) {
+	row := q.db.QueryRow(ctx, countColumnTasks, columnID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createTask = `-- name: CreateTask :one
-INSERT INTO tasks (board_id, user_id, title, description, status, priority, deadline)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, user_id, title, description, status, priority, deadline, created_at, updated_at, board_id
+INSERT INTO tasks (board_id, user_id, title, description, status, priority, deadline, column_id, rank)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, user_id, title, description, status, priority, deadline, created_at, updated_at, board_id, column_id, rank
 `
 
 type CreateTaskParams struct {
@@ -25,6 +37,8 @@ type CreateTaskParams struct {
 	Status      pgtype.Text
 	Priority    string
 	Deadline    pgtype.Timestamp
+	ColumnID    pgtype.Int4
+	Rank        string
 }
 
 type CreateTaskRow struct {
@@ -38,6 +52,8 @@ type CreateTaskRow struct {
 	CreatedAt   pgtype.Timestamp
 	UpdatedAt   pgtype.Timestamp
 	BoardID     pgtype.Int4
+	ColumnID    pgtype.Int4
+	Rank        string
 }
 
 func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (CreateTaskRow, error) {
@@ -49,6 +65,8 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (CreateT
 		arg.Status,
 		arg.Priority,
 		arg.Deadline,
+		arg.ColumnID,
+		arg.Rank,
 	)
 	var i CreateTaskRow
 	err := row.Scan(
@@ -62,32 +80,258 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (CreateT
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.BoardID,
+		&i.ColumnID,
+		&i.Rank,
 	)
 	return i, err
 }
 
 const deleteTask = `-- name: DeleteTask :execrows
 DELETE FROM tasks
-WHERE id = $1 AND board_id = $2 AND user_id = $3
+WHERE id = $1 AND board_id = $2
 `
 
 type DeleteTaskParams struct {
 	ID      int32
 	BoardID pgtype.Int4
-	UserID  int32
 }
 
 func (q *Queries) DeleteTask(ctx context.Context, arg DeleteTaskParams) (int64, error) {
-	result, err := q.db.Exec(ctx, deleteTask, arg.ID, arg.BoardID, arg.UserID)
+	result, err := q.db.Exec(ctx, deleteTask, arg.ID, arg.BoardID)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected(), nil
 }
 
+const getAssignedTasks = `-- name: GetAssignedTasks :many
+SELECT
+  t.id, t.user_id, t.title, t.description, t.status, t.priority, t.deadline, t.created_at, t.updated_at, t.board_id, t.column_id, t.rank,
+  ARRAY(
+    SELECT ta.user_id FROM task_assignees ta WHERE ta.task_id = t.id ORDER BY ta.user_id
+  )::int[] AS assignee_ids
+FROM tasks t
+JOIN task_assignees a ON a.task_id = t.id AND a.user_id = $1
+JOIN board_members bm ON bm.board_id = t.board_id AND bm.user_id = $1
+WHERE (
+    COALESCE($2::text, '') = '' OR
+    t.title ILIKE '%' || $2::text || '%' OR
+    t.description ILIKE '%' || $2::text || '%'
+  )
+  AND (
+    COALESCE($3::text, '') = '' OR
+    t.status = $3
+  )
+  AND (
+    COALESCE($4::text, '') = '' OR
+    t.priority = $4
+  )
+  AND (
+    $5::bool = false
+    OR (
+      $5::bool = true AND
+      (
+        ($6::bool = true AND t.deadline IS NOT NULL)
+        OR
+        ($6::bool = false AND t.deadline IS NULL)
+      )
+    )
+  )
+ORDER BY
+  CASE WHEN $7::int = 0 THEN t.created_at END DESC,
+  CASE WHEN $7::int = 1 THEN t.created_at END ASC,
+  CASE WHEN $7::int = 2 THEN t.deadline END ASC NULLS LAST,
+  CASE WHEN $7::int = 3 THEN t.deadline END DESC NULLS LAST,
+  CASE WHEN $7::int = 4 THEN t.rank END ASC,
+  t.created_at DESC
+`
+
+type GetAssignedTasksParams struct {
+	UserID         int32
+	Search         string
+	Status         string
+	Priority       string
+	HasDeadlineSet bool
+	HasDeadline    bool
+	SortCode       int32
+}
+
+type GetAssignedTasksRow struct {
+	ID          int32
+	UserID      int32
+	Title       string
+	Description pgtype.Text
+	Status      pgtype.Text
+	Priority    string
+	Deadline    pgtype.Timestamp
+	CreatedAt   pgtype.Timestamp
+	UpdatedAt   pgtype.Timestamp
+	BoardID     pgtype.Int4
+	ColumnID    pgtype.Int4
+	Rank        string
+	AssigneeIds []int32
+}
+
+func (q *Queries) GetAssignedTasks(ctx context.Context, arg GetAssignedTasksParams) ([]GetAssignedTasksRow, error) {
+	rows, err := q.db.Query(ctx, getAssignedTasks,
+		arg.UserID,
+		arg.Search,
+		arg.Status,
+		arg.Priority,
+		arg.HasDeadlineSet,
+		arg.HasDeadline,
+		arg.SortCode,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAssignedTasksRow
+	for rows.Next() {
+		var i GetAssignedTasksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.Deadline,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.BoardID,
+			&i.ColumnID,
+			&i.Rank,
+			&i.AssigneeIds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLastRank = `-- name: GetLastRank :one
+SELECT rank FROM tasks
+WHERE column_id = $1 AND id <> $2
+ORDER BY rank DESC, id DESC
+LIMIT 1
+`
+
+type GetLastRankParams struct {
+	ColumnID pgtype.Int4
+	TaskID   int32
+}
+
+func (q *Queries) GetLastRank(ctx context.Context, arg GetLastRankParams) (string, error) {
+	row := q.db.QueryRow(ctx, getLastRank, arg.ColumnID, arg.TaskID)
+	var rank string
+	err := row.Scan(&rank)
+	return rank, err
+}
+
+const getNextRank = `-- name: GetNextRank :one
+SELECT rank FROM tasks
+WHERE column_id = $1 AND id <> $2 AND rank > $3
+ORDER BY rank, id
+LIMIT 1
+`
+
+type GetNextRankParams struct {
+	ColumnID pgtype.Int4
+	TaskID   int32
+	Rank     string
+}
+
+func (q *Queries) GetNextRank(ctx context.Context, arg GetNextRankParams) (string, error) {
+	row := q.db.QueryRow(ctx, getNextRank, arg.ColumnID, arg.TaskID, arg.Rank)
+	var rank string
+	err := row.Scan(&rank)
+	return rank, err
+}
+
+const getPrevRank = `-- name: GetPrevRank :one
+SELECT rank FROM tasks
+WHERE column_id = $1 AND id <> $2 AND rank < $3
+ORDER BY rank DESC, id DESC
+LIMIT 1
+`
+
+type GetPrevRankParams struct {
+	ColumnID pgtype.Int4
+	TaskID   int32
+	Rank     string
+}
+
+func (q *Queries) GetPrevRank(ctx context.Context, arg GetPrevRankParams) (string, error) {
+	row := q.db.QueryRow(ctx, getPrevRank, arg.ColumnID, arg.TaskID, arg.Rank)
+	var rank string
+	err := row.Scan(&rank)
+	return rank, err
+}
+
+const getTaskForUpdate = `-- name: GetTaskForUpdate :one
+SELECT id, user_id, title, description, status, created_at, updated_at, board_id, priority, deadline, column_id, rank FROM tasks
+WHERE id = $1 AND board_id = $2
+FOR UPDATE
+`
+
+type GetTaskForUpdateParams struct {
+	ID      int32
+	BoardID pgtype.Int4
+}
+
+func (q *Queries) GetTaskForUpdate(ctx context.Context, arg GetTaskForUpdateParams) (Task, error) {
+	row := q.db.QueryRow(ctx, getTaskForUpdate, arg.ID, arg.BoardID)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BoardID,
+		&i.Priority,
+		&i.Deadline,
+		&i.ColumnID,
+		&i.Rank,
+	)
+	return i, err
+}
+
+const getTaskRank = `-- name: GetTaskRank :one
+SELECT column_id, rank FROM tasks
+WHERE id = $1 AND board_id = $2
+`
+
+type GetTaskRankParams struct {
+	ID      int32
+	BoardID pgtype.Int4
+}
+
+type GetTaskRankRow struct {
+	ColumnID pgtype.Int4
+	Rank     string
+}
+
+func (q *Queries) GetTaskRank(ctx context.Context, arg GetTaskRankParams) (GetTaskRankRow, error) {
+	row := q.db.QueryRow(ctx, getTaskRank, arg.ID, arg.BoardID)
+	var i GetTaskRankRow
+	err := row.Scan(&i.ColumnID, &i.Rank)
+	return i, err
+}
+
 const getTasks = `-- name: GetTasks :many
 SELECT 
-  id, user_id, title, description, status, priority, deadline, created_at, updated_at, board_id
+  id, user_id, title, description, status, priority, deadline, created_at, updated_at, board_id, column_id, rank,
+  ARRAY(
+    SELECT ta.user_id FROM task_assignees ta WHERE ta.task_id = tasks.id ORDER BY ta.user_id
+  )::int[] AS assignee_ids
 FROM tasks
 WHERE board_id = $1
   AND (
@@ -119,6 +363,7 @@ ORDER BY
   CASE WHEN $7::int = 1 THEN created_at END ASC,
   CASE WHEN $7::int = 2 THEN deadline END ASC NULLS LAST,
   CASE WHEN $7::int = 3 THEN deadline END DESC NULLS LAST,
+  CASE WHEN $7::int = 4 THEN rank END ASC,
   created_at DESC
 `
 
@@ -143,6 +388,9 @@ type GetTasksRow struct {
 	CreatedAt   pgtype.Timestamp
 	UpdatedAt   pgtype.Timestamp
 	BoardID     pgtype.Int4
+	ColumnID    pgtype.Int4
+	Rank        string
+	AssigneeIds []int32
 }
 
 func (q *Queries) GetTasks(ctx context.Context, arg GetTasksParams) ([]GetTasksRow, error) {
@@ -173,6 +421,9 @@ func (q *Queries) GetTasks(ctx context.Context, arg GetTasksParams) ([]GetTasksR
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.BoardID,
+			&i.ColumnID,
+			&i.Rank,
+			&i.AssigneeIds,
 		); err != nil {
 			return nil, err
 		}
@@ -184,6 +435,99 @@ func (q *Queries) GetTasks(ctx context.Context, arg GetTasksParams) ([]GetTasksR
 	return items, nil
 }
 
+const listColumnRanks = `-- name: ListColumnRanks :many
+SELECT id, rank FROM tasks
+WHERE column_id = $1 AND id <> $2
+ORDER BY rank, id
+`
+
+type ListColumnRanksParams struct {
+	ColumnID pgtype.Int4
+	TaskID   int32
+}
+
+type ListColumnRanksRow struct {
+	ID   int32
+	Rank string
+}
+
+func (q *Queries) ListColumnRanks(ctx context.Context, arg ListColumnRanksParams) ([]ListColumnRanksRow, error) {
+	rows, err := q.db.Query(ctx, listColumnRanks, arg.ColumnID, arg.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListColumnRanksRow
+	for rows.Next() {
+		var i ListColumnRanksRow
+		if err := rows.Scan(&i.ID, &i.Rank); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveTask = `-- name: MoveTask :one
+UPDATE tasks
+SET column_id = $1, status = $2, rank = $3, updated_at = now()
+WHERE id = $4 AND board_id = $5
+RETURNING id, user_id, title, description, status, created_at, updated_at, board_id, priority, deadline, column_id, rank
+`
+
+type MoveTaskParams struct {
+	ColumnID pgtype.Int4
+	Status   pgtype.Text
+	Rank     string
+	ID       int32
+	BoardID  pgtype.Int4
+}
+
+func (q *Queries) MoveTask(ctx context.Context, arg MoveTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, moveTask,
+		arg.ColumnID,
+		arg.Status,
+		arg.Rank,
+		arg.ID,
+		arg.BoardID,
+	)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BoardID,
+		&i.Priority,
+		&i.Deadline,
+		&i.ColumnID,
+		&i.Rank,
+	)
+	return i, err
+}
+
+const syncColumnStatus = `-- name: SyncColumnStatus :exec
+UPDATE tasks
+SET status = $1
+WHERE column_id = $2
+`
+
+type SyncColumnStatusParams struct {
+	Name     pgtype.Text
+	ColumnID pgtype.Int4
+}
+
+func (q *Queries) SyncColumnStatus(ctx context.Context, arg SyncColumnStatusParams) error {
+	_, err := q.db.Exec(ctx, syncColumnStatus, arg.Name, arg.ColumnID)
+	return err
+}
+
 const updateTask = `-- name: UpdateTask :one
 UPDATE tasks
 SET
@@ -192,9 +536,11 @@ SET
     status = COALESCE($3, status),
     priority = COALESCE($4, priority),
     deadline = COALESCE($5, deadline),
+    column_id = COALESCE($6, column_id),
+    rank = COALESCE($7, rank),
     updated_at = now()
-WHERE id = $6 AND board_id = $7 AND user_id = $8
-RETURNING id, user_id, title, description, status, created_at, updated_at, board_id, priority, deadline
+WHERE id = $8 AND board_id = $9
+RETURNING id, user_id, title, description, status, created_at, updated_at, board_id, priority, deadline, column_id, rank
 `
 
 type UpdateTaskParams struct {
@@ -203,9 +549,10 @@ type UpdateTaskParams struct {
 	Status      pgtype.Text
 	Priority    pgtype.Text
 	Deadline    pgtype.Timestamp
+	ColumnID    pgtype.Int4
+	Rank        pgtype.Text
 	ID          int32
 	BoardID     pgtype.Int4
-	UserID      int32
 }
 
 func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error) {
@@ -215,9 +562,10 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, e
 		arg.Status,
 		arg.Priority,
 		arg.Deadline,
+		arg.ColumnID,
+		arg.Rank,
 		arg.ID,
 		arg.BoardID,
-		arg.UserID,
 	)
 	var i Task
 	err := row.Scan(
@@ -231,6 +579,25 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, e
 		&i.BoardID,
 		&i.Priority,
 		&i.Deadline,
+		&i.ColumnID,
+		&i.Rank,
 	)
 	return i, err
 }
+
+const updateTaskRanks = `-- name: UpdateTaskRanks :exec
+UPDATE tasks t
+SET rank = u.rank
+FROM unnest($1::int[], $2::text[]) AS u(id, rank)
+WHERE t.id = u.id
+`
+
+type UpdateTaskRanksParams struct {
+	Ids   []int32
+	Ranks []string
+}
+
+func (q *Queries) UpdateTaskRanks(ctx context.Context, arg UpdateTaskRanksParams) error {
+	_, err := q.db.Exec(ctx, updateTaskRanks, arg.Ids, arg.Ranks)
+	return err
+}
@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -34,21 +36,255 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password
+SELECT id, email, password, email_verified_at, totp_enabled_at
 FROM users
 WHERE email = $1
 LIMIT 1
 `
 
 type GetUserByEmailRow struct {
-	ID       int32
-	Email    string
-	Password string
+	ID              int32
+	Email           string
+	Password        string
+	EmailVerifiedAt pgtype.Timestamp
+	TotpEnabledAt   pgtype.Timestamp
 }
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEmailRow, error) {
 	row := q.db.QueryRow(ctx, getUserByEmail, email)
 	var i GetUserByEmailRow
-	err := row.Scan(&i.ID, &i.Email, &i.Password)
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Password,
+		&i.EmailVerifiedAt,
+		&i.TotpEnabledAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, email, email_verified_at, totp_enabled_at
+FROM users
+WHERE id = $1
+LIMIT 1
+`
+
+type GetUserByIDRow struct {
+	ID              int32
+	Email           string
+	EmailVerifiedAt pgtype.Timestamp
+	TotpEnabledAt   pgtype.Timestamp
+}
+
+func (q *Queries) GetUserByID(ctx context.Context, id int32) (GetUserByIDRow, error) {
+	row := q.db.QueryRow(ctx, getUserByID, id)
+	var i GetUserByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.TotpEnabledAt,
+	)
+	return i, err
+}
+
+const getUserPassword = `-- name: GetUserPassword :one
+SELECT email, password
+FROM users
+WHERE id = $1
+LIMIT 1
+`
+
+type GetUserPasswordRow struct {
+	Email    string
+	Password string
+}
+
+func (q *Queries) GetUserPassword(ctx context.Context, id int32) (GetUserPasswordRow, error) {
+	row := q.db.QueryRow(ctx, getUserPassword, id)
+	var i GetUserPasswordRow
+	err := row.Scan(&i.Email, &i.Password)
+	return i, err
+}
+
+const getUserProfile = `-- name: GetUserProfile :one
+SELECT id, email, email_verified_at, totp_enabled_at, display_name, avatar_url, timezone, locale, created_at
+FROM users
+WHERE id = $1
+LIMIT 1
+`
+
+type GetUserProfileRow struct {
+	ID              int32
+	Email           string
+	EmailVerifiedAt pgtype.Timestamp
+	TotpEnabledAt   pgtype.Timestamp
+	DisplayName     pgtype.Text
+	AvatarUrl       pgtype.Text
+	Timezone        string
+	Locale          string
+	CreatedAt       pgtype.Timestamp
+}
+
+func (q *Queries) GetUserProfile(ctx context.Context, id int32) (GetUserProfileRow, error) {
+	row := q.db.QueryRow(ctx, getUserProfile, id)
+	var i GetUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.AvatarUrl,
+		&i.Timezone,
+		&i.Locale,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listUserSummaries = `-- name: ListUserSummaries :many
+SELECT id, email, display_name, avatar_url
+FROM users
+WHERE id = ANY($1::int[])
+ORDER BY id
+`
+
+type ListUserSummariesRow struct {
+	ID          int32
+	Email       string
+	DisplayName pgtype.Text
+	AvatarUrl   pgtype.Text
+}
+
+func (q *Queries) ListUserSummaries(ctx context.Context, ids []int32) ([]ListUserSummariesRow, error) {
+	rows, err := q.db.Query(ctx, listUserSummaries, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserSummariesRow
+	for rows.Next() {
+		var i ListUserSummariesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.DisplayName,
+			&i.AvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :execrows
+UPDATE users
+SET email_verified_at = now()
+WHERE id = $1 AND email_verified_at IS NULL
+`
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, markEmailVerified, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :execrows
+UPDATE users
+SET password = $1
+WHERE id = $2 AND password = $3
+`
+
+type RehashUserPasswordParams struct {
+	NewPassword string
+	ID          int32
+	OldPassword string
+}
+
+// Заменяет хеш, только если пароль не сменили с момента проверки
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rehashUserPassword, arg.NewPassword, arg.ID, arg.OldPassword)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID       int32
+	Password string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.Password)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    display_name = NULLIF(COALESCE($1, display_name), ''),
+    avatar_url = NULLIF(COALESCE($2, avatar_url), ''),
+    timezone = COALESCE($3, timezone),
+    locale = COALESCE($4, locale)
+WHERE id = $5
+RETURNING id, email, email_verified_at, totp_enabled_at, display_name, avatar_url, timezone, locale, created_at
+`
+
+type UpdateUserProfileParams struct {
+	DisplayName pgtype.Text
+	AvatarUrl   pgtype.Text
+	Timezone    pgtype.Text
+	Locale      pgtype.Text
+	ID          int32
+}
+
+type UpdateUserProfileRow struct {
+	ID              int32
+	Email           string
+	EmailVerifiedAt pgtype.Timestamp
+	TotpEnabledAt   pgtype.Timestamp
+	DisplayName     pgtype.Text
+	AvatarUrl       pgtype.Text
+	Timezone        string
+	Locale          string
+	CreatedAt       pgtype.Timestamp
+}
+
+// Пустая строка в display_name или avatar_url очищает поле
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error) {
+	row := q.db.QueryRow(ctx, updateUserProfile,
+		arg.DisplayName,
+		arg.AvatarUrl,
+		arg.Timezone,
+		arg.Locale,
+		arg.ID,
+	)
+	var i UpdateUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.EmailVerifiedAt,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.AvatarUrl,
+		&i.Timezone,
+		&i.Locale,
+		&i.CreatedAt,
+	)
 	return i, err
 }
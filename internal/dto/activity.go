@@ -0,0 +1,19 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ActivityEventDTO struct {
+	ID       int32  `json:"id"`
+	BoardID  int32  `json:"board_id"`
+	TaskID   *int32 `json:"task_id,omitempty"`
+	ActorID  *int32 `json:"actor_id,omitempty"`
+	Entity   string `json:"entity"`
+	EntityID int32  `json:"entity_id"`
+	Action   string `json:"action"`
+	// Changes — {"поле": {"before": ..., "after": ...}}
+	Changes   json.RawMessage `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -6,6 +6,8 @@ type BoardDTO struct {
 	ID        int32     `json:"id"`
 	UserID    int32     `json:"user_id"`
 	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"`
+	WIPMode   string    `json:"wip_mode"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -15,5 +17,6 @@ type CreateBoardRequest struct {
 }
 
 type UpdateBoardRequest struct {
-	Name *string `json:"name,omitempty"`
+	Name    *string `json:"name,omitempty"`
+	WIPMode *string `json:"wip_mode,omitempty" validate:"omitempty,oneof=strict soft"`
 }
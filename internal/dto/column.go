@@ -0,0 +1,39 @@
+package dto
+
+type ColumnDTO struct {
+	ID       int32  `json:"id"`
+	BoardID  int32  `json:"board_id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	IsDone   bool   `json:"is_done"`
+	Position int32  `json:"position"`
+	WIPLimit *int32 `json:"wip_limit"`
+}
+
+type CreateColumnRequest struct {
+	Name     string `json:"name" validate:"required,max=64"`
+	Color    string `json:"color,omitempty" validate:"omitempty,hexcolor"`
+	IsDone   bool   `json:"is_done,omitempty"`
+	WIPLimit *int32 `json:"wip_limit,omitempty" validate:"omitempty,min=1"`
+}
+
+// UpdateColumnRequest — частичное изменение колонки; wip_limit = 0 снимает лимит
+type UpdateColumnRequest struct {
+	Name     *string `json:"name,omitempty" validate:"omitempty,min=1,max=64"`
+	Color    *string `json:"color,omitempty" validate:"omitempty,hexcolor"`
+	IsDone   *bool   `json:"is_done,omitempty"`
+	WIPLimit *int32  `json:"wip_limit,omitempty" validate:"omitempty,min=0"`
+}
+
+type ReorderColumnsRequest struct {
+	ColumnIDs []int32 `json:"column_ids" validate:"required,min=1"`
+}
+
+// WIPLimitErrorDTO — тело 409, когда перенос в колонку превышает её WIP-лимит на строгой доске
+type WIPLimitErrorDTO struct {
+	Error    string `json:"error"`
+	ColumnID int32  `json:"column_id"`
+	Column   string `json:"column"`
+	Limit    int32  `json:"limit"`
+	Count    int64  `json:"count"`
+}
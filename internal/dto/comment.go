@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+type CommentDTO struct {
+	ID        int32        `json:"id"`
+	TaskID    int32        `json:"task_id"`
+	UserID    int32        `json:"user_id"`
+	ParentID  *int32       `json:"parent_id,omitempty"`
+	Body      string       `json:"body"`
+	Deleted   bool         `json:"deleted"`
+	EditedAt  *time.Time   `json:"edited_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	Replies   []CommentDTO `json:"replies,omitempty"`
+}
+
+type CommentListDTO struct {
+	Comments []CommentDTO `json:"comments"`
+	Total    int64        `json:"total"`
+	Limit    int32        `json:"limit"`
+	Offset   int32        `json:"offset"`
+}
+
+type CommentEditDTO struct {
+	Body     string    `json:"body"`
+	EditedBy int32     `json:"edited_by"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+type CreateCommentRequest struct {
+	Body     string `json:"body" validate:"required,max=10000"`
+	ParentID *int32 `json:"parent_id,omitempty"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+type InvitationDTO struct {
+	ID        int32     `json:"id"`
+	BoardID   int32     `json:"board_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InviterID int32     `json:"inviter_id"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=owner editor viewer"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type BoardMemberDTO struct {
+	BoardID   int32     `json:"board_id"`
+	UserID    int32     `json:"user_id"`
+	Email     string    `json:"email,omitempty"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AddBoardMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+type UpdateBoardMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner editor viewer"`
+}
@@ -0,0 +1,33 @@
+package dto
+
+// MFAChallengeResponse возвращается из /login вместо LoginResponse, если включена 2FA
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// MFALoginRequest — второй шаг входа; code — код из приложения или код восстановления
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFAStatusDTO struct {
+	TOTPEnabled       bool  `json:"totp_enabled"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+type TOTPEnrollmentDTO struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesDTO — коды показываются один раз, на сервере остаются только хеши
+type RecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// OIDCStartResponse — куда отправить браузер для входа через провайдера
+type OIDCStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// OIDCCallbackRequest — параметры, с которыми провайдер вернул пользователя на фронт
+type OIDCCallbackRequest struct {
+	State string `json:"state" validate:"required"`
+	Code  string `json:"code" validate:"required"`
+}
+
+type IdentityDTO struct {
+	ID        int32     `json:"id"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
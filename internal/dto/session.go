@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+type SessionDTO struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	// Current — сессия, с которой сделан запрос
+	Current bool `json:"current"`
+}
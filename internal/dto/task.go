@@ -9,24 +9,47 @@ type TaskDTO struct {
 	Title       string     `json:"title"`
 	Description string     `json:"description,omitempty"`
 	Status      string     `json:"status"`
+	ColumnID    int32      `json:"column_id"`
+	Rank        string     `json:"rank"`
 	Priority    string     `json:"priority"`
 	Deadline    *time.Time `json:"deadline,omitempty"`
+	AssigneeIDs []int32    `json:"assignee_ids"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// User и Assignees — имена и аватары для UserID и AssigneeIDs
+	User      *UserSummaryDTO  `json:"user,omitempty"`
+	Assignees []UserSummaryDTO `json:"assignees,omitempty"`
+
+	// WIPLimitExceeded — задача принята в колонку сверх её лимита (мягкий режим доски)
+	WIPLimitExceeded bool `json:"wip_limit_exceeded,omitempty"`
 }
 
 type CreateTaskRequest struct {
 	Title       string     `json:"title"`
 	Description string     `json:"description,omitempty"`
 	Status      string     `json:"status,omitempty"`
+	ColumnID    *int32     `json:"column_id,omitempty"`
 	Priority    string     `json:"priority,omitempty"`
 	Deadline    *time.Time `json:"deadline,omitempty"`
+	AssigneeIDs []int32    `json:"assignee_ids,omitempty"`
 }
 
 type UpdateTaskRequest struct {
 	Title       *string    `json:"title,omitempty"`
 	Description *string    `json:"description,omitempty"`
 	Status      *string    `json:"status,omitempty"`
+	ColumnID    *int32     `json:"column_id,omitempty"`
 	Priority    *string    `json:"priority,omitempty"`
 	Deadline    *time.Time `json:"deadline,omitempty"`
+	AssigneeIDs *[]int32   `json:"assignee_ids,omitempty"`
+}
+
+// MoveTaskRequest — перенос задачи в колонку между соседями.
+// PrevID — задача, которая окажется над перемещённой, NextID — под ней;
+// без соседей задача встаёт в конец колонки.
+type MoveTaskRequest struct {
+	ColumnID int32  `json:"column_id" validate:"required"`
+	PrevID   *int32 `json:"prev_id,omitempty"`
+	NextID   *int32 `json:"next_id,omitempty"`
 }
@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+type PersonalTokenDTO struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+	// Prefix — начало токена, чтобы узнать его в списке
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Token возвращается только при создании
+	Token string `json:"token,omitempty"`
+}
+
+type CreatePersonalTokenRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+	// ExpiresInDays — срок жизни токена; если не указан — 90 дней
+	ExpiresInDays int32 `json:"expires_in_days,omitempty" validate:"omitempty,min=1,max=365"`
+}
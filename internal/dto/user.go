@@ -1,13 +1,41 @@
 package dto
 
+import "time"
+
 type UserDTO struct {
-	ID    int32  `json:"id"`
-	Email string `json:"email"`
+	ID            int32  `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+
+	DisplayName string     `json:"display_name,omitempty"`
+	AvatarURL   string     `json:"avatar_url,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+	Locale      string     `json:"locale,omitempty"`
+	MFAEnabled  bool       `json:"mfa_enabled"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// UserSummaryDTO — то, что видят участники доски друг о друге
+type UserSummaryDTO struct {
+	ID int32 `json:"id"`
+	// Name — display_name, а если его нет — локальная часть email
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+// UpdateProfileRequest — PATCH /me; отсутствующее поле не меняется,
+// пустая строка очищает display_name и avatar_url
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name,omitempty"`
+	AvatarURL   *string `json:"avatar_url,omitempty"`
+	Timezone    *string `json:"timezone,omitempty"`
+	Locale      *string `json:"locale,omitempty"`
 }
 
 type SignupRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Email string `json:"email" validate:"required,email"`
+	// длина и стойкость проверяются парольной политикой
+	Password string `json:"password" validate:"required"`
 }
 type SignupResponse UserDTO
 
@@ -17,11 +45,47 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken string `json:"access_token"`
+	// RefreshToken пуст, если refresh-токен выдан в cookie
+	RefreshToken string `json:"refresh_token,omitempty"`
 	ExpiresIn    int64  `json:"expires_in"`
+	// CSRFToken — для заголовка X-CSRF-Token при refresh-токене в cookie
+	CSRFToken string `json:"csrf_token,omitempty"`
 }
 
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type PasswordViolationDTO struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyErrorDTO — тело 400, когда новый пароль не проходит парольную политику
+type PasswordPolicyErrorDTO struct {
+	Error      string                 `json:"error"`
+	Violations []PasswordViolationDTO `json:"violations"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookDTO struct {
+	ID      int32    `json:"id"`
+	BoardID int32    `json:"board_id"`
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Active  bool     `json:"active"`
+	// Secret возвращается только при создании и смене секрета
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL string `json:"url" validate:"required,url"`
+	// Secret можно не передавать — тогда он будет сгенерирован
+	Secret string   `json:"secret,omitempty" validate:"omitempty,min=16"`
+	Events []string `json:"events" validate:"required,min=1"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url,omitempty" validate:"omitempty,url"`
+	Secret *string  `json:"secret,omitempty" validate:"omitempty,min=16"`
+	Events []string `json:"events,omitempty" validate:"omitempty,min=1"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+type WebhookDeliveryDTO struct {
+	ID             int32           `json:"id"`
+	WebhookID      int32           `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int32          `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	// History — журнал попыток; заполняется только в ответе по одной доставке
+	History []WebhookAttemptDTO `json:"history,omitempty"`
+}
+
+type WebhookAttemptDTO struct {
+	Attempt    int32     `json:"attempt"`
+	StatusCode *int32    `json:"status_code,omitempty"`
+	Error      *string   `json:"error,omitempty"`
+	DurationMs int32     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+)
+
+// authorize достаёт пользователя из контекста и проверяет его право на action над res.
+// Если доступа нет, ответ уже записан в w и возвращается false.
+func authorize(w http.ResponseWriter, r *http.Request, az *authz.Authorizer, action authz.Action, res authz.Resource) (int32, bool) {
+	userID, _ := middleware.GetUserID(r)
+
+	err := az.Can(r.Context(), userID, action, res)
+	switch {
+	case err == nil:
+		return userID, true
+	case errors.Is(err, authz.ErrUnauthenticated):
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	case errors.Is(err, authz.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, authz.ErrForbidden):
+		http.Error(w, "not enough rights on this board", http.StatusForbidden)
+	default:
+		http.Error(w, "cannot check access", http.StatusInternalServerError)
+		log.Println("authorize error:", err)
+	}
+	return 0, false
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+type ActivityHandler struct {
+	queries db.Querier
+	authz   *authz.Authorizer
+}
+
+func NewActivityHandler(q db.Querier) *ActivityHandler {
+	return &ActivityHandler{queries: q, authz: authz.New(q)}
+}
+
+// GET /boards/{boardID}/tasks/{taskID}/history
+func (h *ActivityHandler) TaskHistory(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePage(r, defaultActivityLimit, maxActivityLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// права проверяются по доске, а не по задаче: история удалённой задачи остаётся доступной.
+	// События чужих досок не попадут в ответ — запрос отбирает их по board_id и task_id
+	userID, ok := authorize(w, r, h.authz, authz.ViewBoard, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	events, err := h.queries.ListTaskActivity(r.Context(), db.ListTaskActivityParams{
+		BoardID: int32(boardID),
+		TaskID:  pgtype.Int4{Int32: int32(taskID), Valid: true},
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		http.Error(w, "cannot fetch task history", http.StatusInternalServerError)
+		log.Println("ListTaskActivity error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[TaskHistory] task", taskID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(activityDTOs(events))
+}
+
+// GET /boards/{boardID}/activity
+func (h *ActivityHandler) BoardActivity(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePage(r, defaultActivityLimit, maxActivityLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ViewBoard, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	events, err := h.queries.ListBoardActivity(r.Context(), db.ListBoardActivityParams{
+		BoardID: int32(boardID),
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		http.Error(w, "cannot fetch board activity", http.StatusInternalServerError)
+		log.Println("ListBoardActivity error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[BoardActivity] board", boardID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(activityDTOs(events))
+}
+
+func activityDTOs(events []db.ActivityEvent) []dto.ActivityEventDTO {
+	resp := []dto.ActivityEventDTO{}
+	for _, e := range events {
+		item := dto.ActivityEventDTO{
+			ID:        e.ID,
+			BoardID:   e.BoardID,
+			Entity:    e.Entity,
+			EntityID:  e.EntityID,
+			Action:    e.Action,
+			Changes:   json.RawMessage(e.Changes),
+			CreatedAt: e.CreatedAt.Time,
+		}
+		if e.TaskID.Valid {
+			item.TaskID = &e.TaskID.Int32
+		}
+		if e.ActorID.Valid {
+			item.ActorID = &e.ActorID.Int32
+		}
+		if len(item.Changes) == 0 {
+			item.Changes = json.RawMessage("{}")
+		}
+		resp = append(resp, item)
+	}
+	return resp
+}
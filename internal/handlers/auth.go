@@ -1,25 +1,85 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sqszy/TaskTracker/internal/auth"
 	"github.com/sqszy/TaskTracker/internal/db"
 	"github.com/sqszy/TaskTracker/internal/dto"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/sqszy/TaskTracker/internal/mailer"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/ratelimit"
 )
 
 type AuthHandler struct {
 	queries db.Querier
 	authSvc *auth.Service
+	mailer  mailer.Mailer
+	appURL  string
+	// requireVerified — не выдавать токены, пока email не подтверждён
+	requireVerified bool
+	// lockout — прогрессивная блокировка входа по email после неудачных попыток; nil — выключена
+	lockout *ratelimit.Lockout
+	// cookies — refresh-токен в HttpOnly cookie; nil — в теле ответа
+	cookies *RefreshCookies
+	hasher  auth.PasswordHasher
+	policy  auth.PasswordPolicy
+	// dummyHash — хеш случайного пароля тем же хешером; с ним сравнивается пароль
+	// для несуществующего email, чтобы ответ не выдавал по времени, есть ли аккаунт
+	dummyOnce sync.Once
+	dummyHash string
 }
 
-func NewAuthHandler(q db.Querier, a *auth.Service) *AuthHandler {
-	return &AuthHandler{queries: q, authSvc: a}
+func NewAuthHandler(q db.Querier, a *auth.Service, m mailer.Mailer, appURL string) *AuthHandler {
+	return &AuthHandler{
+		queries: q,
+		authSvc: a,
+		mailer:  m,
+		appURL:  strings.TrimRight(appURL, "/"),
+		hasher:  auth.NewArgon2idHasher(auth.DefaultArgon2idParams),
+		policy:  auth.DefaultPasswordPolicy,
+	}
+}
+
+// RequireVerifiedEmail включает отказ во входе для аккаунтов с неподтверждённым email
+func (h *AuthHandler) RequireVerifiedEmail(on bool) *AuthHandler {
+	h.requireVerified = on
+	return h
+}
+
+// WithLoginLockout включает блокировку входа в аккаунт после серии неверных паролей
+func (h *AuthHandler) WithLoginLockout(l *ratelimit.Lockout) *AuthHandler {
+	h.lockout = l
+	return h
+}
+
+// WithPasswordHasher задаёт алгоритм хеширования паролей (по умолчанию Argon2id с параметрами RFC 9106)
+func (h *AuthHandler) WithPasswordHasher(p auth.PasswordHasher) *AuthHandler {
+	h.hasher = p
+	return h
+}
+
+// WithPasswordPolicy задаёт требования к новым паролям
+func (h *AuthHandler) WithPasswordPolicy(p auth.PasswordPolicy) *AuthHandler {
+	h.policy = p
+	return h
+}
+
+// WithRefreshCookies включает выдачу refresh-токена в cookie для браузерного клиента
+func (h *AuthHandler) WithRefreshCookies(c *RefreshCookies) *AuthHandler {
+	h.cookies = c
+	return h
 }
 
 func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
@@ -35,24 +95,33 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 	if err := validate.Struct(req); err != nil {
 		log.Println("Validation failed:", err)
-		http.Error(w, "invalid email or password too short", http.StatusBadRequest)
+		http.Error(w, "invalid email or password", http.StatusBadRequest)
+		return
+	}
+	if !h.checkPassword(w, req.Password, req.Email) {
 		return
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hash, err := h.hasher.Hash(req.Password)
 	if err != nil {
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 	user, err := h.queries.CreateUser(r.Context(), db.CreateUserParams{
 		Email:    req.Email,
-		Password: string(hash),
+		Password: hash,
 	})
 	if err != nil {
 		log.Println("User already exists:")
 		http.Error(w, "user already exists", http.StatusConflict)
 		return
 	}
+
+	// аккаунт уже создан: если письмо не ушло, его можно запросить повторно
+	if err := h.sendVerification(r.Context(), user.ID, user.Email); err != nil {
+		log.Println("cannot send verification email:", err)
+	}
+
 	resp := dto.UserDTO{
 		ID:    user.ID,
 		Email: user.Email,
@@ -74,64 +143,187 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 
+	// заблокированный аккаунт отклоняется до сравнения пароля: хеширование не тратится на подбор
+	if !h.reserveLoginAttempt(w, r, req.Email) {
+		return
+	}
+
 	user, err := h.queries.GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
+		h.verifyDummyPassword(req.Password)
 		http.Error(w, "invalid email or password", http.StatusUnauthorized)
 		return
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+	ok, rehash, err := h.hasher.Verify(user.Password, req.Password)
+	if err != nil {
+		log.Println("cannot verify password of user", user.ID, "error:", err)
+	}
+	if !ok {
 		http.Error(w, "invalid email or password", http.StatusUnauthorized)
 		return
 	}
-	tp, err := h.authSvc.GenerateTokenPair(r.Context(), user.ID)
+	h.loginSucceeded(r.Context(), req.Email)
+	if rehash {
+		h.rehashPassword(r.Context(), user.ID, user.Password, req.Password)
+	}
+	if h.requireVerified && !user.EmailVerifiedAt.Valid {
+		http.Error(w, "email not verified", http.StatusForbidden)
+		return
+	}
+	completeLogin(w, r, h.authSvc, h.cookies, user.ID, user.Email, user.TotpEnabledAt.Valid)
+}
+
+// verifyDummyPassword тратит на неизвестный email столько же, сколько проверка настоящего пароля
+func (h *AuthHandler) verifyDummyPassword(password string) {
+	h.dummyOnce.Do(func() {
+		hash, err := h.hasher.Hash(rand.Text())
+		if err != nil {
+			log.Println("cannot hash dummy password, error:", err)
+			return
+		}
+		h.dummyHash = hash
+	})
+	if h.dummyHash == "" {
+		return
+	}
+	_, _, _ = h.hasher.Verify(h.dummyHash, password)
+}
+
+// rehashPassword пересчитывает устаревший хеш (bcrypt или старые параметры Argon2id) —
+// открытый пароль есть только в момент входа. Ошибка не мешает войти.
+func (h *AuthHandler) rehashPassword(ctx context.Context, userID int32, oldHash, password string) {
+	hash, err := h.hasher.Hash(password)
 	if err != nil {
-		http.Error(w, "cannot generate token", http.StatusInternalServerError)
+		log.Println("cannot rehash password:", err)
 		return
 	}
-	resp := dto.LoginResponse{
-		AccessToken:  tp.AccessToken,
-		RefreshToken: tp.RefreshToken,
-		ExpiresIn:    tp.ExpiresIn,
+	if _, err := h.queries.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		NewPassword: hash,
+		ID:          userID,
+		OldPassword: oldHash,
+	}); err != nil {
+		log.Println("cannot rehash password:", err)
+		return
 	}
-	log.Println("User logged in:", req.Email)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
+	log.Println("Password hash upgraded for user", userID)
+}
+
+// completeLogin завершает вход после проверки первого фактора (пароль или провайдер OIDC):
+// выдаёт токены или, если включена 2FA, токен второго шага
+func completeLogin(w http.ResponseWriter, r *http.Request, a *auth.Service, cookies *RefreshCookies, userID int32, email string, mfa bool) {
+	if mfa {
+		// первый фактор пройден, но токены выдаются только после кода из приложения: POST /login/mfa
+		challenge, err := a.IssueMFAChallenge(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "cannot start mfa challenge", http.StatusInternalServerError)
+			log.Println("cannot issue mfa challenge:", err)
+			return
+		}
+		log.Println("MFA required for user:", email)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(dto.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    challenge,
+			ExpiresIn:   int64(auth.MFAChallengeTTL.Seconds()),
+		})
+		return
+	}
+	tp, err := a.StartSession(r.Context(), userID, sessionMeta(r))
+	if err != nil {
+		http.Error(w, "cannot generate token", http.StatusInternalServerError)
+		return
+	}
+	log.Println("User logged in:", email)
+	if err := writeTokens(w, cookies, tp); err != nil {
 		http.Error(w, "failed to log in", http.StatusInternalServerError)
 		return
 	}
 }
 
+// reserveLoginAttempt учитывает попытку входа до проверки пароля; false — ответ уже отправлен.
+// Счётчик растёт атомарно, поэтому параллельные запросы не проверят больше паролей, чем
+// разрешено. Несуществующие email считаются так же, чтобы по блокировке нельзя было узнать,
+// зарегистрирован ли адрес. После верного пароля счётчик сбрасывает loginSucceeded.
+func (h *AuthHandler) reserveLoginAttempt(w http.ResponseWriter, r *http.Request, email string) bool {
+	if h.lockout == nil {
+		return true
+	}
+	d, err := h.lockout.Attempt(r.Context(), email)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("cannot count login attempt:", err)
+		return false
+	}
+	if d > 0 {
+		log.Println("Login locked for", email, "for", d)
+		middleware.TooManyRequests(w, d)
+		return false
+	}
+	return true
+}
+
+func (h *AuthHandler) loginSucceeded(ctx context.Context, email string) {
+	if h.lockout == nil {
+		return
+	}
+	if err := h.lockout.Reset(ctx, email); err != nil {
+		log.Println("cannot reset login lockout:", err)
+	}
+}
+
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	log.Println("Refresh called")
 
-	var req dto.RefreshRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	refreshToken, err := refreshTokenFrom(r)
+	if err != nil {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
-	tp, err := h.authSvc.Refresh(r.Context(), req.RefreshToken)
+	meta := sessionMeta(r)
+	tp, err := h.authSvc.RefreshSession(r.Context(), refreshToken, meta)
+	var reuse *auth.ReuseError
+	if errors.As(err, &reuse) {
+		log.Println("Refresh token reuse detected for user", reuse.UserID, "session", reuse.SessionID)
+		recordSecurityEvent(r.Context(), h.queries, reuse.UserID, securityRefreshReuse, meta, map[string]string{
+			"session_id": reuse.SessionID,
+			"token_id":   reuse.TokenID,
+		})
+		h.clearCookies(w)
+		http.Error(w, "refresh token reuse detected, session revoked", http.StatusUnauthorized)
+		return
+	}
 	if err != nil {
+		h.clearCookies(w)
 		http.Error(w, "invalid refresh token", http.StatusUnauthorized)
 		return
 	}
 	log.Println("Refresh done")
-	if err := json.NewEncoder(w).Encode(tp); err != nil {
+	if err := writeTokens(w, h.cookies, tp); err != nil {
 		http.Error(w, "failed to refresh", http.StatusInternalServerError)
 		return
 	}
 }
 
+// clearCookies удаляет refresh-cookie, если включён режим cookie
+func (h *AuthHandler) clearCookies(w http.ResponseWriter) {
+	if h.cookies != nil {
+		h.cookies.clear(w)
+	}
+}
+
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	log.Println("Logout called")
 
-	var req dto.RefreshRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	refreshToken, err := refreshTokenFrom(r)
+	if err != nil {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
-	if err := h.authSvc.Revoke(r.Context(), req.RefreshToken); err != nil {
+	if err := h.authSvc.Revoke(r.Context(), refreshToken); err != nil {
 		http.Error(w, "cannot revoke token", http.StatusInternalServerError)
 		return
 	}
+	h.clearCookies(w)
 	log.Println("Logout done")
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]bool{"success": true}); err != nil {
@@ -139,3 +331,344 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// POST /password/forgot — ответ одинаковый для известных и неизвестных адресов,
+// чтобы по нему нельзя было проверить, зарегистрирован ли email
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	log.Println("ForgotPassword called")
+
+	var req dto.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "invalid email", http.StatusBadRequest)
+		return
+	}
+
+	// ответ одинаковый для любого email; в паузе между письмами запрос ничего не отправляет
+	if user, err := h.queries.GetUserByEmail(r.Context(), req.Email); err == nil {
+		err := h.sendPasswordReset(r.Context(), user.ID, user.Email)
+		if err != nil && !errors.Is(err, auth.ErrResetCooldown) {
+			log.Println("cannot send password reset:", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// POST /password/reset — меняет пароль по токену из письма и завершает все сессии пользователя
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	log.Println("ResetPassword called")
+
+	var req dto.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	// пароль проверяется до погашения токена, чтобы опечатка не сжигала ссылку
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "token and password are required", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.authSvc.PasswordResetUser(r.Context(), req.Token)
+	if errors.Is(err, auth.ErrInvalidResetToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("cannot read reset token:", err)
+		return
+	}
+	user, err := h.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("GetUserByID error:", err)
+		return
+	}
+	if !h.checkPassword(w, req.Password, user.Email) {
+		return
+	}
+
+	hash, err := h.hasher.Hash(req.Password)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	// токен мог погасить параллельный запрос
+	if _, err := h.authSvc.ConsumePasswordReset(r.Context(), req.Token); errors.Is(err, auth.ErrInvalidResetToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("cannot consume reset token:", err)
+		return
+	}
+
+	if err := h.queries.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
+		ID:       userID,
+		Password: hash,
+	}); err != nil {
+		http.Error(w, "cannot update password", http.StatusInternalServerError)
+		log.Println("cannot update password:", err)
+		return
+	}
+	if err := h.authSvc.RevokeAll(r.Context(), userID); err != nil {
+		http.Error(w, "cannot revoke sessions", http.StatusInternalServerError)
+		log.Println("cannot revoke sessions:", err)
+		return
+	}
+
+	log.Println("Password reset for user", userID)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// POST /password/change — смена пароля по текущему паролю. Остальные сессии завершаются,
+// текущая получает новую пару токенов.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "current_password and new_password are required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.queries.GetUserPassword(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("GetUserPassword error:", err)
+		return
+	}
+	// подбор текущего пароля с украденным access-токеном упирается в ту же блокировку, что и вход
+	if !h.reserveLoginAttempt(w, r, user.Email) {
+		return
+	}
+	ok, _, err = h.hasher.Verify(user.Password, req.CurrentPassword)
+	if err != nil {
+		log.Println("cannot verify password of user", userID, "error:", err)
+	}
+	if !ok {
+		http.Error(w, "invalid current password", http.StatusForbidden)
+		return
+	}
+	h.loginSucceeded(r.Context(), user.Email)
+	if !h.checkPassword(w, req.NewPassword, user.Email) {
+		return
+	}
+
+	hash, err := h.hasher.Hash(req.NewPassword)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.queries.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
+		ID:       userID,
+		Password: hash,
+	}); err != nil {
+		http.Error(w, "cannot update password", http.StatusInternalServerError)
+		log.Println("cannot update password:", err)
+		return
+	}
+	if err := h.authSvc.RevokeAll(r.Context(), userID); err != nil {
+		http.Error(w, "cannot revoke sessions", http.StatusInternalServerError)
+		log.Println("cannot revoke sessions:", err)
+		return
+	}
+	meta := sessionMeta(r)
+	recordSecurityEvent(r.Context(), h.queries, userID, securityPasswordChanged, meta, map[string]string{})
+
+	tp, err := h.authSvc.StartSession(r.Context(), userID, meta)
+	if err != nil {
+		http.Error(w, "cannot create session", http.StatusInternalServerError)
+		log.Println("StartSession error:", err)
+		return
+	}
+	log.Println("Password changed for user", userID)
+	if err := writeTokens(w, h.cookies, tp); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+// checkPassword проверяет новый пароль политикой; при нарушении отвечает структурированным 400
+func (h *AuthHandler) checkPassword(w http.ResponseWriter, password, email string) bool {
+	err := h.policy.Check(password, email)
+	if err == nil {
+		return true
+	}
+	var policyErr *auth.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return false
+	}
+	resp := dto.PasswordPolicyErrorDTO{Error: "password_policy"}
+	for _, v := range policyErr.Violations {
+		resp.Violations = append(resp.Violations, dto.PasswordViolationDTO{Code: v.Code, Message: v.Message})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(resp)
+	return false
+}
+
+func (h *AuthHandler) sendPasswordReset(ctx context.Context, userID int32, email string) error {
+	token, err := h.authSvc.IssuePasswordReset(ctx, userID)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/reset-password?token=%s", h.appURL, url.QueryEscape(token))
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Reset your TaskTracker password",
+		Body: fmt.Sprintf(
+			"Someone requested a password reset for your account.\n\nSet a new password: %s\n\nThe link expires in %d minutes and can be used once. If it was not you, ignore this email.",
+			link, int(auth.PasswordResetTTL.Minutes()),
+		),
+	})
+}
+
+// POST /email/verify
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	log.Println("VerifyEmail called")
+
+	var req dto.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.authSvc.ConsumeEmailVerification(r.Context(), req.Token)
+	if errors.Is(err, auth.ErrInvalidVerifyToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("cannot consume verification token:", err)
+		return
+	}
+
+	// 0 строк — email уже был подтверждён раньше, это не ошибка
+	if _, err := h.queries.MarkEmailVerified(r.Context(), userID); err != nil {
+		http.Error(w, "cannot verify email", http.StatusInternalServerError)
+		log.Println("cannot verify email:", err)
+		return
+	}
+
+	// приглашения, отправленные на этот email до регистрации, принимаются только
+	// после подтверждения: иначе роль получил бы любой, кто зарегистрировался с чужим адресом
+	if user, err := h.queries.GetUserByID(r.Context(), userID); err != nil {
+		log.Println("cannot accept pending invitations:", err)
+	} else {
+		joined, err := h.queries.AcceptPendingInvitations(r.Context(), db.AcceptPendingInvitationsParams{
+			Email:  user.Email,
+			UserID: user.ID,
+		})
+		if err != nil {
+			log.Println("cannot accept pending invitations:", err)
+		} else if len(joined) > 0 {
+			log.Println("User", user.ID, "joined", len(joined), "boards by invitation")
+		}
+	}
+
+	log.Println("Email verified for user", userID)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// POST /email/verify/resend
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	log.Println("ResendVerification called")
+
+	var req dto.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "invalid email", http.StatusBadRequest)
+		return
+	}
+
+	// ответ одинаковый для любого email: 429 при повторе выдал бы, что аккаунт есть и не подтверждён.
+	// Пока действует пауза между письмами, запрос просто ничего не отправляет
+	user, err := h.queries.GetUserByEmail(r.Context(), req.Email)
+	if err == nil && !user.EmailVerifiedAt.Valid {
+		err := h.sendVerification(r.Context(), user.ID, user.Email)
+		if err != nil && !errors.Is(err, auth.ErrVerificationCooldown) {
+			log.Println("cannot send verification email:", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// sendVerification отправляет письмо со ссылкой подтверждения; если письмо уже отправлялось
+// недавно, возвращает auth.ErrVerificationCooldown
+func (h *AuthHandler) sendVerification(ctx context.Context, userID int32, email string) error {
+	token, _, err := h.authSvc.IssueEmailVerification(ctx, userID)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/verify-email?token=%s", h.appURL, url.QueryEscape(token))
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your TaskTracker email",
+		Body: fmt.Sprintf(
+			"Confirm your email address: %s\n\nThe link expires in %d hours.",
+			link, int(auth.EmailVerificationTTL.Hours()),
+		),
+	})
+}
+
+// События журнала безопасности
+const (
+	securityRefreshReuse     = "refresh_token_reuse"
+	securityMFAEnabled       = "mfa_enabled"
+	securityMFADisabled      = "mfa_disabled"
+	securityRecoveryCodeUsed = "recovery_code_used"
+	securityRecoveryCodesNew = "recovery_codes_regenerated"
+	securityIdentityLinked   = "identity_linked"
+	securityPasswordChanged  = "password_changed"
+)
+
+// recordSecurityEvent пишет событие в журнал безопасности; ошибка записи только логируется,
+// чтобы не менять ответ клиенту
+func recordSecurityEvent(ctx context.Context, q db.Querier, userID int32, event string, meta auth.SessionMeta, details any) {
+	raw, err := json.Marshal(details)
+	if err != nil {
+		raw = []byte("{}")
+	}
+	if err := q.CreateSecurityEvent(ctx, db.CreateSecurityEventParams{
+		UserID:    userID,
+		Event:     event,
+		Ip:        pgtype.Text{String: meta.IP, Valid: meta.IP != ""},
+		UserAgent: pgtype.Text{String: meta.UserAgent, Valid: meta.UserAgent != ""},
+		Details:   raw,
+	}); err != nil {
+		log.Println("cannot record security event:", err)
+	}
+}
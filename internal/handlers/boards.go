@@ -2,23 +2,28 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/activity"
+	"github.com/sqszy/TaskTracker/internal/authz"
 	"github.com/sqszy/TaskTracker/internal/db"
 	"github.com/sqszy/TaskTracker/internal/dto"
-	"github.com/sqszy/TaskTracker/internal/middleware"
 )
 
 type BoardHandler struct {
-	queries db.Querier
+	queries db.Store
+	authz   *authz.Authorizer
 }
 
-func NewBoardHandler(q db.Querier) *BoardHandler {
-	return &BoardHandler{queries: q}
+func NewBoardHandler(q db.Store) *BoardHandler {
+	return &BoardHandler{queries: q, authz: authz.New(q)}
 }
 
 // POST /CreateBoard
@@ -29,15 +34,41 @@ func (h *BoardHandler) CreateBoard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := middleware.GetUserID(r)
+	userID, ok := authorize(w, r, h.authz, authz.CreateBoard, authz.Resource{})
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	board, err := h.queries.CreateBoard(r.Context(), db.CreateBoardParams{
-		Name:   req.Name,
-		UserID: userID,
+	// доска, владелец и колонки по умолчанию создаются в одной транзакции
+	var board db.Board
+	err := h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		var err error
+		board, err = q.CreateBoard(r.Context(), db.CreateBoardParams{
+			Name:   req.Name,
+			UserID: userID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = q.AddBoardMember(r.Context(), db.AddBoardMemberParams{
+			BoardID: board.ID,
+			UserID:  userID,
+			Role:    authz.RoleOwner,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := q.CreateDefaultColumns(r.Context(), board.ID); err != nil {
+			return err
+		}
+		return activity.Record(r.Context(), q, activity.Event{
+			BoardID:  board.ID,
+			ActorID:  userID,
+			Entity:   activity.EntityBoard,
+			EntityID: board.ID,
+			Action:   activity.Created,
+			Changes:  activity.Diff(nil, activity.BoardFields(board)),
+		})
 	})
 	if err != nil {
 		http.Error(w, "cannot create board", http.StatusInternalServerError)
@@ -49,6 +80,8 @@ func (h *BoardHandler) CreateBoard(w http.ResponseWriter, r *http.Request) {
 		ID:        board.ID,
 		UserID:    board.UserID,
 		Name:      board.Name,
+		Role:      authz.RoleOwner,
+		WIPMode:   board.WipMode,
 		CreatedAt: board.CreatedAt.Time,
 		UpdatedAt: board.UpdatedAt.Time,
 	}
@@ -63,9 +96,8 @@ func (h *BoardHandler) CreateBoard(w http.ResponseWriter, r *http.Request) {
 
 // GET /GetBoards
 func (h *BoardHandler) GetBoards(w http.ResponseWriter, r *http.Request) {
-	userID, ok := middleware.GetUserID(r)
+	userID, ok := authorize(w, r, h.authz, authz.ListBoards, authz.Resource{})
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -81,6 +113,8 @@ func (h *BoardHandler) GetBoards(w http.ResponseWriter, r *http.Request) {
 			ID:        b.ID,
 			UserID:    b.UserID,
 			Name:      b.Name,
+			Role:      b.Role,
+			WIPMode:   b.WipMode,
 			CreatedAt: b.CreatedAt.Time,
 			UpdatedAt: b.UpdatedAt.Time,
 		})
@@ -102,9 +136,8 @@ func (h *BoardHandler) PatchBoard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := middleware.GetUserID(r)
+	userID, ok := authorize(w, r, h.authz, authz.UpdateBoard, authz.Board(int32(boardID)))
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -113,19 +146,52 @@ func (h *BoardHandler) PatchBoard(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "wip_mode must be strict or soft", http.StatusBadRequest)
+		return
+	}
 
 	params := db.UpdateBoardParams{
-		ID:     int32(boardID),
-		UserID: int32(userID),
+		ID: int32(boardID),
 	}
 
 	if req.Name != nil {
 		params.Name = pgtype.Text{String: *req.Name, Valid: true}
 	}
+	if req.WIPMode != nil {
+		params.WipMode = pgtype.Text{String: *req.WIPMode, Valid: true}
+	}
 
-	board, err := h.queries.UpdateBoard(r.Context(), params)
+	// изменение и запись в журнал — в одной транзакции
+	var board db.Board
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		before, err := q.GetBoardForUpdate(r.Context(), int32(boardID))
+		if err != nil {
+			return err
+		}
+		board, err = q.UpdateBoard(r.Context(), params)
+		if err != nil {
+			return err
+		}
+		changes := activity.Diff(activity.BoardFields(before), activity.BoardFields(board))
+		if len(changes) == 0 {
+			return nil
+		}
+		return activity.Record(r.Context(), q, activity.Event{
+			BoardID:  board.ID,
+			ActorID:  userID,
+			Entity:   activity.EntityBoard,
+			EntityID: board.ID,
+			Action:   activity.Updated,
+			Changes:  changes,
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "board not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		http.Error(w, "cannot update board (maybe not yours?)", http.StatusForbidden)
+		http.Error(w, "cannot update board", http.StatusInternalServerError)
 		log.Println("cannot update board:", err)
 		return
 	}
@@ -134,12 +200,14 @@ func (h *BoardHandler) PatchBoard(w http.ResponseWriter, r *http.Request) {
 		ID:        board.ID,
 		UserID:    board.UserID,
 		Name:      board.Name,
+		Role:      authz.RoleOwner,
+		WIPMode:   board.WipMode,
 		CreatedAt: board.CreatedAt.Time,
 		UpdatedAt: board.UpdatedAt.Time,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	log.Println("[PatchBoard] updated board:", board.ID)
+	log.Println("[PatchBoard] updated board:", board.ID, "by user", userID)
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
@@ -154,28 +222,40 @@ func (h *BoardHandler) DeleteBoard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := middleware.GetUserID(r)
+	userID, ok := authorize(w, r, h.authz, authz.DeleteBoard, authz.Board(int32(boardID)))
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	rows, err := h.queries.DeleteBoard(r.Context(), db.DeleteBoardParams{
-		ID:     int32(boardID),
-		UserID: int32(userID),
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		before, err := q.GetBoardForUpdate(r.Context(), int32(boardID))
+		if err != nil {
+			return err
+		}
+		if _, err := q.DeleteBoard(r.Context(), int32(boardID)); err != nil {
+			return err
+		}
+		return activity.Record(r.Context(), q, activity.Event{
+			BoardID:  before.ID,
+			ActorID:  userID,
+			Entity:   activity.EntityBoard,
+			EntityID: before.ID,
+			Action:   activity.Deleted,
+			Changes:  activity.Diff(activity.BoardFields(before), nil),
+		})
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "board not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "cannot delete board", http.StatusInternalServerError)
 		log.Println("delete board error:", err)
 		return
 	}
-	if rows == 0 {
-		http.Error(w, "board not found or not yours", http.StatusForbidden)
-		return
-	}
 
 	w.Header().Set("Content-Type", "application/json")
-	log.Println("[DeleteBoard] deleted board:", boardID)
+	log.Println("[DeleteBoard] deleted board:", boardID, "by user", userID)
 	if err := json.NewEncoder(w).Encode(map[string]bool{"success": true}); err != nil {
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
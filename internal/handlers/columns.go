@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+)
+
+const defaultColumnColor = "#94a3b8"
+
+type ColumnHandler struct {
+	queries db.Store
+	authz   *authz.Authorizer
+}
+
+func NewColumnHandler(q db.Store) *ColumnHandler {
+	return &ColumnHandler{queries: q, authz: authz.New(q)}
+}
+
+// GET /boards/{boardID}/columns
+func (h *ColumnHandler) ListColumns(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ListColumns, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	columns, err := h.queries.ListBoardColumns(r.Context(), int32(boardID))
+	if err != nil {
+		http.Error(w, "cannot fetch columns", http.StatusInternalServerError)
+		log.Println("ListBoardColumns error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[ListColumns] board", boardID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(columnDTOs(columns))
+}
+
+// POST /boards/{boardID}/columns
+func (h *ColumnHandler) CreateColumn(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.CreateColumnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "invalid column name, color or wip limit", http.StatusBadRequest)
+		return
+	}
+	if req.Color == "" {
+		req.Color = defaultColumnColor
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageColumns, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	// новая колонка встаёт в конец доски
+	params := db.CreateBoardColumnParams{
+		BoardID: int32(boardID),
+		Name:    req.Name,
+		Color:   req.Color,
+		IsDone:  req.IsDone,
+	}
+	if req.WIPLimit != nil {
+		params.WipLimit = pgtype.Int4{Int32: *req.WIPLimit, Valid: true}
+	}
+	column, err := h.queries.CreateBoardColumn(r.Context(), params)
+	if isUniqueViolation(err) {
+		http.Error(w, "column with this name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot create column", http.StatusInternalServerError)
+		log.Println("cannot create column:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[CreateColumn] column", column.ID, "on board", boardID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(columnDTO(column))
+}
+
+// PATCH /boards/{boardID}/columns/{columnID}
+func (h *ColumnHandler) UpdateColumn(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	columnID, err := strconv.Atoi(chi.URLParam(r, "columnID"))
+	if err != nil {
+		http.Error(w, "invalid column id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UpdateColumnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		req.Name = &name
+	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "invalid column name, color or wip limit", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageColumns, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	params := db.UpdateBoardColumnParams{
+		ID:      int32(columnID),
+		BoardID: int32(boardID),
+	}
+	if req.Name != nil {
+		params.Name = pgtype.Text{String: *req.Name, Valid: true}
+	}
+	if req.Color != nil {
+		params.Color = pgtype.Text{String: *req.Color, Valid: true}
+	}
+	if req.IsDone != nil {
+		params.IsDone = pgtype.Bool{Bool: *req.IsDone, Valid: true}
+	}
+	if req.WIPLimit != nil {
+		params.WipLimit = pgtype.Int4{Int32: *req.WIPLimit, Valid: true}
+	}
+
+	// при переименовании статус задач колонки меняется вместе с ней
+	var column db.BoardColumn
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		var err error
+		column, err = q.UpdateBoardColumn(r.Context(), params)
+		if err != nil || req.Name == nil {
+			return err
+		}
+		return q.SyncColumnStatus(r.Context(), db.SyncColumnStatusParams{
+			Name:     pgtype.Text{String: column.Name, Valid: true},
+			ColumnID: pgtype.Int4{Int32: column.ID, Valid: true},
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "column not found", http.StatusNotFound)
+		return
+	}
+	if isUniqueViolation(err) {
+		http.Error(w, "column with this name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot update column", http.StatusInternalServerError)
+		log.Println("cannot update column:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[UpdateColumn] column", column.ID, "updated by user", userID)
+	_ = json.NewEncoder(w).Encode(columnDTO(column))
+}
+
+// PUT /boards/{boardID}/columns/order
+func (h *ColumnHandler) ReorderColumns(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.ReorderColumnsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "column_ids are required", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageColumns, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	// порядок задаётся целиком: список должен содержать каждую колонку доски ровно один раз
+	var columns []db.BoardColumn
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		current, err := q.ListBoardColumns(r.Context(), int32(boardID))
+		if err != nil {
+			return err
+		}
+		if !sameIDSet(current, req.ColumnIDs) {
+			return errColumnOrder
+		}
+		if _, err := q.ReorderBoardColumns(r.Context(), db.ReorderBoardColumnsParams{
+			ColumnIds: req.ColumnIDs,
+			BoardID:   int32(boardID),
+		}); err != nil {
+			return err
+		}
+		columns, err = q.ListBoardColumns(r.Context(), int32(boardID))
+		return err
+	})
+	if errors.Is(err, errColumnOrder) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot reorder columns", http.StatusInternalServerError)
+		log.Println("cannot reorder columns:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[ReorderColumns] board", boardID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(columnDTOs(columns))
+}
+
+// DELETE /boards/{boardID}/columns/{columnID}
+func (h *ColumnHandler) DeleteColumn(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	columnID, err := strconv.Atoi(chi.URLParam(r, "columnID"))
+	if err != nil {
+		http.Error(w, "invalid column id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageColumns, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		if _, err := q.GetBoardColumn(r.Context(), db.GetBoardColumnParams{ID: int32(columnID), BoardID: int32(boardID)}); err != nil {
+			return err
+		}
+		// задачи не удаляются вместе с колонкой: их нужно сначала перенести
+		tasks, err := q.CountColumnTasks(r.Context(), pgtype.Int4{Int32: int32(columnID), Valid: true})
+		if err != nil {
+			return err
+		}
+		if tasks > 0 {
+			return errColumnNotEmpty
+		}
+		columns, err := q.ListBoardColumns(r.Context(), int32(boardID))
+		if err != nil {
+			return err
+		}
+		if len(columns) <= 1 {
+			return errLastColumn
+		}
+		_, err = q.DeleteBoardColumn(r.Context(), db.DeleteBoardColumnParams{
+			ID:      int32(columnID),
+			BoardID: int32(boardID),
+		})
+		return err
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, "column not found", http.StatusNotFound)
+		return
+	case errors.Is(err, errColumnNotEmpty), errors.Is(err, errLastColumn):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "cannot delete column", http.StatusInternalServerError)
+		log.Println("cannot delete column:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[DeleteColumn] column", columnID, "deleted by user", userID)
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+var (
+	errColumnOrder    = errors.New("column_ids must list every column of the board exactly once")
+	errColumnNotEmpty = errors.New("column still has tasks")
+	errLastColumn     = errors.New("board must keep at least one column")
+)
+
+func sameIDSet(columns []db.BoardColumn, ids []int32) bool {
+	if len(columns) != len(ids) {
+		return false
+	}
+	seen := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	if len(seen) != len(ids) {
+		return false
+	}
+	for _, c := range columns {
+		if !seen[c.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func columnDTO(c db.BoardColumn) dto.ColumnDTO {
+	resp := dto.ColumnDTO{
+		ID:       c.ID,
+		BoardID:  c.BoardID,
+		Name:     c.Name,
+		Color:    c.Color,
+		IsDone:   c.IsDone,
+		Position: c.Position,
+	}
+	if c.WipLimit.Valid {
+		resp.WIPLimit = &c.WipLimit.Int32
+	}
+	return resp
+}
+
+func columnDTOs(columns []db.BoardColumn) []dto.ColumnDTO {
+	resp := []dto.ColumnDTO{}
+	for _, c := range columns {
+		resp = append(resp, columnDTO(c))
+	}
+	return resp
+}
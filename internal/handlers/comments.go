@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/activity"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+)
+
+const (
+	defaultCommentsLimit = 20
+	maxCommentsLimit     = 100
+)
+
+type CommentHandler struct {
+	queries db.Store
+	authz   *authz.Authorizer
+}
+
+func NewCommentHandler(q db.Store) *CommentHandler {
+	return &CommentHandler{queries: q, authz: authz.New(q)}
+}
+
+// GET /boards/{boardID}/tasks/{taskID}/comments
+func (h *CommentHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePage(r, defaultCommentsLimit, maxCommentsLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ListComments, authz.Task(int32(boardID), int32(taskID)))
+	if !ok {
+		return
+	}
+
+	// пагинация идёт по комментариям верхнего уровня, ответы подгружаются к ним целиком
+	comments, err := h.queries.ListComments(r.Context(), db.ListCommentsParams{
+		TaskID: int32(taskID),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		http.Error(w, "cannot fetch comments", http.StatusInternalServerError)
+		log.Println("ListComments error:", err)
+		return
+	}
+
+	total, err := h.queries.CountComments(r.Context(), int32(taskID))
+	if err != nil {
+		http.Error(w, "cannot fetch comments", http.StatusInternalServerError)
+		log.Println("CountComments error:", err)
+		return
+	}
+
+	ids := make([]int32, 0, len(comments))
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+
+	replies := map[int32][]dto.CommentDTO{}
+	if len(ids) > 0 {
+		rows, err := h.queries.ListCommentReplies(r.Context(), ids)
+		if err != nil {
+			http.Error(w, "cannot fetch comments", http.StatusInternalServerError)
+			log.Println("ListCommentReplies error:", err)
+			return
+		}
+		for _, c := range rows {
+			replies[c.ParentID.Int32] = append(replies[c.ParentID.Int32], commentDTO(c))
+		}
+	}
+
+	resp := dto.CommentListDTO{
+		Comments: []dto.CommentDTO{},
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, c := range comments {
+		item := commentDTO(c)
+		item.Replies = replies[c.ID]
+		resp.Comments = append(resp.Comments, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[ListComments] task", taskID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// POST /boards/{boardID}/tasks/{taskID}/comments
+func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.CreateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "invalid comment body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.CreateComment, authz.Task(int32(boardID), int32(taskID)))
+	if !ok {
+		return
+	}
+
+	params := db.CreateCommentParams{
+		TaskID: int32(taskID),
+		UserID: userID,
+		Body:   req.Body,
+	}
+
+	if req.ParentID != nil {
+		parent, err := h.queries.GetComment(r.Context(), db.GetCommentParams{ID: *req.ParentID, TaskID: int32(taskID)})
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "parent comment not found", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "cannot create comment", http.StatusInternalServerError)
+			log.Println("GetComment error:", err)
+			return
+		}
+		// ветки не глубже одного уровня
+		if parent.ParentID.Valid {
+			http.Error(w, "replies can only target top-level comments", http.StatusBadRequest)
+			return
+		}
+		if parent.DeletedAt.Valid {
+			http.Error(w, "cannot reply to a deleted comment", http.StatusBadRequest)
+			return
+		}
+		params.ParentID = pgtype.Int4{Int32: parent.ID, Valid: true}
+	}
+
+	// комментарии попадают в журнал задачи вместе с остальными изменениями
+	var comment db.TaskComment
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		var err error
+		comment, err = q.CreateComment(r.Context(), params)
+		if err != nil {
+			return err
+		}
+		return recordComment(r, q, int32(boardID), userID, comment, activity.Created)
+	})
+	if err != nil {
+		http.Error(w, "cannot create comment", http.StatusInternalServerError)
+		log.Println("cannot create comment:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[CreateComment] comment", comment.ID, "on task", taskID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(commentDTO(comment))
+}
+
+// PATCH /boards/{boardID}/tasks/{taskID}/comments/{commentID}
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(chi.URLParam(r, "commentID"))
+	if err != nil {
+		http.Error(w, "invalid comment id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UpdateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "invalid comment body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.UpdateComment, authz.Task(int32(boardID), int32(taskID)))
+	if !ok {
+		return
+	}
+
+	current, ok := h.liveComment(w, r, int32(commentID), int32(taskID))
+	if !ok {
+		return
+	}
+	// редактировать текст может только автор, даже владелец доски его не переписывает
+	if current.UserID != userID {
+		http.Error(w, "only the author can edit a comment", http.StatusForbidden)
+		return
+	}
+
+	// предыдущий текст сохраняется в task_comment_edits тем же запросом
+	var comment db.TaskComment
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		var err error
+		comment, err = q.UpdateComment(r.Context(), db.UpdateCommentParams{
+			ID:       int32(commentID),
+			TaskID:   int32(taskID),
+			EditedBy: userID,
+			Body:     req.Body,
+		})
+		if err != nil {
+			return err
+		}
+		return recordComment(r, q, int32(boardID), userID, comment, activity.Updated)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot update comment", http.StatusInternalServerError)
+		log.Println("cannot update comment:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[UpdateComment] comment", comment.ID, "edited by user", userID)
+	_ = json.NewEncoder(w).Encode(commentDTO(comment))
+}
+
+// DELETE /boards/{boardID}/tasks/{taskID}/comments/{commentID}
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(chi.URLParam(r, "commentID"))
+	if err != nil {
+		http.Error(w, "invalid comment id", http.StatusBadRequest)
+		return
+	}
+
+	res := authz.Task(int32(boardID), int32(taskID))
+	userID, ok := authorize(w, r, h.authz, authz.DeleteComment, res)
+	if !ok {
+		return
+	}
+
+	current, ok := h.liveComment(w, r, int32(commentID), int32(taskID))
+	if !ok {
+		return
+	}
+	// чужие комментарии удаляет только владелец доски
+	if current.UserID != userID {
+		if _, ok := authorize(w, r, h.authz, authz.ModerateComments, res); !ok {
+			return
+		}
+	}
+
+	var rows int64
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		var err error
+		rows, err = q.SoftDeleteComment(r.Context(), db.SoftDeleteCommentParams{
+			ID:     int32(commentID),
+			TaskID: int32(taskID),
+		})
+		if err != nil || rows == 0 {
+			return err
+		}
+		return recordComment(r, q, int32(boardID), userID, current, activity.Deleted)
+	})
+	if err != nil {
+		http.Error(w, "cannot delete comment", http.StatusInternalServerError)
+		log.Println("cannot delete comment:", err)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, "comment not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[DeleteComment] comment", commentID, "deleted by user", userID)
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// GET /boards/{boardID}/tasks/{taskID}/comments/{commentID}/history
+func (h *CommentHandler) CommentHistory(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(chi.URLParam(r, "commentID"))
+	if err != nil {
+		http.Error(w, "invalid comment id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ListComments, authz.Task(int32(boardID), int32(taskID)))
+	if !ok {
+		return
+	}
+
+	// у удалённого комментария история тоже скрыта
+	if _, ok := h.liveComment(w, r, int32(commentID), int32(taskID)); !ok {
+		return
+	}
+
+	edits, err := h.queries.ListCommentEdits(r.Context(), int32(commentID))
+	if err != nil {
+		http.Error(w, "cannot fetch comment history", http.StatusInternalServerError)
+		log.Println("ListCommentEdits error:", err)
+		return
+	}
+
+	resp := []dto.CommentEditDTO{}
+	for _, e := range edits {
+		resp = append(resp, dto.CommentEditDTO{
+			Body:     e.Body,
+			EditedBy: e.EditedBy,
+			EditedAt: e.EditedAt.Time,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[CommentHistory] comment", commentID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// liveComment загружает неудалённый комментарий задачи; при ошибке ответ уже записан
+func (h *CommentHandler) liveComment(w http.ResponseWriter, r *http.Request, commentID, taskID int32) (db.TaskComment, bool) {
+	c, err := h.queries.GetComment(r.Context(), db.GetCommentParams{ID: commentID, TaskID: taskID})
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && c.DeletedAt.Valid) {
+		http.Error(w, "comment not found", http.StatusNotFound)
+		return db.TaskComment{}, false
+	}
+	if err != nil {
+		http.Error(w, "cannot fetch comment", http.StatusInternalServerError)
+		log.Println("GetComment error:", err)
+		return db.TaskComment{}, false
+	}
+	return c, true
+}
+
+// recordComment пишет событие комментария. Текст в журнал не попадает ни при создании,
+// ни при правке, ни при удалении: журнал доски видят все её участники и из него ничего
+// не удаляется, а прежние версии текста хранятся в task_comment_edits и скрываются
+// вместе с удалённым комментарием
+func recordComment(r *http.Request, q db.Querier, boardID, actorID int32, c db.TaskComment, action string) error {
+	ref := commentRefFields(c)
+	var changes map[string]activity.Change
+	switch action {
+	case activity.Created:
+		changes = activity.Diff(nil, ref)
+	case activity.Deleted:
+		changes = activity.Diff(ref, nil)
+	default:
+		changes = map[string]activity.Change{}
+		for k, v := range ref {
+			changes[k] = activity.Change{Before: v, After: v}
+		}
+	}
+	return activity.Record(r.Context(), q, activity.Event{
+		BoardID:  boardID,
+		TaskID:   c.TaskID,
+		ActorID:  actorID,
+		Entity:   activity.EntityComment,
+		EntityID: c.ID,
+		Action:   action,
+		Changes:  changes,
+	})
+}
+
+// commentRefFields — что журнал знает о комментарии
+func commentRefFields(c db.TaskComment) activity.Fields {
+	return activity.Fields{"id": c.ID, "author_id": c.UserID}
+}
+
+// parsePage разбирает limit/offset из query-параметров
+func parsePage(r *http.Request, defLimit, maxLimit int32) (int32, int32, error) {
+	q := r.URL.Query()
+	limit, offset := defLimit, int32(0)
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, 0, errors.New("invalid limit")
+		}
+		limit = int32(min(n, int(maxLimit)))
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("invalid offset")
+		}
+		offset = int32(n)
+	}
+	return limit, offset, nil
+}
+
+// commentDTO скрывает текст удалённого комментария, оставляя место в ветке
+func commentDTO(c db.TaskComment) dto.CommentDTO {
+	resp := dto.CommentDTO{
+		ID:        c.ID,
+		TaskID:    c.TaskID,
+		UserID:    c.UserID,
+		Body:      c.Body,
+		Deleted:   c.DeletedAt.Valid,
+		CreatedAt: c.CreatedAt.Time,
+	}
+	if c.ParentID.Valid {
+		resp.ParentID = &c.ParentID.Int32
+	}
+	if c.EditedAt.Valid {
+		resp.EditedAt = &c.EditedAt.Time
+	}
+	if resp.Deleted {
+		resp.Body = ""
+	}
+	return resp
+}
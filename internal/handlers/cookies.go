@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+)
+
+// refreshCookiePaths — refresh-cookie уходит браузером только на эти маршруты
+var refreshCookiePaths = []string{"/refresh", "/logout"}
+
+// RefreshCookies — режим для браузерного клиента: refresh-токен выдаётся в HttpOnly cookie
+// и не попадает в JSON, поэтому недоступен скриптам страницы. Вместе с ним выдаётся
+// CSRF-токен: в cookie csrf_token и в поле csrf_token ответа, фронт возвращает его в X-CSRF-Token.
+type RefreshCookies struct {
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+	// MaxAge — срок жизни cookie, равен сроку жизни refresh-токена
+	MaxAge time.Duration
+}
+
+// set выставляет refresh-cookie (по одной на каждый путь) и новый CSRF-токен
+func (c *RefreshCookies) set(w http.ResponseWriter, refreshToken string) (string, error) {
+	csrf, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	maxAge := int(c.MaxAge.Seconds())
+	for _, path := range refreshCookiePaths {
+		http.SetCookie(w, c.cookie(middleware.RefreshCookieName, refreshToken, path, maxAge, true))
+	}
+	http.SetCookie(w, c.cookie(middleware.CSRFCookieName, csrf, "/", maxAge, false))
+	return csrf, nil
+}
+
+// clear удаляет cookie, выставленные set
+func (c *RefreshCookies) clear(w http.ResponseWriter) {
+	for _, path := range refreshCookiePaths {
+		http.SetCookie(w, c.cookie(middleware.RefreshCookieName, "", path, -1, true))
+	}
+	http.SetCookie(w, c.cookie(middleware.CSRFCookieName, "", "/", -1, false))
+}
+
+func (c *RefreshCookies) cookie(name, value, path string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		MaxAge:   maxAge,
+		Secure:   c.Secure,
+		HttpOnly: httpOnly,
+		SameSite: c.SameSite,
+	}
+}
+
+// writeTokens отдаёт пару токенов; в режиме cookie refresh-токен уходит только в cookie
+func writeTokens(w http.ResponseWriter, cookies *RefreshCookies, tp *auth.TokenPair) error {
+	resp := dto.LoginResponse{
+		AccessToken:  tp.AccessToken,
+		RefreshToken: tp.RefreshToken,
+		ExpiresIn:    tp.ExpiresIn,
+	}
+	if cookies != nil {
+		csrf, err := cookies.set(w, tp.RefreshToken)
+		if err != nil {
+			return err
+		}
+		resp.RefreshToken = ""
+		resp.CSRFToken = csrf
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// refreshTokenFrom берёт refresh-токен из cookie, а если её нет — из тела запроса.
+// Запрос с cookie к этому моменту уже прошёл проверку middleware.CSRF.
+func refreshTokenFrom(r *http.Request) (string, error) {
+	if c, err := r.Cookie(middleware.RefreshCookieName); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+	var req dto.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return "", err
+	}
+	return req.RefreshToken, nil
+}
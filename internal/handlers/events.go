@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/realtime"
+)
+
+// streamHeartbeat — период комментариев-пингов, которые не дают прокси закрыть простаивающее соединение
+const streamHeartbeat = 25 * time.Second
+
+type EventsHandler struct {
+	authz     *authz.Authorizer
+	authSvc   *auth.Service
+	broker    *realtime.Broker
+	heartbeat time.Duration
+}
+
+func NewEventsHandler(q db.Querier, a *auth.Service, broker *realtime.Broker) *EventsHandler {
+	return &EventsHandler{authz: authz.New(q), authSvc: a, broker: broker, heartbeat: streamHeartbeat}
+}
+
+// WithHeartbeat меняет период пингов (для тестов)
+func (h *EventsHandler) WithHeartbeat(d time.Duration) *EventsHandler {
+	h.heartbeat = d
+	return h
+}
+
+// GET /boards/{boardID}/events — поток Server-Sent Events
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	res := authz.Board(int32(boardID))
+	userID, ok := authorize(w, r, h.authz, authz.ViewBoard, res)
+	if !ok {
+		return
+	}
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := h.broker.Subscribe(int32(boardID))
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	log.Println("[Stream] user", userID, "subscribed to board", boardID)
+	defer log.Println("[Stream] user", userID, "unsubscribed from board", boardID)
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	// поток живёт не дольше токена, которым открыт: клиент переподключается с новым
+	var expired <-chan time.Time
+	if claims.ExpiresAt != nil {
+		timer := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-expired:
+			return
+		case <-ticker.C:
+			// пока поток открыт, сессию могли завершить, а пользователя — исключить из доски
+			if err := h.authSvc.CheckAccessToken(r.Context(), claims); err != nil {
+				return
+			}
+			if err := h.authz.Can(r.Context(), userID, authz.ViewBoard, res); err != nil {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/mailer"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+// Статусы приглашения
+const (
+	invitationPending  = "pending"
+	invitationAccepted = "accepted"
+	invitationDeclined = "declined"
+)
+
+var (
+	errInvitationNotFound = errors.New("invitation not found")
+	errInvitationClosed   = errors.New("invitation is no longer pending")
+	errInvitationExpired  = errors.New("invitation expired")
+	errInvitationEmail    = errors.New("invitation was sent to another email")
+)
+
+type InvitationHandler struct {
+	queries db.Store
+	authz   *authz.Authorizer
+	mailer  mailer.Mailer
+	appURL  string
+}
+
+func NewInvitationHandler(q db.Store, m mailer.Mailer, appURL string) *InvitationHandler {
+	return &InvitationHandler{
+		queries: q,
+		authz:   authz.New(q),
+		mailer:  m,
+		appURL:  strings.TrimRight(appURL, "/"),
+	}
+}
+
+// POST /boards/{boardID}/invitations
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "invalid email or role", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageMembers, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	// уже зарегистрированный участник доски в приглашении не нуждается
+	if user, err := h.queries.GetUserByEmail(r.Context(), req.Email); err == nil {
+		_, err := h.queries.GetBoardMemberRole(r.Context(), db.GetBoardMemberRoleParams{
+			BoardID: int32(boardID),
+			UserID:  user.ID,
+		})
+		if err == nil {
+			http.Error(w, "user is already a member", http.StatusConflict)
+			return
+		}
+	}
+
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	// письмо отправляется внутри транзакции: если доставка не удалась, приглашение не сохраняется
+	var inv db.BoardInvitation
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		var err error
+		inv, err = q.CreateInvitation(r.Context(), db.CreateInvitationParams{
+			BoardID:   int32(boardID),
+			Email:     req.Email,
+			Role:      req.Role,
+			TokenHash: hash,
+			InviterID: userID,
+			ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(invitationTTL), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		return h.sendInvitation(r.Context(), inv, token)
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		http.Error(w, "invitation already pending", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot create invitation", http.StatusInternalServerError)
+		log.Println("cannot create invitation:", err)
+		return
+	}
+
+	log.Println("[CreateInvitation] board", boardID, "invited", inv.Email, "as", inv.Role, "by user", userID)
+	_ = json.NewEncoder(w).Encode(invitationDTO(inv))
+}
+
+// GET /boards/{boardID}/invitations
+func (h *InvitationHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageMembers, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	invs, err := h.queries.ListBoardInvitations(r.Context(), int32(boardID))
+	if err != nil {
+		http.Error(w, "cannot fetch invitations", http.StatusInternalServerError)
+		log.Println("ListBoardInvitations error:", err)
+		return
+	}
+
+	resp := []dto.InvitationDTO{}
+	for _, inv := range invs {
+		resp = append(resp, invitationDTO(inv))
+	}
+
+	log.Println("[ListInvitations] board", boardID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// DELETE /boards/{boardID}/invitations/{invitationID}
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	invitationID, err := strconv.Atoi(chi.URLParam(r, "invitationID"))
+	if err != nil {
+		http.Error(w, "invalid invitation id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageMembers, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	rows, err := h.queries.RevokeInvitation(r.Context(), db.RevokeInvitationParams{
+		ID:      int32(invitationID),
+		BoardID: int32(boardID),
+	})
+	if err != nil {
+		http.Error(w, "cannot revoke invitation", http.StatusInternalServerError)
+		log.Println("cannot revoke invitation:", err)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, "invitation not found", http.StatusNotFound)
+		return
+	}
+
+	log.Println("[RevokeInvitation] invitation", invitationID, "revoked by user", userID)
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// POST /invitations/{token}/accept
+func (h *InvitationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, invitationAccepted)
+}
+
+// POST /invitations/{token}/decline
+func (h *InvitationHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, invitationDeclined)
+}
+
+func (h *InvitationHandler) respond(w http.ResponseWriter, r *http.Request, status string) {
+	token := chi.URLParam(r, "token")
+	if token == "" {
+		http.Error(w, "invalid token", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.RespondInvitation, authz.Resource{})
+	if !ok {
+		return
+	}
+
+	user, err := h.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "user not found", http.StatusUnauthorized)
+		return
+	}
+	// совпадение email ничего не доказывает, пока адрес не подтверждён
+	if status == invitationAccepted && !user.EmailVerifiedAt.Valid {
+		http.Error(w, "verify your email to accept invitations", http.StatusForbidden)
+		return
+	}
+
+	var inv db.BoardInvitation
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		var err error
+		inv, err = q.GetInvitationByTokenHash(r.Context(), auth.HashToken(token))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errInvitationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if inv.Email != user.Email {
+			return errInvitationEmail
+		}
+		if inv.Status != invitationPending {
+			return errInvitationClosed
+		}
+		if time.Now().After(inv.ExpiresAt.Time) {
+			return errInvitationExpired
+		}
+
+		if _, err := q.UpdateInvitationStatus(r.Context(), db.UpdateInvitationStatusParams{
+			Status: status,
+			ID:     inv.ID,
+		}); err != nil {
+			return err
+		}
+		inv.Status = status
+		if status != invitationAccepted {
+			return nil
+		}
+
+		// повторное приглашение действующего участника не понижает и не повышает его роль
+		_, err = q.GetBoardMemberRole(r.Context(), db.GetBoardMemberRoleParams{BoardID: inv.BoardID, UserID: userID})
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		_, err = q.AddBoardMember(r.Context(), db.AddBoardMemberParams{
+			BoardID: inv.BoardID,
+			UserID:  userID,
+			Role:    inv.Role,
+		})
+		return err
+	})
+	switch {
+	case errors.Is(err, errInvitationNotFound), errors.Is(err, errInvitationEmail):
+		// чужое приглашение не отличаем от несуществующего
+		http.Error(w, "invitation not found", http.StatusNotFound)
+		return
+	case errors.Is(err, errInvitationClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, errInvitationExpired):
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	case err != nil:
+		http.Error(w, "cannot respond to invitation", http.StatusInternalServerError)
+		log.Println("cannot respond to invitation:", err)
+		return
+	}
+
+	log.Println("[RespondInvitation] invitation", inv.ID, status, "by user", userID)
+	_ = json.NewEncoder(w).Encode(invitationDTO(inv))
+}
+
+func (h *InvitationHandler) sendInvitation(ctx context.Context, inv db.BoardInvitation, token string) error {
+	link := fmt.Sprintf("%s/invitations/%s", h.appURL, token)
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      inv.Email,
+		Subject: "You have been invited to a TaskTracker board",
+		Body: fmt.Sprintf(
+			"You have been invited to join a board as %s.\n\nAccept or decline the invitation: %s\n\nThe link expires on %s.",
+			inv.Role, link, inv.ExpiresAt.Time.Format(time.RFC1123),
+		),
+	})
+}
+
+func invitationDTO(inv db.BoardInvitation) dto.InvitationDTO {
+	return dto.InvitationDTO{
+		ID:        inv.ID,
+		BoardID:   inv.BoardID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		InviterID: inv.InviterID,
+		Status:    inv.Status,
+		ExpiresAt: inv.ExpiresAt.Time,
+		CreatedAt: inv.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/sqszy/TaskTracker/internal/auth"
+)
+
+type JWKSHandler struct {
+	keys *auth.KeyManager
+}
+
+// NewJWKSHandler публикует открытые ключи; keys == nil — подпись HS256, публиковать нечего
+func NewJWKSHandler(km *auth.KeyManager) *JWKSHandler {
+	return &JWKSHandler{keys: km}
+}
+
+// GET /.well-known/jwks.json — ключи для проверки наших токенов другими сервисами
+func (h *JWKSHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	set := auth.JWKS{Keys: []auth.JWK{}}
+	if h.keys != nil {
+		set = h.keys.JWKS()
+	}
+	// новый ключ появляется здесь за auth.JWKSMaxAge до того, как начнёт подписывать,
+	// так что закешированного набора клиентам хватает
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(auth.JWKSMaxAge.Seconds())))
+	_ = json.NewEncoder(w).Encode(set)
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+)
+
+type MemberHandler struct {
+	queries db.Store
+	authz   *authz.Authorizer
+}
+
+func NewMemberHandler(q db.Store) *MemberHandler {
+	return &MemberHandler{queries: q, authz: authz.New(q)}
+}
+
+// GET /boards/{boardID}/members
+func (h *MemberHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ViewBoard, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	members, err := h.queries.ListBoardMembers(r.Context(), int32(boardID))
+	if err != nil {
+		http.Error(w, "cannot fetch members", http.StatusInternalServerError)
+		log.Println("ListBoardMembers error:", err)
+		return
+	}
+
+	resp := []dto.BoardMemberDTO{}
+	for _, m := range members {
+		resp = append(resp, dto.BoardMemberDTO{
+			BoardID:   m.BoardID,
+			UserID:    m.UserID,
+			Email:     m.Email,
+			Role:      m.Role,
+			CreatedAt: m.CreatedAt.Time,
+		})
+	}
+
+	log.Println("[ListMembers] board", boardID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// POST /boards/{boardID}/members
+func (h *MemberHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.AddBoardMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "invalid email or role", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageMembers, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	user, err := h.queries.GetUserByEmail(r.Context(), req.Email)
+	if err != nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	_, err = h.queries.GetBoardMemberRole(r.Context(), db.GetBoardMemberRoleParams{
+		BoardID: int32(boardID),
+		UserID:  user.ID,
+	})
+	if err == nil {
+		http.Error(w, "user is already a member", http.StatusConflict)
+		return
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "cannot add member", http.StatusInternalServerError)
+		log.Println("cannot check membership:", err)
+		return
+	}
+
+	member, err := h.queries.AddBoardMember(r.Context(), db.AddBoardMemberParams{
+		BoardID: int32(boardID),
+		UserID:  user.ID,
+		Role:    req.Role,
+	})
+	if err != nil {
+		http.Error(w, "cannot add member", http.StatusInternalServerError)
+		log.Println("cannot add member:", err)
+		return
+	}
+
+	resp := dto.BoardMemberDTO{
+		BoardID:   member.BoardID,
+		UserID:    member.UserID,
+		Email:     user.Email,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt.Time,
+	}
+
+	log.Println("[AddMember] user", user.ID, "added to board", boardID, "as", member.Role, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// PATCH /boards/{boardID}/members/{userID}
+func (h *MemberHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	memberID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UpdateBoardMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "invalid role", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageMembers, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	var member db.BoardMember
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		// нельзя понизить последнего владельца — доска останется без управления
+		if req.Role != authz.RoleOwner {
+			if err := keepOwner(r.Context(), q, int32(boardID), int32(memberID)); err != nil {
+				return err
+			}
+		}
+		var err error
+		member, err = q.UpdateBoardMemberRole(r.Context(), db.UpdateBoardMemberRoleParams{
+			BoardID: int32(boardID),
+			UserID:  int32(memberID),
+			Role:    req.Role,
+		})
+		return err
+	})
+	if errors.Is(err, errLastOwner) {
+		http.Error(w, errLastOwner.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "member not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot update member", http.StatusInternalServerError)
+		log.Println("cannot update member:", err)
+		return
+	}
+
+	resp := dto.BoardMemberDTO{
+		BoardID:   member.BoardID,
+		UserID:    member.UserID,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt.Time,
+	}
+
+	log.Println("[UpdateMember] user", memberID, "on board", boardID, "is now", member.Role, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// DELETE /boards/{boardID}/members/{userID}
+func (h *MemberHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	memberID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	// покинуть доску может любой участник, удалить другого — только владелец
+	currentID, _ := middleware.GetUserID(r)
+	action := authz.ManageMembers
+	if int32(memberID) == currentID {
+		action = authz.LeaveBoard
+	}
+	if _, ok := authorize(w, r, h.authz, action, authz.Board(int32(boardID))); !ok {
+		return
+	}
+
+	// вместе с участником снимаются и его назначения на задачи доски
+	var rows int64
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		if err := keepOwner(r.Context(), q, int32(boardID), int32(memberID)); err != nil {
+			return err
+		}
+		var err error
+		rows, err = q.RemoveBoardMember(r.Context(), db.RemoveBoardMemberParams{
+			BoardID: int32(boardID),
+			UserID:  int32(memberID),
+		})
+		if err != nil || rows == 0 {
+			return err
+		}
+		return q.UnassignUserFromBoard(r.Context(), db.UnassignUserFromBoardParams{
+			BoardID: pgtype.Int4{Int32: int32(boardID), Valid: true},
+			UserID:  int32(memberID),
+		})
+	})
+	if errors.Is(err, errLastOwner) {
+		http.Error(w, errLastOwner.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot remove member", http.StatusInternalServerError)
+		log.Println("cannot remove member:", err)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, "member not found", http.StatusNotFound)
+		return
+	}
+
+	log.Println("[RemoveMember] user", memberID, "removed from board", boardID)
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+var errLastOwner = errors.New("board must keep at least one owner")
+
+// keepOwner в транзакции проверяет, что после снятия роли owner с memberID у доски останется
+// владелец. Строки владельцев блокируются до конца транзакции: иначе двое владельцев,
+// одновременно понижая друг друга, оба увидели бы второго и оставили доску без владельца
+func keepOwner(ctx context.Context, q db.Querier, boardID, memberID int32) error {
+	owners, err := q.LockBoardOwners(ctx, boardID)
+	if err != nil {
+		return err
+	}
+	if len(owners) <= 1 && slices.Contains(owners, memberID) {
+		return errLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/ratelimit"
+)
+
+// totpIssuer — название сервиса в приложении-аутентификаторе
+const totpIssuer = "TaskTracker"
+
+var (
+	errMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	errMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+)
+
+type MFAHandler struct {
+	store   db.Store
+	authSvc *auth.Service
+	cookies *RefreshCookies
+	// lockout — блокировка проверки кодов по пользователю; nil — выключена
+	lockout *ratelimit.Lockout
+}
+
+func NewMFAHandler(store db.Store, a *auth.Service) *MFAHandler {
+	return &MFAHandler{store: store, authSvc: a}
+}
+
+// WithRefreshCookies — как у AuthHandler: второй шаг входа тоже выдаёт refresh-токен
+func (h *MFAHandler) WithRefreshCookies(c *RefreshCookies) *MFAHandler {
+	h.cookies = c
+	return h
+}
+
+// WithCodeLockout ограничивает неверные коды на пользователя, а не на токен второго шага:
+// тот, кто знает пароль, получает новый токен при каждом входе
+func (h *MFAHandler) WithCodeLockout(l *ratelimit.Lockout) *MFAHandler {
+	h.lockout = l
+	return h
+}
+
+// GET /mfa
+func (h *MFAHandler) Status(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.store.GetUserTOTP(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "cannot fetch user", http.StatusInternalServerError)
+		log.Println("GetUserTOTP error:", err)
+		return
+	}
+	resp := dto.MFAStatusDTO{TOTPEnabled: user.TotpEnabledAt.Valid}
+	if resp.TOTPEnabled {
+		resp.RecoveryCodesLeft, err = h.store.CountUnusedRecoveryCodes(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "cannot fetch recovery codes", http.StatusInternalServerError)
+			log.Println("CountUnusedRecoveryCodes error:", err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// POST /mfa/totp/enroll — выдаёт новый секрет; 2FA включается только после /mfa/totp/confirm
+func (h *MFAHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.store.GetUserTOTP(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "cannot fetch user", http.StatusInternalServerError)
+		log.Println("GetUserTOTP error:", err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		http.Error(w, errMFAAlreadyEnabled.Error(), http.StatusConflict)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	n, err := h.store.SetPendingTOTPSecret(r.Context(), db.SetPendingTOTPSecretParams{
+		ID:         userID,
+		TotpSecret: pgtype.Text{String: secret, Valid: true},
+	})
+	if err != nil {
+		http.Error(w, "cannot start enrollment", http.StatusInternalServerError)
+		log.Println("SetPendingTOTPSecret error:", err)
+		return
+	}
+	if n == 0 {
+		http.Error(w, errMFAAlreadyEnabled.Error(), http.StatusConflict)
+		return
+	}
+
+	log.Println("[EnrollTOTP] user", userID, "started enrollment")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dto.TOTPEnrollmentDTO{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+// POST /mfa/totp/confirm — первый верный код включает 2FA и выдаёт коды восстановления
+func (h *MFAHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.store.GetUserTOTP(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "cannot fetch user", http.StatusInternalServerError)
+		log.Println("GetUserTOTP error:", err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		http.Error(w, errMFAAlreadyEnabled.Error(), http.StatusConflict)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		http.Error(w, "start enrollment first", http.StatusBadRequest)
+		return
+	}
+	valid, err := h.authSvc.VerifyTOTP(r.Context(), userID, user.TotpSecret.String, code)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("VerifyTOTP error:", err)
+		return
+	}
+	if !valid {
+		http.Error(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := auth.GenerateRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	err = h.store.ExecTx(r.Context(), func(q db.Querier) error {
+		n, err := q.EnableTOTP(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errMFAAlreadyEnabled
+		}
+		return replaceRecoveryCodes(r.Context(), q, userID, hashes)
+	})
+	if errors.Is(err, errMFAAlreadyEnabled) {
+		// параллельный confirm уже включил 2FA
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot enable two-factor authentication", http.StatusInternalServerError)
+		log.Println("cannot enable totp:", err)
+		return
+	}
+
+	recordSecurityEvent(r.Context(), h.store, userID, securityMFAEnabled, sessionMeta(r), map[string]string{"method": "totp"})
+	log.Println("[ConfirmTOTP] user", userID, "enabled two-factor authentication")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dto.RecoveryCodesDTO{RecoveryCodes: codes})
+}
+
+// POST /mfa/recovery-codes — заменяет все коды восстановления; нужен код из приложения
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+
+	user, ok := h.enabledUser(w, r, userID)
+	if !ok {
+		return
+	}
+	if !h.reserveCodeAttempt(w, r, userID) {
+		return
+	}
+	valid, err := h.authSvc.VerifyTOTP(r.Context(), userID, user.TotpSecret.String, code)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("VerifyTOTP error:", err)
+		return
+	}
+	if !valid {
+		http.Error(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+	h.codeAccepted(r.Context(), userID)
+
+	codes, hashes, err := auth.GenerateRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.store.ExecTx(r.Context(), func(q db.Querier) error {
+		return replaceRecoveryCodes(r.Context(), q, userID, hashes)
+	}); err != nil {
+		http.Error(w, "cannot generate recovery codes", http.StatusInternalServerError)
+		log.Println("cannot replace recovery codes:", err)
+		return
+	}
+
+	recordSecurityEvent(r.Context(), h.store, userID, securityRecoveryCodesNew, sessionMeta(r), map[string]string{})
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dto.RecoveryCodesDTO{RecoveryCodes: codes})
+}
+
+// POST /mfa/totp/disable — принимает код из приложения или код восстановления
+func (h *MFAHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+
+	user, ok := h.enabledUser(w, r, userID)
+	if !ok {
+		return
+	}
+	if !h.reserveCodeAttempt(w, r, userID) {
+		return
+	}
+	method, err := h.checkCode(r.Context(), user, code)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("cannot check mfa code:", err)
+		return
+	}
+	if method == "" {
+		http.Error(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+	h.codeAccepted(r.Context(), userID)
+
+	if err := h.store.ExecTx(r.Context(), func(q db.Querier) error {
+		if err := q.DisableTOTP(r.Context(), userID); err != nil {
+			return err
+		}
+		return q.DeleteRecoveryCodes(r.Context(), userID)
+	}); err != nil {
+		http.Error(w, "cannot disable two-factor authentication", http.StatusInternalServerError)
+		log.Println("cannot disable totp:", err)
+		return
+	}
+
+	recordSecurityEvent(r.Context(), h.store, userID, securityMFADisabled, sessionMeta(r), map[string]string{"method": method})
+	log.Println("[DisableTOTP] user", userID, "disabled two-factor authentication")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// POST /login/mfa — второй шаг входа: токен из /login и код меняются на пару токенов
+func (h *MFAHandler) Login(w http.ResponseWriter, r *http.Request) {
+	log.Println("MFA login called")
+
+	var req dto.MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "mfa_token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.authSvc.MFAChallengeUser(r.Context(), req.MFAToken)
+	if errors.Is(err, auth.ErrInvalidMFAChallenge) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("cannot read mfa challenge:", err)
+		return
+	}
+
+	user, err := h.store.GetUserTOTP(r.Context(), userID)
+	if err != nil || !user.TotpEnabledAt.Valid {
+		http.Error(w, auth.ErrInvalidMFAChallenge.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !h.reserveCodeAttempt(w, r, userID) {
+		return
+	}
+	method, err := h.checkCode(r.Context(), user, req.Code)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("cannot check mfa code:", err)
+		return
+	}
+	if method == "" {
+		if err := h.authSvc.FailMFAChallenge(r.Context(), req.MFAToken); err != nil {
+			log.Println("cannot count mfa attempt:", err)
+		}
+		http.Error(w, "invalid code", http.StatusUnauthorized)
+		return
+	}
+	h.codeAccepted(r.Context(), userID)
+	if err := h.authSvc.ConsumeMFAChallenge(r.Context(), req.MFAToken); err != nil {
+		http.Error(w, auth.ErrInvalidMFAChallenge.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	meta := sessionMeta(r)
+	if method == mfaMethodRecovery {
+		recordSecurityEvent(r.Context(), h.store, userID, securityRecoveryCodeUsed, meta, map[string]string{})
+	}
+	tp, err := h.authSvc.StartSession(r.Context(), userID, meta)
+	if err != nil {
+		http.Error(w, "cannot generate token", http.StatusInternalServerError)
+		return
+	}
+	log.Println("User logged in with", method, "code:", user.Email)
+	if err := writeTokens(w, h.cookies, tp); err != nil {
+		http.Error(w, "failed to log in", http.StatusInternalServerError)
+	}
+}
+
+const (
+	mfaMethodTOTP     = "totp"
+	mfaMethodRecovery = "recovery_code"
+)
+
+// checkCode проверяет код из приложения или погашает код восстановления.
+// Возвращает способ подтверждения или "", если код не подошёл.
+func (h *MFAHandler) checkCode(ctx context.Context, user db.GetUserTOTPRow, code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == auth.TOTPDigits && isDigits(code) {
+		ok, err := h.authSvc.VerifyTOTP(ctx, user.ID, user.TotpSecret.String, code)
+		if err != nil || !ok {
+			return "", err
+		}
+		return mfaMethodTOTP, nil
+	}
+	n, err := h.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		UserID:   user.ID,
+		CodeHash: auth.HashRecoveryCode(code),
+	})
+	if err != nil || n == 0 {
+		return "", err
+	}
+	return mfaMethodRecovery, nil
+}
+
+// reserveCodeAttempt учитывает попытку до проверки кода; false — ответ уже отправлен.
+// При недоступном Redis попытка не допускается: без счётчика код можно было бы перебирать
+func (h *MFAHandler) reserveCodeAttempt(w http.ResponseWriter, r *http.Request, userID int32) bool {
+	if h.lockout == nil {
+		return true
+	}
+	d, err := h.lockout.Attempt(r.Context(), strconv.Itoa(int(userID)))
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("cannot count mfa attempt:", err)
+		return false
+	}
+	if d > 0 {
+		log.Println("MFA codes locked for user", userID, "for", d)
+		middleware.TooManyRequests(w, d)
+		return false
+	}
+	return true
+}
+
+// codeAccepted забывает неверные коды пользователя после верного
+func (h *MFAHandler) codeAccepted(ctx context.Context, userID int32) {
+	if h.lockout == nil {
+		return
+	}
+	if err := h.lockout.Reset(ctx, strconv.Itoa(int(userID))); err != nil {
+		log.Println("cannot reset mfa lockout:", err)
+	}
+}
+
+// enabledUser загружает пользователя и отвечает 400, если 2FA не включена
+func (h *MFAHandler) enabledUser(w http.ResponseWriter, r *http.Request, userID int32) (db.GetUserTOTPRow, bool) {
+	user, err := h.store.GetUserTOTP(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "cannot fetch user", http.StatusInternalServerError)
+		log.Println("GetUserTOTP error:", err)
+		return user, false
+	}
+	if !user.TotpEnabledAt.Valid || !user.TotpSecret.Valid {
+		http.Error(w, errMFANotEnabled.Error(), http.StatusBadRequest)
+		return user, false
+	}
+	return user, true
+}
+
+func replaceRecoveryCodes(ctx context.Context, q db.Querier, userID int32, hashes []string) error {
+	if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+	return q.CreateRecoveryCodes(ctx, db.CreateRecoveryCodesParams{UserID: userID, CodeHashes: hashes})
+}
+
+func decodeMFACode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req dto.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return "", false
+	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return "", false
+	}
+	return strings.TrimSpace(req.Code), true
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/oidc"
+)
+
+// oidcStateCookieName — cookie с хешем state: callback принимается только от браузера,
+// который начал вход, иначе чужие code и state можно подсунуть жертве (login CSRF)
+const oidcStateCookieName = "oidc_state"
+
+var (
+	errIdentityNoEmail     = errors.New("identity provider did not return an email")
+	errIdentityUnverified  = errors.New("an account with this email already exists; sign in with your password and link the identity")
+	errIdentityTaken       = errors.New("this identity is linked to another account")
+	errIdentityAlreadyLink = errors.New("another identity of this provider is already linked")
+)
+
+// OIDCHandler — вход через внешнего провайдера OpenID Connect и привязка внешних аккаунтов.
+// Провайдер возвращает пользователя на фронт (redirect_uri), фронт передаёт code и state в POST /oidc/callback.
+type OIDCHandler struct {
+	store     db.Store
+	authSvc   *auth.Service
+	providers map[string]*oidc.Provider
+	// requireVerified — не выдавать токены, пока email не подтверждён (провайдером или письмом)
+	requireVerified bool
+	cookies         *RefreshCookies
+}
+
+func NewOIDCHandler(store db.Store, a *auth.Service, providers ...*oidc.Provider) *OIDCHandler {
+	h := &OIDCHandler{store: store, authSvc: a, providers: map[string]*oidc.Provider{}}
+	for _, p := range providers {
+		h.providers[p.Name()] = p
+	}
+	return h
+}
+
+// RequireVerifiedEmail — как у AuthHandler
+func (h *OIDCHandler) RequireVerifiedEmail(on bool) *OIDCHandler {
+	h.requireVerified = on
+	return h
+}
+
+// WithRefreshCookies — как у AuthHandler
+func (h *OIDCHandler) WithRefreshCookies(c *RefreshCookies) *OIDCHandler {
+	h.cookies = c
+	return h
+}
+
+// GET /oidc/{provider}/start
+func (h *OIDCHandler) Start(w http.ResponseWriter, r *http.Request) {
+	h.start(w, r, 0)
+}
+
+// POST /oidc/{provider}/link — привязать внешний аккаунт к текущему пользователю
+func (h *OIDCHandler) Link(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.start(w, r, userID)
+}
+
+func (h *OIDCHandler) start(w http.ResponseWriter, r *http.Request, linkUserID int32) {
+	p, ok := h.providers[chi.URLParam(r, "provider")]
+	if !ok {
+		http.Error(w, "unknown identity provider", http.StatusNotFound)
+		return
+	}
+
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	nonce, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	state, err := h.authSvc.IssueOIDCState(r.Context(), auth.OIDCState{
+		Provider:   p.Name(),
+		Nonce:      nonce,
+		Verifier:   verifier,
+		LinkUserID: linkUserID,
+	})
+	if err != nil {
+		http.Error(w, "cannot start login", http.StatusInternalServerError)
+		log.Println("IssueOIDCState error:", err)
+		return
+	}
+	authURL, err := p.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		http.Error(w, "identity provider unavailable", http.StatusBadGateway)
+		log.Println("oidc", p.Name(), "error:", err)
+		return
+	}
+	http.SetCookie(w, h.stateCookie(auth.HashToken(state), int(auth.OIDCStateTTL.Seconds())))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dto.OIDCStartResponse{AuthorizationURL: authURL})
+}
+
+// POST /oidc/callback — вход (LoginResponse или MFAChallengeResponse) либо привязка (IdentityDTO)
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	var req dto.OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "state and code are required", http.StatusBadRequest)
+		return
+	}
+
+	// state проверяется до ConsumeOIDCState, чтобы чужой запрос не сжёг вход настоящего пользователя
+	c, err := r.Cookie(oidcStateCookieName)
+	if err != nil || subtle.ConstantTimeCompare([]byte(c.Value), []byte(auth.HashToken(req.State))) != 1 {
+		http.Error(w, auth.ErrInvalidOIDCState.Error(), http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, h.stateCookie("", -1))
+
+	st, err := h.authSvc.ConsumeOIDCState(r.Context(), req.State)
+	if errors.Is(err, auth.ErrInvalidOIDCState) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		log.Println("ConsumeOIDCState error:", err)
+		return
+	}
+	p, ok := h.providers[st.Provider]
+	if !ok {
+		http.Error(w, "unknown identity provider", http.StatusBadRequest)
+		return
+	}
+	ident, err := p.Exchange(r.Context(), req.Code, st.Verifier, st.Nonce)
+	if err != nil {
+		http.Error(w, "identity provider login failed", http.StatusUnauthorized)
+		log.Println("oidc", p.Name(), "exchange error:", err)
+		return
+	}
+
+	if st.LinkUserID != 0 {
+		h.link(w, r, st.LinkUserID, p.Name(), ident)
+		return
+	}
+
+	userID, err := h.resolveUser(r.Context(), r, p.Name(), ident)
+	switch {
+	case errors.Is(err, errIdentityNoEmail):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, errIdentityUnverified):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "cannot sign in", http.StatusInternalServerError)
+		log.Println("oidc resolve user error:", err)
+		return
+	}
+
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "cannot sign in", http.StatusInternalServerError)
+		log.Println("GetUserByID error:", err)
+		return
+	}
+	if h.requireVerified && !user.EmailVerifiedAt.Valid {
+		http.Error(w, "email not verified", http.StatusForbidden)
+		return
+	}
+	completeLogin(w, r, h.authSvc, h.cookies, user.ID, user.Email, user.TotpEnabledAt.Valid)
+}
+
+// resolveUser находит пользователя по внешнему аккаунту. Незнакомый аккаунт привязывается
+// к пользователю с тем же email, только если email подтвердили и провайдер, и сам пользователь,
+// иначе по чужому адресу можно было бы войти в существующий аккаунт. Если пользователя нет — он создаётся.
+func (h *OIDCHandler) resolveUser(ctx context.Context, r *http.Request, provider string, ident *oidc.Identity) (int32, error) {
+	existing, err := h.store.GetUserIdentity(ctx, db.GetUserIdentityParams{Provider: provider, Subject: ident.Subject})
+	if err == nil {
+		return existing.UserID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+	if ident.Email == "" {
+		return 0, errIdentityNoEmail
+	}
+
+	var userID int32
+	linked := false
+	err = h.store.ExecTx(ctx, func(q db.Querier) error {
+		user, err := q.GetUserByEmail(ctx, ident.Email)
+		switch {
+		case err == nil:
+			// оба адреса должны быть подтверждены: иначе аккаунт, заранее зарегистрированный
+			// на чужой email, получил бы вход владельца адреса, а регистратор сохранил бы свой пароль
+			if !ident.EmailVerified || !user.EmailVerifiedAt.Valid {
+				return errIdentityUnverified
+			}
+			userID = user.ID
+			linked = true
+		case errors.Is(err, pgx.ErrNoRows):
+			// пароля нет: пустой хеш не совпадает ни с одним паролем, войти можно только через провайдера
+			// или задав пароль через /password/forgot
+			created, err := q.CreateUser(ctx, db.CreateUserParams{Email: ident.Email, Password: ""})
+			if err != nil {
+				return err
+			}
+			userID = created.ID
+			// приглашения принимаются только на адрес, подтверждённый провайдером;
+			// иначе они дождутся /email/verify
+			if ident.EmailVerified {
+				if _, err := q.MarkEmailVerified(ctx, userID); err != nil {
+					return err
+				}
+				if _, err := q.AcceptPendingInvitations(ctx, db.AcceptPendingInvitationsParams{
+					Email:  created.Email,
+					UserID: userID,
+				}); err != nil {
+					return err
+				}
+			}
+			log.Println("User signed up via", provider+":", ident.Email)
+		default:
+			return err
+		}
+		_, err = q.CreateUserIdentity(ctx, identityParams(userID, provider, ident))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if linked {
+		recordSecurityEvent(ctx, h.store, userID, securityIdentityLinked, sessionMeta(r), map[string]string{
+			"provider": provider,
+			"reason":   "verified_email",
+		})
+	}
+	return userID, nil
+}
+
+// stateCookie — HttpOnly cookie только для маршрутов /oidc; в режиме refresh-cookie
+// берёт Secure, SameSite и Domain из его настроек
+func (h *OIDCHandler) stateCookie(value string, maxAge int) *http.Cookie {
+	c := &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    value,
+		Path:     "/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if h.cookies != nil {
+		c.Secure, c.SameSite, c.Domain = h.cookies.Secure, h.cookies.SameSite, h.cookies.Domain
+	}
+	return c
+}
+
+func (h *OIDCHandler) link(w http.ResponseWriter, r *http.Request, userID int32, provider string, ident *oidc.Identity) {
+	existing, err := h.store.GetUserIdentity(r.Context(), db.GetUserIdentityParams{Provider: provider, Subject: ident.Subject})
+	if err == nil {
+		if existing.UserID != userID {
+			http.Error(w, errIdentityTaken.Error(), http.StatusConflict)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(identityDTO(existing))
+		return
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "cannot link identity", http.StatusInternalServerError)
+		log.Println("GetUserIdentity error:", err)
+		return
+	}
+
+	created, err := h.store.CreateUserIdentity(r.Context(), identityParams(userID, provider, ident))
+	if isUniqueViolation(err) {
+		http.Error(w, errIdentityAlreadyLink.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot link identity", http.StatusInternalServerError)
+		log.Println("CreateUserIdentity error:", err)
+		return
+	}
+	recordSecurityEvent(r.Context(), h.store, userID, securityIdentityLinked, sessionMeta(r), map[string]string{
+		"provider": provider,
+		"reason":   "user_request",
+	})
+	log.Println("[LinkIdentity] user", userID, "linked", provider, "identity")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(identityDTO(created))
+}
+
+// GET /identities
+func (h *OIDCHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := h.store.ListUserIdentities(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "cannot fetch identities", http.StatusInternalServerError)
+		log.Println("ListUserIdentities error:", err)
+		return
+	}
+	resp := []dto.IdentityDTO{}
+	for _, i := range identities {
+		resp = append(resp, identityDTO(i))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func identityParams(userID int32, provider string, ident *oidc.Identity) db.CreateUserIdentityParams {
+	return db.CreateUserIdentityParams{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  ident.Subject,
+		Email:    pgtype.Text{String: ident.Email, Valid: ident.Email != ""},
+	}
+}
+
+func identityDTO(i db.UserIdentity) dto.IdentityDTO {
+	return dto.IdentityDTO{
+		ID:        i.ID,
+		Provider:  i.Provider,
+		Email:     i.Email.String,
+		CreatedAt: i.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // часовые пояса не зависят от tzdata в образе
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+)
+
+const (
+	maxDisplayNameLen = 64
+	maxAvatarURLLen   = 2048
+	// maxAvatarSize — предел загружаемой картинки; больше аватару не нужно
+	maxAvatarSize = 1 << 20
+)
+
+// avatarTypes — форматы, которые браузер покажет в <img> и которые не исполняют скриптов
+var avatarTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// localeRe — тег BCP 47 вида язык[-Письменность][-РЕГИОН]
+var localeRe = regexp.MustCompile(`^([A-Za-z]{2,3})(?:[-_]([A-Za-z]{4}))?(?:[-_]([A-Za-z]{2}|[0-9]{3}))?$`)
+
+type ProfileHandler struct {
+	store db.Store
+}
+
+func NewProfileHandler(store db.Store) *ProfileHandler {
+	return &ProfileHandler{store: store}
+}
+
+// GET /me
+func (h *ProfileHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	u, err := h.store.GetUserProfile(r.Context(), userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot fetch profile", http.StatusInternalServerError)
+		log.Println("GetUserProfile error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(profileDTO(db.UpdateUserProfileRow(u)))
+}
+
+// PATCH /me
+func (h *ProfileHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	params := db.UpdateUserProfileParams{ID: userID}
+	if req.DisplayName != nil {
+		name, err := normalizeDisplayName(*req.DisplayName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.DisplayName = pgtype.Text{String: name, Valid: true}
+	}
+	if req.AvatarURL != nil {
+		avatar := strings.TrimSpace(*req.AvatarURL)
+		if avatar != "" && !validAvatarURL(avatar) {
+			http.Error(w, "avatar_url must be an absolute http or https URL", http.StatusBadRequest)
+			return
+		}
+		params.AvatarUrl = pgtype.Text{String: avatar, Valid: true}
+	}
+	if req.Timezone != nil {
+		tz := strings.TrimSpace(*req.Timezone)
+		if !validTimezone(tz) {
+			http.Error(w, "unknown timezone", http.StatusBadRequest)
+			return
+		}
+		params.Timezone = pgtype.Text{String: tz, Valid: true}
+	}
+	if req.Locale != nil {
+		locale, ok := normalizeLocale(*req.Locale)
+		if !ok {
+			http.Error(w, "invalid locale", http.StatusBadRequest)
+			return
+		}
+		params.Locale = pgtype.Text{String: locale, Valid: true}
+	}
+
+	var u db.UpdateUserProfileRow
+	err := h.store.ExecTx(r.Context(), func(q db.Querier) error {
+		// ссылка на внешний аватар заменяет загруженный
+		if params.AvatarUrl.Valid {
+			if _, err := q.DeleteUserAvatar(r.Context(), userID); err != nil {
+				return err
+			}
+		}
+		var err error
+		u, err = q.UpdateUserProfile(r.Context(), params)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot update profile", http.StatusInternalServerError)
+		log.Println("UpdateUserProfile error:", err)
+		return
+	}
+
+	log.Println("[UpdateMe] profile updated by user", userID)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(profileDTO(u))
+}
+
+// PUT /me/avatar — тело запроса целиком и есть картинка
+func (h *ProfileHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAvatarSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("avatar must be at most %d bytes", maxAvatarSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	// тип определяется по содержимому: заголовку клиента верить нельзя
+	contentType := http.DetectContentType(data)
+	if !avatarTypes[contentType] {
+		http.Error(w, "avatar must be a PNG, JPEG, GIF or WebP image", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var u db.UpdateUserProfileRow
+	err = h.store.ExecTx(r.Context(), func(q db.Querier) error {
+		if err := q.UpsertUserAvatar(r.Context(), db.UpsertUserAvatarParams{
+			UserID:      userID,
+			ContentType: contentType,
+			Data:        data,
+		}); err != nil {
+			return err
+		}
+		// версия в ссылке сбрасывает кеш браузеров после замены картинки
+		avatar := fmt.Sprintf("/users/%d/avatar?v=%d", userID, time.Now().Unix())
+		var err error
+		u, err = q.UpdateUserProfile(r.Context(), db.UpdateUserProfileParams{
+			AvatarUrl: pgtype.Text{String: avatar, Valid: true},
+			ID:        userID,
+		})
+		return err
+	})
+	if err != nil {
+		http.Error(w, "cannot save avatar", http.StatusInternalServerError)
+		log.Println("cannot save avatar:", err)
+		return
+	}
+
+	log.Println("[UploadAvatar] avatar uploaded by user", userID)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(profileDTO(u))
+}
+
+// DELETE /me/avatar — убирает и загруженную картинку, и внешнюю ссылку
+func (h *ProfileHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := h.store.ExecTx(r.Context(), func(q db.Querier) error {
+		if _, err := q.DeleteUserAvatar(r.Context(), userID); err != nil {
+			return err
+		}
+		_, err := q.UpdateUserProfile(r.Context(), db.UpdateUserProfileParams{
+			AvatarUrl: pgtype.Text{String: "", Valid: true},
+			ID:        userID,
+		})
+		return err
+	})
+	if err != nil {
+		http.Error(w, "cannot delete avatar", http.StatusInternalServerError)
+		log.Println("cannot delete avatar:", err)
+		return
+	}
+
+	log.Println("[DeleteAvatar] avatar removed by user", userID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /users/{userID}/avatar — без авторизации: <img> не передаёт заголовок Authorization
+func (h *ProfileHandler) Avatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	avatar, err := h.store.GetUserAvatar(r.Context(), int32(userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "avatar not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot fetch avatar", http.StatusInternalServerError)
+		log.Println("GetUserAvatar error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", avatar.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeContent(w, r, "", avatar.UpdatedAt.Time, bytes.NewReader(avatar.Data))
+}
+
+func profileDTO(u db.UpdateUserProfileRow) dto.UserDTO {
+	resp := dto.UserDTO{
+		ID:            u.ID,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerifiedAt.Valid,
+		DisplayName:   u.DisplayName.String,
+		AvatarURL:     u.AvatarUrl.String,
+		Timezone:      u.Timezone,
+		Locale:        u.Locale,
+		MFAEnabled:    u.TotpEnabledAt.Valid,
+	}
+	if u.CreatedAt.Valid {
+		resp.CreatedAt = &u.CreatedAt.Time
+	}
+	return resp
+}
+
+func userSummary(u db.ListUserSummariesRow) dto.UserSummaryDTO {
+	name := u.DisplayName.String
+	if name == "" {
+		name, _, _ = strings.Cut(u.Email, "@")
+	}
+	return dto.UserSummaryDTO{ID: u.ID, Name: name, AvatarURL: u.AvatarUrl.String}
+}
+
+func normalizeDisplayName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxDisplayNameLen {
+		return "", fmt.Errorf("display_name must be at most %d characters", maxDisplayNameLen)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return "", errors.New("display_name contains invalid characters")
+		}
+	}
+	return name, nil
+}
+
+func validAvatarURL(s string) bool {
+	if len(s) > maxAvatarURLLen {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil
+}
+
+// validTimezone принимает только имена из базы IANA: "Local" зависит от сервера
+func validTimezone(tz string) bool {
+	if tz == "" || tz == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(tz)
+	return err == nil
+}
+
+// normalizeLocale приводит тег к каноническому регистру: en-us -> en-US, zh_hant -> zh-Hant
+func normalizeLocale(s string) (string, bool) {
+	m := localeRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return "", false
+	}
+	tag := strings.ToLower(m[1])
+	if m[2] != "" {
+		tag += "-" + strings.ToUpper(m[2][:1]) + strings.ToLower(m[2][1:])
+	}
+	if m[3] != "" {
+		tag += "-" + strings.ToUpper(m[3])
+	}
+	return tag, true
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+)
+
+// maxUserAgentLen — сколько символов User-Agent хранится в сессии
+const maxUserAgentLen = 256
+
+type SessionHandler struct {
+	authSvc *auth.Service
+}
+
+func NewSessionHandler(a *auth.Service) *SessionHandler {
+	return &SessionHandler{authSvc: a}
+}
+
+// GET /sessions
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.authSvc.ListSessions(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "cannot fetch sessions", http.StatusInternalServerError)
+		log.Println("ListSessions error:", err)
+		return
+	}
+
+	current := middleware.GetSessionID(r)
+	resp := []dto.SessionDTO{}
+	for _, sess := range sessions {
+		resp = append(resp, dto.SessionDTO{
+			ID:         sess.ID,
+			UserAgent:  sess.UserAgent,
+			IP:         sess.IP,
+			CreatedAt:  sess.CreatedAt,
+			LastUsedAt: sess.LastUsedAt,
+			Current:    sess.ID == current,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// DELETE /sessions/{sessionID}
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sid := chi.URLParam(r, "sessionID")
+	err := h.authSvc.RevokeSession(r.Context(), userID, sid)
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot revoke session", http.StatusInternalServerError)
+		log.Println("cannot revoke session:", err)
+		return
+	}
+
+	log.Println("[RevokeSession] session", sid, "revoked by user", userID)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// DELETE /sessions — выход на всех устройствах, включая текущее
+func (h *SessionHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.authSvc.RevokeAll(r.Context(), userID); err != nil {
+		http.Error(w, "cannot revoke sessions", http.StatusInternalServerError)
+		log.Println("cannot revoke sessions:", err)
+		return
+	}
+
+	log.Println("[RevokeAllSessions] user", userID, "logged out everywhere")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// sessionMeta — устройство и адрес клиента для индекса сессий
+func sessionMeta(r *http.Request) auth.SessionMeta {
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLen {
+		ua = ua[:maxUserAgentLen]
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return auth.SessionMeta{UserAgent: ua, IP: ip}
+}
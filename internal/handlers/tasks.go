@@ -1,25 +1,40 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/activity"
+	"github.com/sqszy/TaskTracker/internal/authz"
 	"github.com/sqszy/TaskTracker/internal/db"
 	"github.com/sqszy/TaskTracker/internal/dto"
-	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/rank"
+	"github.com/sqszy/TaskTracker/internal/realtime"
+)
+
+var (
+	errInvalidAssignees  = errors.New("assignees must be board members")
+	errUnknownColumn     = errors.New("status does not exist on this board")
+	errInvalidNeighbours = errors.New("neighbour tasks must be ordered tasks of the target column")
 )
 
 type TaskHandler struct {
-	queries db.Querier
+	queries db.Store
+	authz   *authz.Authorizer
+	events  realtime.Publisher
 }
 
-func NewTaskHandler(q db.Querier) *TaskHandler {
-	return &TaskHandler{queries: q}
+func NewTaskHandler(q db.Store, events realtime.Publisher) *TaskHandler {
+	return &TaskHandler{queries: q, authz: authz.New(q), events: events}
 }
 
 // POST /boards/{boardID}/CreateTask
@@ -31,9 +46,8 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := middleware.GetUserID(r)
+	userID, ok := authorize(w, r, h.authz, authz.CreateTask, authz.Board(int32(boardID)))
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -43,10 +57,10 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// defaults
-	status := req.Status
-	if status == "" {
-		status = "todo"
+	// defaults; статус по умолчанию — первая колонка доски
+	var status *string
+	if req.Status != "" {
+		status = &req.Status
 	}
 	priority := req.Priority
 	if priority == "" {
@@ -58,15 +72,61 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		deadline = pgtype.Timestamp{Time: *req.Deadline, Valid: true}
 	}
 
-	task, err := h.queries.CreateTask(r.Context(), db.CreateTaskParams{
-		BoardID:     pgtype.Int4{Int32: int32(boardID), Valid: true},
-		UserID:      int32(userID),
-		Title:       req.Title,
-		Description: pgtype.Text{String: req.Description, Valid: req.Description != ""},
-		Status:      pgtype.Text{String: status, Valid: true},
-		Priority:    priority,
-		Deadline:    deadline,
+	assignees := uniqueIDs(req.AssigneeIDs)
+
+	// задача и её исполнители сохраняются в одной транзакции
+	var (
+		task      db.CreateTaskRow
+		overLimit bool
+	)
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		column, err := resolveColumn(r.Context(), q, int32(boardID), status, req.ColumnID)
+		if err != nil {
+			return err
+		}
+		if overLimit, err = checkWIP(r.Context(), q, column, 0); err != nil {
+			return err
+		}
+		key, err := lastRank(r.Context(), q, column.ID, 0)
+		if err != nil {
+			return err
+		}
+		task, err = q.CreateTask(r.Context(), db.CreateTaskParams{
+			BoardID:     pgtype.Int4{Int32: int32(boardID), Valid: true},
+			UserID:      int32(userID),
+			Title:       req.Title,
+			Description: pgtype.Text{String: req.Description, Valid: req.Description != ""},
+			Status:      pgtype.Text{String: column.Name, Valid: true},
+			Priority:    priority,
+			Deadline:    deadline,
+			ColumnID:    pgtype.Int4{Int32: column.ID, Valid: true},
+			Rank:        key,
+		})
+		if err != nil {
+			return err
+		}
+		if len(assignees) > 0 {
+			if err := setAssignees(r.Context(), q, int32(boardID), task.ID, assignees); err != nil {
+				return err
+			}
+		}
+		return activity.Record(r.Context(), q, activity.Event{
+			BoardID:  int32(boardID),
+			TaskID:   task.ID,
+			ActorID:  userID,
+			Entity:   activity.EntityTask,
+			EntityID: task.ID,
+			Action:   activity.Created,
+			Changes:  activity.Diff(nil, activity.TaskFields(createdTask(task), assignees)),
+		})
 	})
+	if errors.Is(err, errInvalidAssignees) || errors.Is(err, errUnknownColumn) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if writeWIPError(w, err) {
+		return
+	}
 	if err != nil {
 		http.Error(w, "cannot create task", http.StatusInternalServerError)
 		log.Println("cannot create task:", err)
@@ -80,13 +140,21 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		Title:       task.Title,
 		Description: task.Description.String,
 		Status:      task.Status.String,
+		ColumnID:    task.ColumnID.Int32,
+		Rank:        task.Rank,
 		Priority:    task.Priority,
+		AssigneeIDs: assignees,
 		CreatedAt:   task.CreatedAt.Time,
 		UpdatedAt:   task.UpdatedAt.Time,
+
+		WIPLimitExceeded: overLimit,
 	}
 	if task.Deadline.Valid {
 		resp.Deadline = &task.Deadline.Time
 	}
+	attachTaskUsers(r.Context(), h.queries, &resp)
+
+	h.publish(r, realtime.TaskCreated, userID, resp)
 
 	w.Header().Set("Content-Type", "application/json")
 	log.Println("[CreateTask] task created:", task.ID, "in board", boardID, "by user", userID)
@@ -107,9 +175,8 @@ func (h *TaskHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := middleware.GetUserID(r)
+	userID, ok := authorize(w, r, h.authz, authz.UpdateTask, authz.Task(int32(boardID), int32(taskID)))
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -122,7 +189,6 @@ func (h *TaskHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
 	params := db.UpdateTaskParams{
 		ID:      int32(taskID),
 		BoardID: pgtype.Int4{Int32: int32(boardID), Valid: true},
-		UserID:  int32(userID),
 	}
 
 	if req.Title != nil {
@@ -131,9 +197,6 @@ func (h *TaskHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
 	if req.Description != nil {
 		params.Description = pgtype.Text{String: *req.Description, Valid: true}
 	}
-	if req.Status != nil {
-		params.Status = pgtype.Text{String: *req.Status, Valid: true}
-	}
 	if req.Priority != nil {
 		params.Priority = pgtype.Text{String: *req.Priority, Valid: true}
 	}
@@ -141,30 +204,214 @@ func (h *TaskHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
 		params.Deadline = pgtype.Timestamp{Time: *req.Deadline, Valid: true}
 	}
 
-	task, err := h.queries.UpdateTask(r.Context(), params)
+	var (
+		task      db.Task
+		assignees []int32
+		overLimit bool
+	)
+	// изменение и запись в журнал — в одной транзакции
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		before, err := q.GetTaskForUpdate(r.Context(), db.GetTaskForUpdateParams{ID: params.ID, BoardID: params.BoardID})
+		if err != nil {
+			return err
+		}
+		beforeAssignees, err := q.ListTaskAssignees(r.Context(), before.ID)
+		if err != nil {
+			return err
+		}
+		if req.Status != nil || req.ColumnID != nil {
+			column, err := resolveColumn(r.Context(), q, int32(boardID), req.Status, req.ColumnID)
+			if err != nil {
+				return err
+			}
+			params.Status = pgtype.Text{String: column.Name, Valid: true}
+			params.ColumnID = pgtype.Int4{Int32: column.ID, Valid: true}
+			// при смене колонки задача встаёт в её конец
+			if params.ColumnID != before.ColumnID {
+				if overLimit, err = checkWIP(r.Context(), q, column, before.ID); err != nil {
+					return err
+				}
+				key, err := lastRank(r.Context(), q, column.ID, before.ID)
+				if err != nil {
+					return err
+				}
+				params.Rank = pgtype.Text{String: key, Valid: true}
+			}
+		}
+
+		task, err = q.UpdateTask(r.Context(), params)
+		if err != nil {
+			return err
+		}
+		assignees = nonNilIDs(beforeAssignees)
+		if req.AssigneeIDs != nil {
+			if err := setAssignees(r.Context(), q, int32(boardID), task.ID, uniqueIDs(*req.AssigneeIDs)); err != nil {
+				return err
+			}
+			if assignees, err = q.ListTaskAssignees(r.Context(), task.ID); err != nil {
+				return err
+			}
+			assignees = nonNilIDs(assignees)
+		}
+
+		changes := activity.Diff(
+			activity.TaskFields(before, nonNilIDs(beforeAssignees)),
+			activity.TaskFields(task, assignees),
+		)
+		if len(changes) == 0 {
+			return nil
+		}
+		return activity.Record(r.Context(), q, activity.Event{
+			BoardID:  int32(boardID),
+			TaskID:   task.ID,
+			ActorID:  userID,
+			Entity:   activity.EntityTask,
+			EntityID: task.ID,
+			Action:   activity.Updated,
+			Changes:  changes,
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errInvalidAssignees) || errors.Is(err, errUnknownColumn) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if writeWIPError(w, err) {
+		return
+	}
 	if err != nil {
 		http.Error(w, "cannot update task", http.StatusInternalServerError)
 		log.Println("cannot update task:", err)
 		return
 	}
 
-	resp := dto.TaskDTO{
-		ID:          task.ID,
-		BoardID:     task.BoardID.Int32,
-		UserID:      task.UserID,
-		Title:       task.Title,
-		Description: task.Description.String,
-		Status:      task.Status.String,
-		Priority:    task.Priority,
-		CreatedAt:   task.CreatedAt.Time,
-		UpdatedAt:   task.UpdatedAt.Time,
+	resp := taskDTO(task, assignees)
+	attachTaskUsers(r.Context(), h.queries, &resp)
+	h.publish(r, realtime.TaskUpdated, userID, resp)
+	resp.WIPLimitExceeded = overLimit
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[PatchTask] is updated task:", task.ID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// POST /boards/{boardID}/tasks/{taskID}/move
+func (h *TaskHandler) MoveTask(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
 	}
-	if task.Deadline.Valid {
-		resp.Deadline = &task.Deadline.Time
+
+	taskID, err := strconv.Atoi(chi.URLParam(r, "taskID"))
+	if err != nil {
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.UpdateTask, authz.Task(int32(boardID), int32(taskID)))
+	if !ok {
+		return
 	}
 
+	var req dto.MoveTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "column_id is required", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		task      db.Task
+		assignees []int32
+		overLimit bool
+	)
+	// обновляется только перемещённая задача; колонка пересчитывается, лишь когда кончился промежуток
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		before, err := q.GetTaskForUpdate(r.Context(), db.GetTaskForUpdateParams{
+			ID:      int32(taskID),
+			BoardID: pgtype.Int4{Int32: int32(boardID), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		column, err := resolveColumn(r.Context(), q, int32(boardID), nil, &req.ColumnID)
+		if err != nil {
+			return err
+		}
+		if before.ColumnID.Int32 != column.ID {
+			if overLimit, err = checkWIP(r.Context(), q, column, before.ID); err != nil {
+				return err
+			}
+		}
+		slot, err := neighbourSlot(r.Context(), q, before, column.ID, req.PrevID, req.NextID)
+		if err != nil {
+			return err
+		}
+		key, err := placeRank(r.Context(), q, column.ID, before.ID, slot)
+		if err != nil {
+			return err
+		}
+		task, err = q.MoveTask(r.Context(), db.MoveTaskParams{
+			ColumnID: pgtype.Int4{Int32: column.ID, Valid: true},
+			Status:   pgtype.Text{String: column.Name, Valid: true},
+			Rank:     key,
+			ID:       before.ID,
+			BoardID:  before.BoardID,
+		})
+		if err != nil {
+			return err
+		}
+		if assignees, err = q.ListTaskAssignees(r.Context(), task.ID); err != nil {
+			return err
+		}
+		assignees = nonNilIDs(assignees)
+
+		// перестановка внутри колонки в журнал не попадает, смена колонки — как смена статуса
+		changes := activity.Diff(activity.TaskFields(before, nil), activity.TaskFields(task, nil))
+		if len(changes) == 0 {
+			return nil
+		}
+		return activity.Record(r.Context(), q, activity.Event{
+			BoardID:  int32(boardID),
+			TaskID:   task.ID,
+			ActorID:  userID,
+			Entity:   activity.EntityTask,
+			EntityID: task.ID,
+			Action:   activity.Updated,
+			Changes:  changes,
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errUnknownColumn) || errors.Is(err, errInvalidNeighbours) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if writeWIPError(w, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot move task", http.StatusInternalServerError)
+		log.Println("cannot move task:", err)
+		return
+	}
+
+	resp := taskDTO(task, assignees)
+	attachTaskUsers(r.Context(), h.queries, &resp)
+	h.publish(r, realtime.TaskMoved, userID, resp)
+	resp.WIPLimitExceeded = overLimit
+
 	w.Header().Set("Content-Type", "application/json")
-	log.Println("[PatchTask] is updated task:", task.ID)
+	log.Println("[MoveTask] task", task.ID, "moved to column", task.ColumnID.Int32, "by user", userID)
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
@@ -182,29 +429,47 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := middleware.GetUserID(r)
+	userID, ok := authorize(w, r, h.authz, authz.DeleteTask, authz.Task(int32(boardID), int32(taskID)))
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	rows, err := h.queries.DeleteTask(r.Context(), db.DeleteTaskParams{
+	params := db.DeleteTaskParams{
 		ID:      int32(taskID),
 		BoardID: pgtype.Int4{Int32: int32(boardID), Valid: true},
-		UserID:  int32(userID),
+	}
+	err = h.queries.ExecTx(r.Context(), func(q db.Querier) error {
+		before, err := q.GetTaskForUpdate(r.Context(), db.GetTaskForUpdateParams(params))
+		if err != nil {
+			return err
+		}
+		if _, err := q.DeleteTask(r.Context(), params); err != nil {
+			return err
+		}
+		return activity.Record(r.Context(), q, activity.Event{
+			BoardID:  int32(boardID),
+			TaskID:   before.ID,
+			ActorID:  userID,
+			Entity:   activity.EntityTask,
+			EntityID: before.ID,
+			Action:   activity.Deleted,
+			Changes:  activity.Diff(activity.TaskFields(before, nil), nil),
+		})
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "cannot delete task", http.StatusInternalServerError)
+		log.Println("cannot delete task:", err)
 		return
 	}
 
-	if rows == 0 {
-		http.Error(w, "task not found or not yours", http.StatusForbidden)
-		return
-	}
+	h.publish(r, realtime.TaskDeleted, userID, dto.TaskDTO{ID: int32(taskID), BoardID: int32(boardID)})
 
 	w.Header().Set("Content-Type", "application/json")
-	log.Println("[DeleteTask] deleted task:", taskID)
+	log.Println("[DeleteTask] deleted task:", taskID, "by user", userID)
 	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
 }
 
@@ -217,65 +482,22 @@ func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// userID, ok := middleware.GetUserID(r)
-	// if !ok {
-	// 	http.Error(w, "unauthorized", http.StatusUnauthorized)
-	// 	return
-	// }
-
-	q := r.URL.Query()
-	search := q.Get("search")           // по title/description
-	status := q.Get("status")           // todo | in_progress | done | need_review
-	priority := q.Get("priority")       // low | medium | high
-	deadlineFilter := q.Get("deadline") // with | without | ""
-	sortBy := q.Get("sort_by")          // created | deadline
-	sortDir := q.Get("sort_dir")        // asc | desc
-
-	log.Printf("[GetTasks] boardID: %d, search: '%s', status: '%s', priority: '%s', deadline: '%s', sortBy: '%s', sortDir: '%s'",
-		boardID, search, status, priority, deadlineFilter, sortBy, sortDir)
-
-	// defaults
-	if sortBy == "" {
-		sortBy = "created"
-	}
-	if sortDir == "" {
-		sortDir = "desc"
+	userID, ok := authorize(w, r, h.authz, authz.ListTasks, authz.Board(int32(boardID)))
+	if !ok {
+		return
 	}
 
-	hasDeadlineSet := false
-	var hasDeadline bool
-	switch deadlineFilter {
-	case "with":
-		hasDeadlineSet = true
-		hasDeadline = true
-	case "without":
-		hasDeadlineSet = true
-		hasDeadline = false
-	default:
-		hasDeadlineSet = false
-	}
-
-	// sort code
-	var sortCode int32
-	switch {
-	case sortBy == "created" && sortDir == "asc":
-		sortCode = 1
-	case sortBy == "deadline" && sortDir == "asc":
-		sortCode = 2
-	case sortBy == "deadline" && sortDir == "desc":
-		sortCode = 3
-	default: // created desc
-		sortCode = 0
-	}
+	f := parseTaskFilter(r)
+	log.Printf("[GetTasks] boardID: %d, userID: %d, filter: %+v", boardID, userID, f)
 
 	tasks, err := h.queries.GetTasks(r.Context(), db.GetTasksParams{
 		BoardID:        pgtype.Int4{Int32: int32(boardID), Valid: true},
-		Search:         search,
-		Status:         status,
-		Priority:       priority,
-		HasDeadlineSet: hasDeadlineSet,
-		HasDeadline:    hasDeadline,
-		SortCode:       sortCode,
+		Search:         f.Search,
+		Status:         f.Status,
+		Priority:       f.Priority,
+		HasDeadlineSet: f.HasDeadlineSet,
+		HasDeadline:    f.HasDeadline,
+		SortCode:       f.SortCode,
 	})
 	if err != nil {
 		http.Error(w, "cannot fetch tasks", http.StatusInternalServerError)
@@ -296,14 +518,412 @@ func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
 			Title:       t.Title,
 			Description: t.Description.String,
 			Status:      t.Status.String,
+			ColumnID:    t.ColumnID.Int32,
+			Rank:        t.Rank,
 			Priority:    t.Priority,
 			Deadline:    dl,
+			AssigneeIDs: nonNilIDs(t.AssigneeIds),
 			CreatedAt:   t.CreatedAt.Time,
 			UpdatedAt:   t.UpdatedAt.Time,
 		})
 	}
+	attachTaskListUsers(r.Context(), h.queries, resp)
 
 	w.Header().Set("Content-Type", "application/json")
 	log.Println("[GetTasks] done")
 	_ = json.NewEncoder(w).Encode(resp)
 }
+
+// GET /tasks/assigned
+func (h *TaskHandler) GetAssignedTasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authorize(w, r, h.authz, authz.ListAssignedTasks, authz.Resource{})
+	if !ok {
+		return
+	}
+
+	f := parseTaskFilter(r)
+	log.Printf("[GetAssignedTasks] userID: %d, filter: %+v", userID, f)
+
+	tasks, err := h.queries.GetAssignedTasks(r.Context(), db.GetAssignedTasksParams{
+		UserID:         userID,
+		Search:         f.Search,
+		Status:         f.Status,
+		Priority:       f.Priority,
+		HasDeadlineSet: f.HasDeadlineSet,
+		HasDeadline:    f.HasDeadline,
+		SortCode:       f.SortCode,
+	})
+	if err != nil {
+		http.Error(w, "cannot fetch tasks", http.StatusInternalServerError)
+		log.Println("GetAssignedTasks error:", err)
+		return
+	}
+
+	resp := []dto.TaskDTO{}
+	for _, t := range tasks {
+		var dl *time.Time
+		if t.Deadline.Valid {
+			dl = &t.Deadline.Time
+		}
+		resp = append(resp, dto.TaskDTO{
+			ID:          t.ID,
+			BoardID:     t.BoardID.Int32,
+			UserID:      t.UserID,
+			Title:       t.Title,
+			Description: t.Description.String,
+			Status:      t.Status.String,
+			ColumnID:    t.ColumnID.Int32,
+			Rank:        t.Rank,
+			Priority:    t.Priority,
+			Deadline:    dl,
+			AssigneeIDs: nonNilIDs(t.AssigneeIds),
+			CreatedAt:   t.CreatedAt.Time,
+			UpdatedAt:   t.UpdatedAt.Time,
+		})
+	}
+	attachTaskListUsers(r.Context(), h.queries, resp)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// publish рассылает изменение задачи подписчикам доски. Изменение уже сохранено,
+// поэтому ошибка публикации только логируется.
+func (h *TaskHandler) publish(r *http.Request, eventType string, userID int32, task dto.TaskDTO) {
+	ev := realtime.Event{
+		Type:    eventType,
+		BoardID: task.BoardID,
+		TaskID:  task.ID,
+		ActorID: userID,
+	}
+	if eventType != realtime.TaskDeleted {
+		// предупреждение о WIP-лимите адресовано только автору запроса
+		task.WIPLimitExceeded = false
+		ev.Task = &task
+	}
+	if err := h.events.Publish(r.Context(), ev); err != nil {
+		log.Println("cannot publish task event:", err)
+	}
+}
+
+// taskFilter — параметры фильтрации и сортировки списков задач
+type taskFilter struct {
+	Search         string
+	Status         string
+	Priority       string
+	HasDeadlineSet bool
+	HasDeadline    bool
+	SortCode       int32
+}
+
+func parseTaskFilter(r *http.Request) taskFilter {
+	q := r.URL.Query()
+	f := taskFilter{
+		Search:   q.Get("search"),   // по title/description
+		Status:   q.Get("status"),   // имя колонки доски
+		Priority: q.Get("priority"), // low | medium | high
+	}
+	deadlineFilter := q.Get("deadline") // with | without | ""
+	sortBy := q.Get("sort_by")          // created | deadline | position
+	sortDir := q.Get("sort_dir")        // asc | desc
+
+	// defaults
+	if sortBy == "" {
+		sortBy = "created"
+	}
+	if sortDir == "" {
+		sortDir = "desc"
+	}
+
+	switch deadlineFilter {
+	case "with":
+		f.HasDeadlineSet = true
+		f.HasDeadline = true
+	case "without":
+		f.HasDeadlineSet = true
+		f.HasDeadline = false
+	}
+
+	// sort code
+	switch {
+	case sortBy == "position": // ручной порядок внутри колонок
+		f.SortCode = 4
+	case sortBy == "created" && sortDir == "asc":
+		f.SortCode = 1
+	case sortBy == "deadline" && sortDir == "asc":
+		f.SortCode = 2
+	case sortBy == "deadline" && sortDir == "desc":
+		f.SortCode = 3
+	default: // created desc
+		f.SortCode = 0
+	}
+	return f
+}
+
+// setAssignees заменяет исполнителей задачи; назначать можно только участников доски
+func setAssignees(ctx context.Context, q db.Querier, boardID, taskID int32, ids []int32) error {
+	if len(ids) > 0 {
+		n, err := q.CountBoardMembersByIDs(ctx, db.CountBoardMembersByIDsParams{BoardID: boardID, UserIds: ids})
+		if err != nil {
+			return err
+		}
+		if n != int64(len(ids)) {
+			return errInvalidAssignees
+		}
+	}
+	return q.SetTaskAssignees(ctx, db.SetTaskAssigneesParams{TaskID: taskID, UserIds: ids})
+}
+
+func createdTask(t db.CreateTaskRow) db.Task {
+	return db.Task{
+		ID:          t.ID,
+		UserID:      t.UserID,
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+		BoardID:     t.BoardID,
+		Priority:    t.Priority,
+		Deadline:    t.Deadline,
+		ColumnID:    t.ColumnID,
+		Rank:        t.Rank,
+	}
+}
+
+func taskDTO(t db.Task, assignees []int32) dto.TaskDTO {
+	resp := dto.TaskDTO{
+		ID:          t.ID,
+		BoardID:     t.BoardID.Int32,
+		UserID:      t.UserID,
+		Title:       t.Title,
+		Description: t.Description.String,
+		Status:      t.Status.String,
+		ColumnID:    t.ColumnID.Int32,
+		Rank:        t.Rank,
+		Priority:    t.Priority,
+		AssigneeIDs: assignees,
+		CreatedAt:   t.CreatedAt.Time,
+		UpdatedAt:   t.UpdatedAt.Time,
+	}
+	if t.Deadline.Valid {
+		resp.Deadline = &t.Deadline.Time
+	}
+	return resp
+}
+
+// attachTaskUsers подставляет имена и аватары автора и исполнителей. Без них задача
+// остаётся полной, поэтому ошибка только логируется.
+func attachTaskUsers(ctx context.Context, q db.Querier, tasks ...*dto.TaskDTO) {
+	var ids []int32
+	for _, t := range tasks {
+		ids = append(ids, t.UserID)
+		ids = append(ids, t.AssigneeIDs...)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	rows, err := q.ListUserSummaries(ctx, uniqueIDs(ids))
+	if err != nil {
+		log.Println("cannot fetch user summaries:", err)
+		return
+	}
+	users := make(map[int32]dto.UserSummaryDTO, len(rows))
+	for _, u := range rows {
+		users[u.ID] = userSummary(u)
+	}
+
+	for _, t := range tasks {
+		if u, ok := users[t.UserID]; ok {
+			t.User = &u
+		}
+		t.Assignees = []dto.UserSummaryDTO{}
+		for _, id := range t.AssigneeIDs {
+			if u, ok := users[id]; ok {
+				t.Assignees = append(t.Assignees, u)
+			}
+		}
+	}
+}
+
+func attachTaskListUsers(ctx context.Context, q db.Querier, tasks []dto.TaskDTO) {
+	ptrs := make([]*dto.TaskDTO, len(tasks))
+	for i := range tasks {
+		ptrs[i] = &tasks[i]
+	}
+	attachTaskUsers(ctx, q, ptrs...)
+}
+
+// rankSlot — место задачи в колонке: ключи соседей и, если их указал клиент, их ID.
+// Пустой lower — начало колонки, пустой upper — конец.
+type rankSlot struct {
+	lower, upper   string
+	prevID, nextID int32
+}
+
+// lastRank возвращает ключ для конца колонки
+func lastRank(ctx context.Context, q db.Querier, columnID, taskID int32) (string, error) {
+	column := pgtype.Int4{Int32: columnID, Valid: true}
+	lower, err := optionalRank(q.GetLastRank(ctx, db.GetLastRankParams{ColumnID: column, TaskID: taskID}))
+	if err != nil {
+		return "", err
+	}
+	return placeRank(ctx, q, columnID, taskID, rankSlot{lower: lower})
+}
+
+// neighbourSlot определяет место задачи по соседям. Если указан только один сосед,
+// второй берётся из колонки, чтобы задача встала к указанному вплотную.
+func neighbourSlot(ctx context.Context, q db.Querier, task db.Task, columnID int32, prevID, nextID *int32) (rankSlot, error) {
+	column := pgtype.Int4{Int32: columnID, Valid: true}
+	neighbour := func(id int32) (string, error) {
+		if id == task.ID {
+			return "", errInvalidNeighbours
+		}
+		n, err := q.GetTaskRank(ctx, db.GetTaskRankParams{ID: id, BoardID: task.BoardID})
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && n.ColumnID != column) {
+			return "", errInvalidNeighbours
+		}
+		return n.Rank, err
+	}
+
+	var (
+		slot rankSlot
+		err  error
+	)
+	if prevID != nil {
+		slot.prevID = *prevID
+		if slot.lower, err = neighbour(*prevID); err != nil {
+			return slot, err
+		}
+	}
+	if nextID != nil {
+		slot.nextID = *nextID
+		if slot.upper, err = neighbour(*nextID); err != nil {
+			return slot, err
+		}
+	}
+
+	switch {
+	case prevID != nil && nextID != nil:
+		if slot.lower > slot.upper {
+			err = errInvalidNeighbours
+		}
+	case prevID != nil:
+		slot.upper, err = optionalRank(q.GetNextRank(ctx, db.GetNextRankParams{ColumnID: column, TaskID: task.ID, Rank: slot.lower}))
+	case nextID != nil:
+		slot.lower, err = optionalRank(q.GetPrevRank(ctx, db.GetPrevRankParams{ColumnID: column, TaskID: task.ID, Rank: slot.upper}))
+	default:
+		slot.lower, err = optionalRank(q.GetLastRank(ctx, db.GetLastRankParams{ColumnID: column, TaskID: task.ID}))
+	}
+	return slot, err
+}
+
+// placeRank возвращает ключ для slot. Если промежуток между соседями исчерпан
+// (или их ключи совпали после конкурентных перемещений), ключи всей колонки
+// пересчитываются заново.
+func placeRank(ctx context.Context, q db.Querier, columnID, taskID int32, slot rankSlot) (string, error) {
+	key, err := rank.Between(slot.lower, slot.upper)
+	if !errors.Is(err, rank.ErrNoGap) {
+		return key, err
+	}
+
+	column := pgtype.Int4{Int32: columnID, Valid: true}
+	tasks, err := q.ListColumnRanks(ctx, db.ListColumnRanksParams{ColumnID: column, TaskID: taskID})
+	if err != nil {
+		return "", err
+	}
+	pos := rebalancePosition(tasks, slot)
+
+	keys := rank.Spread(len(tasks) + 1)
+	params := db.UpdateTaskRanksParams{
+		Ids:   make([]int32, 0, len(tasks)),
+		Ranks: make([]string, 0, len(tasks)),
+	}
+	for i, t := range tasks {
+		k := keys[i]
+		if i >= pos {
+			k = keys[i+1]
+		}
+		params.Ids = append(params.Ids, t.ID)
+		params.Ranks = append(params.Ranks, k)
+	}
+	if err := q.UpdateTaskRanks(ctx, params); err != nil {
+		return "", err
+	}
+	log.Println("[placeRank] rebalanced column", columnID, "with", len(tasks), "tasks")
+	return keys[pos], nil
+}
+
+// rebalancePosition — индекс задачи в упорядоченной колонке; при совпадающих ключах
+// место определяется по ID соседей, а не по ключам
+func rebalancePosition(tasks []db.ListColumnRanksRow, slot rankSlot) int {
+	for i, t := range tasks {
+		if slot.prevID != 0 && t.ID == slot.prevID {
+			return i + 1
+		}
+		if slot.prevID == 0 && slot.nextID != 0 && t.ID == slot.nextID {
+			return i
+		}
+	}
+	pos := 0
+	for pos < len(tasks) && tasks[pos].Rank <= slot.lower {
+		pos++
+	}
+	return pos
+}
+
+// optionalRank превращает отсутствие соседа в пустую границу
+func optionalRank(key string, err error) (string, error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return key, err
+}
+
+// resolveColumn находит колонку доски по ID или имени статуса; если не задано ни то ни другое —
+// первую колонку доски. Статус, которого на доске нет, даёт errUnknownColumn.
+func resolveColumn(ctx context.Context, q db.Querier, boardID int32, status *string, columnID *int32) (db.BoardColumn, error) {
+	var (
+		column db.BoardColumn
+		err    error
+	)
+	switch {
+	case columnID != nil:
+		column, err = q.GetBoardColumn(ctx, db.GetBoardColumnParams{ID: *columnID, BoardID: boardID})
+	case status != nil:
+		column, err = q.GetBoardColumnByName(ctx, db.GetBoardColumnByNameParams{BoardID: boardID, Name: *status})
+	default:
+		column, err = q.GetFirstBoardColumn(ctx, boardID)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return column, errUnknownColumn
+	}
+	if err != nil {
+		return column, err
+	}
+	// status и column_id одновременно должны указывать на одну колонку
+	if columnID != nil && status != nil && *status != column.Name {
+		return column, errUnknownColumn
+	}
+	return column, nil
+}
+
+func uniqueIDs(ids []int32) []int32 {
+	seen := make(map[int32]bool, len(ids))
+	out := []int32{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func nonNilIDs(ids []int32) []int32 {
+	if ids == nil {
+		return []int32{}
+	}
+	return ids
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+)
+
+const (
+	// defaultTokenExpiryDays — срок жизни токена, если он не указан при создании
+	defaultTokenExpiryDays = 90
+	// tokenPrefixLen — сколько первых символов токена хранится для показа в списке
+	tokenPrefixLen = 12
+)
+
+type TokenHandler struct {
+	queries db.Querier
+}
+
+func NewTokenHandler(q db.Querier) *TokenHandler {
+	return &TokenHandler{queries: q}
+}
+
+// GET /tokens
+func (h *TokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.queries.ListPersonalAccessTokens(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "cannot fetch tokens", http.StatusInternalServerError)
+		log.Println("ListPersonalAccessTokens error:", err)
+		return
+	}
+
+	resp := []dto.PersonalTokenDTO{}
+	for _, t := range tokens {
+		resp = append(resp, personalTokenDTO(t))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// POST /tokens — сам токен возвращается только в этом ответе
+func (h *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CreatePersonalTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validator.New().Struct(req); err != nil {
+		http.Error(w, "name and at least one scope are required, expiry is 1-365 days", http.StatusBadRequest)
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			http.Error(w, "unknown scope: "+scope+"; allowed: "+strings.Join(auth.Scopes, ", "), http.StatusBadRequest)
+			return
+		}
+	}
+	slices.Sort(req.Scopes)
+	req.Scopes = slices.Compact(req.Scopes)
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = defaultTokenExpiryDays
+	}
+
+	token, hash, err := auth.NewPersonalToken()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	pat, err := h.queries.CreatePersonalAccessToken(r.Context(), db.CreatePersonalAccessTokenParams{
+		UserID:        userID,
+		Name:          req.Name,
+		TokenHash:     hash,
+		TokenPrefix:   token[:tokenPrefixLen],
+		Scopes:        req.Scopes,
+		ExpiresInDays: req.ExpiresInDays,
+	})
+	if err != nil {
+		http.Error(w, "cannot create token", http.StatusInternalServerError)
+		log.Println("CreatePersonalAccessToken error:", err)
+		return
+	}
+
+	log.Println("[CreateToken] user", userID, "created token", pat.ID)
+	resp := personalTokenDTO(pat)
+	resp.Token = token
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// DELETE /tokens/{tokenID}
+func (h *TokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	tokenID, err := strconv.Atoi(chi.URLParam(r, "tokenID"))
+	if err != nil {
+		http.Error(w, "invalid token id", http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.queries.DeletePersonalAccessToken(r.Context(), db.DeletePersonalAccessTokenParams{
+		ID:     int32(tokenID),
+		UserID: userID,
+	})
+	if err != nil {
+		http.Error(w, "cannot revoke token", http.StatusInternalServerError)
+		log.Println("DeletePersonalAccessToken error:", err)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "token not found", http.StatusNotFound)
+		return
+	}
+
+	log.Println("[RevokeToken] user", userID, "revoked token", tokenID)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+func personalTokenDTO(t db.PersonalAccessToken) dto.PersonalTokenDTO {
+	resp := dto.PersonalTokenDTO{
+		ID:        t.ID,
+		Name:      t.Name,
+		Prefix:    t.TokenPrefix,
+		Scopes:    t.Scopes,
+		CreatedAt: t.CreatedAt.Time,
+	}
+	if t.ExpiresAt.Valid {
+		resp.ExpiresAt = &t.ExpiresAt.Time
+	}
+	if t.LastUsedAt.Valid {
+		resp.LastUsedAt = &t.LastUsedAt.Time
+	}
+	return resp
+}
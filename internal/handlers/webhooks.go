@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/webhook"
+)
+
+type WebhookHandler struct {
+	queries db.Store
+	authz   *authz.Authorizer
+}
+
+func NewWebhookHandler(q db.Store) *WebhookHandler {
+	return &WebhookHandler{queries: q, authz: authz.New(q)}
+}
+
+// GET /boards/{boardID}/webhooks
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageWebhooks, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	hooks, err := h.queries.ListWebhooks(r.Context(), int32(boardID))
+	if err != nil {
+		http.Error(w, "cannot fetch webhooks", http.StatusInternalServerError)
+		log.Println("ListWebhooks error:", err)
+		return
+	}
+
+	resp := []dto.WebhookDTO{}
+	for _, hook := range hooks {
+		resp = append(resp, webhookDTO(hook))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[ListWebhooks] board", boardID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// POST /boards/{boardID}/webhooks — секрет возвращается только в этом ответе
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if err := validator.New().Struct(req); err != nil || !validWebhookURL(req.URL) {
+		http.Error(w, "invalid url, secret or events", http.StatusBadRequest)
+		return
+	}
+	events, err := webhookEvents(req.Events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageWebhooks, authz.Board(int32(boardID)))
+	if !ok {
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = newWebhookSecret(); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	hook, err := h.queries.CreateWebhook(r.Context(), db.CreateWebhookParams{
+		BoardID:   int32(boardID),
+		Url:       req.URL,
+		Secret:    secret,
+		Events:    events,
+		CreatedBy: pgtype.Int4{Int32: userID, Valid: true},
+	})
+	if err != nil {
+		http.Error(w, "cannot create webhook", http.StatusInternalServerError)
+		log.Println("cannot create webhook:", err)
+		return
+	}
+
+	resp := webhookDTO(hook)
+	resp.Secret = hook.Secret
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[CreateWebhook] webhook", hook.ID, "on board", boardID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// PATCH /boards/{boardID}/webhooks/{webhookID}
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	boardID, webhookID, ok := webhookPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.URL != nil {
+		u := strings.TrimSpace(*req.URL)
+		req.URL = &u
+	}
+	if err := validator.New().Struct(req); err != nil || (req.URL != nil && !validWebhookURL(*req.URL)) {
+		http.Error(w, "invalid url, secret or events", http.StatusBadRequest)
+		return
+	}
+
+	params := db.UpdateWebhookParams{ID: webhookID, BoardID: boardID}
+	if req.Events != nil {
+		events, err := webhookEvents(req.Events)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.Events = events
+	}
+	if req.URL != nil {
+		params.Url = pgtype.Text{String: *req.URL, Valid: true}
+	}
+	if req.Secret != nil {
+		params.Secret = pgtype.Text{String: *req.Secret, Valid: true}
+	}
+	if req.Active != nil {
+		params.Active = pgtype.Bool{Bool: *req.Active, Valid: true}
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageWebhooks, authz.Board(boardID))
+	if !ok {
+		return
+	}
+
+	hook, err := h.queries.UpdateWebhook(r.Context(), params)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot update webhook", http.StatusInternalServerError)
+		log.Println("cannot update webhook:", err)
+		return
+	}
+
+	resp := webhookDTO(hook)
+	if req.Secret != nil {
+		resp.Secret = hook.Secret
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[UpdateWebhook] webhook", hook.ID, "updated by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// DELETE /boards/{boardID}/webhooks/{webhookID}
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	boardID, webhookID, ok := webhookPath(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageWebhooks, authz.Board(boardID))
+	if !ok {
+		return
+	}
+
+	rows, err := h.queries.DeleteWebhook(r.Context(), db.DeleteWebhookParams{ID: webhookID, BoardID: boardID})
+	if err != nil {
+		http.Error(w, "cannot delete webhook", http.StatusInternalServerError)
+		log.Println("cannot delete webhook:", err)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[DeleteWebhook] webhook", webhookID, "deleted by user", userID)
+	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// GET /boards/{boardID}/webhooks/{webhookID}/deliveries?limit=&offset=
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	boardID, webhookID, ok := webhookPath(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePage(r, 50, 200)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageWebhooks, authz.Board(boardID))
+	if !ok {
+		return
+	}
+
+	if _, err := h.queries.GetWebhook(r.Context(), db.GetWebhookParams{ID: webhookID, BoardID: boardID}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "webhook not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "cannot fetch webhook", http.StatusInternalServerError)
+		log.Println("GetWebhook error:", err)
+		return
+	}
+
+	deliveries, err := h.queries.ListWebhookDeliveries(r.Context(), db.ListWebhookDeliveriesParams{
+		WebhookID: webhookID,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		http.Error(w, "cannot fetch deliveries", http.StatusInternalServerError)
+		log.Println("ListWebhookDeliveries error:", err)
+		return
+	}
+
+	resp := []dto.WebhookDeliveryDTO{}
+	for _, d := range deliveries {
+		resp = append(resp, webhookDeliveryDTO(d))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[ListDeliveries] webhook", webhookID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// GET /boards/{boardID}/webhooks/{webhookID}/deliveries/{deliveryID} — доставка с журналом попыток
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	boardID, webhookID, ok := webhookPath(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.Atoi(chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		http.Error(w, "invalid delivery id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageWebhooks, authz.Board(boardID))
+	if !ok {
+		return
+	}
+
+	delivery, err := h.queries.GetWebhookDelivery(r.Context(), db.GetWebhookDeliveryParams{
+		ID:        int32(deliveryID),
+		WebhookID: webhookID,
+		BoardID:   boardID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "delivery not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot fetch delivery", http.StatusInternalServerError)
+		log.Println("GetWebhookDelivery error:", err)
+		return
+	}
+
+	attempts, err := h.queries.ListWebhookAttempts(r.Context(), delivery.ID)
+	if err != nil {
+		http.Error(w, "cannot fetch delivery attempts", http.StatusInternalServerError)
+		log.Println("ListWebhookAttempts error:", err)
+		return
+	}
+
+	resp := webhookDeliveryDTO(delivery)
+	for _, a := range attempts {
+		resp.History = append(resp.History, webhookAttemptDTO(a))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	log.Println("[GetDelivery] delivery", delivery.ID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// POST /boards/{boardID}/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	boardID, webhookID, ok := webhookPath(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.Atoi(chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		http.Error(w, "invalid delivery id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := authorize(w, r, h.authz, authz.ManageWebhooks, authz.Board(boardID))
+	if !ok {
+		return
+	}
+
+	delivery, err := h.queries.RedeliverWebhookDelivery(r.Context(), db.RedeliverWebhookDeliveryParams{
+		ID:        int32(deliveryID),
+		WebhookID: webhookID,
+		BoardID:   boardID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "delivery not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot redeliver", http.StatusInternalServerError)
+		log.Println("cannot redeliver:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	log.Println("[Redeliver] delivery", deliveryID, "queued again as", delivery.ID, "by user", userID)
+	_ = json.NewEncoder(w).Encode(webhookDeliveryDTO(delivery))
+}
+
+func webhookPath(w http.ResponseWriter, r *http.Request) (int32, int32, bool) {
+	boardID, err := strconv.Atoi(chi.URLParam(r, "boardID"))
+	if err != nil {
+		http.Error(w, "invalid board id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	webhookID, err := strconv.Atoi(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return int32(boardID), int32(webhookID), true
+}
+
+// validWebhookURL пропускает только абсолютные http(s)-адреса вне внутренней сети.
+// Имя хоста здесь не резолвится: окончательная проверка — при каждой отправке
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && webhook.AllowedHost(u.Hostname())
+}
+
+// webhookEvents проверяет типы событий и убирает повторы
+func webhookEvents(events []string) ([]string, error) {
+	resp := []string{}
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if !webhook.ValidEvent(e) {
+			return nil, fmt.Errorf("unknown event %q", e)
+		}
+		if !slices.Contains(resp, e) {
+			resp = append(resp, e)
+		}
+	}
+	return resp, nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func webhookDTO(hook db.Webhook) dto.WebhookDTO {
+	return dto.WebhookDTO{
+		ID:        hook.ID,
+		BoardID:   hook.BoardID,
+		URL:       hook.Url,
+		Events:    hook.Events,
+		Active:    hook.Active,
+		CreatedAt: hook.CreatedAt.Time,
+		UpdatedAt: hook.UpdatedAt.Time,
+	}
+}
+
+func webhookDeliveryDTO(d db.WebhookDelivery) dto.WebhookDeliveryDTO {
+	resp := dto.WebhookDeliveryDTO{
+		ID:        d.ID,
+		WebhookID: d.WebhookID,
+		Event:     d.Event,
+		Payload:   json.RawMessage(d.Payload),
+		Status:    d.Status,
+		Attempts:  d.Attempts,
+		CreatedAt: d.CreatedAt.Time,
+		UpdatedAt: d.UpdatedAt.Time,
+	}
+	if d.Status == webhook.StatusPending && d.NextAttemptAt.Valid {
+		resp.NextAttemptAt = &d.NextAttemptAt.Time
+	}
+	if d.LastStatusCode.Valid {
+		resp.LastStatusCode = &d.LastStatusCode.Int32
+	}
+	if d.LastError.Valid {
+		resp.LastError = &d.LastError.String
+	}
+	if len(resp.Payload) == 0 {
+		resp.Payload = json.RawMessage("{}")
+	}
+	return resp
+}
+
+func webhookAttemptDTO(a db.WebhookAttempt) dto.WebhookAttemptDTO {
+	resp := dto.WebhookAttemptDTO{
+		Attempt:    a.Attempt,
+		DurationMs: a.DurationMs,
+		CreatedAt:  a.CreatedAt.Time,
+	}
+	if a.StatusCode.Valid {
+		resp.StatusCode = &a.StatusCode.Int32
+	}
+	if a.Error.Valid {
+		resp.Error = &a.Error.String
+	}
+	return resp
+}
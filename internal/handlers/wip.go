@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+)
+
+// Режимы соблюдения WIP-лимитов доски
+const (
+	wipStrict = "strict"
+	wipSoft   = "soft"
+)
+
+// wipLimitError — перенос в колонку превышает её лимит на строгой доске
+type wipLimitError struct {
+	column db.BoardColumn
+	limit  int32
+	count  int64
+}
+
+func (e *wipLimitError) Error() string {
+	return fmt.Sprintf("column %q is limited to %d tasks", e.column.Name, e.limit)
+}
+
+// checkWIP проверяет, помещается ли задача taskID в колонку (0 — новая задача).
+// На строгой доске превышение даёт *wipLimitError, на мягкой — exceeded = true.
+// Колонка блокируется до конца транзакции, поэтому вызывать нужно внутри ExecTx.
+func checkWIP(ctx context.Context, q db.Querier, column db.BoardColumn, taskID int32) (bool, error) {
+	if !column.WipLimit.Valid {
+		return false, nil
+	}
+	wip, err := q.GetColumnWIP(ctx, db.GetColumnWIPParams{TaskID: taskID, ColumnID: column.ID})
+	if err != nil {
+		return false, err
+	}
+	if !wip.WipLimit.Valid || wip.TaskCount+1 <= int64(wip.WipLimit.Int32) {
+		return false, nil
+	}
+	if wip.WipMode == wipStrict {
+		return false, &wipLimitError{column: column, limit: wip.WipLimit.Int32, count: wip.TaskCount}
+	}
+	return true, nil
+}
+
+// writeWIPError отвечает структурированным 409, если err — превышение WIP-лимита
+func writeWIPError(w http.ResponseWriter, err error) bool {
+	var wipErr *wipLimitError
+	if !errors.As(err, &wipErr) {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	_ = json.NewEncoder(w).Encode(dto.WIPLimitErrorDTO{
+		Error:    "wip_limit_exceeded",
+		ColumnID: wipErr.column.ID,
+		Column:   wipErr.column.Name,
+		Limit:    wipErr.limit,
+		Count:    wipErr.count,
+	})
+	return true
+}
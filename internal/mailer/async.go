@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrQueueFull — очередь писем переполнена, письмо не принято
+var ErrQueueFull = errors.New("mail queue is full")
+
+// asyncSendTimeout — сколько ждать почтовый сервер при отправке одного письма
+const asyncSendTimeout = 30 * time.Second
+
+// Async отправляет письма в фоне. Запрос не ждёт почтовый сервер, поэтому по времени
+// ответа нельзя понять, ушло ли письмо — например, есть ли аккаунт с таким email.
+type Async struct {
+	next  Mailer
+	queue chan Message
+}
+
+// NewAsync создаёт очередь на size писем; письма отправляет Run
+func NewAsync(next Mailer, size int) *Async {
+	return &Async{next: next, queue: make(chan Message, size)}
+}
+
+// Send ставит письмо в очередь и сразу возвращается
+func (m *Async) Send(_ context.Context, msg Message) error {
+	select {
+	case m.queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run отправляет письма из очереди, пока не отменён ctx
+func (m *Async) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-m.queue:
+			sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asyncSendTimeout)
+			if err := m.next.Send(sendCtx, msg); err != nil {
+				log.Println("[Mailer] cannot send to", msg.To+":", err)
+			}
+			cancel()
+		}
+	}
+}
+
+var _ Mailer = (*Async)(nil)
@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer — способ доставки писем. В проде подставляется SMTP/API-провайдер,
+// локально достаточно LogMailer.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer пишет письма в лог или дописывает их в файл — для локальной разработки
+type LogMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogMailer создаёт LogMailer. При пустом path письма уходят в стандартный лог.
+func NewLogMailer(path string) *LogMailer {
+	return &LogMailer{path: path}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if m.path == "" {
+		log.Printf("[Mailer] to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n---\n",
+		time.Now().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+var _ Mailer = (*LogMailer)(nil)
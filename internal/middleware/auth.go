@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
@@ -10,7 +12,15 @@ import (
 
 type ctxKey string
 
-const userIDKey ctxKey = "userID"
+const (
+	userIDKey        ctxKey = "userID"
+	sessionIDKey     ctxKey = "sessionID"
+	personalTokenKey ctxKey = "personalToken"
+	claimsKey        ctxKey = "claims"
+)
+
+// AuthMiddleware принимает access-токен из /login или personal access token (ttp_…).
+// Права personal access token проверяются отдельно: RequireScope и SessionOnly.
 
 func AuthMiddleware(authSvc *auth.Service) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -21,17 +31,93 @@ func AuthMiddleware(authSvc *auth.Service) func(http.Handler) http.Handler {
 				return
 			}
 			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-			userID, err := authSvc.ValidateAccessToken(tokenStr)
+			if auth.IsPersonalToken(tokenStr) {
+				pat, err := authSvc.AuthenticatePersonalToken(r.Context(), tokenStr)
+				if errors.Is(err, auth.ErrInvalidPersonalToken) || errors.Is(err, auth.ErrPersonalTokensOff) {
+					http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+					return
+				}
+				if err != nil {
+					log.Println("cannot check personal access token:", err)
+					http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
+					return
+				}
+				ctx := context.WithValue(r.Context(), userIDKey, pat.UserID)
+				next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, personalTokenKey, pat)))
+				return
+			}
+			claims, err := authSvc.ParseAccessToken(r.Context(), tokenStr)
 			if err != nil {
 				http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
 				return
 			}
-			ctx := context.WithValue(r.Context(), userIDKey, userID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// RequireScope пропускает запрос по personal access token, только если у него есть
+// "<resource>:read" (GET, HEAD) или "<resource>:write" (остальные методы).
+// Запросы с токеном из /login не ограничиваются.
+func RequireScope(resource string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pat := GetPersonalToken(r)
+			write := r.Method != http.MethodGet && r.Method != http.MethodHead
+			if pat != nil && !pat.HasScope(resource, write) {
+				scope := resource + ":read"
+				if write {
+					scope = resource + ":write"
+				}
+				http.Error(w, `{"error":"token does not have scope `+scope+`"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// SessionOnly закрывает маршруты управления аккаунтом (сессии, 2FA, сами токены)
+// от personal access token: украденный токен не должен давать выпустить новый.
+func SessionOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetPersonalToken(r) != nil {
+			http.Error(w, `{"error":"personal access tokens cannot be used for this endpoint"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StreamAuthMiddleware — как AuthMiddleware, но токен можно передать и в ?access_token=:
+// EventSource в браузере не умеет выставлять заголовок Authorization.
+func StreamAuthMiddleware(authSvc *auth.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenStr := r.URL.Query().Get("access_token")
+			if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+				tokenStr = strings.TrimPrefix(authHeader, "Bearer ")
+			}
+			if tokenStr == "" {
+				http.Error(w, `{"error":"missing access token"}`, http.StatusUnauthorized)
+				return
+			}
+			claims, err := authSvc.ParseAccessToken(r.Context(), tokenStr)
+			if err != nil {
+				http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+func withClaims(ctx context.Context, claims *auth.Claims) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, claimsKey, claims)
+	return context.WithValue(ctx, sessionIDKey, claims.SessionID)
+}
+
 func GetUserID(r *http.Request) (int32, bool) {
 	v := r.Context().Value(userIDKey)
 	if v == nil {
@@ -40,3 +126,21 @@ func GetUserID(r *http.Request) (int32, bool) {
 	id, ok := v.(int32)
 	return id, ok
 }
+
+// GetPersonalToken возвращает personal access token запроса или nil, если вход по JWT
+func GetPersonalToken(r *http.Request) *auth.PersonalToken {
+	pat, _ := r.Context().Value(personalTokenKey).(*auth.PersonalToken)
+	return pat
+}
+
+// GetClaims возвращает claims access-токена запроса или nil, если вход по personal access token
+func GetClaims(r *http.Request) *auth.Claims {
+	claims, _ := r.Context().Value(claimsKey).(*auth.Claims)
+	return claims
+}
+
+// GetSessionID возвращает сессию, которой выдан access-токен запроса ("" для старых токенов)
+func GetSessionID(r *http.Request) string {
+	sid, _ := r.Context().Value(sessionIDKey).(string)
+	return sid
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// Имена cookie и заголовка для refresh-токена в cookie
+const (
+	RefreshCookieName = "refresh_token"
+	CSRFCookieName    = "csrf_token"
+	CSRFHeader        = "X-CSRF-Token"
+)
+
+// CSRF — защита по схеме double-submit для маршрутов, которые принимают refresh-токен из cookie.
+// Браузер приложит cookie к любому запросу, в том числе с чужого сайта, а заголовок
+// X-CSRF-Token, равный cookie csrf_token, может выставить только наш фронт.
+// Запросы без refresh-cookie (токен в теле) не проверяются: подделать их чужой сайт не может.
+func CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie(RefreshCookieName); err != nil || c.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		header := r.Header.Get(CSRFHeader)
+		c, err := r.Cookie(CSRFCookieName)
+		if err != nil || c.Value == "" || header == "" ||
+			subtle.ConstantTimeCompare([]byte(header), []byte(c.Value)) != 1 {
+			http.Error(w, `{"error":"invalid csrf token"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
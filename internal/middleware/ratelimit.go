@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sqszy/TaskTracker/internal/ratelimit"
+)
+
+// maxKeyBody — сколько тела запроса читает ByEmail в поисках email
+const maxKeyBody = 64 << 10
+
+// KeyFunc выбирает ключ лимита для запроса; "" — запрос этим лимитом не считается
+type KeyFunc func(r *http.Request) string
+
+// RateLimit отвечает 429 с Retry-After, если по ключу запроса превышен лимит.
+// При недоступности Redis запросы пропускаются: лимит не должен ронять вход.
+func RateLimit(l *ratelimit.Limiter, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := l.Allow(r.Context(), k)
+			if err != nil {
+				log.Println("rate limit check failed:", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !res.Allowed {
+				TooManyRequests(w, res.RetryAfter)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TooManyRequests отвечает 429 и сообщает, через сколько можно повторить запрос
+func TooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", ratelimit.RetryAfterSeconds(retryAfter))
+	http.Error(w, `{"error":"too many requests"}`, http.StatusTooManyRequests)
+}
+
+// ByIP — ключ по адресу соединения (RemoteAddr). X-Forwarded-For и X-Real-IP не учитываются:
+// их подставляет сам клиент. За обратным прокси все запросы придут с адреса прокси и попадут
+// в один лимит — тогда нужен middleware, принимающий эти заголовки только от доверенных прокси.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ByEmail — ключ по полю email JSON-тела; прочитанное тело остаётся доступным хендлеру
+func ByEmail(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+	// тело длиннее maxKeyBody обрезается: иначе email можно спрятать за длинным паролем,
+	// а хендлер всё равно прочитал бы его целиком
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxKeyBody))
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.ToLower(req.Email))
+}
@@ -0,0 +1,114 @@
+// Package rank реализует дробные ключи порядка (fractional indexing) для ручной
+// сортировки задач внутри колонки. Ключ — строка из цифр base36 без нулей в конце,
+// интерпретируемая как дробь 0.xxx; ключи сравниваются побайтно (COLLATE "C" в БД).
+package rank
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// MaxLen — предельная длина ключа; если ключ между соседями получается длиннее,
+// колонку нужно перебалансировать.
+const MaxLen = 32
+
+// ErrNoGap — между соседями нельзя вставить ключ допустимой длины
+// (или сами соседи некорректны): колонку нужно перебалансировать.
+var ErrNoGap = errors.New("rank: no gap between neighbours")
+
+// Between возвращает ключ строго между a и b. Пустой a означает начало колонки,
+// пустой b — её конец.
+func Between(a, b string) (string, error) {
+	if !valid(a) || !valid(b) || (b != "" && a >= b) {
+		return "", ErrNoGap
+	}
+	key := midpoint(a, b)
+	if len(key) > MaxLen {
+		return "", ErrNoGap
+	}
+	return key, nil
+}
+
+// Spread возвращает n возрастающих ключей, равномерно распределённых по всему диапазону.
+// Используется при перебалансировке: между соседними ключами остаётся запас для вставок.
+func Spread(n int) []string {
+	if n <= 0 {
+		return []string{}
+	}
+	// ширина подбирается так, чтобы между соседями было не меньше 36^2 значений
+	width, total := 2, int64(36*36)
+	for total/int64(n+1) < 36*36 && width < 12 {
+		width++
+		total *= 36
+	}
+	step := total / int64(n+1)
+
+	keys := make([]string, n)
+	for i := range keys {
+		key := strconv.FormatInt(step*int64(i+1), 36)
+		key = strings.Repeat("0", width-len(key)) + key
+		// нули в конце не меняют значение дроби, но мешают вставке перед ключом
+		keys[i] = strings.TrimRight(key, "0")
+	}
+	return keys
+}
+
+func valid(key string) bool {
+	if strings.HasSuffix(key, "0") {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// midpoint ищет кратчайший ключ между a и b; b == "" — верхняя граница 1
+func midpoint(a, b string) string {
+	if b != "" {
+		// общий префикс (a дополняется нулями) переносится в результат как есть
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(tail(a, n), b[n:])
+		}
+	}
+
+	da := 0
+	if a != "" {
+		da = strings.IndexByte(digits, a[0])
+	}
+	db := len(digits)
+	if b != "" {
+		db = strings.IndexByte(digits, b[0])
+	}
+	if db-da > 1 {
+		return string(digits[(da+db+1)/2])
+	}
+	// первые цифры соседние: либо хватает первой цифры b, либо уходим на разряд глубже
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(digits[da]) + midpoint(tail(a, 1), "")
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return '0'
+}
+
+func tail(s string, n int) string {
+	if n >= len(s) {
+		return ""
+	}
+	return s[n:]
+}
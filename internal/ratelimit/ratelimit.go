@@ -0,0 +1,189 @@
+// Package ratelimit ограничивает частоту запросов и число неудачных попыток входа.
+// Состояние хранится в Redis, поэтому лимиты общие для всех экземпляров API.
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Limit — не больше Requests запросов за любой отрезок длиной Window
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Result — итог проверки лимита
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter — через сколько освободится место в окне (0, если запрос пропущен)
+	RetryAfter time.Duration
+}
+
+// slidingWindow — скользящее окно на sorted set: score — время запроса в мс по часам Redis,
+// чтобы все экземпляры API считали окно одинаково. Отклонённые запросы в окно не попадают.
+var slidingWindow = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[3])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, limit - count - 1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, 0, tonumber(oldest[2]) + window - now}
+`)
+
+// Limiter считает запросы по произвольному ключу (IP, email) в скользящем окне
+type Limiter struct {
+	redis *redis.Client
+	name  string
+	limit Limit
+}
+
+// NewLimiter создаёт лимитер; name отделяет его ключи в Redis от других лимитеров
+func NewLimiter(r *redis.Client, name string, limit Limit) *Limiter {
+	return &Limiter{redis: r, name: name, limit: limit}
+}
+
+// Allow учитывает запрос с ключом key и сообщает, укладывается ли он в лимит
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	res, err := slidingWindow.Run(ctx, l.redis, []string{l.redisKey(key)},
+		l.limit.Window.Milliseconds(), l.limit.Requests, uuid.NewString()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    res[0] == 1,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+	}, nil
+}
+
+func (l *Limiter) redisKey(key string) string {
+	return "ratelimit:" + l.name + ":" + key
+}
+
+// LockoutPolicy — прогрессивная блокировка: после Threshold неудач подряд ключ блокируется
+// на Base, каждая следующая неудача удваивает срок, но не больше Max.
+// Счётчик неудач забывается через Memory после последней из них.
+type LockoutPolicy struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Memory    time.Duration
+}
+
+// DefaultLockoutPolicy — для входа по паролю: 5 неудач, затем 1, 2, 4… минуты, не больше часа
+var DefaultLockoutPolicy = LockoutPolicy{
+	Threshold: 5,
+	Base:      time.Minute,
+	Max:       time.Hour,
+	Memory:    24 * time.Hour,
+}
+
+// lockoutAttempt — Attempt одним скриптом: проверка блокировки, счётчик и новая блокировка
+// атомарны, иначе параллельная попытка успела бы пройти между ними. Срок считается так же,
+// как в LockoutPolicy.duration. Возвращает оставшийся срок блокировки в мс (0 — попытка допущена).
+var lockoutAttempt = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[2])
+if ttl > 0 then
+	return ttl
+end
+local n = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+local threshold = tonumber(ARGV[2])
+if n < threshold then
+	return 0
+end
+local d = tonumber(ARGV[3])
+local max = tonumber(ARGV[4])
+for i = threshold, n - 1 do
+	if d >= max then break end
+	d = d * 2
+end
+redis.call('SET', KEYS[2], n, 'PX', math.min(d, max))
+return 0
+`)
+
+// Lockout блокирует ключ (например, email аккаунта) после серии неудачных попыток
+type Lockout struct {
+	redis  *redis.Client
+	name   string
+	policy LockoutPolicy
+}
+
+func NewLockout(r *redis.Client, name string, policy LockoutPolicy) *Lockout {
+	return &Lockout{redis: r, name: name, policy: policy}
+}
+
+// Locked возвращает, сколько ещё действует блокировка ключа (0 — не заблокирован)
+func (l *Lockout) Locked(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := l.redis.PTTL(ctx, l.lockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		// -2 — ключа нет; -1 (без срока) не выставляется
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// Attempt учитывает попытку ещё до её проверки и возвращает срок блокировки, если попытку
+// делать нельзя. Счётчик растёт заранее, поэтому параллельные запросы не проверят больше
+// кодов, чем разрешено: из попыток, дошедших до порога, проходит только та, что выставила
+// блокировку, — она действует на случай, если и эта попытка окажется неверной.
+// После успешной попытки нужно вызвать Reset.
+func (l *Lockout) Attempt(ctx context.Context, key string) (time.Duration, error) {
+	ms, err := lockoutAttempt.Run(ctx, l.redis, []string{l.failuresKey(key), l.lockKey(key)},
+		l.policy.Memory.Milliseconds(), l.policy.Threshold,
+		l.policy.Base.Milliseconds(), l.policy.Max.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// Reset забывает неудачи ключа, например после успешного входа
+func (l *Lockout) Reset(ctx context.Context, key string) error {
+	return l.redis.Del(ctx, l.failuresKey(key), l.lockKey(key)).Err()
+}
+
+// duration — срок блокировки после failures неудач подряд
+func (p LockoutPolicy) duration(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+	d := p.Base
+	for i := p.Threshold; i < failures && d < p.Max; i++ {
+		d *= 2
+	}
+	return min(d, p.Max)
+}
+
+func (l *Lockout) failuresKey(key string) string {
+	return "lockout:" + l.name + ":failures:" + key
+}
+
+func (l *Lockout) lockKey(key string) string {
+	return "lockout:" + l.name + ":" + key
+}
+
+// RetryAfterSeconds округляет ожидание вверх до целых секунд для заголовка Retry-After
+func RetryAfterSeconds(d time.Duration) string {
+	s := int64((d + time.Second - 1) / time.Second)
+	if s < 1 {
+		s = 1
+	}
+	return strconv.FormatInt(s, 10)
+}
@@ -0,0 +1,164 @@
+// Package realtime рассылает события досок подписчикам всех экземпляров API.
+// Событие публикуется в Redis, каждый экземпляр слушает общий шаблон каналов
+// и раздаёт события своим локальным подписчикам (SSE-соединениям).
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sqszy/TaskTracker/internal/dto"
+)
+
+// Типы событий
+const (
+	TaskCreated = "task.created"
+	TaskUpdated = "task.updated"
+	TaskDeleted = "task.deleted"
+	TaskMoved   = "task.moved"
+)
+
+const (
+	channelPrefix  = "board:"
+	channelSuffix  = ":events"
+	channelPattern = channelPrefix + "*" + channelSuffix
+
+	// subscriberBuffer — сколько событий может ждать медленный подписчик, прежде чем новые начнут отбрасываться
+	subscriberBuffer = 32
+)
+
+// Event — событие доски. Task не заполняется для task.deleted.
+type Event struct {
+	Type    string       `json:"type"`
+	BoardID int32        `json:"board_id"`
+	TaskID  int32        `json:"task_id"`
+	ActorID int32        `json:"actor_id"`
+	Task    *dto.TaskDTO `json:"task,omitempty"`
+	At      time.Time    `json:"at"`
+}
+
+// Publisher публикует события; хендлеры зависят от него, а не от Broker
+type Publisher interface {
+	Publish(ctx context.Context, ev Event) error
+}
+
+type Broker struct {
+	rdb *redis.Client
+
+	mu   sync.Mutex
+	subs map[int32]map[*Subscription]struct{}
+}
+
+func NewBroker(rdb *redis.Client) *Broker {
+	return &Broker{rdb: rdb, subs: map[int32]map[*Subscription]struct{}{}}
+}
+
+func channel(boardID int32) string {
+	return fmt.Sprintf("%s%d%s", channelPrefix, boardID, channelSuffix)
+}
+
+// Publish отправляет событие в Redis; до локальных подписчиков оно доходит через Start
+func (b *Broker) Publish(ctx context.Context, ev Event) error {
+	if ev.At.IsZero() {
+		ev.At = time.Now().UTC()
+	}
+	raw, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return b.rdb.Publish(ctx, channel(ev.BoardID), raw).Err()
+}
+
+// Start подписывается на каналы всех досок и раздаёт события, пока не отменён ctx.
+// Возвращается после подтверждения подписки, чтобы ни одно событие не потерялось.
+func (b *Broker) Start(ctx context.Context) error {
+	ps := b.rdb.PSubscribe(ctx, channelPattern)
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return err
+	}
+
+	go func() {
+		defer ps.Close()
+		// Channel сам переподключается к Redis после обрыва
+		messages := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				b.dispatch(msg)
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *Broker) dispatch(msg *redis.Message) {
+	boardID, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(msg.Channel, channelPrefix), channelSuffix))
+	if err != nil {
+		return
+	}
+	var ev Event
+	if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+		log.Println("[realtime] bad event payload:", err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs[int32(boardID)] {
+		select {
+		case sub.ch <- ev:
+		default:
+			log.Println("[realtime] subscriber of board", boardID, "is too slow, event dropped")
+		}
+	}
+}
+
+// Subscription — локальная подписка на события одной доски
+type Subscription struct {
+	C <-chan Event
+
+	ch      chan Event
+	boardID int32
+	broker  *Broker
+}
+
+func (b *Broker) Subscribe(boardID int32) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, boardID: boardID, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[boardID] == nil {
+		b.subs[boardID] = map[*Subscription]struct{}{}
+	}
+	b.subs[boardID][sub] = struct{}{}
+	return sub
+}
+
+// Close отписывает и закрывает C; повторный вызов безопасен
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s.boardID][s]; !ok {
+		return
+	}
+	delete(b.subs[s.boardID], s)
+	if len(b.subs[s.boardID]) == 0 {
+		delete(b.subs, s.boardID)
+	}
+	close(s.ch)
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress — адрес получателя во внутренней сети. Вебхук задаёт владелец доски,
+// и без этой проверки сервер ходил бы от своего имени к localhost, метаданным облака и т.п. (SSRF)
+var ErrForbiddenAddress = errors.New("webhook destination address is not allowed")
+
+// forbiddenPrefixes — сети, которые не покрывают методы netip.Addr
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // стенды для тестов производительности
+	netip.MustParsePrefix("240.0.0.0/4"),   // резерв и broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64 — им можно дотянуться до IPv4 внутри сети
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// PublicAddr сообщает, можно ли отправлять вебхук на этот адрес
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, p := range forbiddenPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowedHost — быстрая проверка при создании вебхука: отсекает localhost и внутренние IP,
+// записанные прямо в URL. Имена резолвятся только при отправке, см. NewClient.
+func AllowedHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return PublicAddr(ip)
+	}
+	return true
+}
+
+// NewClient — HTTP-клиент воркера. Адрес проверяется в Control, то есть уже после DNS,
+// непосредственно перед соединением: так подмена DNS-ответа между проверкой и запросом
+// (DNS rebinding) не помогает. Редиректы не выполняются — иначе внешний адрес мог бы
+// перенаправить запрос внутрь; ответ 3xx записывается как неуспешная попытка.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil || !PublicAddr(ap.Addr()) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// прокси из окружения не используется: через него проверка адреса потеряла бы смысл
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   timeout,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,261 @@
+// Package webhook доставляет события досок на внешние URL.
+// События ставятся в очередь (webhook_deliveries) в транзакции изменения,
+// Worker забирает созревшие доставки, отправляет подписанный POST и при
+// неудаче планирует повтор с экспоненциальной задержкой.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sqszy/TaskTracker/internal/db"
+)
+
+// События, на которые можно подписаться
+var Events = []string{
+	"task.created",
+	"task.updated",
+	"task.deleted",
+	"board.updated",
+	"comment.created",
+	"comment.updated",
+	"comment.deleted",
+}
+
+// Заголовки запроса доставки
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Статусы доставки
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	defaultBatchSize   = 20
+	defaultMaxAttempts = 8
+	defaultBaseDelay   = 30 * time.Second
+	defaultMaxDelay    = time.Hour
+	defaultTimeout     = 10 * time.Second
+
+	// lease — на сколько доставка скрывается от других воркеров, пока идёт отправка.
+	// Доставки забираются по одной, поэтому lease должна покрывать только одну отправку
+	// (defaultTimeout) и запись результата, а не всю пачку
+	lease = time.Minute
+	// maxErrorLen — сколько текста ошибки сохраняется в журнале попыток
+	maxErrorLen = 500
+)
+
+// ValidEvent сообщает, можно ли подписаться на событие
+func ValidEvent(event string) bool {
+	return slices.Contains(Events, event)
+}
+
+// Sign возвращает подпись "sha256=<hex>" — HMAC-SHA256 от "<timestamp>.<body>".
+// Метка времени входит в подпись, чтобы получатель мог отбрасывать повторы старых запросов.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись за постоянное время — для получателей и тестов
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+type Worker struct {
+	store       db.Store
+	client      *http.Client
+	batchSize   int32
+	maxAttempts int32
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	now         func() time.Time
+}
+
+func NewWorker(store db.Store) *Worker {
+	return &Worker{
+		store:       store,
+		client:      NewClient(defaultTimeout),
+		batchSize:   defaultBatchSize,
+		maxAttempts: defaultMaxAttempts,
+		baseDelay:   defaultBaseDelay,
+		maxDelay:    defaultMaxDelay,
+		now:         time.Now,
+	}
+}
+
+// WithClient подменяет HTTP-клиент (для тестов)
+func (w *Worker) WithClient(c *http.Client) *Worker {
+	w.client = c
+	return w
+}
+
+// WithRetries задаёт число попыток и задержки между ними
+func (w *Worker) WithRetries(maxAttempts int32, base, maxDelay time.Duration) *Worker {
+	w.maxAttempts = maxAttempts
+	w.baseDelay = base
+	w.maxDelay = maxDelay
+	return w
+}
+
+// Backoff — задержка перед попыткой attempt+1: base, 2·base, 4·base… но не больше max
+func (w *Worker) Backoff(attempt int32) time.Duration {
+	d := w.baseDelay
+	for i := int32(1); i < attempt; i++ {
+		d *= 2
+		if d >= w.maxDelay {
+			return w.maxDelay
+		}
+	}
+	return d
+}
+
+// Run обрабатывает очередь каждые interval, пока не отменён ctx.
+// Если пачка заполнена целиком, следующая забирается сразу.
+func (w *Worker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := w.ProcessDue(ctx)
+		if err != nil {
+			log.Println("[webhook] process deliveries:", err)
+		}
+		if err == nil && n == int(w.batchSize) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue отправляет до batchSize созревших доставок и возвращает их число.
+// Каждая доставка забирается отдельно непосредственно перед отправкой: при общей аренде
+// на пачку хвост пачки мог дождаться конца lease и уйти второму воркеру.
+func (w *Worker) ProcessDue(ctx context.Context) (int, error) {
+	for n := 0; n < int(w.batchSize); n++ {
+		due, err := w.store.ClaimDueWebhookDeliveries(ctx, db.ClaimDueWebhookDeliveriesParams{
+			LeaseSeconds: int32(lease / time.Second),
+			BatchSize:    1,
+		})
+		if err != nil {
+			return n, err
+		}
+		if len(due) == 0 {
+			return n, nil
+		}
+		if err := w.deliver(ctx, due[0]); err != nil {
+			return n, err
+		}
+	}
+	return int(w.batchSize), nil
+}
+
+func (w *Worker) deliver(ctx context.Context, d db.ClaimDueWebhookDeliveriesRow) error {
+	attempt := d.Attempts + 1
+	start := w.now()
+	code, sendErr := w.send(ctx, d, start)
+	duration := w.now().Sub(start)
+
+	var statusCode pgtype.Int4
+	if code != 0 {
+		statusCode = pgtype.Int4{Int32: int32(code), Valid: true}
+	}
+	var lastError pgtype.Text
+	if errors.Is(sendErr, ErrForbiddenAddress) {
+		// в журнал не попадает, во что резолвится имя: владелец вебхука видит журнал
+		sendErr = ErrForbiddenAddress
+	}
+	if sendErr != nil {
+		msg := sendErr.Error()
+		if len(msg) > maxErrorLen {
+			msg = msg[:maxErrorLen]
+		}
+		lastError = pgtype.Text{String: msg, Valid: true}
+	}
+
+	result := db.UpdateWebhookDeliveryResultParams{
+		Status:         StatusSucceeded,
+		Attempts:       attempt,
+		LastStatusCode: statusCode,
+		LastError:      lastError,
+		ID:             d.ID,
+	}
+	switch {
+	case sendErr == nil:
+	case attempt >= w.maxAttempts:
+		result.Status = StatusFailed
+	default:
+		result.Status = StatusPending
+		result.RetryIn = int32(w.Backoff(attempt) / time.Second)
+	}
+
+	return w.store.ExecTx(ctx, func(q db.Querier) error {
+		if err := q.CreateWebhookAttempt(ctx, db.CreateWebhookAttemptParams{
+			DeliveryID: d.ID,
+			Attempt:    attempt,
+			StatusCode: statusCode,
+			Error:      lastError,
+			DurationMs: int32(duration / time.Millisecond),
+		}); err != nil {
+			return err
+		}
+		return q.UpdateWebhookDeliveryResult(ctx, result)
+	})
+}
+
+// send возвращает код ответа (0, если ответа не было) и ошибку для любого исхода, кроме 2xx
+func (w *Worker) send(ctx context.Context, d db.ClaimDueWebhookDeliveriesRow, at time.Time) (int, error) {
+	// не зависит от таймаута клиента из WithClient: отправка обязана уложиться в lease
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	ts := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaskTracker-Webhooks/1")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(int(d.ID)))
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(d.Secret, ts, d.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// тело читается, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sqszy/TaskTracker/internal/activity"
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+func TestActivityDiff(t *testing.T) {
+	deadline := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	before := activity.TaskFields(db.Task{
+		Title:    "Task",
+		Status:   pgtype.Text{String: "in_progress", Valid: true},
+		Priority: "medium",
+		Deadline: pgtype.Timestamp{Time: deadline, Valid: true},
+	}, []int32{1})
+	after := activity.TaskFields(db.Task{
+		Title:    "Task",
+		Status:   pgtype.Text{String: "done", Valid: true},
+		Priority: "medium",
+		Deadline: pgtype.Timestamp{Time: deadline, Valid: true},
+	}, []int32{1})
+
+	changes := activity.Diff(before, after)
+	require.Equal(t, map[string]activity.Change{
+		"status": {Before: "in_progress", After: "done"},
+	}, changes)
+
+	created := activity.Diff(nil, activity.Fields{"name": "b"})
+	require.Equal(t, activity.Change{Before: nil, After: "b"}, created["name"])
+
+	deleted := activity.Diff(activity.Fields{"name": "b"}, nil)
+	require.Equal(t, activity.Change{Before: "b", After: nil}, deleted["name"])
+}
+
+func TestTaskHistory(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	authSvc := appauth.NewService(rdb, "acc", "ref", time.Minute, time.Hour)
+	tp, err := authSvc.GenerateTokenPair(context.Background(), 3)
+	require.NoError(t, err)
+
+	// задача 2 уже удалена: GetTaskMemberRole не настроен, права проверяются по доске
+	m := new(mocks.MockQuerier)
+	m.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 1, UserID: 3}).
+		Return(authz.RoleViewer, nil)
+	m.On("ListTaskActivity", mock.Anything, db.ListTaskActivityParams{
+		BoardID: 1,
+		TaskID:  pgtype.Int4{Int32: 2, Valid: true},
+		Limit:   50,
+	}).Return([]db.ActivityEvent{
+		{
+			ID:       8,
+			BoardID:  1,
+			TaskID:   pgtype.Int4{Int32: 2, Valid: true},
+			ActorID:  pgtype.Int4{Int32: 4, Valid: true},
+			Entity:   activity.EntityTask,
+			EntityID: 2,
+			Action:   activity.Deleted,
+			Changes:  []byte(`{"status":{"before":"done","after":null}}`),
+		},
+		{ID: 7, BoardID: 1, TaskID: pgtype.Int4{Int32: 2, Valid: true}, Entity: activity.EntityComment, EntityID: 5, Action: activity.Created},
+	}, nil)
+
+	h := handlers.NewActivityHandler(m)
+	r := chi.NewRouter()
+	r.Use(middleware.AuthMiddleware(authSvc))
+	r.Get("/boards/{boardID}/tasks/{taskID}/history", h.TaskHistory)
+
+	req := httptest.NewRequest("GET", "/boards/1/tasks/2/history", nil)
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp []dto.ActivityEventDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 2)
+	require.Equal(t, int32(4), *resp[0].ActorID)
+	require.Equal(t, activity.Deleted, resp[0].Action)
+	require.JSONEq(t, `{"status":{"before":"done","after":null}}`, string(resp[0].Changes))
+
+	// события комментариев входят в историю задачи
+	require.Equal(t, activity.EntityComment, resp[1].Entity)
+	require.JSONEq(t, `{}`, string(resp[1].Changes))
+
+	m.AssertExpectations(t)
+}
@@ -7,15 +7,18 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
-	"golang.org/x/crypto/bcrypt"
 
 	appauth "github.com/sqszy/TaskTracker/internal/auth"
 	"github.com/sqszy/TaskTracker/internal/db"
@@ -30,6 +33,7 @@ type AuthTestSuite struct {
 	redis   *miniredis.Miniredis
 	rdb     *redis.Client
 	authSvc *appauth.Service
+	mailer  *mocks.MockMailer
 	handler *handlers.AuthHandler
 	ctx     context.Context
 }
@@ -42,7 +46,8 @@ func (s *AuthTestSuite) SetupTest() {
 
 	s.authSvc = appauth.NewService(s.rdb, "access-secret", "refresh-secret", time.Minute, time.Hour*24)
 	s.mockQ = new(mocks.MockQuerier)
-	s.handler = handlers.NewAuthHandler(s.mockQ, s.authSvc)
+	s.mailer = new(mocks.MockMailer)
+	s.handler = handlers.NewAuthHandler(s.mockQ, s.authSvc, s.mailer, "http://app.local/").WithPasswordHasher(testHasher)
 }
 
 func (s *AuthTestSuite) TearDownTest() {
@@ -55,7 +60,7 @@ func (s *AuthTestSuite) TearDownTest() {
 }
 
 func (s *AuthTestSuite) TestSignupSuccess() {
-	reqBody := dto.SignupRequest{Email: "user@example.com", Password: "123456"}
+	reqBody := dto.SignupRequest{Email: "user@example.com", Password: "tangerine-Kettle-42"}
 	b, _ := json.Marshal(reqBody)
 
 	createdRow := db.CreateUserRow{
@@ -77,12 +82,18 @@ func (s *AuthTestSuite) TestSignupSuccess() {
 	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
 	require.Equal(s.T(), int32(7), got.ID)
 	require.Equal(s.T(), "user@example.com", got.Email)
+	require.False(s.T(), got.EmailVerified)
 
+	require.Equal(s.T(), "user@example.com", s.mailer.Last().To)
+	require.Contains(s.T(), s.mailer.Last().Body, "http://app.local/verify-email?token=")
+
+	// приглашения ждут подтверждения email
+	s.mockQ.AssertNotCalled(s.T(), "AcceptPendingInvitations", mock.Anything, mock.Anything)
 	s.mockQ.AssertExpectations(s.T())
 }
 
 func (s *AuthTestSuite) TestSignupConflict() {
-	reqBody := dto.SignupRequest{Email: "exists@example.com", Password: "123456"}
+	reqBody := dto.SignupRequest{Email: "exists@example.com", Password: "tangerine-Kettle-42"}
 	b, _ := json.Marshal(reqBody)
 
 	s.mockQ.On("CreateUser", mock.Anything, mock.Anything).Return(db.CreateUserRow{}, errors.New("unique_violation"))
@@ -98,12 +109,10 @@ func (s *AuthTestSuite) TestSignupConflict() {
 
 func (s *AuthTestSuite) TestLoginSuccess() {
 	pass := "mypassword"
-	hash, _ := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
-
 	userRow := db.GetUserByEmailRow{
 		ID:       11,
 		Email:    "me@ex.com",
-		Password: string(hash),
+		Password: hashPassword(s.T(), pass),
 	}
 
 	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").Return(userRow, nil)
@@ -127,12 +136,10 @@ func (s *AuthTestSuite) TestLoginSuccess() {
 
 func (s *AuthTestSuite) TestLoginWrongPassword() {
 	pass := "rightpass"
-	hash, _ := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
-
 	userRow := db.GetUserByEmailRow{
 		ID:       12,
 		Email:    "bob@ex.com",
-		Password: string(hash),
+		Password: hashPassword(s.T(), pass),
 	}
 
 	s.mockQ.On("GetUserByEmail", mock.Anything, "bob@ex.com").Return(userRow, nil)
@@ -176,6 +183,179 @@ func (s *AuthTestSuite) TestRefreshAndLogout() {
 	require.True(s.T(), res["success"])
 }
 
+// mailedToken достаёт токен из ссылки в последнем письме
+func (s *AuthTestSuite) mailedToken() string {
+	body := s.mailer.Last().Body
+	i := strings.Index(body, "token=")
+	require.NotEqual(s.T(), -1, i, body)
+	token, err := url.QueryUnescape(strings.Fields(body[i+len("token="):])[0])
+	require.NoError(s.T(), err)
+	return token
+}
+
+func (s *AuthTestSuite) post(handler http.HandlerFunc, path string, body interface{}) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", path, bytes.NewReader(b))
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func (s *AuthTestSuite) TestForgotPasswordSendsResetLink() {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "me@ex.com"}, nil)
+
+	w := s.post(s.handler.ForgotPassword, "/password/forgot", dto.ForgotPasswordRequest{Email: " Me@Ex.com "})
+	require.Equal(s.T(), http.StatusAccepted, w.Code)
+
+	require.Len(s.T(), s.mailer.Sent, 1)
+	require.Equal(s.T(), "me@ex.com", s.mailer.Last().To)
+	require.Contains(s.T(), s.mailer.Last().Body, "http://app.local/reset-password?token=")
+	require.NotEmpty(s.T(), s.mailedToken())
+}
+
+func (s *AuthTestSuite) TestForgotPasswordUnknownEmail() {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "ghost@ex.com").
+		Return(db.GetUserByEmailRow{}, pgx.ErrNoRows)
+
+	w := s.post(s.handler.ForgotPassword, "/password/forgot", dto.ForgotPasswordRequest{Email: "ghost@ex.com"})
+	require.Equal(s.T(), http.StatusAccepted, w.Code)
+	require.Empty(s.T(), s.mailer.Sent)
+}
+
+func (s *AuthTestSuite) TestResetPasswordRevokesSessions() {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "me@ex.com"}, nil)
+	s.mockQ.On("GetUserByID", mock.Anything, int32(11)).Return(db.GetUserByIDRow{ID: 11, Email: "me@ex.com"}, nil)
+	s.mockQ.On("UpdateUserPassword", mock.Anything, mock.MatchedBy(func(p db.UpdateUserPasswordParams) bool {
+		ok, _, err := testHasher.Verify(p.Password, "tangerine-Kettle-42")
+		return p.ID == 11 && ok && err == nil
+	})).Return(nil).Once()
+
+	tp1, err := s.authSvc.GenerateTokenPair(s.ctx, 11)
+	require.NoError(s.T(), err)
+	tp2, err := s.authSvc.GenerateTokenPair(s.ctx, 11)
+	require.NoError(s.T(), err)
+	other, err := s.authSvc.GenerateTokenPair(s.ctx, 12)
+	require.NoError(s.T(), err)
+
+	s.post(s.handler.ForgotPassword, "/password/forgot", dto.ForgotPasswordRequest{Email: "me@ex.com"})
+	token := s.mailedToken()
+
+	// слишком короткий пароль не сжигает токен
+	w := s.post(s.handler.ResetPassword, "/password/reset", dto.ResetPasswordRequest{Token: token, Password: "123"})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	w = s.post(s.handler.ResetPassword, "/password/reset", dto.ResetPasswordRequest{Token: token, Password: "tangerine-Kettle-42"})
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+
+	_, err = s.authSvc.Refresh(s.ctx, tp1.RefreshToken)
+	require.Error(s.T(), err)
+	_, err = s.authSvc.Refresh(s.ctx, tp2.RefreshToken)
+	require.Error(s.T(), err)
+	_, err = s.authSvc.Refresh(s.ctx, other.RefreshToken)
+	require.NoError(s.T(), err)
+
+	// токен одноразовый
+	w = s.post(s.handler.ResetPassword, "/password/reset", dto.ResetPasswordRequest{Token: token, Password: "another-password"})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *AuthTestSuite) TestResetTokenReplacedByNewRequest() {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "me@ex.com"}, nil)
+
+	s.post(s.handler.ForgotPassword, "/password/forgot", dto.ForgotPasswordRequest{Email: "me@ex.com"})
+	first := s.mailedToken()
+
+	// повторный запрос в паузе отвечает так же, но письма не шлёт и ссылку не гасит
+	w := s.post(s.handler.ForgotPassword, "/password/forgot", dto.ForgotPasswordRequest{Email: "me@ex.com"})
+	require.Equal(s.T(), http.StatusAccepted, w.Code)
+	require.Len(s.T(), s.mailer.Sent, 1)
+	require.Equal(s.T(), first, s.mailedToken())
+
+	s.redis.FastForward(appauth.PasswordResetCooldown)
+	s.post(s.handler.ForgotPassword, "/password/forgot", dto.ForgotPasswordRequest{Email: "me@ex.com"})
+	require.Len(s.T(), s.mailer.Sent, 2)
+	second := s.mailedToken()
+	require.NotEqual(s.T(), first, second)
+
+	_, err := s.authSvc.ConsumePasswordReset(s.ctx, first)
+	require.ErrorIs(s.T(), err, appauth.ErrInvalidResetToken)
+
+	s.redis.FastForward(appauth.PasswordResetTTL + time.Second)
+	_, err = s.authSvc.ConsumePasswordReset(s.ctx, second)
+	require.ErrorIs(s.T(), err, appauth.ErrInvalidResetToken)
+}
+
+func (s *AuthTestSuite) TestVerifyEmail() {
+	token, _, err := s.authSvc.IssueEmailVerification(s.ctx, 11)
+	require.NoError(s.T(), err)
+	s.mockQ.On("MarkEmailVerified", mock.Anything, int32(11)).Return(int64(1), nil).Once()
+	s.mockQ.On("GetUserByID", mock.Anything, int32(11)).Return(db.GetUserByIDRow{ID: 11, Email: "new@ex.com"}, nil).Once()
+	s.mockQ.On("AcceptPendingInvitations", mock.Anything, db.AcceptPendingInvitationsParams{
+		Email:  "new@ex.com",
+		UserID: 11,
+	}).Return([]db.BoardMember{{BoardID: 3, UserID: 11, Role: "editor"}}, nil).Once()
+
+	w := s.post(s.handler.VerifyEmail, "/email/verify", dto.VerifyEmailRequest{Token: token})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	w = s.post(s.handler.VerifyEmail, "/email/verify", dto.VerifyEmailRequest{Token: token})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *AuthTestSuite) TestResendVerificationCooldown() {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "new@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "new@ex.com"}, nil)
+	s.mockQ.On("GetUserByEmail", mock.Anything, "done@ex.com").
+		Return(db.GetUserByEmailRow{ID: 12, Email: "done@ex.com", EmailVerifiedAt: pgtype.Timestamp{Time: time.Now(), Valid: true}}, nil)
+
+	w := s.post(s.handler.ResendVerification, "/email/verify/resend", dto.ResendVerificationRequest{Email: "new@ex.com"})
+	require.Equal(s.T(), http.StatusAccepted, w.Code)
+	require.Len(s.T(), s.mailer.Sent, 1)
+
+	// повтор в паузе не отправляет письмо, но ответ тот же, что для чужого или подтверждённого email
+	w = s.post(s.handler.ResendVerification, "/email/verify/resend", dto.ResendVerificationRequest{Email: "new@ex.com"})
+	require.Equal(s.T(), http.StatusAccepted, w.Code)
+	require.Empty(s.T(), w.Header().Get("Retry-After"))
+	require.Len(s.T(), s.mailer.Sent, 1)
+
+	s.redis.FastForward(appauth.VerificationCooldown)
+	w = s.post(s.handler.ResendVerification, "/email/verify/resend", dto.ResendVerificationRequest{Email: "new@ex.com"})
+	require.Equal(s.T(), http.StatusAccepted, w.Code)
+	require.Len(s.T(), s.mailer.Sent, 2)
+
+	// подтверждённому аккаунту письмо не отправляется
+	w = s.post(s.handler.ResendVerification, "/email/verify/resend", dto.ResendVerificationRequest{Email: "done@ex.com"})
+	require.Equal(s.T(), http.StatusAccepted, w.Code)
+	require.Len(s.T(), s.mailer.Sent, 2)
+}
+
+func (s *AuthTestSuite) TestLoginRequiresVerifiedEmail() {
+	hash := hashPassword(s.T(), "mypassword")
+	s.mockQ.On("GetUserByEmail", mock.Anything, "new@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "new@ex.com", Password: hash}, nil)
+	s.mockQ.On("GetUserByEmail", mock.Anything, "done@ex.com").
+		Return(db.GetUserByEmailRow{
+			ID: 12, Email: "done@ex.com", Password: hash,
+			EmailVerifiedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		}, nil)
+
+	// без настройки неподтверждённый аккаунт входит как раньше
+	w := s.post(s.handler.Login, "/login", dto.LoginRequest{Email: "new@ex.com", Password: "mypassword"})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	s.handler.RequireVerifiedEmail(true)
+	w = s.post(s.handler.Login, "/login", dto.LoginRequest{Email: "new@ex.com", Password: "mypassword"})
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+
+	w = s.post(s.handler.Login, "/login", dto.LoginRequest{Email: "done@ex.com", Password: "mypassword"})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+}
+
 func TestAuthSuite(t *testing.T) {
 	suite.Run(t, new(AuthTestSuite))
 }
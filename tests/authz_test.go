@@ -0,0 +1,323 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+// roleNone — пользователь не состоит в доске
+const roleNone = ""
+
+var errStub = errors.New("stub")
+
+func TestAuthzCan(t *testing.T) {
+	cases := []struct {
+		action  authz.Action
+		res     authz.Resource
+		role    string
+		wantErr error
+	}{
+		{authz.ListBoards, authz.Resource{}, roleNone, nil},
+		{authz.CreateBoard, authz.Resource{}, roleNone, nil},
+
+		{authz.ViewBoard, authz.Board(1), roleNone, authz.ErrNotFound},
+		{authz.ViewBoard, authz.Board(1), authz.RoleViewer, nil},
+		{authz.UpdateBoard, authz.Board(1), authz.RoleEditor, authz.ErrForbidden},
+		{authz.UpdateBoard, authz.Board(1), authz.RoleOwner, nil},
+		{authz.DeleteBoard, authz.Board(1), authz.RoleEditor, authz.ErrForbidden},
+		{authz.DeleteBoard, authz.Board(1), authz.RoleOwner, nil},
+		{authz.ManageMembers, authz.Board(1), authz.RoleEditor, authz.ErrForbidden},
+		{authz.ManageMembers, authz.Board(1), authz.RoleOwner, nil},
+		{authz.ManageWebhooks, authz.Board(1), authz.RoleEditor, authz.ErrForbidden},
+		{authz.ManageWebhooks, authz.Board(1), authz.RoleOwner, nil},
+		{authz.LeaveBoard, authz.Board(1), authz.RoleViewer, nil},
+
+		{authz.ListTasks, authz.Board(1), roleNone, authz.ErrNotFound},
+		{authz.ListTasks, authz.Board(1), authz.RoleViewer, nil},
+		{authz.CreateTask, authz.Board(1), authz.RoleViewer, authz.ErrForbidden},
+		{authz.CreateTask, authz.Board(1), authz.RoleEditor, nil},
+		{authz.UpdateTask, authz.Task(1, 2), roleNone, authz.ErrNotFound},
+		{authz.UpdateTask, authz.Task(1, 2), authz.RoleViewer, authz.ErrForbidden},
+		{authz.UpdateTask, authz.Task(1, 2), authz.RoleEditor, nil},
+		{authz.DeleteTask, authz.Task(1, 2), authz.RoleViewer, authz.ErrForbidden},
+		{authz.DeleteTask, authz.Task(1, 2), authz.RoleOwner, nil},
+
+		{authz.Action("unknown"), authz.Board(1), authz.RoleOwner, authz.ErrForbidden},
+	}
+
+	for _, tc := range cases {
+		name := fmt.Sprintf("%s/%s", tc.action, roleName(tc.role))
+		t.Run(name, func(t *testing.T) {
+			m := new(mocks.MockQuerier)
+			stubRole(m, tc.role)
+
+			err := authz.New(m).Can(context.Background(), 3, tc.action, tc.res)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthzUnauthenticated(t *testing.T) {
+	err := authz.New(new(mocks.MockQuerier)).Can(context.Background(), 0, authz.ListBoards, authz.Resource{})
+	require.ErrorIs(t, err, authz.ErrUnauthenticated)
+}
+
+// TestRoutesEnforceAccess проходит по всем защищённым маршрутам и проверяет,
+// что каждый хендлер пропускает только роли, разрешённые политикой.
+func TestRoutesEnforceAccess(t *testing.T) {
+	routes := []struct {
+		method  string
+		path    string
+		body    string
+		minRole string // roleNone — маршрут доступен любому авторизованному
+	}{
+		{"GET", "/GetBoards", "", roleNone},
+		{"POST", "/CreateBoard", `{"name":"b"}`, roleNone},
+		{"PATCH", "/boards/1", `{"name":"b"}`, authz.RoleOwner},
+		{"DELETE", "/boards/1", "", authz.RoleOwner},
+
+		{"GET", "/boards/1/members", "", authz.RoleViewer},
+		{"POST", "/boards/1/members", `{"email":"a@ex.com","role":"viewer"}`, authz.RoleOwner},
+		{"PATCH", "/boards/1/members/9", `{"role":"viewer"}`, authz.RoleOwner},
+		{"DELETE", "/boards/1/members/9", "", authz.RoleOwner},
+
+		{"GET", "/boards/1/columns", "", authz.RoleViewer},
+		{"POST", "/boards/1/columns", `{"name":"qa"}`, authz.RoleOwner},
+		{"PUT", "/boards/1/columns/order", `{"column_ids":[1]}`, authz.RoleOwner},
+		{"PATCH", "/boards/1/columns/6", `{"name":"qa"}`, authz.RoleOwner},
+		{"DELETE", "/boards/1/columns/6", "", authz.RoleOwner},
+
+		{"GET", "/boards/1/webhooks", "", authz.RoleOwner},
+		{"POST", "/boards/1/webhooks", `{"url":"https://ci.ex.com/hook","events":["task.created"]}`, authz.RoleOwner},
+		{"PATCH", "/boards/1/webhooks/7", `{"active":false}`, authz.RoleOwner},
+		{"DELETE", "/boards/1/webhooks/7", "", authz.RoleOwner},
+		{"GET", "/boards/1/webhooks/7/deliveries", "", authz.RoleOwner},
+		{"GET", "/boards/1/webhooks/7/deliveries/8", "", authz.RoleOwner},
+		{"POST", "/boards/1/webhooks/7/deliveries/8/redeliver", "", authz.RoleOwner},
+
+		{"GET", "/boards/1/invitations", "", authz.RoleOwner},
+		{"POST", "/boards/1/invitations", `{"email":"a@ex.com","role":"viewer"}`, authz.RoleOwner},
+		{"DELETE", "/boards/1/invitations/4", "", authz.RoleOwner},
+		{"POST", "/invitations/tok/accept", "", roleNone},
+		{"POST", "/invitations/tok/decline", "", roleNone},
+
+		{"GET", "/boards/1/GetTasks", "", authz.RoleViewer},
+		{"GET", "/tasks/assigned", "", roleNone},
+		{"POST", "/boards/1/CreateTask", `{"title":"t"}`, authz.RoleEditor},
+		{"PATCH", "/boards/1/tasks/2", `{"title":"t"}`, authz.RoleEditor},
+		{"DELETE", "/boards/1/tasks/2", "", authz.RoleEditor},
+		{"POST", "/boards/1/tasks/2/move", `{"column_id":1}`, authz.RoleEditor},
+
+		{"GET", "/boards/1/activity", "", authz.RoleViewer},
+		{"GET", "/boards/1/tasks/2/history", "", authz.RoleViewer},
+
+		{"GET", "/boards/1/tasks/2/comments", "", authz.RoleViewer},
+		{"POST", "/boards/1/tasks/2/comments", `{"body":"hi"}`, authz.RoleEditor},
+		{"PATCH", "/boards/1/tasks/2/comments/5", `{"body":"hi"}`, authz.RoleEditor},
+		{"DELETE", "/boards/1/tasks/2/comments/5", "", authz.RoleEditor},
+		{"GET", "/boards/1/tasks/2/comments/5/history", "", authz.RoleViewer},
+	}
+	roles := []string{roleNone, authz.RoleViewer, authz.RoleEditor, authz.RoleOwner}
+	rank := map[string]int{roleNone: 0, authz.RoleViewer: 1, authz.RoleEditor: 2, authz.RoleOwner: 3}
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	authSvc := appauth.NewService(rdb, "acc", "ref", time.Minute, time.Hour)
+	tp, err := authSvc.GenerateTokenPair(context.Background(), 3)
+	require.NoError(t, err)
+
+	for _, rt := range routes {
+		for _, role := range roles {
+			name := fmt.Sprintf("%s %s as %s", rt.method, rt.path, roleName(role))
+			t.Run(name, func(t *testing.T) {
+				m := new(mocks.MockQuerier)
+				stubRole(m, role)
+				stubData(m)
+				router := newProtectedRouter(m, authSvc)
+
+				req := httptest.NewRequest(rt.method, rt.path, bytes.NewBufferString(rt.body))
+				req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				switch {
+				case rt.minRole == roleNone || rank[role] >= rank[rt.minRole]:
+					require.False(t, deniedByAuthz(w), "expected access, got %d %q", w.Code, w.Body.String())
+				case role == roleNone:
+					require.Equal(t, http.StatusNotFound, w.Code)
+					require.True(t, deniedByAuthz(w))
+				default:
+					require.Equal(t, http.StatusForbidden, w.Code)
+				}
+			})
+		}
+	}
+}
+
+func TestRoutesRequireToken(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	authSvc := appauth.NewService(rdb, "acc", "ref", time.Minute, time.Hour)
+	router := newProtectedRouter(new(mocks.MockQuerier), authSvc)
+
+	req := httptest.NewRequest("GET", "/boards/1/GetTasks", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func newProtectedRouter(q *mocks.MockQuerier, authSvc *appauth.Service) *chi.Mux {
+	boardHandler := handlers.NewBoardHandler(q)
+	taskHandler := handlers.NewTaskHandler(q, new(mocks.MockPublisher))
+	q.On("ListUserSummaries", mock.Anything, mock.Anything).Return([]db.ListUserSummariesRow{}, nil).Maybe()
+	memberHandler := handlers.NewMemberHandler(q)
+	invitationHandler := handlers.NewInvitationHandler(q, new(mocks.MockMailer), "http://app.local")
+	commentHandler := handlers.NewCommentHandler(q)
+	columnHandler := handlers.NewColumnHandler(q)
+	activityHandler := handlers.NewActivityHandler(q)
+	webhookHandler := handlers.NewWebhookHandler(q)
+
+	r := chi.NewRouter()
+	r.Use(middleware.AuthMiddleware(authSvc))
+
+	r.Get("/GetBoards", boardHandler.GetBoards)
+	r.Post("/CreateBoard", boardHandler.CreateBoard)
+	r.Patch("/boards/{boardID}", boardHandler.PatchBoard)
+	r.Delete("/boards/{boardID}", boardHandler.DeleteBoard)
+
+	r.Get("/boards/{boardID}/members", memberHandler.ListMembers)
+	r.Post("/boards/{boardID}/members", memberHandler.AddMember)
+	r.Patch("/boards/{boardID}/members/{userID}", memberHandler.UpdateMember)
+	r.Delete("/boards/{boardID}/members/{userID}", memberHandler.RemoveMember)
+
+	r.Get("/boards/{boardID}/columns", columnHandler.ListColumns)
+	r.Post("/boards/{boardID}/columns", columnHandler.CreateColumn)
+	r.Put("/boards/{boardID}/columns/order", columnHandler.ReorderColumns)
+	r.Patch("/boards/{boardID}/columns/{columnID}", columnHandler.UpdateColumn)
+	r.Delete("/boards/{boardID}/columns/{columnID}", columnHandler.DeleteColumn)
+
+	r.Get("/boards/{boardID}/webhooks", webhookHandler.ListWebhooks)
+	r.Post("/boards/{boardID}/webhooks", webhookHandler.CreateWebhook)
+	r.Patch("/boards/{boardID}/webhooks/{webhookID}", webhookHandler.UpdateWebhook)
+	r.Delete("/boards/{boardID}/webhooks/{webhookID}", webhookHandler.DeleteWebhook)
+	r.Get("/boards/{boardID}/webhooks/{webhookID}/deliveries", webhookHandler.ListDeliveries)
+	r.Get("/boards/{boardID}/webhooks/{webhookID}/deliveries/{deliveryID}", webhookHandler.GetDelivery)
+	r.Post("/boards/{boardID}/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", webhookHandler.Redeliver)
+
+	r.Get("/boards/{boardID}/invitations", invitationHandler.ListInvitations)
+	r.Post("/boards/{boardID}/invitations", invitationHandler.CreateInvitation)
+	r.Delete("/boards/{boardID}/invitations/{invitationID}", invitationHandler.RevokeInvitation)
+	r.Post("/invitations/{token}/accept", invitationHandler.AcceptInvitation)
+	r.Post("/invitations/{token}/decline", invitationHandler.DeclineInvitation)
+
+	r.Get("/boards/{boardID}/GetTasks", taskHandler.GetTasks)
+	r.Get("/tasks/assigned", taskHandler.GetAssignedTasks)
+	r.Post("/boards/{boardID}/CreateTask", taskHandler.CreateTask)
+	r.Patch("/boards/{boardID}/tasks/{taskID}", taskHandler.PatchTask)
+	r.Delete("/boards/{boardID}/tasks/{taskID}", taskHandler.DeleteTask)
+	r.Post("/boards/{boardID}/tasks/{taskID}/move", taskHandler.MoveTask)
+
+	r.Get("/boards/{boardID}/activity", activityHandler.BoardActivity)
+	r.Get("/boards/{boardID}/tasks/{taskID}/history", activityHandler.TaskHistory)
+
+	r.Get("/boards/{boardID}/tasks/{taskID}/comments", commentHandler.ListComments)
+	r.Post("/boards/{boardID}/tasks/{taskID}/comments", commentHandler.CreateComment)
+	r.Patch("/boards/{boardID}/tasks/{taskID}/comments/{commentID}", commentHandler.UpdateComment)
+	r.Delete("/boards/{boardID}/tasks/{taskID}/comments/{commentID}", commentHandler.DeleteComment)
+	r.Get("/boards/{boardID}/tasks/{taskID}/comments/{commentID}/history", commentHandler.CommentHistory)
+	return r
+}
+
+// stubRole настраивает ответы membership-запросов для текущего пользователя (ID 3)
+func stubRole(m *mocks.MockQuerier, role string) {
+	var err error
+	if role == roleNone {
+		err = pgx.ErrNoRows
+	}
+	m.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 1, UserID: 3}).Return(role, err).Maybe()
+	m.On("GetTaskMemberRole", mock.Anything, db.GetTaskMemberRoleParams{TaskID: 2, BoardID: 1, UserID: 3}).Return(role, err).Maybe()
+}
+
+// stubData заставляет все запросы к данным падать: тест проверяет только решение authz
+func stubData(m *mocks.MockQuerier) {
+	m.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return("", errStub).Maybe()
+	m.On("GetBoards", mock.Anything, mock.Anything).Return([]db.GetBoardsRow(nil), errStub).Maybe()
+	m.On("CreateBoard", mock.Anything, mock.Anything).Return(db.Board{}, errStub).Maybe()
+	m.On("UpdateBoard", mock.Anything, mock.Anything).Return(db.Board{}, errStub).Maybe()
+	m.On("DeleteBoard", mock.Anything, mock.Anything).Return(int64(0), errStub).Maybe()
+	m.On("ListBoardColumns", mock.Anything, mock.Anything).Return([]db.BoardColumn(nil), errStub).Maybe()
+	m.On("CreateBoardColumn", mock.Anything, mock.Anything).Return(db.BoardColumn{}, errStub).Maybe()
+	m.On("UpdateBoardColumn", mock.Anything, mock.Anything).Return(db.BoardColumn{}, errStub).Maybe()
+	m.On("GetBoardColumn", mock.Anything, mock.Anything).Return(db.BoardColumn{}, errStub).Maybe()
+	m.On("GetFirstBoardColumn", mock.Anything, mock.Anything).Return(db.BoardColumn{}, errStub).Maybe()
+	m.On("ListBoardMembers", mock.Anything, mock.Anything).Return([]db.ListBoardMembersRow(nil), errStub).Maybe()
+	m.On("GetUserByEmail", mock.Anything, mock.Anything).Return(db.GetUserByEmailRow{}, errStub).Maybe()
+	m.On("LockBoardOwners", mock.Anything, mock.Anything).Return([]int32(nil), errStub).Maybe()
+	m.On("GetUserByID", mock.Anything, mock.Anything).Return(db.GetUserByIDRow{ID: 3, Email: "me@ex.com", EmailVerifiedAt: pgtype.Timestamp{Time: time.Now(), Valid: true}}, nil).Maybe()
+	m.On("ListBoardInvitations", mock.Anything, mock.Anything).Return([]db.BoardInvitation(nil), errStub).Maybe()
+	m.On("CreateInvitation", mock.Anything, mock.Anything).Return(db.BoardInvitation{}, errStub).Maybe()
+	m.On("RevokeInvitation", mock.Anything, mock.Anything).Return(int64(0), errStub).Maybe()
+	m.On("GetInvitationByTokenHash", mock.Anything, mock.Anything).Return(db.BoardInvitation{}, errStub).Maybe()
+	m.On("GetTasks", mock.Anything, mock.Anything).Return([]db.GetTasksRow(nil), errStub).Maybe()
+	m.On("GetAssignedTasks", mock.Anything, mock.Anything).Return([]db.GetAssignedTasksRow(nil), errStub).Maybe()
+	m.On("CreateTask", mock.Anything, mock.Anything).Return(db.CreateTaskRow{}, errStub).Maybe()
+	m.On("UpdateTask", mock.Anything, mock.Anything).Return(db.Task{}, errStub).Maybe()
+	m.On("DeleteTask", mock.Anything, mock.Anything).Return(int64(0), errStub).Maybe()
+	m.On("GetBoardForUpdate", mock.Anything, mock.Anything).Return(db.Board{}, errStub).Maybe()
+	m.On("GetTaskForUpdate", mock.Anything, mock.Anything).Return(db.Task{}, errStub).Maybe()
+	m.On("ListBoardActivity", mock.Anything, mock.Anything).Return([]db.ActivityEvent(nil), errStub).Maybe()
+	m.On("ListTaskActivity", mock.Anything, mock.Anything).Return([]db.ActivityEvent(nil), errStub).Maybe()
+	m.On("ListComments", mock.Anything, mock.Anything).Return([]db.TaskComment(nil), errStub).Maybe()
+	m.On("CreateComment", mock.Anything, mock.Anything).Return(db.TaskComment{}, errStub).Maybe()
+	m.On("GetComment", mock.Anything, mock.Anything).Return(db.TaskComment{}, errStub).Maybe()
+	m.On("ListWebhooks", mock.Anything, mock.Anything).Return([]db.Webhook(nil), errStub).Maybe()
+	m.On("CreateWebhook", mock.Anything, mock.Anything).Return(db.Webhook{}, errStub).Maybe()
+	m.On("UpdateWebhook", mock.Anything, mock.Anything).Return(db.Webhook{}, errStub).Maybe()
+	m.On("DeleteWebhook", mock.Anything, mock.Anything).Return(int64(0), errStub).Maybe()
+	m.On("GetWebhook", mock.Anything, mock.Anything).Return(db.Webhook{}, errStub).Maybe()
+	m.On("GetWebhookDelivery", mock.Anything, mock.Anything).Return(db.WebhookDelivery{}, errStub).Maybe()
+	m.On("RedeliverWebhookDelivery", mock.Anything, mock.Anything).Return(db.WebhookDelivery{}, errStub).Maybe()
+}
+
+// deniedByAuthz отличает отказ authorize от ошибок, которые хендлер вернул уже после проверки доступа
+func deniedByAuthz(w *httptest.ResponseRecorder) bool {
+	body := w.Body.String()
+	return w.Code == http.StatusUnauthorized ||
+		w.Code == http.StatusForbidden ||
+		(w.Code == http.StatusNotFound && body == "not found\n")
+}
+
+func roleName(role string) string {
+	if role == roleNone {
+		return "non-member"
+	}
+	return role
+}
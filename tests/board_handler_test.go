@@ -16,8 +16,10 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
 	"github.com/sqszy/TaskTracker/internal/db"
 	"github.com/sqszy/TaskTracker/internal/dto"
 	"github.com/sqszy/TaskTracker/internal/handlers"
@@ -49,6 +51,8 @@ func (s *BoardTestSuite) SetupTest() {
 	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
 
 	s.mockQ = new(mocks.MockQuerier)
+	// каждое событие журнала попадает и в очередь вебхуков
+	s.mockQ.On("EnqueueWebhookDeliveries", mock.Anything, mock.Anything).Return(nil).Maybe()
 	s.handler = handlers.NewBoardHandler(s.mockQ)
 
 	s.router = chi.NewRouter()
@@ -92,6 +96,15 @@ func (s *BoardTestSuite) TestCreateBoard() {
 		},
 	}
 	s.mockQ.On("CreateBoard", mock.Anything, mock.Anything).Return(mockBoard, nil)
+	s.mockQ.On("AddBoardMember", mock.Anything, db.AddBoardMemberParams{
+		BoardID: 77,
+		UserID:  s.userID,
+		Role:    authz.RoleOwner,
+	}).Return(db.BoardMember{BoardID: 77, UserID: s.userID, Role: authz.RoleOwner}, nil)
+	s.mockQ.On("CreateDefaultColumns", mock.Anything, int32(77)).Return([]db.BoardColumn{{ID: 1, BoardID: 77, Name: "todo"}}, nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.MatchedBy(func(e db.CreateActivityEventParams) bool {
+		return e.BoardID == 77 && e.Entity == "board" && e.Action == "created"
+	})).Return(nil)
 
 	s.router.ServeHTTP(w, req)
 
@@ -100,6 +113,7 @@ func (s *BoardTestSuite) TestCreateBoard() {
 	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
 	require.Equal(s.T(), int32(77), got.ID)
 	require.Equal(s.T(), "MyBoard", got.Name)
+	require.Equal(s.T(), authz.RoleOwner, got.Role)
 
 	s.mockQ.AssertExpectations(s.T())
 }
@@ -112,9 +126,9 @@ func (s *BoardTestSuite) TestGetBoards() {
 	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
 	w := httptest.NewRecorder()
 
-	mockB := db.Board{
+	mockB := db.GetBoardsRow{
 		ID:     1,
-		UserID: s.userID,
+		UserID: 8,
 		Name:   "B1",
 		CreatedAt: pgtype.Timestamp{
 			Time:  s.now,
@@ -124,8 +138,9 @@ func (s *BoardTestSuite) TestGetBoards() {
 			Time:  s.now,
 			Valid: true,
 		},
+		Role: authz.RoleEditor,
 	}
-	s.mockQ.On("GetBoards", mock.Anything, s.userID).Return([]db.Board{mockB}, nil)
+	s.mockQ.On("GetBoards", mock.Anything, s.userID).Return([]db.GetBoardsRow{mockB}, nil)
 
 	s.router.ServeHTTP(w, req)
 
@@ -134,6 +149,7 @@ func (s *BoardTestSuite) TestGetBoards() {
 	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
 	require.Len(s.T(), resp, 1)
 	require.Equal(s.T(), "B1", resp[0].Name)
+	require.Equal(s.T(), authz.RoleEditor, resp[0].Role)
 
 	s.mockQ.AssertExpectations(s.T())
 }
@@ -161,8 +177,16 @@ func (s *BoardTestSuite) TestPatchBoard() {
 			Valid: true,
 		},
 	}
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 42, UserID: s.userID}).
+		Return(authz.RoleOwner, nil)
+	s.mockQ.On("GetBoardForUpdate", mock.Anything, int32(42)).Return(db.Board{ID: 42, UserID: s.userID, Name: "Old"}, nil)
 	s.mockQ.On("UpdateBoard", mock.Anything, mock.Anything).Return(mockB, nil)
 
+	var event db.CreateActivityEventParams
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { event = args.Get(1).(db.CreateActivityEventParams) }).
+		Return(nil)
+
 	s.router.ServeHTTP(w, req)
 
 	require.Equal(s.T(), http.StatusOK, w.Code)
@@ -171,6 +195,11 @@ func (s *BoardTestSuite) TestPatchBoard() {
 	require.Equal(s.T(), int32(42), got.ID)
 	require.Equal(s.T(), "Renamed", got.Name)
 
+	// в журнал попадает только изменившееся поле
+	require.Equal(s.T(), "updated", event.Action)
+	require.Equal(s.T(), pgtype.Int4{Int32: s.userID, Valid: true}, event.ActorID)
+	require.JSONEq(s.T(), `{"name":{"before":"Old","after":"Renamed"}}`, string(event.Changes))
+
 	s.mockQ.AssertExpectations(s.T())
 }
 
@@ -182,7 +211,13 @@ func (s *BoardTestSuite) TestDeleteBoard() {
 	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
 	w := httptest.NewRecorder()
 
-	s.mockQ.On("DeleteBoard", mock.Anything, mock.Anything).Return(int64(1), nil)
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 99, UserID: s.userID}).
+		Return(authz.RoleOwner, nil)
+	s.mockQ.On("GetBoardForUpdate", mock.Anything, int32(99)).Return(db.Board{ID: 99, Name: "Gone"}, nil)
+	s.mockQ.On("DeleteBoard", mock.Anything, int32(99)).Return(int64(1), nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.MatchedBy(func(e db.CreateActivityEventParams) bool {
+		return e.BoardID == 99 && e.Entity == "board" && e.Action == "deleted"
+	})).Return(nil)
 
 	s.router.ServeHTTP(w, req)
 
@@ -194,6 +229,55 @@ func (s *BoardTestSuite) TestDeleteBoard() {
 	s.mockQ.AssertExpectations(s.T())
 }
 
+func (s *BoardTestSuite) TestDeleteBoardByEditorForbidden() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest("DELETE", "/boards/99", nil)
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleEditor, nil)
+
+	s.router.ServeHTTP(w, req)
+
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "DeleteBoard", mock.Anything, mock.Anything)
+}
+
+func (s *BoardTestSuite) TestPatchBoardNotMember() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	b, _ := json.Marshal(map[string]string{"name": "Hijacked"})
+	req := httptest.NewRequest("PATCH", "/boards/42", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return("", pgx.ErrNoRows)
+
+	s.router.ServeHTTP(w, req)
+
+	require.Equal(s.T(), http.StatusNotFound, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "UpdateBoard", mock.Anything, mock.Anything)
+}
+
 func TestBoardSuite(t *testing.T) {
 	suite.Run(t, new(BoardTestSuite))
 }
+
+func (s *BoardTestSuite) TestPatchBoardInvalidWIPMode() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest("PATCH", "/boards/42", bytes.NewBufferString(`{"wip_mode":"lenient"}`))
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleOwner, nil)
+
+	s.router.ServeHTTP(w, req)
+
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "UpdateBoard", mock.Anything, mock.Anything)
+}
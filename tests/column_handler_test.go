@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+type ColumnTestSuite struct {
+	suite.Suite
+	mockQ   *mocks.MockQuerier
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	handler *handlers.ColumnHandler
+	router  *chi.Mux
+	userID  int32
+	ctx     context.Context
+}
+
+func (s *ColumnTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.userID = 4
+
+	mr := miniredis.RunT(s.T())
+	s.redis = mr
+	s.rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+
+	s.mockQ = new(mocks.MockQuerier)
+	s.handler = handlers.NewColumnHandler(s.mockQ)
+
+	s.router = chi.NewRouter()
+	s.router.Use(middleware.AuthMiddleware(s.authSvc))
+	s.router.Get("/boards/{boardID}/columns", s.handler.ListColumns)
+	s.router.Post("/boards/{boardID}/columns", s.handler.CreateColumn)
+	s.router.Put("/boards/{boardID}/columns/order", s.handler.ReorderColumns)
+	s.router.Patch("/boards/{boardID}/columns/{columnID}", s.handler.UpdateColumn)
+	s.router.Delete("/boards/{boardID}/columns/{columnID}", s.handler.DeleteColumn)
+}
+
+func (s *ColumnTestSuite) TearDownTest() {
+	if s.rdb != nil {
+		_ = s.rdb.Close()
+	}
+	if s.redis != nil {
+		s.redis.Close()
+	}
+}
+
+func (s *ColumnTestSuite) do(method, path string, body interface{}) *httptest.ResponseRecorder {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(s.T(), json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *ColumnTestSuite) role(role string) {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 1, UserID: s.userID}).
+		Return(role, nil)
+}
+
+func (s *ColumnTestSuite) columns() []db.BoardColumn {
+	return []db.BoardColumn{
+		{ID: 10, BoardID: 1, Name: "todo", Position: 0},
+		{ID: 11, BoardID: 1, Name: "in_progress", Position: 1},
+		{ID: 12, BoardID: 1, Name: "done", IsDone: true, Position: 2},
+	}
+}
+
+func (s *ColumnTestSuite) TestListColumns() {
+	s.role(authz.RoleViewer)
+	s.mockQ.On("ListBoardColumns", mock.Anything, int32(1)).Return(s.columns(), nil)
+
+	w := s.do("GET", "/boards/1/columns", nil)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var resp []dto.ColumnDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(s.T(), resp, 3)
+	require.Equal(s.T(), "done", resp[2].Name)
+	require.True(s.T(), resp[2].IsDone)
+}
+
+func (s *ColumnTestSuite) TestCreateColumnDuplicate() {
+	s.role(authz.RoleOwner)
+	s.mockQ.On("CreateBoardColumn", mock.Anything, db.CreateBoardColumnParams{
+		BoardID: 1,
+		Name:    "todo",
+		Color:   "#94a3b8",
+	}).Return(db.BoardColumn{}, &pgconn.PgError{Code: "23505"})
+
+	w := s.do("POST", "/boards/1/columns", dto.CreateColumnRequest{Name: " todo "})
+	require.Equal(s.T(), http.StatusConflict, w.Code)
+}
+
+func (s *ColumnTestSuite) TestCreateColumnRequiresOwner() {
+	s.role(authz.RoleEditor)
+
+	w := s.do("POST", "/boards/1/columns", dto.CreateColumnRequest{Name: "blocked"})
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "CreateBoardColumn", mock.Anything, mock.Anything)
+}
+
+func (s *ColumnTestSuite) TestRenameColumnSyncsTaskStatus() {
+	s.role(authz.RoleOwner)
+	name := "review"
+	s.mockQ.On("UpdateBoardColumn", mock.Anything, db.UpdateBoardColumnParams{
+		Name:    pgtype.Text{String: "review", Valid: true},
+		ID:      11,
+		BoardID: 1,
+	}).Return(db.BoardColumn{ID: 11, BoardID: 1, Name: "review", Position: 1}, nil)
+	s.mockQ.On("SyncColumnStatus", mock.Anything, db.SyncColumnStatusParams{
+		Name:     pgtype.Text{String: "review", Valid: true},
+		ColumnID: pgtype.Int4{Int32: 11, Valid: true},
+	}).Return(nil)
+
+	w := s.do("PATCH", "/boards/1/columns/11", dto.UpdateColumnRequest{Name: &name})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *ColumnTestSuite) TestReorderColumnsRequiresEveryColumn() {
+	s.role(authz.RoleOwner)
+	s.mockQ.On("ListBoardColumns", mock.Anything, int32(1)).Return(s.columns(), nil)
+
+	w := s.do("PUT", "/boards/1/columns/order", dto.ReorderColumnsRequest{ColumnIDs: []int32{12, 10}})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "ReorderBoardColumns", mock.Anything, mock.Anything)
+}
+
+func (s *ColumnTestSuite) TestDeleteColumnWithTasks() {
+	s.role(authz.RoleOwner)
+	s.mockQ.On("GetBoardColumn", mock.Anything, db.GetBoardColumnParams{ID: 11, BoardID: 1}).
+		Return(s.columns()[1], nil)
+	s.mockQ.On("CountColumnTasks", mock.Anything, pgtype.Int4{Int32: 11, Valid: true}).Return(int64(2), nil)
+
+	w := s.do("DELETE", "/boards/1/columns/11", nil)
+	require.Equal(s.T(), http.StatusConflict, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "DeleteBoardColumn", mock.Anything, mock.Anything)
+}
+
+func (s *ColumnTestSuite) TestUpdateColumnClearsWIPLimit() {
+	s.role(authz.RoleOwner)
+	zero := int32(0)
+	s.mockQ.On("UpdateBoardColumn", mock.Anything, db.UpdateBoardColumnParams{
+		WipLimit: pgtype.Int4{Int32: 0, Valid: true},
+		ID:       11,
+		BoardID:  1,
+	}).Return(db.BoardColumn{ID: 11, BoardID: 1, Name: "in_progress"}, nil)
+
+	w := s.do("PATCH", "/boards/1/columns/11", dto.UpdateColumnRequest{WIPLimit: &zero})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	require.Contains(s.T(), w.Body.String(), `"wip_limit":null`)
+
+	negative := int32(-1)
+	w = s.do("PATCH", "/boards/1/columns/11", dto.UpdateColumnRequest{WIPLimit: &negative})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+func TestColumnSuite(t *testing.T) {
+	suite.Run(t, new(ColumnTestSuite))
+}
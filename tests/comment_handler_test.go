@@ -0,0 +1,291 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+type CommentTestSuite struct {
+	suite.Suite
+	mockQ   *mocks.MockQuerier
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	handler *handlers.CommentHandler
+	router  *chi.Mux
+	userID  int32
+	now     time.Time
+	ctx     context.Context
+}
+
+func (s *CommentTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.now = time.Now()
+	s.userID = 6
+
+	mr := miniredis.RunT(s.T())
+	s.redis = mr
+	s.rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+
+	s.mockQ = new(mocks.MockQuerier)
+	// каждое событие журнала попадает и в очередь вебхуков
+	s.mockQ.On("EnqueueWebhookDeliveries", mock.Anything, mock.Anything).Return(nil).Maybe()
+	s.handler = handlers.NewCommentHandler(s.mockQ)
+
+	s.router = chi.NewRouter()
+	s.router.Use(middleware.AuthMiddleware(s.authSvc))
+	s.router.Get("/boards/{boardID}/tasks/{taskID}/comments", s.handler.ListComments)
+	s.router.Post("/boards/{boardID}/tasks/{taskID}/comments", s.handler.CreateComment)
+	s.router.Patch("/boards/{boardID}/tasks/{taskID}/comments/{commentID}", s.handler.UpdateComment)
+	s.router.Delete("/boards/{boardID}/tasks/{taskID}/comments/{commentID}", s.handler.DeleteComment)
+	s.router.Get("/boards/{boardID}/tasks/{taskID}/comments/{commentID}/history", s.handler.CommentHistory)
+}
+
+func (s *CommentTestSuite) TearDownTest() {
+	if s.rdb != nil {
+		_ = s.rdb.Close()
+	}
+	if s.redis != nil {
+		s.redis.Close()
+	}
+}
+
+func (s *CommentTestSuite) do(method, path string, body interface{}) *httptest.ResponseRecorder {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(s.T(), json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *CommentTestSuite) role(role string) {
+	s.mockQ.On("GetTaskMemberRole", mock.Anything, db.GetTaskMemberRoleParams{TaskID: 3, BoardID: 1, UserID: s.userID}).
+		Return(role, nil)
+}
+
+func (s *CommentTestSuite) comment(id, authorID int32, parentID int32) db.TaskComment {
+	return db.TaskComment{
+		ID:        id,
+		TaskID:    3,
+		UserID:    authorID,
+		ParentID:  pgtype.Int4{Int32: parentID, Valid: parentID != 0},
+		Body:      "text",
+		CreatedAt: pgtype.Timestamp{Time: s.now, Valid: true},
+	}
+}
+
+func (s *CommentTestSuite) TestListCommentsWithReplies() {
+	s.role(authz.RoleViewer)
+	deleted := s.comment(11, 8, 0)
+	deleted.DeletedAt = pgtype.Timestamp{Time: s.now, Valid: true}
+
+	s.mockQ.On("ListComments", mock.Anything, db.ListCommentsParams{TaskID: 3, Limit: 2, Offset: 4}).
+		Return([]db.TaskComment{s.comment(10, 8, 0), deleted}, nil)
+	s.mockQ.On("CountComments", mock.Anything, int32(3)).Return(int64(6), nil)
+	s.mockQ.On("ListCommentReplies", mock.Anything, []int32{10, 11}).
+		Return([]db.TaskComment{s.comment(20, s.userID, 11)}, nil)
+
+	w := s.do("GET", "/boards/1/tasks/3/comments?limit=2&offset=4", nil)
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var resp dto.CommentListDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(s.T(), int64(6), resp.Total)
+	require.Len(s.T(), resp.Comments, 2)
+	require.Empty(s.T(), resp.Comments[0].Replies)
+
+	// удалённый комментарий остаётся в ветке без текста
+	require.True(s.T(), resp.Comments[1].Deleted)
+	require.Empty(s.T(), resp.Comments[1].Body)
+	require.Len(s.T(), resp.Comments[1].Replies, 1)
+	require.Equal(s.T(), int32(20), resp.Comments[1].Replies[0].ID)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *CommentTestSuite) TestCreateReply() {
+	s.role(authz.RoleEditor)
+	parent := int32(10)
+	s.mockQ.On("GetComment", mock.Anything, db.GetCommentParams{ID: 10, TaskID: 3}).Return(s.comment(10, 8, 0), nil)
+	s.mockQ.On("CreateComment", mock.Anything, db.CreateCommentParams{
+		TaskID:   3,
+		UserID:   s.userID,
+		ParentID: pgtype.Int4{Int32: 10, Valid: true},
+		Body:     "agreed",
+	}).Return(s.comment(12, s.userID, 10), nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.MatchedBy(func(e db.CreateActivityEventParams) bool {
+		return e.Entity == "comment" && e.EntityID == 12 && e.TaskID.Int32 == 3 && e.Action == "created"
+	})).Return(nil)
+
+	w := s.do("POST", "/boards/1/tasks/3/comments", dto.CreateCommentRequest{Body: "  agreed ", ParentID: &parent})
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var got dto.CommentDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(s.T(), int32(10), *got.ParentID)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *CommentTestSuite) TestReplyToReplyRejected() {
+	s.role(authz.RoleEditor)
+	parent := int32(20)
+	s.mockQ.On("GetComment", mock.Anything, db.GetCommentParams{ID: 20, TaskID: 3}).Return(s.comment(20, 8, 10), nil)
+
+	w := s.do("POST", "/boards/1/tasks/3/comments", dto.CreateCommentRequest{Body: "deeper", ParentID: &parent})
+
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "CreateComment", mock.Anything, mock.Anything)
+}
+
+func (s *CommentTestSuite) TestCreateEmptyComment() {
+	w := s.do("POST", "/boards/1/tasks/3/comments", dto.CreateCommentRequest{Body: "   "})
+
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+func (s *CommentTestSuite) TestUpdateOwnComment() {
+	s.role(authz.RoleEditor)
+	s.mockQ.On("GetComment", mock.Anything, db.GetCommentParams{ID: 10, TaskID: 3}).Return(s.comment(10, s.userID, 0), nil)
+
+	edited := s.comment(10, s.userID, 0)
+	edited.Body = "fixed"
+	edited.EditedAt = pgtype.Timestamp{Time: s.now, Valid: true}
+	s.mockQ.On("UpdateComment", mock.Anything, db.UpdateCommentParams{ID: 10, TaskID: 3, EditedBy: s.userID, Body: "fixed"}).
+		Return(edited, nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.MatchedBy(func(e db.CreateActivityEventParams) bool {
+		return e.Action == "updated" && string(e.Changes) == `{"author_id":{"before":6,"after":6},"id":{"before":10,"after":10}}`
+	})).Return(nil)
+
+	w := s.do("PATCH", "/boards/1/tasks/3/comments/10", dto.UpdateCommentRequest{Body: "fixed"})
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var got dto.CommentDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(s.T(), "fixed", got.Body)
+	require.NotNil(s.T(), got.EditedAt)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *CommentTestSuite) TestUpdateForeignCommentForbidden() {
+	s.role(authz.RoleOwner)
+	s.mockQ.On("GetComment", mock.Anything, mock.Anything).Return(s.comment(10, 8, 0), nil)
+
+	w := s.do("PATCH", "/boards/1/tasks/3/comments/10", dto.UpdateCommentRequest{Body: "rewrite"})
+
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "UpdateComment", mock.Anything, mock.Anything)
+}
+
+func (s *CommentTestSuite) TestDeleteForeignCommentByEditorForbidden() {
+	s.role(authz.RoleEditor)
+	s.mockQ.On("GetComment", mock.Anything, mock.Anything).Return(s.comment(10, 8, 0), nil)
+
+	w := s.do("DELETE", "/boards/1/tasks/3/comments/10", nil)
+
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "SoftDeleteComment", mock.Anything, mock.Anything)
+}
+
+func (s *CommentTestSuite) TestDeleteForeignCommentByOwner() {
+	s.role(authz.RoleOwner)
+	s.mockQ.On("GetComment", mock.Anything, mock.Anything).Return(s.comment(10, 8, 0), nil)
+	s.mockQ.On("SoftDeleteComment", mock.Anything, db.SoftDeleteCommentParams{ID: 10, TaskID: 3}).Return(int64(1), nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.MatchedBy(func(e db.CreateActivityEventParams) bool {
+		// текст удалённого комментария в журнал не попадает
+		return e.Entity == "comment" && e.Action == "deleted" && e.ActorID.Int32 == s.userID &&
+			string(e.Changes) == `{"author_id":{"before":8,"after":null},"id":{"before":10,"after":null}}`
+	})).Return(nil)
+
+	w := s.do("DELETE", "/boards/1/tasks/3/comments/10", nil)
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *CommentTestSuite) TestActivityNeverContainsCommentText() {
+	s.role(authz.RoleEditor)
+	var events []db.CreateActivityEventParams
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(db.CreateActivityEventParams))
+	})
+
+	created := s.comment(10, s.userID, 0)
+	created.Body = "first secret"
+	s.mockQ.On("CreateComment", mock.Anything, mock.Anything).Return(created, nil).Once()
+	w := s.do("POST", "/boards/1/tasks/3/comments", dto.CreateCommentRequest{Body: "first secret"})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	edited := created
+	edited.Body = "second secret"
+	s.mockQ.On("GetComment", mock.Anything, db.GetCommentParams{ID: 10, TaskID: 3}).Return(created, nil).Once()
+	s.mockQ.On("UpdateComment", mock.Anything, mock.Anything).Return(edited, nil).Once()
+	w = s.do("PATCH", "/boards/1/tasks/3/comments/10", dto.UpdateCommentRequest{Body: "second secret"})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	s.mockQ.On("GetComment", mock.Anything, db.GetCommentParams{ID: 10, TaskID: 3}).Return(edited, nil).Once()
+	s.mockQ.On("SoftDeleteComment", mock.Anything, db.SoftDeleteCommentParams{ID: 10, TaskID: 3}).Return(int64(1), nil).Once()
+	w = s.do("DELETE", "/boards/1/tasks/3/comments/10", nil)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	// журнал доски видят все участники: текст, даже прежний, в нём не хранится
+	require.Len(s.T(), events, 3)
+	for i, action := range []string{"created", "updated", "deleted"} {
+		require.Equal(s.T(), action, events[i].Action)
+		require.NotContains(s.T(), string(events[i].Changes), "secret")
+		require.NotContains(s.T(), string(events[i].Changes), "body")
+	}
+}
+
+func (s *CommentTestSuite) TestCommentHistory() {
+	s.role(authz.RoleViewer)
+	s.mockQ.On("GetComment", mock.Anything, mock.Anything).Return(s.comment(10, 8, 0), nil)
+	s.mockQ.On("ListCommentEdits", mock.Anything, int32(10)).Return([]db.TaskCommentEdit{
+		{ID: 2, CommentID: 10, Body: "second", EditedBy: 8, EditedAt: pgtype.Timestamp{Time: s.now, Valid: true}},
+		{ID: 1, CommentID: 10, Body: "first", EditedBy: 8, EditedAt: pgtype.Timestamp{Time: s.now.Add(-time.Hour), Valid: true}},
+	}, nil)
+
+	w := s.do("GET", "/boards/1/tasks/3/comments/10/history", nil)
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var resp []dto.CommentEditDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(s.T(), resp, 2)
+	require.Equal(s.T(), "second", resp[0].Body)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func TestCommentSuite(t *testing.T) {
+	suite.Run(t, new(CommentTestSuite))
+}
@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	appmw "github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+type CookieTestSuite struct {
+	suite.Suite
+	mockQ   *mocks.MockQuerier
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	router  http.Handler
+	ctx     context.Context
+}
+
+func (s *CookieTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+	s.mockQ = new(mocks.MockQuerier)
+
+	h := handlers.NewAuthHandler(s.mockQ, s.authSvc, new(mocks.MockMailer), "http://app.local/").
+		WithPasswordHasher(testHasher).
+		WithRefreshCookies(&handlers.RefreshCookies{
+			Secure:   true,
+			SameSite: http.SameSiteStrictMode,
+			MaxAge:   time.Hour,
+		})
+	r := chi.NewRouter()
+	r.Post("/login", h.Login)
+	r.With(appmw.CSRF).Post("/refresh", h.Refresh)
+	r.With(appmw.CSRF).Post("/logout", h.Logout)
+	s.router = r
+}
+
+func (s *CookieTestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+func (s *CookieTestSuite) do(path string, body interface{}, cookies []*http.Cookie, csrf string) *httptest.ResponseRecorder {
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest("POST", path, bytes.NewReader(b))
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	if csrf != "" {
+		req.Header.Set(appmw.CSRFHeader, csrf)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// cookiesFor — cookie, которые браузер приложит к запросу на path
+func cookiesFor(w *httptest.ResponseRecorder, path string) []*http.Cookie {
+	var res []*http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Path == path || c.Path == "/" {
+			res = append(res, &http.Cookie{Name: c.Name, Value: c.Value})
+		}
+	}
+	return res
+}
+
+func cookieValue(w *httptest.ResponseRecorder, name, path string) (*http.Cookie, bool) {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name && c.Path == path {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+func (s *CookieTestSuite) login() (*httptest.ResponseRecorder, dto.LoginResponse) {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "me@ex.com", Password: hashPassword(s.T(), "mypassword")}, nil)
+
+	w := s.do("/login", dto.LoginRequest{Email: "me@ex.com", Password: "mypassword"}, nil, "")
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	var resp dto.LoginResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	return w, resp
+}
+
+func (s *CookieTestSuite) TestLoginSetsCookies() {
+	w, resp := s.login()
+	require.NotEmpty(s.T(), resp.AccessToken)
+	require.Empty(s.T(), resp.RefreshToken, "refresh token is not exposed to scripts")
+	require.NotContains(s.T(), w.Body.String(), "refresh_token")
+	require.NotEmpty(s.T(), resp.CSRFToken)
+
+	var refreshValue string
+	for _, path := range []string{"/refresh", "/logout"} {
+		c, ok := cookieValue(w, appmw.RefreshCookieName, path)
+		require.True(s.T(), ok, path)
+		require.True(s.T(), c.HttpOnly)
+		require.True(s.T(), c.Secure)
+		require.Equal(s.T(), http.SameSiteStrictMode, c.SameSite)
+		require.Equal(s.T(), 3600, c.MaxAge)
+		require.NotEmpty(s.T(), c.Value)
+		refreshValue = c.Value
+	}
+	_, ok := cookieValue(w, appmw.RefreshCookieName, "/")
+	require.False(s.T(), ok, "refresh cookie must not be sent to every route")
+
+	csrf, ok := cookieValue(w, appmw.CSRFCookieName, "/")
+	require.True(s.T(), ok)
+	require.False(s.T(), csrf.HttpOnly, "frontend reads the csrf cookie")
+	require.Equal(s.T(), resp.CSRFToken, csrf.Value)
+
+	_, err := s.authSvc.RefreshSession(s.ctx, refreshValue, appauth.SessionMeta{})
+	require.NoError(s.T(), err, "cookie holds a valid refresh token")
+}
+
+func (s *CookieTestSuite) TestRefreshWithCookie() {
+	w, resp := s.login()
+
+	w2 := s.do("/refresh", nil, cookiesFor(w, "/refresh"), resp.CSRFToken)
+	require.Equal(s.T(), http.StatusOK, w2.Code, w2.Body.String())
+	var resp2 dto.LoginResponse
+	require.NoError(s.T(), json.Unmarshal(w2.Body.Bytes(), &resp2))
+	require.NotEmpty(s.T(), resp2.AccessToken)
+	require.Empty(s.T(), resp2.RefreshToken)
+	require.NotEmpty(s.T(), resp2.CSRFToken)
+	require.NotEqual(s.T(), resp.CSRFToken, resp2.CSRFToken)
+
+	oldRefresh, _ := cookieValue(w, appmw.RefreshCookieName, "/refresh")
+	newRefresh, ok := cookieValue(w2, appmw.RefreshCookieName, "/refresh")
+	require.True(s.T(), ok)
+	require.NotEqual(s.T(), oldRefresh.Value, newRefresh.Value, "refresh token is rotated")
+
+	// с новыми cookie можно обновиться ещё раз
+	w3 := s.do("/refresh", nil, cookiesFor(w2, "/refresh"), resp2.CSRFToken)
+	require.Equal(s.T(), http.StatusOK, w3.Code, w3.Body.String())
+}
+
+func (s *CookieTestSuite) TestRefreshRejectsMissingCSRF() {
+	w, resp := s.login()
+	cookies := cookiesFor(w, "/refresh")
+
+	w2 := s.do("/refresh", nil, cookies, "")
+	require.Equal(s.T(), http.StatusForbidden, w2.Code)
+
+	w3 := s.do("/refresh", nil, cookies, resp.CSRFToken+"x")
+	require.Equal(s.T(), http.StatusForbidden, w3.Code)
+
+	// заголовок без cookie csrf_token не проходит
+	var onlyRefresh []*http.Cookie
+	for _, c := range cookies {
+		if c.Name == appmw.RefreshCookieName {
+			onlyRefresh = append(onlyRefresh, c)
+		}
+	}
+	w4 := s.do("/refresh", nil, onlyRefresh, resp.CSRFToken)
+	require.Equal(s.T(), http.StatusForbidden, w4.Code)
+
+	// токен не израсходован отклонёнными запросами
+	w5 := s.do("/refresh", nil, cookies, resp.CSRFToken)
+	require.Equal(s.T(), http.StatusOK, w5.Code, w5.Body.String())
+}
+
+func (s *CookieTestSuite) TestInvalidRefreshCookieIsCleared() {
+	w := s.do("/refresh", nil, []*http.Cookie{
+		{Name: appmw.RefreshCookieName, Value: "garbage"},
+		{Name: appmw.CSRFCookieName, Value: "csrf"},
+	}, "csrf")
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	c, ok := cookieValue(w, appmw.RefreshCookieName, "/refresh")
+	require.True(s.T(), ok)
+	require.Equal(s.T(), -1, c.MaxAge)
+}
+
+func (s *CookieTestSuite) TestBodyTokenStillAccepted() {
+	tp, err := s.authSvc.StartSession(s.ctx, 11, appauth.SessionMeta{})
+	require.NoError(s.T(), err)
+
+	w := s.do("/refresh", dto.RefreshRequest{RefreshToken: tp.RefreshToken}, nil, "")
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+}
+
+func (s *CookieTestSuite) TestLogoutClearsCookies() {
+	w, resp := s.login()
+
+	w2 := s.do("/logout", nil, cookiesFor(w, "/logout"), resp.CSRFToken)
+	require.Equal(s.T(), http.StatusOK, w2.Code, w2.Body.String())
+	for _, path := range []string{"/refresh", "/logout"} {
+		c, ok := cookieValue(w2, appmw.RefreshCookieName, path)
+		require.True(s.T(), ok, path)
+		require.Equal(s.T(), -1, c.MaxAge)
+	}
+	c, ok := cookieValue(w2, appmw.CSRFCookieName, "/")
+	require.True(s.T(), ok)
+	require.Equal(s.T(), -1, c.MaxAge)
+
+	_, err := s.authSvc.ValidateAccessToken(s.ctx, resp.AccessToken)
+	require.ErrorIs(s.T(), err, appauth.ErrTokenRevoked)
+	w3 := s.do("/refresh", nil, cookiesFor(w, "/refresh"), resp.CSRFToken)
+	require.Equal(s.T(), http.StatusUnauthorized, w3.Code)
+}
+
+func (s *CookieTestSuite) TestLogoutRequiresCSRF() {
+	w, resp := s.login()
+
+	w2 := s.do("/logout", nil, cookiesFor(w, "/logout"), "")
+	require.Equal(s.T(), http.StatusForbidden, w2.Code)
+	_, err := s.authSvc.ValidateAccessToken(s.ctx, resp.AccessToken)
+	require.NoError(s.T(), err, "session survives forged logout")
+}
+
+func TestCookieTestSuite(t *testing.T) {
+	suite.Run(t, new(CookieTestSuite))
+}
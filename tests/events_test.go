@@ -0,0 +1,264 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/realtime"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+// startBroker — отдельный клиент Redis на брокер, как у отдельного экземпляра API
+func startBroker(t *testing.T, ctx context.Context, mr *miniredis.Miniredis) *realtime.Broker {
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	b := realtime.NewBroker(rdb)
+	require.NoError(t, b.Start(ctx))
+	return b
+}
+
+func TestBrokerFanoutAcrossInstances(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr := miniredis.RunT(t)
+
+	publisher := startBroker(t, ctx, mr)
+	listener := startBroker(t, ctx, mr)
+
+	sub := listener.Subscribe(7)
+	defer sub.Close()
+	other := listener.Subscribe(8)
+	defer other.Close()
+
+	require.NoError(t, publisher.Publish(ctx, realtime.Event{
+		Type:    realtime.TaskUpdated,
+		BoardID: 7,
+		TaskID:  3,
+		Task:    &dto.TaskDTO{ID: 3, BoardID: 7, Title: "T"},
+	}))
+
+	select {
+	case ev := <-sub.C:
+		require.Equal(t, realtime.TaskUpdated, ev.Type)
+		require.Equal(t, int32(3), ev.TaskID)
+		require.Equal(t, "T", ev.Task.Title)
+		require.False(t, ev.At.IsZero())
+	case <-time.After(2 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+
+	// подписчики других досок событие не получают
+	select {
+	case ev := <-other.C:
+		t.Fatalf("unexpected event for board 8: %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// после Close канал закрыт, повторный Close безопасен
+	sub.Close()
+	sub.Close()
+	_, ok := <-sub.C
+	require.False(t, ok)
+}
+
+func newStreamServer(t *testing.T, m *mocks.MockQuerier, broker *realtime.Broker, authSvc *appauth.Service) *httptest.Server {
+	return newStreamServerWithHeartbeat(t, m, broker, authSvc, time.Hour)
+}
+
+func newStreamServerWithHeartbeat(t *testing.T, m *mocks.MockQuerier, broker *realtime.Broker, authSvc *appauth.Service, heartbeat time.Duration) *httptest.Server {
+	h := handlers.NewEventsHandler(m, authSvc, broker).WithHeartbeat(heartbeat)
+	r := chi.NewRouter()
+	r.With(middleware.StreamAuthMiddleware(authSvc)).Get("/boards/{boardID}/events", h.Stream)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEventStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr := miniredis.RunT(t)
+	broker := startBroker(t, ctx, mr)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	authSvc := appauth.NewService(rdb, "acc", "ref", time.Minute, time.Hour)
+	tp, err := authSvc.GenerateTokenPair(ctx, 3)
+	require.NoError(t, err)
+
+	m := new(mocks.MockQuerier)
+	m.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 7, UserID: 3}).
+		Return(authz.RoleViewer, nil)
+	srv := newStreamServer(t, m, broker, authSvc)
+
+	// EventSource передаёт токен в query
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/boards/7/events?access_token="+tp.AccessToken, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := bufio.NewReader(resp.Body)
+	line, err := lines.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ": connected\n", line)
+
+	// событие с другого экземпляра доходит до открытого потока
+	other := startBroker(t, ctx, mr)
+	require.NoError(t, other.Publish(ctx, realtime.Event{Type: realtime.TaskMoved, BoardID: 7, TaskID: 5}))
+
+	var event, data string
+	for data == "" {
+		line, err := lines.ReadString('\n')
+		require.NoError(t, err)
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+		}
+	}
+	require.Equal(t, realtime.TaskMoved, event)
+
+	var ev realtime.Event
+	require.NoError(t, json.Unmarshal([]byte(data), &ev))
+	require.Equal(t, int32(5), ev.TaskID)
+}
+
+func TestEventStreamRequiresMembership(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr := miniredis.RunT(t)
+	broker := startBroker(t, ctx, mr)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	authSvc := appauth.NewService(rdb, "acc", "ref", time.Minute, time.Hour)
+	tp, err := authSvc.GenerateTokenPair(ctx, 3)
+	require.NoError(t, err)
+
+	m := new(mocks.MockQuerier)
+	m.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return("", pgx.ErrNoRows)
+	srv := newStreamServer(t, m, broker, authSvc)
+
+	resp, err := http.Get(srv.URL + "/boards/7/events")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, err := http.NewRequest("GET", srv.URL+"/boards/7/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// openStream открывает поток и дочитывает приветствие
+func openStream(t *testing.T, ctx context.Context, url string) *bufio.Reader {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	lines := bufio.NewReader(resp.Body)
+	line, err := lines.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ": connected\n", line)
+	_, err = lines.ReadString('\n')
+	require.NoError(t, err)
+	return lines
+}
+
+// waitClosed ждёт, пока сервер закроет поток; пинги до этого пропускаются
+func waitClosed(t *testing.T, lines *bufio.Reader, within time.Duration) {
+	done := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := lines.ReadString('\n'); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, io.EOF)
+	case <-time.After(within):
+		t.Fatal("stream was not closed")
+	}
+}
+
+func TestEventStreamClosedWhenSessionRevoked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr := miniredis.RunT(t)
+	broker := startBroker(t, ctx, mr)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	authSvc := appauth.NewService(rdb, "acc", "ref", time.Minute, time.Hour)
+	tp, err := authSvc.GenerateTokenPair(ctx, 3)
+	require.NoError(t, err)
+
+	m := new(mocks.MockQuerier)
+	m.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 7, UserID: 3}).
+		Return(authz.RoleViewer, nil)
+	srv := newStreamServerWithHeartbeat(t, m, broker, authSvc, 20*time.Millisecond)
+
+	lines := openStream(t, ctx, srv.URL+"/boards/7/events?access_token="+tp.AccessToken)
+	line, err := lines.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ": ping\n", line)
+
+	// выход везде закрывает и уже открытый поток, не дожидаясь истечения токена
+	require.NoError(t, authSvc.RevokeAll(ctx, 3))
+	waitClosed(t, lines, 2*time.Second)
+}
+
+func TestEventStreamClosedWhenTokenExpires(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr := miniredis.RunT(t)
+	broker := startBroker(t, ctx, mr)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	authSvc := appauth.NewService(rdb, "acc", "ref", time.Second, time.Hour)
+	tp, err := authSvc.GenerateTokenPair(ctx, 3)
+	require.NoError(t, err)
+
+	m := new(mocks.MockQuerier)
+	m.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 7, UserID: 3}).
+		Return(authz.RoleViewer, nil)
+	srv := newStreamServer(t, m, broker, authSvc)
+
+	lines := openStream(t, ctx, srv.URL+"/boards/7/events?access_token="+tp.AccessToken)
+	waitClosed(t, lines, 3*time.Second)
+}
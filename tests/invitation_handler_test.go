@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+type InvitationTestSuite struct {
+	suite.Suite
+	mockQ   *mocks.MockQuerier
+	mail    *mocks.MockMailer
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	handler *handlers.InvitationHandler
+	router  *chi.Mux
+	userID  int32
+	now     time.Time
+	ctx     context.Context
+}
+
+func (s *InvitationTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.now = time.Now()
+	s.userID = 4
+
+	mr := miniredis.RunT(s.T())
+	s.redis = mr
+	s.rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+
+	s.mockQ = new(mocks.MockQuerier)
+	s.mail = new(mocks.MockMailer)
+	s.handler = handlers.NewInvitationHandler(s.mockQ, s.mail, "http://app.local/")
+
+	s.router = chi.NewRouter()
+	s.router.Use(middleware.AuthMiddleware(s.authSvc))
+	s.router.Get("/boards/{boardID}/invitations", s.handler.ListInvitations)
+	s.router.Post("/boards/{boardID}/invitations", s.handler.CreateInvitation)
+	s.router.Delete("/boards/{boardID}/invitations/{invitationID}", s.handler.RevokeInvitation)
+	s.router.Post("/invitations/{token}/accept", s.handler.AcceptInvitation)
+	s.router.Post("/invitations/{token}/decline", s.handler.DeclineInvitation)
+}
+
+func (s *InvitationTestSuite) TearDownTest() {
+	if s.rdb != nil {
+		_ = s.rdb.Close()
+	}
+	if s.redis != nil {
+		s.redis.Close()
+	}
+}
+
+func (s *InvitationTestSuite) do(method, path string, body interface{}) *httptest.ResponseRecorder {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(s.T(), json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *InvitationTestSuite) invitation(status string, expiresAt time.Time) db.BoardInvitation {
+	return db.BoardInvitation{
+		ID:        12,
+		BoardID:   2,
+		Email:     "guest@ex.com",
+		Role:      authz.RoleEditor,
+		InviterID: 1,
+		Status:    status,
+		ExpiresAt: pgtype.Timestamp{Time: expiresAt, Valid: true},
+		CreatedAt: pgtype.Timestamp{Time: s.now, Valid: true},
+	}
+}
+
+func (s *InvitationTestSuite) TestCreateInvitationSendsMail() {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 2, UserID: s.userID}).
+		Return(authz.RoleOwner, nil)
+	s.mockQ.On("GetUserByEmail", mock.Anything, "guest@ex.com").Return(db.GetUserByEmailRow{}, pgx.ErrNoRows)
+
+	var stored db.CreateInvitationParams
+	s.mockQ.On("CreateInvitation", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(db.CreateInvitationParams) }).
+		Return(s.invitation("pending", s.now.Add(time.Hour)), nil)
+
+	w := s.do("POST", "/boards/2/invitations", dto.CreateInvitationRequest{Email: "Guest@ex.com", Role: authz.RoleEditor})
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var got dto.InvitationDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(s.T(), int32(12), got.ID)
+	require.Equal(s.T(), "pending", got.Status)
+	require.NotContains(s.T(), w.Body.String(), "token")
+
+	require.Equal(s.T(), s.userID, stored.InviterID)
+	require.Len(s.T(), s.mail.Sent, 1)
+	msg := s.mail.Last()
+	require.Equal(s.T(), "guest@ex.com", msg.To)
+
+	// в письме лежит сам токен, в базе — только его хэш
+	token := regexp.MustCompile(`http://app\.local/invitations/(\S+)`).FindStringSubmatch(msg.Body)
+	require.Len(s.T(), token, 2)
+	require.Equal(s.T(), appauth.HashToken(token[1]), stored.TokenHash)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *InvitationTestSuite) TestCreateInvitationMailFailure() {
+	s.mail.Err = errors.New("smtp down")
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleOwner, nil)
+	s.mockQ.On("GetUserByEmail", mock.Anything, mock.Anything).Return(db.GetUserByEmailRow{}, pgx.ErrNoRows)
+	s.mockQ.On("CreateInvitation", mock.Anything, mock.Anything).Return(s.invitation("pending", s.now.Add(time.Hour)), nil)
+
+	w := s.do("POST", "/boards/2/invitations", dto.CreateInvitationRequest{Email: "guest@ex.com", Role: authz.RoleViewer})
+
+	require.Equal(s.T(), http.StatusInternalServerError, w.Code)
+}
+
+func (s *InvitationTestSuite) TestCreateInvitationByEditorForbidden() {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleEditor, nil)
+
+	w := s.do("POST", "/boards/2/invitations", dto.CreateInvitationRequest{Email: "guest@ex.com", Role: authz.RoleViewer})
+
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+	require.Empty(s.T(), s.mail.Sent)
+}
+
+func (s *InvitationTestSuite) TestRevokeInvitation() {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleOwner, nil)
+	s.mockQ.On("RevokeInvitation", mock.Anything, db.RevokeInvitationParams{ID: 12, BoardID: 2}).Return(int64(1), nil)
+
+	w := s.do("DELETE", "/boards/2/invitations/12", nil)
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *InvitationTestSuite) TestAcceptInvitation() {
+	s.mockQ.On("GetUserByID", mock.Anything, s.userID).Return(s.verifiedUser("guest@ex.com"), nil)
+	s.mockQ.On("GetInvitationByTokenHash", mock.Anything, appauth.HashToken("tok")).
+		Return(s.invitation("pending", s.now.Add(time.Hour)), nil)
+	s.mockQ.On("UpdateInvitationStatus", mock.Anything, db.UpdateInvitationStatusParams{Status: "accepted", ID: 12}).
+		Return(int64(1), nil)
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 2, UserID: s.userID}).
+		Return("", pgx.ErrNoRows)
+	s.mockQ.On("AddBoardMember", mock.Anything, db.AddBoardMemberParams{BoardID: 2, UserID: s.userID, Role: authz.RoleEditor}).
+		Return(db.BoardMember{BoardID: 2, UserID: s.userID, Role: authz.RoleEditor}, nil)
+
+	w := s.do("POST", "/invitations/tok/accept", nil)
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var got dto.InvitationDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(s.T(), "accepted", got.Status)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *InvitationTestSuite) TestDeclineInvitation() {
+	s.mockQ.On("GetUserByID", mock.Anything, s.userID).Return(s.verifiedUser("guest@ex.com"), nil)
+	s.mockQ.On("GetInvitationByTokenHash", mock.Anything, appauth.HashToken("tok")).
+		Return(s.invitation("pending", s.now.Add(time.Hour)), nil)
+	s.mockQ.On("UpdateInvitationStatus", mock.Anything, db.UpdateInvitationStatusParams{Status: "declined", ID: 12}).
+		Return(int64(1), nil)
+
+	w := s.do("POST", "/invitations/tok/decline", nil)
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "AddBoardMember", mock.Anything, mock.Anything)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *InvitationTestSuite) TestAcceptExpiredInvitation() {
+	s.mockQ.On("GetUserByID", mock.Anything, s.userID).Return(s.verifiedUser("guest@ex.com"), nil)
+	s.mockQ.On("GetInvitationByTokenHash", mock.Anything, mock.Anything).
+		Return(s.invitation("pending", s.now.Add(-time.Hour)), nil)
+
+	w := s.do("POST", "/invitations/tok/accept", nil)
+
+	require.Equal(s.T(), http.StatusGone, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "AddBoardMember", mock.Anything, mock.Anything)
+}
+
+func (s *InvitationTestSuite) TestAcceptInvitationForAnotherEmail() {
+	s.mockQ.On("GetUserByID", mock.Anything, s.userID).Return(s.verifiedUser("intruder@ex.com"), nil)
+	s.mockQ.On("GetInvitationByTokenHash", mock.Anything, mock.Anything).
+		Return(s.invitation("pending", s.now.Add(time.Hour)), nil)
+
+	w := s.do("POST", "/invitations/tok/accept", nil)
+
+	require.Equal(s.T(), http.StatusNotFound, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "UpdateInvitationStatus", mock.Anything, mock.Anything)
+}
+
+func (s *InvitationTestSuite) TestAcceptInvitationRequiresVerifiedEmail() {
+	s.mockQ.On("GetUserByID", mock.Anything, s.userID).Return(db.GetUserByIDRow{ID: s.userID, Email: "guest@ex.com"}, nil)
+
+	w := s.do("POST", "/invitations/tok/accept", nil)
+
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "GetInvitationByTokenHash", mock.Anything, mock.Anything)
+	s.mockQ.AssertNotCalled(s.T(), "AddBoardMember", mock.Anything, mock.Anything)
+}
+
+func (s *InvitationTestSuite) verifiedUser(email string) db.GetUserByIDRow {
+	return db.GetUserByIDRow{ID: s.userID, Email: email, EmailVerifiedAt: pgtype.Timestamp{Time: s.now, Valid: true}}
+}
+
+func TestInvitationSuite(t *testing.T) {
+	suite.Run(t, new(InvitationTestSuite))
+}
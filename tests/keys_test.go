@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+)
+
+type KeysTestSuite struct {
+	suite.Suite
+	redis *miniredis.Miniredis
+	rdb   *redis.Client
+	ctx   context.Context
+}
+
+func (s *KeysTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+}
+
+func (s *KeysTestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+func (s *KeysTestSuite) keys(alg string, rotateEvery, retainFor time.Duration) *appauth.KeyManager {
+	km, err := appauth.NewKeyManager(s.rdb, alg, rotateEvery, retainFor)
+	require.NoError(s.T(), err)
+	return km
+}
+
+func (s *KeysTestSuite) service(km *appauth.KeyManager) *appauth.Service {
+	return appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour).WithKeys(km)
+}
+
+func (s *KeysTestSuite) jwks(km *appauth.KeyManager) appauth.JWKS {
+	w := httptest.NewRecorder()
+	handlers.NewJWKSHandler(km).JWKS(w, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var set appauth.JWKS
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &set))
+	return set
+}
+
+// verifyWithJWKS проверяет токен так, как это сделал бы сторонний сервис: только по опубликованным ключам
+func (s *KeysTestSuite) verifyWithJWKS(set appauth.JWKS, token string) (*jwt.Token, error) {
+	return jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		for _, k := range set.Keys {
+			if k.Kid != t.Header["kid"] {
+				continue
+			}
+			switch k.Kty {
+			case "RSA":
+				n, _ := base64.RawURLEncoding.DecodeString(k.N)
+				e, _ := base64.RawURLEncoding.DecodeString(k.E)
+				return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+			case "OKP":
+				x, _ := base64.RawURLEncoding.DecodeString(k.X)
+				return ed25519.PublicKey(x), nil
+			}
+		}
+		return nil, appauth.ErrUnknownKey
+	})
+}
+
+func (s *KeysTestSuite) TestRS256TokensCarryKidAndVerifyViaJWKS() {
+	km := s.keys(appauth.AlgRS256, time.Hour, time.Hour)
+	rotated, err := km.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+	require.True(s.T(), rotated)
+	rotated, err = km.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+	require.False(s.T(), rotated, "current key is still fresh")
+
+	svc := s.service(km)
+	tp, err := svc.GenerateTokenPair(s.ctx, 5)
+	require.NoError(s.T(), err)
+
+	set := s.jwks(km)
+	require.Len(s.T(), set.Keys, 1)
+	require.Equal(s.T(), "RSA", set.Keys[0].Kty)
+	require.Equal(s.T(), "RS256", set.Keys[0].Alg)
+	require.Equal(s.T(), "sig", set.Keys[0].Use)
+
+	tok, err := s.verifyWithJWKS(set, tp.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "RS256", tok.Method.Alg())
+	require.Equal(s.T(), set.Keys[0].Kid, tok.Header["kid"])
+
+	uid, err := svc.ValidateAccessToken(s.ctx, tp.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int32(5), uid)
+	_, err = svc.Refresh(s.ctx, tp.RefreshToken)
+	require.NoError(s.T(), err)
+}
+
+func (s *KeysTestSuite) TestEdDSA() {
+	km := s.keys(appauth.AlgEdDSA, time.Hour, time.Hour)
+	_, err := km.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+	tp, err := s.service(km).GenerateTokenPair(s.ctx, 5)
+	require.NoError(s.T(), err)
+
+	set := s.jwks(km)
+	require.Len(s.T(), set.Keys, 1)
+	require.Equal(s.T(), "OKP", set.Keys[0].Kty)
+	require.Equal(s.T(), "Ed25519", set.Keys[0].Crv)
+	tok, err := s.verifyWithJWKS(set, tp.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "EdDSA", tok.Method.Alg())
+}
+
+func (s *KeysTestSuite) TestRefreshTokenIsNotAnAccessToken() {
+	km := s.keys(appauth.AlgEdDSA, time.Hour, time.Hour)
+	_, err := km.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+	svc := s.service(km)
+	tp, err := svc.GenerateTokenPair(s.ctx, 5)
+	require.NoError(s.T(), err)
+
+	_, err = svc.ParseAccessToken(s.ctx, tp.RefreshToken)
+	require.Error(s.T(), err)
+	_, err = svc.Refresh(s.ctx, tp.AccessToken)
+	require.Error(s.T(), err)
+}
+
+func (s *KeysTestSuite) TestRotationKeepsOldKeysUntilTokensExpire() {
+	km := s.keys(appauth.AlgEdDSA, 200*time.Millisecond, 250*time.Millisecond).WithPublishAhead(100 * time.Millisecond)
+	_, err := km.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+	svc := s.service(km)
+	old, err := svc.GenerateTokenPair(s.ctx, 5)
+	require.NoError(s.T(), err)
+	oldTok, _, _ := jwt.NewParser().ParseUnverified(old.AccessToken, &appauth.Claims{})
+
+	// следующий ключ выпускается за publishAhead до смены и сразу публикуется, но ещё не подписывает
+	time.Sleep(130 * time.Millisecond)
+	rotated, err := km.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+	require.True(s.T(), rotated)
+	rotated, err = km.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+	require.False(s.T(), rotated, "next key is already published")
+	require.Len(s.T(), s.jwks(km).Keys, 2)
+	early, err := svc.GenerateTokenPair(s.ctx, 5)
+	require.NoError(s.T(), err)
+	earlyTok, _, _ := jwt.NewParser().ParseUnverified(early.AccessToken, &appauth.Claims{})
+	require.Equal(s.T(), oldTok.Header["kid"], earlyTok.Header["kid"])
+
+	// в срок новый ключ начинает подписывать без нового Rotate
+	time.Sleep(100 * time.Millisecond)
+	fresh, err := svc.GenerateTokenPair(s.ctx, 5)
+	require.NoError(s.T(), err)
+	freshTok, _, _ := jwt.NewParser().ParseUnverified(fresh.AccessToken, &appauth.Claims{})
+	require.NotEqual(s.T(), oldTok.Header["kid"], freshTok.Header["kid"], "new tokens use the new key")
+	_, err = s.verifyWithJWKS(s.jwks(km), fresh.AccessToken)
+	require.NoError(s.T(), err)
+
+	// старый ключ ещё опубликован и принимается
+	require.Len(s.T(), s.jwks(km).Keys, 2)
+	_, err = svc.ParseAccessToken(s.ctx, old.AccessToken)
+	require.NoError(s.T(), err)
+
+	// через retainFor после смены старый ключ удаляется
+	time.Sleep(300 * time.Millisecond)
+	_, err = km.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+	set := s.jwks(km)
+	for _, k := range set.Keys {
+		require.NotEqual(s.T(), oldTok.Header["kid"], k.Kid)
+	}
+	_, err = svc.ParseAccessToken(s.ctx, old.AccessToken)
+	require.Error(s.T(), err)
+}
+
+func (s *KeysTestSuite) TestJWKSCachedNoLongerThanPublishAhead() {
+	w := httptest.NewRecorder()
+	handlers.NewJWKSHandler(nil).JWKS(w, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	require.Equal(s.T(), "public, max-age=300", w.Header().Get("Cache-Control"))
+}
+
+func (s *KeysTestSuite) TestOtherInstancePicksUpNewKey() {
+	first := s.keys(appauth.AlgRS256, time.Hour, time.Hour)
+	second := s.keys(appauth.AlgRS256, time.Hour, time.Hour)
+	_, err := first.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+
+	// второй экземпляр не выпускает свой ключ, а берёт уже выпущенный
+	rotated, err := second.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+	require.False(s.T(), rotated)
+	require.Equal(s.T(), s.jwks(first), s.jwks(second))
+
+	// ключ, выпущенный после последнего перечитывания, находится по kid
+	third := s.keys(appauth.AlgRS256, time.Hour, time.Hour)
+	tp, err := s.service(first).GenerateTokenPair(s.ctx, 5)
+	require.NoError(s.T(), err)
+	_, err = s.service(third).ParseAccessToken(s.ctx, tp.AccessToken)
+	require.NoError(s.T(), err)
+}
+
+func (s *KeysTestSuite) TestLegacyHS256TokensStillAccepted() {
+	legacy, err := appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour).GenerateTokenPair(s.ctx, 5)
+	require.NoError(s.T(), err)
+
+	km := s.keys(appauth.AlgRS256, time.Hour, time.Hour)
+	_, err = km.Rotate(s.ctx)
+	require.NoError(s.T(), err)
+	svc := s.service(km)
+
+	uid, err := svc.ValidateAccessToken(s.ctx, legacy.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int32(5), uid)
+	next, err := svc.Refresh(s.ctx, legacy.RefreshToken)
+	require.NoError(s.T(), err)
+	tok, _, _ := jwt.NewParser().ParseUnverified(next.AccessToken, &appauth.Claims{})
+	require.Equal(s.T(), "RS256", tok.Method.Alg(), "refreshed tokens are re-signed with the new key")
+
+	// токен HS256 с чужим kid не проверяется секретом
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &appauth.Claims{
+		UserID:           5,
+		TokenType:        "access",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	})
+	forged.Header["kid"] = s.jwks(km).Keys[0].Kid
+	str, err := forged.SignedString([]byte("acc"))
+	require.NoError(s.T(), err)
+	_, err = svc.ParseAccessToken(s.ctx, str)
+	require.Error(s.T(), err)
+}
+
+func (s *KeysTestSuite) TestUnsupportedAlgorithm() {
+	_, err := appauth.NewKeyManager(s.rdb, "none", time.Hour, time.Hour)
+	require.Error(s.T(), err)
+	require.Empty(s.T(), s.jwks(nil).Keys)
+}
+
+func TestKeysSuite(t *testing.T) {
+	suite.Run(t, new(KeysTestSuite))
+}
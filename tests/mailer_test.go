@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sqszy/TaskTracker/internal/mailer"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+// blockingMailer держит отправку, пока не закрыт release
+type blockingMailer struct {
+	release chan struct{}
+	sent    chan mailer.Message
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	<-m.release
+	m.sent <- msg
+	return nil
+}
+
+func TestAsyncMailerDoesNotWaitForDelivery(t *testing.T) {
+	next := &blockingMailer{release: make(chan struct{}), sent: make(chan mailer.Message, 1)}
+	async := mailer.NewAsync(next, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go async.Run(ctx)
+
+	// Send возвращается, хотя почтовый сервер ещё не ответил
+	require.NoError(t, async.Send(ctx, mailer.Message{To: "me@ex.com"}))
+	close(next.release)
+	select {
+	case msg := <-next.sent:
+		require.Equal(t, "me@ex.com", msg.To)
+	case <-time.After(time.Second):
+		t.Fatal("message was not delivered")
+	}
+}
+
+func TestAsyncMailerQueueFull(t *testing.T) {
+	async := mailer.NewAsync(&mocks.MockMailer{}, 1)
+	require.NoError(t, async.Send(context.Background(), mailer.Message{To: "a@ex.com"}))
+	require.ErrorIs(t, async.Send(context.Background(), mailer.Message{To: "b@ex.com"}), mailer.ErrQueueFull)
+}
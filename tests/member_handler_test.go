@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+type MemberTestSuite struct {
+	suite.Suite
+	mockQ   *mocks.MockQuerier
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	handler *handlers.MemberHandler
+	router  *chi.Mux
+	userID  int32
+	now     time.Time
+	ctx     context.Context
+}
+
+func (s *MemberTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.now = time.Now()
+	s.userID = 3
+
+	mr := miniredis.RunT(s.T())
+	s.redis = mr
+	s.rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+
+	s.mockQ = new(mocks.MockQuerier)
+	s.handler = handlers.NewMemberHandler(s.mockQ)
+
+	s.router = chi.NewRouter()
+	s.router.Use(middleware.AuthMiddleware(s.authSvc))
+	s.router.Get("/boards/{boardID}/members", s.handler.ListMembers)
+	s.router.Post("/boards/{boardID}/members", s.handler.AddMember)
+	s.router.Patch("/boards/{boardID}/members/{userID}", s.handler.UpdateMember)
+	s.router.Delete("/boards/{boardID}/members/{userID}", s.handler.RemoveMember)
+}
+
+func (s *MemberTestSuite) TearDownTest() {
+	if s.rdb != nil {
+		_ = s.rdb.Close()
+	}
+	if s.redis != nil {
+		s.redis.Close()
+	}
+}
+
+func (s *MemberTestSuite) do(method, path string, body interface{}) *httptest.ResponseRecorder {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(s.T(), json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *MemberTestSuite) TestListMembers() {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 1, UserID: s.userID}).
+		Return(authz.RoleViewer, nil)
+	s.mockQ.On("ListBoardMembers", mock.Anything, int32(1)).Return([]db.ListBoardMembersRow{
+		{BoardID: 1, UserID: 2, Email: "owner@ex.com", Role: authz.RoleOwner, CreatedAt: pgtype.Timestamp{Time: s.now, Valid: true}},
+		{BoardID: 1, UserID: s.userID, Email: "me@ex.com", Role: authz.RoleViewer, CreatedAt: pgtype.Timestamp{Time: s.now, Valid: true}},
+	}, nil)
+
+	w := s.do("GET", "/boards/1/members", nil)
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var resp []dto.BoardMemberDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(s.T(), resp, 2)
+	require.Equal(s.T(), "owner@ex.com", resp[0].Email)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *MemberTestSuite) TestAddMember() {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 1, UserID: s.userID}).
+		Return(authz.RoleOwner, nil)
+	s.mockQ.On("GetUserByEmail", mock.Anything, "mate@ex.com").
+		Return(db.GetUserByEmailRow{ID: 9, Email: "mate@ex.com"}, nil)
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 1, UserID: 9}).
+		Return("", pgx.ErrNoRows)
+	s.mockQ.On("AddBoardMember", mock.Anything, db.AddBoardMemberParams{BoardID: 1, UserID: 9, Role: authz.RoleEditor}).
+		Return(db.BoardMember{BoardID: 1, UserID: 9, Role: authz.RoleEditor}, nil)
+
+	w := s.do("POST", "/boards/1/members", dto.AddBoardMemberRequest{Email: " Mate@ex.com", Role: authz.RoleEditor})
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var got dto.BoardMemberDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(s.T(), int32(9), got.UserID)
+	require.Equal(s.T(), authz.RoleEditor, got.Role)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *MemberTestSuite) TestAddMemberByEditorForbidden() {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleEditor, nil)
+
+	w := s.do("POST", "/boards/1/members", dto.AddBoardMemberRequest{Email: "mate@ex.com", Role: authz.RoleViewer})
+
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "AddBoardMember", mock.Anything, mock.Anything)
+}
+
+func (s *MemberTestSuite) TestAddMemberInvalidRole() {
+	w := s.do("POST", "/boards/1/members", dto.AddBoardMemberRequest{Email: "mate@ex.com", Role: "admin"})
+
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+func (s *MemberTestSuite) TestDemoteLastOwner() {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 1, UserID: s.userID}).
+		Return(authz.RoleOwner, nil)
+	s.mockQ.On("LockBoardOwners", mock.Anything, int32(1)).Return([]int32{s.userID}, nil)
+
+	w := s.do("PATCH", "/boards/1/members/3", dto.UpdateBoardMemberRequest{Role: authz.RoleViewer})
+
+	require.Equal(s.T(), http.StatusConflict, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "UpdateBoardMemberRole", mock.Anything, mock.Anything)
+}
+
+func (s *MemberTestSuite) TestDemoteOwnerWhenAnotherRemains() {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 1, UserID: s.userID}).
+		Return(authz.RoleOwner, nil)
+	s.mockQ.On("LockBoardOwners", mock.Anything, int32(1)).Return([]int32{s.userID, 9}, nil).Once()
+	s.mockQ.On("UpdateBoardMemberRole", mock.Anything, db.UpdateBoardMemberRoleParams{BoardID: 1, UserID: 9, Role: authz.RoleEditor}).
+		Return(db.BoardMember{BoardID: 1, UserID: 9, Role: authz.RoleEditor}, nil).Once()
+
+	w := s.do("PATCH", "/boards/1/members/9", dto.UpdateBoardMemberRequest{Role: authz.RoleEditor})
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *MemberTestSuite) TestLastOwnerCannotLeave() {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 1, UserID: s.userID}).
+		Return(authz.RoleOwner, nil)
+	s.mockQ.On("LockBoardOwners", mock.Anything, int32(1)).Return([]int32{s.userID}, nil)
+
+	w := s.do("DELETE", "/boards/1/members/3", nil)
+
+	require.Equal(s.T(), http.StatusConflict, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "RemoveBoardMember", mock.Anything, mock.Anything)
+}
+
+func (s *MemberTestSuite) TestLeaveBoard() {
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 1, UserID: s.userID}).
+		Return(authz.RoleViewer, nil)
+	s.mockQ.On("LockBoardOwners", mock.Anything, int32(1)).Return([]int32{7}, nil)
+	s.mockQ.On("RemoveBoardMember", mock.Anything, db.RemoveBoardMemberParams{BoardID: 1, UserID: s.userID}).
+		Return(int64(1), nil)
+	s.mockQ.On("UnassignUserFromBoard", mock.Anything, db.UnassignUserFromBoardParams{
+		BoardID: pgtype.Int4{Int32: 1, Valid: true},
+		UserID:  s.userID,
+	}).Return(nil)
+
+	w := s.do("DELETE", "/boards/1/members/3", nil)
+
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func TestMemberSuite(t *testing.T) {
+	suite.Run(t, new(MemberTestSuite))
+}
@@ -0,0 +1,334 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/ratelimit"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+type MFATestSuite struct {
+	suite.Suite
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	mockQ   *mocks.MockQuerier
+	router  *chi.Mux
+	ctx     context.Context
+	secret  string
+}
+
+func (s *MFATestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+	s.mockQ = new(mocks.MockQuerier)
+	s.mockQ.On("CreateSecurityEvent", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	var err error
+	s.secret, err = appauth.GenerateTOTPSecret()
+	require.NoError(s.T(), err)
+
+	auth := handlers.NewAuthHandler(s.mockQ, s.authSvc, new(mocks.MockMailer), "http://app.local").WithPasswordHasher(testHasher)
+	h := handlers.NewMFAHandler(s.mockQ, s.authSvc).
+		WithCodeLockout(ratelimit.NewLockout(s.rdb, "mfa", ratelimit.DefaultLockoutPolicy))
+	s.router = chi.NewRouter()
+	s.router.Post("/login", auth.Login)
+	s.router.Post("/login/mfa", h.Login)
+	s.router.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(s.authSvc))
+		r.Get("/mfa", h.Status)
+		r.Post("/mfa/totp/enroll", h.EnrollTOTP)
+		r.Post("/mfa/totp/confirm", h.ConfirmTOTP)
+		r.Post("/mfa/totp/disable", h.DisableTOTP)
+		r.Post("/mfa/recovery-codes", h.RegenerateRecoveryCodes)
+	})
+}
+
+func (s *MFATestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+func (s *MFATestSuite) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *MFATestSuite) accessToken(userID int32) string {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, userID)
+	require.NoError(s.T(), err)
+	return tp.AccessToken
+}
+
+func (s *MFATestSuite) code() string {
+	code, err := appauth.TOTPCode(s.secret, time.Now())
+	require.NoError(s.T(), err)
+	return code
+}
+
+// wrongCode — код, не совпадающий ни с одним из допустимых интервалов
+func (s *MFATestSuite) wrongCode() string {
+	for _, c := range []string{"000000", "111111", "222222"} {
+		if _, ok := appauth.MatchTOTP(s.secret, c, time.Now()); !ok {
+			return c
+		}
+	}
+	s.T().Fatal("no wrong code")
+	return ""
+}
+
+func (s *MFATestSuite) enabledUser() db.GetUserTOTPRow {
+	return db.GetUserTOTPRow{
+		ID:            5,
+		Email:         "me@ex.com",
+		TotpSecret:    pgtype.Text{String: s.secret, Valid: true},
+		TotpEnabledAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}
+}
+
+// startLogin проходит первый шаг входа для пользователя с включённой 2FA
+func (s *MFATestSuite) startLogin() string {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").Return(db.GetUserByEmailRow{
+		ID:            5,
+		Email:         "me@ex.com",
+		Password:      hashPassword(s.T(), "secret-pass"),
+		TotpEnabledAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}, nil).Maybe()
+	s.mockQ.On("GetUserTOTP", mock.Anything, int32(5)).Return(s.enabledUser(), nil).Maybe()
+
+	w := s.do("POST", "/login", "", dto.LoginRequest{Email: "me@ex.com", Password: "secret-pass"})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(s.T(), true, resp["mfa_required"])
+	require.NotContains(s.T(), resp, "access_token")
+	return resp["mfa_token"].(string)
+}
+
+func (s *MFATestSuite) TestTOTPMatchesRFCVectors() {
+	// RFC 6238, приложение B: ключ "12345678901234567890", SHA1, последние 6 цифр
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for ts, want := range map[int64]string{59: "287082", 1111111109: "081804", 2000000000: "279037"} {
+		code, err := appauth.TOTPCode(secret, time.Unix(ts, 0))
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), want, code)
+	}
+	_, ok := appauth.MatchTOTP(secret, "287082", time.Unix(59+30, 0))
+	require.True(s.T(), ok, "previous step accepted")
+	_, ok = appauth.MatchTOTP(secret, "287082", time.Unix(59+90, 0))
+	require.False(s.T(), ok)
+}
+
+func (s *MFATestSuite) TestEnrollAndConfirm() {
+	var pending string
+	s.mockQ.On("GetUserTOTP", mock.Anything, int32(5)).Return(db.GetUserTOTPRow{ID: 5, Email: "me@ex.com"}, nil).Once()
+	s.mockQ.On("SetPendingTOTPSecret", mock.Anything, mock.MatchedBy(func(p db.SetPendingTOTPSecretParams) bool {
+		pending = p.TotpSecret.String
+		return p.ID == 5 && p.TotpSecret.Valid
+	})).Return(int64(1), nil).Once()
+
+	token := s.accessToken(5)
+	w := s.do("POST", "/mfa/totp/enroll", token, nil)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var enroll dto.TOTPEnrollmentDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &enroll))
+	require.Equal(s.T(), pending, enroll.Secret)
+
+	uri, err := url.Parse(enroll.OTPAuthURI)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "otpauth", uri.Scheme)
+	require.Equal(s.T(), "totp", uri.Host)
+	require.Equal(s.T(), "/TaskTracker:me@ex.com", uri.Path)
+	require.Equal(s.T(), enroll.Secret, uri.Query().Get("secret"))
+	require.Equal(s.T(), "TaskTracker", uri.Query().Get("issuer"))
+
+	s.secret = enroll.Secret
+	s.mockQ.On("GetUserTOTP", mock.Anything, int32(5)).Return(db.GetUserTOTPRow{
+		ID:         5,
+		Email:      "me@ex.com",
+		TotpSecret: pgtype.Text{String: enroll.Secret, Valid: true},
+	}, nil)
+
+	w = s.do("POST", "/mfa/totp/confirm", token, dto.MFACodeRequest{Code: s.wrongCode()})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	var stored []string
+	s.mockQ.On("EnableTOTP", mock.Anything, int32(5)).Return(int64(1), nil).Once()
+	s.mockQ.On("DeleteRecoveryCodes", mock.Anything, int32(5)).Return(nil).Once()
+	s.mockQ.On("CreateRecoveryCodes", mock.Anything, mock.MatchedBy(func(p db.CreateRecoveryCodesParams) bool {
+		stored = p.CodeHashes
+		return p.UserID == 5
+	})).Return(nil).Once()
+
+	w = s.do("POST", "/mfa/totp/confirm", token, dto.MFACodeRequest{Code: s.code()})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var codes dto.RecoveryCodesDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &codes))
+	require.Len(s.T(), codes.RecoveryCodes, appauth.RecoveryCodeCount)
+	require.Len(s.T(), stored, appauth.RecoveryCodeCount)
+	for i, c := range codes.RecoveryCodes {
+		require.Equal(s.T(), stored[i], appauth.HashRecoveryCode(c))
+		require.NotEqual(s.T(), c, stored[i], "only hashes are stored")
+	}
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *MFATestSuite) TestEnrollWhenAlreadyEnabled() {
+	s.mockQ.On("GetUserTOTP", mock.Anything, int32(5)).Return(s.enabledUser(), nil)
+	w := s.do("POST", "/mfa/totp/enroll", s.accessToken(5), nil)
+	require.Equal(s.T(), http.StatusConflict, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "SetPendingTOTPSecret", mock.Anything, mock.Anything)
+}
+
+func (s *MFATestSuite) TestTwoStepLogin() {
+	challenge := s.startLogin()
+
+	w := s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: challenge, Code: s.wrongCode()})
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+
+	code := s.code()
+	w = s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: challenge, Code: code})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var tp dto.LoginResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &tp))
+	uid, err := s.authSvc.ValidateAccessToken(s.ctx, tp.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int32(5), uid)
+
+	// токен второго шага одноразовый
+	w = s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: challenge, Code: code})
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+
+	// и код из приложения тоже нельзя предъявить повторно
+	w = s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: s.startLogin(), Code: code})
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (s *MFATestSuite) TestChallengeBurnsAfterTooManyAttempts() {
+	challenge := s.startLogin()
+	for range appauth.MaxMFAAttempts {
+		w := s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: challenge, Code: s.wrongCode()})
+		require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	}
+	w := s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: challenge, Code: s.code()})
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (s *MFATestSuite) TestFreshChallengeDoesNotResetCodeLimit() {
+	// новый токен второго шага выдаётся за верный пароль, поэтому предел считается на пользователя
+	for range ratelimit.DefaultLockoutPolicy.Threshold {
+		w := s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: s.startLogin(), Code: s.wrongCode()})
+		require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	}
+	w := s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: s.startLogin(), Code: s.code()})
+	require.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	require.Equal(s.T(), "60", w.Header().Get("Retry-After"))
+
+	// по истечении блокировки верный код принимается и сбрасывает счётчик
+	s.redis.FastForward(time.Minute)
+	w = s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: s.startLogin(), Code: s.code()})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: s.startLogin(), Code: s.wrongCode()})
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (s *MFATestSuite) TestCodeLimitSharedWithSessionEndpoints() {
+	s.mockQ.On("GetUserTOTP", mock.Anything, int32(5)).Return(s.enabledUser(), nil)
+	token := s.accessToken(5)
+
+	for range ratelimit.DefaultLockoutPolicy.Threshold - 1 {
+		w := s.do("POST", "/mfa/totp/disable", token, dto.MFACodeRequest{Code: s.wrongCode()})
+		require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	}
+	w := s.do("POST", "/mfa/recovery-codes", token, dto.MFACodeRequest{Code: s.wrongCode()})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	w = s.do("POST", "/mfa/totp/disable", token, dto.MFACodeRequest{Code: s.code()})
+	require.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	w = s.do("POST", "/mfa/recovery-codes", token, dto.MFACodeRequest{Code: s.code()})
+	require.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	w = s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: s.startLogin(), Code: s.code()})
+	require.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "DisableTOTP", mock.Anything, mock.Anything)
+}
+
+func (s *MFATestSuite) TestChallengeExpires() {
+	challenge := s.startLogin()
+	s.redis.FastForward(appauth.MFAChallengeTTL + time.Second)
+	w := s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: challenge, Code: s.code()})
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (s *MFATestSuite) TestLoginWithRecoveryCode() {
+	hash := appauth.HashRecoveryCode("abcd-efgh")
+	s.mockQ.On("UseRecoveryCode", mock.Anything, db.UseRecoveryCodeParams{UserID: 5, CodeHash: hash}).Return(int64(1), nil).Once()
+	s.mockQ.On("UseRecoveryCode", mock.Anything, db.UseRecoveryCodeParams{UserID: 5, CodeHash: hash}).Return(int64(0), nil)
+	s.mockQ.On("CreateSecurityEvent", mock.Anything, mock.MatchedBy(func(p db.CreateSecurityEventParams) bool {
+		return p.UserID == 5 && p.Event == "recovery_code_used"
+	})).Return(nil).Once()
+
+	// регистр и пробелы не важны
+	w := s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: s.startLogin(), Code: " ABCD EFGH "})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	w = s.do("POST", "/login/mfa", "", dto.MFALoginRequest{MFAToken: s.startLogin(), Code: "abcd-efgh"})
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (s *MFATestSuite) TestDisableTOTP() {
+	s.mockQ.On("GetUserTOTP", mock.Anything, int32(5)).Return(s.enabledUser(), nil)
+	token := s.accessToken(5)
+
+	w := s.do("POST", "/mfa/totp/disable", token, dto.MFACodeRequest{Code: s.wrongCode()})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	s.mockQ.On("DisableTOTP", mock.Anything, int32(5)).Return(nil).Once()
+	s.mockQ.On("DeleteRecoveryCodes", mock.Anything, int32(5)).Return(nil).Once()
+	w = s.do("POST", "/mfa/totp/disable", token, dto.MFACodeRequest{Code: s.code()})
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *MFATestSuite) TestStatus() {
+	s.mockQ.On("GetUserTOTP", mock.Anything, int32(5)).Return(s.enabledUser(), nil)
+	s.mockQ.On("CountUnusedRecoveryCodes", mock.Anything, int32(5)).Return(int64(7), nil)
+
+	w := s.do("GET", "/mfa", s.accessToken(5), nil)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var status dto.MFAStatusDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &status))
+	require.True(s.T(), status.TOTPEnabled)
+	require.Equal(s.T(), int64(7), status.RecoveryCodesLeft)
+}
+
+func TestMFASuite(t *testing.T) {
+	suite.Run(t, new(MFATestSuite))
+}
@@ -0,0 +1,34 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sqszy/TaskTracker/internal/mailer"
+)
+
+// MockMailer запоминает отправленные письма
+type MockMailer struct {
+	mu   sync.Mutex
+	Sent []mailer.Message
+	Err  error
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Sent = append(m.Sent, msg)
+	return nil
+}
+
+func (m *MockMailer) Last() mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Sent) == 0 {
+		return mailer.Message{}
+	}
+	return m.Sent[len(m.Sent)-1]
+}
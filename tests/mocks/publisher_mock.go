@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sqszy/TaskTracker/internal/realtime"
+)
+
+// MockPublisher запоминает опубликованные события
+type MockPublisher struct {
+	mu     sync.Mutex
+	Events []realtime.Event
+	Err    error
+}
+
+func (m *MockPublisher) Publish(ctx context.Context, ev realtime.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Events = append(m.Events, ev)
+	return nil
+}
+
+func (m *MockPublisher) Types() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	types := make([]string, 0, len(m.Events))
+	for _, ev := range m.Events {
+		types = append(types, ev.Type)
+	}
+	return types
+}
@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sqszy/TaskTracker/internal/db"
 	"github.com/stretchr/testify/mock"
 )
@@ -11,14 +12,19 @@ type MockQuerier struct {
 	mock.Mock
 }
 
+// ExecTx выполняет fn без реальной транзакции, чтобы мок подходил под db.Store
+func (m *MockQuerier) ExecTx(ctx context.Context, fn func(db.Querier) error) error {
+	return fn(m)
+}
+
 func (m *MockQuerier) CreateBoard(ctx context.Context, arg db.CreateBoardParams) (db.Board, error) {
 	args := m.Called(ctx, arg)
 	return args.Get(0).(db.Board), args.Error(1)
 }
 
-func (m *MockQuerier) GetBoards(ctx context.Context, userID int32) ([]db.Board, error) {
+func (m *MockQuerier) GetBoards(ctx context.Context, userID int32) ([]db.GetBoardsRow, error) {
 	args := m.Called(ctx, userID)
-	return args.Get(0).([]db.Board), args.Error(1)
+	return args.Get(0).([]db.GetBoardsRow), args.Error(1)
 }
 
 func (m *MockQuerier) UpdateBoard(ctx context.Context, arg db.UpdateBoardParams) (db.Board, error) {
@@ -26,11 +32,76 @@ func (m *MockQuerier) UpdateBoard(ctx context.Context, arg db.UpdateBoardParams)
 	return args.Get(0).(db.Board), args.Error(1)
 }
 
-func (m *MockQuerier) DeleteBoard(ctx context.Context, arg db.DeleteBoardParams) (int64, error) {
+func (m *MockQuerier) DeleteBoard(ctx context.Context, id int32) (int64, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) GetBoardMemberRole(ctx context.Context, arg db.GetBoardMemberRoleParams) (string, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(string), args.Error(1)
+}
+
+func (m *MockQuerier) GetTaskMemberRole(ctx context.Context, arg db.GetTaskMemberRoleParams) (string, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(string), args.Error(1)
+}
+
+func (m *MockQuerier) ListBoardMembers(ctx context.Context, boardID int32) ([]db.ListBoardMembersRow, error) {
+	args := m.Called(ctx, boardID)
+	return args.Get(0).([]db.ListBoardMembersRow), args.Error(1)
+}
+
+func (m *MockQuerier) AddBoardMember(ctx context.Context, arg db.AddBoardMemberParams) (db.BoardMember, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.BoardMember), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateBoardMemberRole(ctx context.Context, arg db.UpdateBoardMemberRoleParams) (db.BoardMember, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.BoardMember), args.Error(1)
+}
+
+func (m *MockQuerier) RemoveBoardMember(ctx context.Context, arg db.RemoveBoardMemberParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) LockBoardOwners(ctx context.Context, boardID int32) ([]int32, error) {
+	args := m.Called(ctx, boardID)
+	return args.Get(0).([]int32), args.Error(1)
+}
+
+func (m *MockQuerier) CreateInvitation(ctx context.Context, arg db.CreateInvitationParams) (db.BoardInvitation, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.BoardInvitation), args.Error(1)
+}
+
+func (m *MockQuerier) ListBoardInvitations(ctx context.Context, boardID int32) ([]db.BoardInvitation, error) {
+	args := m.Called(ctx, boardID)
+	return args.Get(0).([]db.BoardInvitation), args.Error(1)
+}
+
+func (m *MockQuerier) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (db.BoardInvitation, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(db.BoardInvitation), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateInvitationStatus(ctx context.Context, arg db.UpdateInvitationStatusParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) RevokeInvitation(ctx context.Context, arg db.RevokeInvitationParams) (int64, error) {
 	args := m.Called(ctx, arg)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockQuerier) AcceptPendingInvitations(ctx context.Context, arg db.AcceptPendingInvitationsParams) ([]db.BoardMember, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.BoardMember), args.Error(1)
+}
+
 func (m *MockQuerier) CreateTask(ctx context.Context, arg db.CreateTaskParams) (db.CreateTaskRow, error) {
 	args := m.Called(ctx, arg)
 	return args.Get(0).(db.CreateTaskRow), args.Error(1)
@@ -41,6 +112,11 @@ func (m *MockQuerier) GetTasks(ctx context.Context, arg db.GetTasksParams) ([]db
 	return args.Get(0).([]db.GetTasksRow), args.Error(1)
 }
 
+func (m *MockQuerier) GetAssignedTasks(ctx context.Context, arg db.GetAssignedTasksParams) ([]db.GetAssignedTasksRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.GetAssignedTasksRow), args.Error(1)
+}
+
 func (m *MockQuerier) UpdateTask(ctx context.Context, arg db.UpdateTaskParams) (db.Task, error) {
 	args := m.Called(ctx, arg)
 	return args.Get(0).(db.Task), args.Error(1)
@@ -51,6 +127,186 @@ func (m *MockQuerier) DeleteTask(ctx context.Context, arg db.DeleteTaskParams) (
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockQuerier) SetTaskAssignees(ctx context.Context, arg db.SetTaskAssigneesParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListTaskAssignees(ctx context.Context, taskID int32) ([]int32, error) {
+	args := m.Called(ctx, taskID)
+	return args.Get(0).([]int32), args.Error(1)
+}
+
+func (m *MockQuerier) CountBoardMembersByIDs(ctx context.Context, arg db.CountBoardMembersByIDsParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) UnassignUserFromBoard(ctx context.Context, arg db.UnassignUserFromBoardParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CreateComment(ctx context.Context, arg db.CreateCommentParams) (db.TaskComment, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.TaskComment), args.Error(1)
+}
+
+func (m *MockQuerier) GetComment(ctx context.Context, arg db.GetCommentParams) (db.TaskComment, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.TaskComment), args.Error(1)
+}
+
+func (m *MockQuerier) ListComments(ctx context.Context, arg db.ListCommentsParams) ([]db.TaskComment, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.TaskComment), args.Error(1)
+}
+
+func (m *MockQuerier) CountComments(ctx context.Context, taskID int32) (int64, error) {
+	args := m.Called(ctx, taskID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) ListCommentReplies(ctx context.Context, parentIds []int32) ([]db.TaskComment, error) {
+	args := m.Called(ctx, parentIds)
+	return args.Get(0).([]db.TaskComment), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateComment(ctx context.Context, arg db.UpdateCommentParams) (db.TaskComment, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.TaskComment), args.Error(1)
+}
+
+func (m *MockQuerier) SoftDeleteComment(ctx context.Context, arg db.SoftDeleteCommentParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) ListCommentEdits(ctx context.Context, commentID int32) ([]db.TaskCommentEdit, error) {
+	args := m.Called(ctx, commentID)
+	return args.Get(0).([]db.TaskCommentEdit), args.Error(1)
+}
+
+func (m *MockQuerier) GetBoardForUpdate(ctx context.Context, id int32) (db.Board, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(db.Board), args.Error(1)
+}
+
+func (m *MockQuerier) GetTaskForUpdate(ctx context.Context, arg db.GetTaskForUpdateParams) (db.Task, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Task), args.Error(1)
+}
+
+func (m *MockQuerier) CreateActivityEvent(ctx context.Context, arg db.CreateActivityEventParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListTaskActivity(ctx context.Context, arg db.ListTaskActivityParams) ([]db.ActivityEvent, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.ActivityEvent), args.Error(1)
+}
+
+func (m *MockQuerier) ListBoardActivity(ctx context.Context, arg db.ListBoardActivityParams) ([]db.ActivityEvent, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.ActivityEvent), args.Error(1)
+}
+
+func (m *MockQuerier) CreateDefaultColumns(ctx context.Context, boardID int32) ([]db.BoardColumn, error) {
+	args := m.Called(ctx, boardID)
+	return args.Get(0).([]db.BoardColumn), args.Error(1)
+}
+
+func (m *MockQuerier) ListBoardColumns(ctx context.Context, boardID int32) ([]db.BoardColumn, error) {
+	args := m.Called(ctx, boardID)
+	return args.Get(0).([]db.BoardColumn), args.Error(1)
+}
+
+func (m *MockQuerier) GetBoardColumn(ctx context.Context, arg db.GetBoardColumnParams) (db.BoardColumn, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.BoardColumn), args.Error(1)
+}
+
+func (m *MockQuerier) GetBoardColumnByName(ctx context.Context, arg db.GetBoardColumnByNameParams) (db.BoardColumn, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.BoardColumn), args.Error(1)
+}
+
+func (m *MockQuerier) GetFirstBoardColumn(ctx context.Context, boardID int32) (db.BoardColumn, error) {
+	args := m.Called(ctx, boardID)
+	return args.Get(0).(db.BoardColumn), args.Error(1)
+}
+
+func (m *MockQuerier) GetColumnWIP(ctx context.Context, arg db.GetColumnWIPParams) (db.GetColumnWIPRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.GetColumnWIPRow), args.Error(1)
+}
+
+func (m *MockQuerier) CreateBoardColumn(ctx context.Context, arg db.CreateBoardColumnParams) (db.BoardColumn, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.BoardColumn), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateBoardColumn(ctx context.Context, arg db.UpdateBoardColumnParams) (db.BoardColumn, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.BoardColumn), args.Error(1)
+}
+
+func (m *MockQuerier) ReorderBoardColumns(ctx context.Context, arg db.ReorderBoardColumnsParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteBoardColumn(ctx context.Context, arg db.DeleteBoardColumnParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CountColumnTasks(ctx context.Context, columnID pgtype.Int4) (int64, error) {
+	args := m.Called(ctx, columnID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) SyncColumnStatus(ctx context.Context, arg db.SyncColumnStatusParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) MoveTask(ctx context.Context, arg db.MoveTaskParams) (db.Task, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Task), args.Error(1)
+}
+
+func (m *MockQuerier) GetTaskRank(ctx context.Context, arg db.GetTaskRankParams) (db.GetTaskRankRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.GetTaskRankRow), args.Error(1)
+}
+
+func (m *MockQuerier) GetNextRank(ctx context.Context, arg db.GetNextRankParams) (string, error) {
+	args := m.Called(ctx, arg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockQuerier) GetPrevRank(ctx context.Context, arg db.GetPrevRankParams) (string, error) {
+	args := m.Called(ctx, arg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockQuerier) GetLastRank(ctx context.Context, arg db.GetLastRankParams) (string, error) {
+	args := m.Called(ctx, arg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockQuerier) ListColumnRanks(ctx context.Context, arg db.ListColumnRanksParams) ([]db.ListColumnRanksRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.ListColumnRanksRow), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateTaskRanks(ctx context.Context, arg db.UpdateTaskRanksParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
 func (m *MockQuerier) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.CreateUserRow, error) {
 	args := m.Called(ctx, arg)
 	return args.Get(0).(db.CreateUserRow), args.Error(1)
@@ -60,3 +316,208 @@ func (m *MockQuerier) GetUserByEmail(ctx context.Context, email string) (db.GetU
 	args := m.Called(ctx, email)
 	return args.Get(0).(db.GetUserByEmailRow), args.Error(1)
 }
+
+func (m *MockQuerier) GetUserByID(ctx context.Context, id int32) (db.GetUserByIDRow, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(db.GetUserByIDRow), args.Error(1)
+}
+
+func (m *MockQuerier) GetUserPassword(ctx context.Context, id int32) (db.GetUserPasswordRow, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(db.GetUserPasswordRow), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateUserPassword(ctx context.Context, arg db.UpdateUserPasswordParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) RehashUserPassword(ctx context.Context, arg db.RehashUserPasswordParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) MarkEmailVerified(ctx context.Context, id int32) (int64, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) GetUserProfile(ctx context.Context, id int32) (db.GetUserProfileRow, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(db.GetUserProfileRow), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateUserProfile(ctx context.Context, arg db.UpdateUserProfileParams) (db.UpdateUserProfileRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.UpdateUserProfileRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListUserSummaries(ctx context.Context, ids []int32) ([]db.ListUserSummariesRow, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]db.ListUserSummariesRow), args.Error(1)
+}
+
+func (m *MockQuerier) UpsertUserAvatar(ctx context.Context, arg db.UpsertUserAvatarParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetUserAvatar(ctx context.Context, userID int32) (db.UserAvatar, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(db.UserAvatar), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteUserAvatar(ctx context.Context, userID int32) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateSecurityEvent(ctx context.Context, arg db.CreateSecurityEventParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetUserTOTP(ctx context.Context, id int32) (db.GetUserTOTPRow, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(db.GetUserTOTPRow), args.Error(1)
+}
+
+func (m *MockQuerier) SetPendingTOTPSecret(ctx context.Context, arg db.SetPendingTOTPSecretParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) EnableTOTP(ctx context.Context, id int32) (int64, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DisableTOTP(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) CreateRecoveryCodes(ctx context.Context, arg db.CreateRecoveryCodesParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteRecoveryCodes(ctx context.Context, userID int32) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) UseRecoveryCode(ctx context.Context, arg db.UseRecoveryCodeParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CountUnusedRecoveryCodes(ctx context.Context, userID int32) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreatePersonalAccessToken(ctx context.Context, arg db.CreatePersonalAccessTokenParams) (db.PersonalAccessToken, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockQuerier) ListPersonalAccessTokens(ctx context.Context, userID int32) ([]db.PersonalAccessToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]db.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockQuerier) GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (db.PersonalAccessToken, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(db.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockQuerier) TouchPersonalAccessToken(ctx context.Context, id int32) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeletePersonalAccessToken(ctx context.Context, arg db.DeletePersonalAccessTokenParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateUserIdentity(ctx context.Context, arg db.CreateUserIdentityParams) (db.UserIdentity, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.UserIdentity), args.Error(1)
+}
+
+func (m *MockQuerier) GetUserIdentity(ctx context.Context, arg db.GetUserIdentityParams) (db.UserIdentity, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.UserIdentity), args.Error(1)
+}
+
+func (m *MockQuerier) ListUserIdentities(ctx context.Context, userID int32) ([]db.UserIdentity, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]db.UserIdentity), args.Error(1)
+}
+
+func (m *MockQuerier) CreateWebhook(ctx context.Context, arg db.CreateWebhookParams) (db.Webhook, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Webhook), args.Error(1)
+}
+
+func (m *MockQuerier) ListWebhooks(ctx context.Context, boardID int32) ([]db.Webhook, error) {
+	args := m.Called(ctx, boardID)
+	return args.Get(0).([]db.Webhook), args.Error(1)
+}
+
+func (m *MockQuerier) GetWebhook(ctx context.Context, arg db.GetWebhookParams) (db.Webhook, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Webhook), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateWebhook(ctx context.Context, arg db.UpdateWebhookParams) (db.Webhook, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Webhook), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteWebhook(ctx context.Context, arg db.DeleteWebhookParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) EnqueueWebhookDeliveries(ctx context.Context, arg db.EnqueueWebhookDeliveriesParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ClaimDueWebhookDeliveries(ctx context.Context, arg db.ClaimDueWebhookDeliveriesParams) ([]db.ClaimDueWebhookDeliveriesRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.ClaimDueWebhookDeliveriesRow), args.Error(1)
+}
+
+func (m *MockQuerier) CreateWebhookAttempt(ctx context.Context, arg db.CreateWebhookAttemptParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) UpdateWebhookDeliveryResult(ctx context.Context, arg db.UpdateWebhookDeliveryResultParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListWebhookDeliveries(ctx context.Context, arg db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockQuerier) GetWebhookDelivery(ctx context.Context, arg db.GetWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockQuerier) ListWebhookAttempts(ctx context.Context, deliveryID int32) ([]db.WebhookAttempt, error) {
+	args := m.Called(ctx, deliveryID)
+	return args.Get(0).([]db.WebhookAttempt), args.Error(1)
+}
+
+func (m *MockQuerier) RedeliverWebhookDelivery(ctx context.Context, arg db.RedeliverWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.WebhookDelivery), args.Error(1)
+}
@@ -0,0 +1,523 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/oidc"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+const (
+	idpClientID     = "tasktracker"
+	idpClientSecret = "s3cret"
+	idpRedirectURL  = "http://app.local/oidc/callback"
+)
+
+// fakeIdP — провайдер OpenID Connect на httptest: discovery, JWKS и token endpoint с проверкой PKCE
+type fakeIdP struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+	kid string
+	// issuer в discovery; пусто — адрес сервера
+	issuer string
+	// tamper правит claims ID token перед подписью
+	tamper func(jwt.MapClaims)
+	// signWith — чужой ключ вместо опубликованного
+	signWith *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]idpGrant
+}
+
+type idpGrant struct {
+	challenge string
+	redirect  string
+	claims    jwt.MapClaims
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &fakeIdP{t: t, key: key, kid: "idp-key-1", codes: map[string]idpGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := idp.issuer
+		if issuer == "" {
+			issuer = idp.srv.URL
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": idp.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func (idp *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	fail := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != idpClientID || secret != idpClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		fail("unsupported_grant_type")
+		return
+	}
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.PostFormValue("code")]
+	delete(idp.codes, r.PostFormValue("code"))
+	idp.mu.Unlock()
+	if !ok || grant.redirect != r.PostFormValue("redirect_uri") {
+		fail("invalid_grant")
+		return
+	}
+	if oidc.Challenge(r.PostFormValue("code_verifier")) != grant.challenge {
+		fail("invalid_grant")
+		return
+	}
+
+	claims := grant.claims
+	if idp.tamper != nil {
+		idp.tamper(claims)
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = idp.kid
+	key := idp.key
+	if idp.signWith != nil {
+		key = idp.signWith
+	}
+	signed, err := tok.SignedString(key)
+	require.NoError(idp.t, err)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "idp-access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+// authorize — пользователь вошёл у провайдера и подтвердил доступ; возвращает state и code для фронта
+func (idp *fakeIdP) authorize(authURL, sub, email string, verified bool) (state, code string) {
+	u, err := url.Parse(authURL)
+	require.NoError(idp.t, err)
+	require.Equal(idp.t, idp.srv.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	q := u.Query()
+	require.Equal(idp.t, "code", q.Get("response_type"))
+	require.Equal(idp.t, idpClientID, q.Get("client_id"))
+	require.Equal(idp.t, "S256", q.Get("code_challenge_method"))
+	require.Contains(idp.t, q.Get("scope"), "openid")
+	require.NotEmpty(idp.t, q.Get("nonce"))
+
+	code = "code-" + q.Get("state")[:8]
+	now := time.Now()
+	idp.mu.Lock()
+	idp.codes[code] = idpGrant{
+		challenge: q.Get("code_challenge"),
+		redirect:  q.Get("redirect_uri"),
+		claims: jwt.MapClaims{
+			"iss":            idp.srv.URL,
+			"aud":            idpClientID,
+			"sub":            sub,
+			"email":          email,
+			"email_verified": verified,
+			"nonce":          q.Get("nonce"),
+			"iat":            now.Unix(),
+			"exp":            now.Add(5 * time.Minute).Unix(),
+		},
+	}
+	idp.mu.Unlock()
+	return q.Get("state"), code
+}
+
+type OIDCTestSuite struct {
+	suite.Suite
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	mockQ   *mocks.MockQuerier
+	idp     *fakeIdP
+	router  *chi.Mux
+	ctx     context.Context
+	// stateCookie — cookie из последнего /start; do прикладывает её, как браузер
+	stateCookie *http.Cookie
+}
+
+func (s *OIDCTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+	s.mockQ = new(mocks.MockQuerier)
+	s.idp = newFakeIdP(s.T())
+
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "corp",
+		Issuer:       s.idp.srv.URL,
+		ClientID:     idpClientID,
+		ClientSecret: idpClientSecret,
+		RedirectURL:  idpRedirectURL,
+	}, s.idp.srv.Client())
+	h := handlers.NewOIDCHandler(s.mockQ, s.authSvc, provider)
+
+	s.router = chi.NewRouter()
+	s.router.Get("/oidc/{provider}/start", h.Start)
+	s.router.Post("/oidc/callback", h.Callback)
+	s.router.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(s.authSvc))
+		r.Post("/oidc/{provider}/link", h.Link)
+		r.Get("/identities", h.ListIdentities)
+	})
+}
+
+func (s *OIDCTestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+func (s *OIDCTestSuite) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if s.stateCookie != nil {
+		req.AddCookie(s.stateCookie)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *OIDCTestSuite) start(method, path, token string) string {
+	w := s.do(method, path, token, nil)
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	cookies := w.Result().Cookies()
+	require.Len(s.T(), cookies, 1)
+	require.Equal(s.T(), "oidc_state", cookies[0].Name)
+	require.True(s.T(), cookies[0].HttpOnly)
+	s.stateCookie = cookies[0]
+	var resp dto.OIDCStartResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.AuthorizationURL
+}
+
+// loginVia проходит весь путь: старт на API, вход у провайдера, возврат кода на API
+func (s *OIDCTestSuite) loginVia(sub, email string, verified bool) *httptest.ResponseRecorder {
+	state, code := s.idp.authorize(s.start("GET", "/oidc/corp/start", ""), sub, email, verified)
+	return s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: state, Code: code})
+}
+
+func (s *OIDCTestSuite) requireTokensFor(w *httptest.ResponseRecorder, userID int32) {
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	var resp dto.LoginResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	uid, err := s.authSvc.ValidateAccessToken(s.ctx, resp.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userID, uid)
+	require.NotEmpty(s.T(), resp.RefreshToken)
+}
+
+func (s *OIDCTestSuite) noIdentity(sub string) {
+	s.mockQ.On("GetUserIdentity", mock.Anything, db.GetUserIdentityParams{Provider: "corp", Subject: sub}).
+		Return(db.UserIdentity{}, pgx.ErrNoRows)
+}
+
+func (s *OIDCTestSuite) TestNewUserSignsUp() {
+	s.noIdentity("sub-1")
+	s.mockQ.On("GetUserByEmail", mock.Anything, "new@corp.com").Return(db.GetUserByEmailRow{}, pgx.ErrNoRows)
+	s.mockQ.On("CreateUser", mock.Anything, db.CreateUserParams{Email: "new@corp.com", Password: ""}).
+		Return(db.CreateUserRow{ID: 21, Email: "new@corp.com"}, nil).Once()
+	s.mockQ.On("MarkEmailVerified", mock.Anything, int32(21)).Return(int64(1), nil).Once()
+	s.mockQ.On("AcceptPendingInvitations", mock.Anything, db.AcceptPendingInvitationsParams{Email: "new@corp.com", UserID: 21}).
+		Return([]db.BoardMember{}, nil)
+	s.mockQ.On("CreateUserIdentity", mock.Anything, db.CreateUserIdentityParams{
+		UserID:   21,
+		Provider: "corp",
+		Subject:  "sub-1",
+		Email:    pgtype.Text{String: "new@corp.com", Valid: true},
+	}).Return(db.UserIdentity{ID: 1, UserID: 21}, nil).Once()
+	s.mockQ.On("GetUserByID", mock.Anything, int32(21)).Return(db.GetUserByIDRow{
+		ID:              21,
+		Email:           "new@corp.com",
+		EmailVerifiedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}, nil)
+
+	// email от провайдера приводится к нижнему регистру
+	s.requireTokensFor(s.loginVia("sub-1", "New@Corp.com", true), 21)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *OIDCTestSuite) TestUnverifiedNewUserWaitsForInvitations() {
+	s.noIdentity("sub-8")
+	s.mockQ.On("GetUserByEmail", mock.Anything, "new@corp.com").Return(db.GetUserByEmailRow{}, pgx.ErrNoRows)
+	s.mockQ.On("CreateUser", mock.Anything, mock.Anything).Return(db.CreateUserRow{ID: 22, Email: "new@corp.com"}, nil).Once()
+	s.mockQ.On("CreateUserIdentity", mock.Anything, mock.Anything).Return(db.UserIdentity{ID: 5, UserID: 22}, nil).Once()
+	s.mockQ.On("GetUserByID", mock.Anything, int32(22)).Return(db.GetUserByIDRow{ID: 22, Email: "new@corp.com"}, nil)
+
+	s.requireTokensFor(s.loginVia("sub-8", "new@corp.com", false), 22)
+	s.mockQ.AssertNotCalled(s.T(), "MarkEmailVerified", mock.Anything, mock.Anything)
+	s.mockQ.AssertNotCalled(s.T(), "AcceptPendingInvitations", mock.Anything, mock.Anything)
+}
+
+func (s *OIDCTestSuite) TestKnownIdentityLogsIn() {
+	s.mockQ.On("GetUserIdentity", mock.Anything, db.GetUserIdentityParams{Provider: "corp", Subject: "sub-7"}).
+		Return(db.UserIdentity{ID: 3, UserID: 7, Provider: "corp", Subject: "sub-7"}, nil)
+	s.mockQ.On("GetUserByID", mock.Anything, int32(7)).Return(db.GetUserByIDRow{ID: 7, Email: "me@corp.com"}, nil)
+
+	// email у провайдера мог смениться — аккаунт находится по subject
+	s.requireTokensFor(s.loginVia("sub-7", "renamed@corp.com", true), 7)
+	s.mockQ.AssertNotCalled(s.T(), "GetUserByEmail", mock.Anything, mock.Anything)
+	s.mockQ.AssertNotCalled(s.T(), "CreateUser", mock.Anything, mock.Anything)
+}
+
+func (s *OIDCTestSuite) TestVerifiedEmailLinksExistingAccount() {
+	s.noIdentity("sub-2")
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@corp.com").Return(db.GetUserByEmailRow{
+		ID:              7,
+		Email:           "me@corp.com",
+		EmailVerifiedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}, nil)
+	s.mockQ.On("CreateUserIdentity", mock.Anything, mock.MatchedBy(func(p db.CreateUserIdentityParams) bool {
+		return p.UserID == 7 && p.Subject == "sub-2"
+	})).Return(db.UserIdentity{ID: 2, UserID: 7}, nil).Once()
+	s.mockQ.On("CreateSecurityEvent", mock.Anything, mock.MatchedBy(func(p db.CreateSecurityEventParams) bool {
+		return p.UserID == 7 && p.Event == "identity_linked"
+	})).Return(nil).Once()
+	s.mockQ.On("GetUserByID", mock.Anything, int32(7)).Return(db.GetUserByIDRow{ID: 7, Email: "me@corp.com"}, nil)
+
+	s.requireTokensFor(s.loginVia("sub-2", "me@corp.com", true), 7)
+	s.mockQ.AssertExpectations(s.T())
+	s.mockQ.AssertNotCalled(s.T(), "CreateUser", mock.Anything, mock.Anything)
+}
+
+func (s *OIDCTestSuite) TestUnverifiedEmailDoesNotTakeOverAccount() {
+	s.noIdentity("sub-3")
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@corp.com").Return(db.GetUserByEmailRow{ID: 7, Email: "me@corp.com"}, nil)
+
+	w := s.loginVia("sub-3", "me@corp.com", false)
+	require.Equal(s.T(), http.StatusConflict, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "CreateUserIdentity", mock.Anything, mock.Anything)
+}
+
+func (s *OIDCTestSuite) TestUnverifiedLocalAccountIsNotLinked() {
+	// кто-то зарегистрировал адрес с паролем, но не подтвердил его
+	s.noIdentity("sub-4")
+	s.mockQ.On("GetUserByEmail", mock.Anything, "victim@corp.com").
+		Return(db.GetUserByEmailRow{ID: 9, Email: "victim@corp.com", Password: "squatter-hash"}, nil)
+
+	w := s.loginVia("sub-4", "victim@corp.com", true)
+	require.Equal(s.T(), http.StatusConflict, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "CreateUserIdentity", mock.Anything, mock.Anything)
+	s.mockQ.AssertNotCalled(s.T(), "CreateUser", mock.Anything, mock.Anything)
+}
+
+func (s *OIDCTestSuite) TestMFAStillRequired() {
+	s.mockQ.On("GetUserIdentity", mock.Anything, mock.Anything).Return(db.UserIdentity{UserID: 7}, nil)
+	s.mockQ.On("GetUserByID", mock.Anything, int32(7)).Return(db.GetUserByIDRow{
+		ID:            7,
+		Email:         "me@corp.com",
+		TotpEnabledAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}, nil)
+
+	w := s.loginVia("sub-7", "me@corp.com", true)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var resp dto.MFAChallengeResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.True(s.T(), resp.MFARequired)
+	require.NotEmpty(s.T(), resp.MFAToken)
+	require.NotContains(s.T(), w.Body.String(), "access_token")
+}
+
+func (s *OIDCTestSuite) TestStateIsSingleUse() {
+	s.mockQ.On("GetUserIdentity", mock.Anything, mock.Anything).Return(db.UserIdentity{UserID: 7}, nil)
+	s.mockQ.On("GetUserByID", mock.Anything, int32(7)).Return(db.GetUserByIDRow{ID: 7, Email: "me@corp.com"}, nil)
+
+	state, code := s.idp.authorize(s.start("GET", "/oidc/corp/start", ""), "sub-7", "me@corp.com", true)
+	s.requireTokensFor(s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: state, Code: code}), 7)
+	w := s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: state, Code: code})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	w = s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: "forged", Code: code})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+func (s *OIDCTestSuite) TestStateBoundToBrowser() {
+	s.mockQ.On("GetUserIdentity", mock.Anything, mock.Anything).Return(db.UserIdentity{UserID: 7}, nil)
+	s.mockQ.On("GetUserByID", mock.Anything, int32(7)).Return(db.GetUserByIDRow{ID: 7, Email: "me@corp.com"}, nil)
+
+	// злоумышленник прошёл вход у провайдера и подсовывает свои code и state другому браузеру
+	state, code := s.idp.authorize(s.start("GET", "/oidc/corp/start", ""), "sub-7", "me@corp.com", true)
+	own := s.stateCookie
+	s.stateCookie = nil
+	w := s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: state, Code: code})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	s.stateCookie = &http.Cookie{Name: "oidc_state", Value: appauth.HashToken("another-state")}
+	w = s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: state, Code: code})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "GetUserIdentity", mock.Anything, mock.Anything)
+
+	// отклонённые попытки не сжигают state: браузер, начавший вход, его завершает
+	s.stateCookie = own
+	w = s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: state, Code: code})
+	s.requireTokensFor(w, 7)
+	var cleared bool
+	for _, c := range w.Result().Cookies() {
+		cleared = cleared || (c.Name == "oidc_state" && c.MaxAge < 0)
+	}
+	require.True(s.T(), cleared)
+}
+
+func (s *OIDCTestSuite) TestStateExpires() {
+	state, code := s.idp.authorize(s.start("GET", "/oidc/corp/start", ""), "sub-7", "me@corp.com", true)
+	s.redis.FastForward(appauth.OIDCStateTTL + time.Second)
+	w := s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: state, Code: code})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+func (s *OIDCTestSuite) TestCodeFromAnotherFlowFailsPKCE() {
+	// перехваченный code не подходит к чужому state: у того свой code_verifier
+	_, stolen := s.idp.authorize(s.start("GET", "/oidc/corp/start", ""), "victim", "victim@corp.com", true)
+	attackerState, _ := s.idp.authorize(s.start("GET", "/oidc/corp/start", ""), "attacker", "a@corp.com", true)
+	w := s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: attackerState, Code: stolen})
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "GetUserIdentity", mock.Anything, mock.Anything)
+}
+
+func (s *OIDCTestSuite) TestRejectsInvalidIDToken() {
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(s.T(), err)
+	cases := map[string]func(){
+		"wrong nonce":    func() { s.idp.tamper = func(c jwt.MapClaims) { c["nonce"] = "replayed" } },
+		"no nonce":       func() { s.idp.tamper = func(c jwt.MapClaims) { delete(c, "nonce") } },
+		"wrong audience": func() { s.idp.tamper = func(c jwt.MapClaims) { c["aud"] = "another-app" } },
+		"foreign azp": func() {
+			s.idp.tamper = func(c jwt.MapClaims) { c["aud"] = []string{idpClientID, "other"}; c["azp"] = "other" }
+		},
+		"wrong issuer":   func() { s.idp.tamper = func(c jwt.MapClaims) { c["iss"] = "https://evil.example" } },
+		"expired":        func() { s.idp.tamper = func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() } },
+		"no subject":     func() { s.idp.tamper = func(c jwt.MapClaims) { delete(c, "sub") } },
+		"foreign key":    func() { s.idp.signWith = other },
+		"no expiry":      func() { s.idp.tamper = func(c jwt.MapClaims) { delete(c, "exp") } },
+		"issued in 2099": func() { s.idp.tamper = func(c jwt.MapClaims) { c["iat"] = time.Now().AddDate(70, 0, 0).Unix() } },
+	}
+	for name, setup := range cases {
+		s.idp.tamper, s.idp.signWith = nil, nil
+		setup()
+		w := s.loginVia("sub-7", "me@corp.com", true)
+		require.Equal(s.T(), http.StatusUnauthorized, w.Code, name)
+	}
+	s.mockQ.AssertNotCalled(s.T(), "GetUserIdentity", mock.Anything, mock.Anything)
+}
+
+func (s *OIDCTestSuite) TestUnknownProviderAndBadDiscovery() {
+	require.Equal(s.T(), http.StatusNotFound, s.do("GET", "/oidc/nope/start", "", nil).Code)
+
+	// провайдер отдаёт чужой issuer — discovery не принимается
+	s.idp.issuer = "https://evil.example"
+	require.Equal(s.T(), http.StatusBadGateway, s.do("GET", "/oidc/corp/start", "", nil).Code)
+}
+
+func (s *OIDCTestSuite) TestLinkIdentity() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, 5)
+	require.NoError(s.T(), err)
+	s.noIdentity("sub-5")
+	s.mockQ.On("CreateUserIdentity", mock.Anything, db.CreateUserIdentityParams{
+		UserID:   5,
+		Provider: "corp",
+		Subject:  "sub-5",
+		Email:    pgtype.Text{String: "work@corp.com", Valid: true},
+	}).Return(db.UserIdentity{
+		ID:        4,
+		UserID:    5,
+		Provider:  "corp",
+		Subject:   "sub-5",
+		Email:     pgtype.Text{String: "work@corp.com", Valid: true},
+		CreatedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}, nil).Once()
+	s.mockQ.On("CreateSecurityEvent", mock.Anything, mock.MatchedBy(func(p db.CreateSecurityEventParams) bool {
+		return p.UserID == 5 && p.Event == "identity_linked"
+	})).Return(nil).Once()
+
+	// email провайдера может отличаться от email аккаунта и не обязан быть подтверждён
+	state, code := s.idp.authorize(s.start("POST", "/oidc/corp/link", tp.AccessToken), "sub-5", "work@corp.com", false)
+	w := s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: state, Code: code})
+	require.Equal(s.T(), http.StatusCreated, w.Code, w.Body.String())
+	var resp dto.IdentityDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(s.T(), "corp", resp.Provider)
+	require.Equal(s.T(), "work@corp.com", resp.Email)
+	require.NotContains(s.T(), w.Body.String(), "access_token", "linking does not log in")
+	s.mockQ.AssertExpectations(s.T())
+
+	s.mockQ.On("ListUserIdentities", mock.Anything, int32(5)).Return([]db.UserIdentity{{ID: 4, UserID: 5, Provider: "corp"}}, nil)
+	w = s.do("GET", "/identities", tp.AccessToken, nil)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	require.JSONEq(s.T(), `[{"id":4,"provider":"corp","created_at":"0001-01-01T00:00:00Z"}]`, w.Body.String())
+}
+
+func (s *OIDCTestSuite) TestLinkIdentityOfAnotherUser() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, 5)
+	require.NoError(s.T(), err)
+	s.mockQ.On("GetUserIdentity", mock.Anything, mock.Anything).Return(db.UserIdentity{ID: 3, UserID: 7}, nil)
+
+	state, code := s.idp.authorize(s.start("POST", "/oidc/corp/link", tp.AccessToken), "sub-7", "me@corp.com", true)
+	w := s.do("POST", "/oidc/callback", "", dto.OIDCCallbackRequest{State: state, Code: code})
+	require.Equal(s.T(), http.StatusConflict, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "CreateUserIdentity", mock.Anything, mock.Anything)
+
+	require.Equal(s.T(), http.StatusUnauthorized, s.do("POST", "/oidc/corp/link", "", nil).Code)
+}
+
+func TestOIDCSuite(t *testing.T) {
+	suite.Run(t, new(OIDCTestSuite))
+}
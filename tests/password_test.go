@@ -0,0 +1,240 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+// testHasherParams — дешёвые параметры, чтобы тесты входа не тратили 64 МиБ на каждый хеш
+var testHasherParams = appauth.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+var testHasher = appauth.NewArgon2idHasher(testHasherParams)
+
+func hashPassword(t *testing.T, password string) string {
+	hash, err := testHasher.Hash(password)
+	require.NoError(t, err)
+	return hash
+}
+
+type PasswordTestSuite struct {
+	suite.Suite
+	mockQ   *mocks.MockQuerier
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	handler *handlers.AuthHandler
+	ctx     context.Context
+}
+
+func (s *PasswordTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.mockQ = new(mocks.MockQuerier)
+	authSvc := appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+	s.handler = handlers.NewAuthHandler(s.mockQ, authSvc, new(mocks.MockMailer), "http://app.local").
+		WithPasswordHasher(testHasher)
+}
+
+func (s *PasswordTestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+func (s *PasswordTestSuite) login(email, password string) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(dto.LoginRequest{Email: email, Password: password})
+	w := httptest.NewRecorder()
+	s.handler.Login(w, httptest.NewRequest("POST", "/login", bytes.NewReader(b)))
+	return w
+}
+
+func (s *PasswordTestSuite) TestHashIsPHC() {
+	a := hashPassword(s.T(), "correct horse")
+	b := hashPassword(s.T(), "correct horse")
+	require.True(s.T(), strings.HasPrefix(a, "$argon2id$v=19$m=64,t=1,p=1$"), a)
+	require.Len(s.T(), strings.Split(a, "$"), 6)
+	require.NotEqual(s.T(), a, b, "every hash gets its own salt")
+
+	ok, rehash, err := testHasher.Verify(a, "correct horse")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.False(s.T(), rehash)
+
+	ok, _, err = testHasher.Verify(a, "wrong horse")
+	require.NoError(s.T(), err)
+	require.False(s.T(), ok)
+}
+
+func (s *PasswordTestSuite) TestVerifyLegacyBcrypt() {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(s.T(), err)
+
+	ok, rehash, err := testHasher.Verify(string(legacy), "old-password")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.True(s.T(), rehash, "bcrypt hashes are upgraded")
+
+	ok, rehash, err = testHasher.Verify(string(legacy), "other")
+	require.NoError(s.T(), err)
+	require.False(s.T(), ok)
+	require.False(s.T(), rehash)
+}
+
+func (s *PasswordTestSuite) TestRehashWhenParamsChange() {
+	hash := hashPassword(s.T(), "secret")
+	stronger := testHasherParams
+	stronger.Iterations = 2
+	h := appauth.NewArgon2idHasher(stronger)
+
+	ok, rehash, err := h.Verify(hash, "secret")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok, "old parameters still verify")
+	require.True(s.T(), rehash)
+
+	upgraded, err := h.Hash("secret")
+	require.NoError(s.T(), err)
+	ok, rehash, err = h.Verify(upgraded, "secret")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.False(s.T(), rehash)
+}
+
+func (s *PasswordTestSuite) TestVerifyMalformed() {
+	ok, _, err := testHasher.Verify("", "anything")
+	require.NoError(s.T(), err, "accounts without a password never match")
+	require.False(s.T(), ok)
+
+	for _, bad := range []string{
+		"plain-text",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHRzYWx0$aGFzaA",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHRzYWx0$aGFzaA",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$aGFzaA",
+	} {
+		ok, _, err := testHasher.Verify(bad, "anything")
+		require.ErrorIs(s.T(), err, appauth.ErrUnknownPasswordHash, bad)
+		require.False(s.T(), ok)
+	}
+}
+
+func (s *PasswordTestSuite) TestLoginUpgradesLegacyHash() {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(s.T(), err)
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "me@ex.com", Password: string(legacy)}, nil)
+	s.mockQ.On("RehashUserPassword", mock.Anything, mock.MatchedBy(func(p db.RehashUserPasswordParams) bool {
+		ok, rehash, err := testHasher.Verify(p.NewPassword, "old-password")
+		return p.ID == 11 && p.OldPassword == string(legacy) && ok && !rehash && err == nil
+	})).Return(int64(1), nil).Once()
+
+	w := s.login("me@ex.com", "old-password")
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *PasswordTestSuite) TestLoginKeepsCurrentHash() {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "me@ex.com", Password: hashPassword(s.T(), "secret")}, nil)
+
+	w := s.login("me@ex.com", "secret")
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	s.mockQ.AssertNotCalled(s.T(), "RehashUserPassword", mock.Anything, mock.Anything)
+}
+
+func (s *PasswordTestSuite) TestWrongPasswordDoesNotUpgrade() {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(s.T(), err)
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "me@ex.com", Password: string(legacy)}, nil)
+
+	w := s.login("me@ex.com", "guess")
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "RehashUserPassword", mock.Anything, mock.Anything)
+}
+
+func (s *PasswordTestSuite) TestRehashFailureDoesNotBlockLogin() {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(s.T(), err)
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "me@ex.com", Password: string(legacy)}, nil)
+	s.mockQ.On("RehashUserPassword", mock.Anything, mock.Anything).Return(int64(0), errors.New("db down"))
+
+	w := s.login("me@ex.com", "old-password")
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+}
+
+func (s *PasswordTestSuite) TestMalformedHashRejectsLogin() {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "me@ex.com", Password: "plain-text"}, nil)
+
+	w := s.login("me@ex.com", "plain-text")
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (s *PasswordTestSuite) TestSignupStoresArgon2id() {
+	authSvc := appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+	// без WithPasswordHasher — параметры по умолчанию
+	h := handlers.NewAuthHandler(s.mockQ, authSvc, new(mocks.MockMailer), "http://app.local")
+	s.mockQ.On("CreateUser", mock.Anything, mock.MatchedBy(func(p db.CreateUserParams) bool {
+		return strings.HasPrefix(p.Password, "$argon2id$v=19$m=65536,t=3,p=4$")
+	})).Return(db.CreateUserRow{ID: 7, Email: "new@ex.com"}, nil).Once()
+
+	b, _ := json.Marshal(dto.SignupRequest{Email: "new@ex.com", Password: "tangerine-Kettle-42"})
+	w := httptest.NewRecorder()
+	h.Signup(w, httptest.NewRequest("POST", "/signup", bytes.NewReader(b)))
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	s.mockQ.AssertExpectations(s.T())
+}
+
+// countingHasher считает вызовы, чтобы проверить, что хеширование действительно выполнялось
+type countingHasher struct {
+	appauth.PasswordHasher
+	hashed, verified int
+}
+
+func (h *countingHasher) Hash(password string) (string, error) {
+	h.hashed++
+	return h.PasswordHasher.Hash(password)
+}
+
+func (h *countingHasher) Verify(encoded, password string) (bool, bool, error) {
+	h.verified++
+	return h.PasswordHasher.Verify(encoded, password)
+}
+
+func (s *PasswordTestSuite) TestUnknownEmailStillVerifiesPassword() {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "ghost@ex.com").Return(db.GetUserByEmailRow{}, pgx.ErrNoRows)
+	hasher := &countingHasher{PasswordHasher: testHasher}
+	s.handler.WithPasswordHasher(hasher)
+
+	// время ответа не должно выдавать, что аккаунта нет: пароль сверяется с заготовленным хешем
+	for range 2 {
+		require.Equal(s.T(), http.StatusUnauthorized, s.login("ghost@ex.com", "guess").Code)
+	}
+	require.Equal(s.T(), 2, hasher.verified)
+	require.Equal(s.T(), 1, hasher.hashed)
+}
+
+func TestPasswordTestSuite(t *testing.T) {
+	suite.Run(t, new(PasswordTestSuite))
+}
@@ -0,0 +1,271 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+const strongPassword = "tangerine-Kettle-42"
+
+type PolicyTestSuite struct {
+	suite.Suite
+	mockQ    *mocks.MockQuerier
+	mailer   *mocks.MockMailer
+	redis    *miniredis.Miniredis
+	rdb      *redis.Client
+	authSvc  *appauth.Service
+	handler  *handlers.AuthHandler
+	router   *chi.Mux
+	breached *appauth.BreachedCorpus
+	ctx      context.Context
+}
+
+func (s *PolicyTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+	s.mockQ = new(mocks.MockQuerier)
+	s.mailer = new(mocks.MockMailer)
+	s.mockQ.On("CreateSecurityEvent", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	s.breached = s.corpus("Summer-Breeze-1987", "Purple-Monkey-Dishwasher")
+	policy := appauth.DefaultPasswordPolicy
+	policy.Breached = s.breached
+	s.handler = handlers.NewAuthHandler(s.mockQ, s.authSvc, s.mailer, "http://app.local").
+		WithPasswordHasher(testHasher).
+		WithPasswordPolicy(policy)
+
+	s.router = chi.NewRouter()
+	s.router.Post("/signup", s.handler.Signup)
+	s.router.Post("/password/forgot", s.handler.ForgotPassword)
+	s.router.Post("/password/reset", s.handler.ResetPassword)
+	s.router.With(middleware.AuthMiddleware(s.authSvc)).Post("/password/change", s.handler.ChangePassword)
+}
+
+func (s *PolicyTestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+// corpus пишет файл утёкших паролей в формате pwnedpasswords и загружает его
+func (s *PolicyTestSuite) corpus(passwords ...string) *appauth.BreachedCorpus {
+	lines := []string{"# test corpus", ""}
+	for _, p := range passwords {
+		sum := sha1.Sum([]byte(p))
+		lines = append(lines, strings.ToUpper(hex.EncodeToString(sum[:]))+":42")
+	}
+	path := filepath.Join(s.T().TempDir(), "breached.txt")
+	require.NoError(s.T(), os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+	c, err := appauth.LoadBreachedCorpus(path)
+	require.NoError(s.T(), err)
+	return c
+}
+
+func (s *PolicyTestSuite) do(path, token string, body interface{}) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", path, bytes.NewReader(b))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func violationCodes(err error) []string {
+	var policyErr *appauth.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+	var codes []string
+	for _, v := range policyErr.Violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func (s *PolicyTestSuite) policyError(w *httptest.ResponseRecorder) []string {
+	require.Equal(s.T(), http.StatusBadRequest, w.Code, w.Body.String())
+	require.Contains(s.T(), w.Header().Get("Content-Type"), "application/json")
+	var resp dto.PasswordPolicyErrorDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(s.T(), "password_policy", resp.Error)
+	var codes []string
+	for _, v := range resp.Violations {
+		require.NotEmpty(s.T(), v.Message)
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func (s *PolicyTestSuite) TestStrengthScore() {
+	for _, weak := range []string{"password", "P@ssw0rd", "123456789", "qwertyuiop", "aaaaaaaaaa", "abcabcabcabc", "Summer2024", "dragon1987"} {
+		require.Less(s.T(), appauth.PasswordStrength(weak), 2, weak)
+	}
+	for _, strong := range []string{strongPassword, "correct horse battery staple", "Kx9#mQ2v!rT4"} {
+		require.GreaterOrEqual(s.T(), appauth.PasswordStrength(strong), 3, strong)
+	}
+	// части email считаются словарными словами
+	require.Greater(s.T(), appauth.PasswordStrength("Vasilisa-1990"), appauth.PasswordStrength("Vasilisa-1990", "vasilisa"))
+}
+
+func (s *PolicyTestSuite) TestCheckViolations() {
+	p := appauth.DefaultPasswordPolicy
+	p.Breached = s.breached
+
+	require.NoError(s.T(), p.Check(strongPassword, "john.smith@example.com"))
+	require.Equal(s.T(), []string{appauth.PasswordTooShort, appauth.PasswordTooWeak}, violationCodes(p.Check("abc12", "a@b.io")))
+	require.Equal(s.T(), []string{appauth.PasswordTooLong}, violationCodes(p.Check(strings.Repeat("x", 129), "a@b.io")))
+	require.Equal(s.T(), []string{appauth.PasswordBreached}, violationCodes(p.Check("Purple-Monkey-Dishwasher", "a@b.io")))
+
+	for _, derived := range []string{"J0hnSmith-Rules-77", "smith.john!2024xyz", "Example-Company-99x"} {
+		require.Contains(s.T(), violationCodes(p.Check(derived, "john.smith+tasks@example.com")), appauth.PasswordContainsEmail, derived)
+	}
+	// короткие куски адреса не мешают
+	require.NoError(s.T(), p.Check("Bob-Tangerine-Kettle", "bob@ex.io"))
+	// политика без проверки стойкости
+	require.NoError(s.T(), appauth.PasswordPolicy{MinLength: 4}.Check("aaaa", "a@b.io"))
+}
+
+func (s *PolicyTestSuite) TestLoadBreachedCorpus() {
+	require.Equal(s.T(), 2, s.breached.Len())
+	require.True(s.T(), s.breached.Contains("Summer-Breeze-1987"))
+	require.False(s.T(), s.breached.Contains("summer-breeze-1987"))
+
+	// строчные hex и строки без счётчика тоже принимаются
+	sum := sha1.Sum([]byte("hunter2"))
+	path := filepath.Join(s.T().TempDir(), "lower.txt")
+	require.NoError(s.T(), os.WriteFile(path, []byte(hex.EncodeToString(sum[:])+"\n"), 0o600))
+	c, err := appauth.LoadBreachedCorpus(path)
+	require.NoError(s.T(), err)
+	require.True(s.T(), c.Contains("hunter2"))
+
+	bad := filepath.Join(s.T().TempDir(), "bad.txt")
+	require.NoError(s.T(), os.WriteFile(bad, []byte("not-a-hash:1\n"), 0o600))
+	_, err = appauth.LoadBreachedCorpus(bad)
+	require.ErrorContains(s.T(), err, "bad.txt:1")
+
+	_, err = appauth.LoadBreachedCorpus(filepath.Join(s.T().TempDir(), "missing.txt"))
+	require.Error(s.T(), err)
+}
+
+func (s *PolicyTestSuite) TestSignupRejectsWeakPassword() {
+	w := s.do("/signup", "", dto.SignupRequest{Email: "anna.k@example.com", Password: "annak123"})
+	codes := s.policyError(w)
+	require.Contains(s.T(), codes, appauth.PasswordContainsEmail)
+	require.Contains(s.T(), codes, appauth.PasswordTooWeak)
+
+	w = s.do("/signup", "", dto.SignupRequest{Email: "anna.k@example.com", Password: "Summer-Breeze-1987"})
+	require.Equal(s.T(), []string{appauth.PasswordBreached}, s.policyError(w))
+	s.mockQ.AssertNotCalled(s.T(), "CreateUser", mock.Anything, mock.Anything)
+}
+
+func (s *PolicyTestSuite) TestResetRejectsWeakPasswordWithoutBurningToken() {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "me@ex.com").
+		Return(db.GetUserByEmailRow{ID: 11, Email: "me@ex.com"}, nil)
+	s.mockQ.On("GetUserByID", mock.Anything, int32(11)).Return(db.GetUserByIDRow{ID: 11, Email: "me@ex.com"}, nil)
+	s.mockQ.On("UpdateUserPassword", mock.Anything, mock.MatchedBy(func(p db.UpdateUserPasswordParams) bool {
+		return p.ID == 11
+	})).Return(nil).Once()
+
+	s.do("/password/forgot", "", dto.ForgotPasswordRequest{Email: "me@ex.com"})
+	body := s.mailer.Last().Body
+	token := strings.Fields(body[strings.Index(body, "token=")+len("token="):])[0]
+
+	w := s.do("/password/reset", "", dto.ResetPasswordRequest{Token: token, Password: "letmein1"})
+	require.Contains(s.T(), s.policyError(w), appauth.PasswordTooWeak)
+
+	w = s.do("/password/reset", "", dto.ResetPasswordRequest{Token: token, Password: strongPassword})
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *PolicyTestSuite) TestChangePassword() {
+	s.mockQ.On("GetUserPassword", mock.Anything, int32(11)).
+		Return(db.GetUserPasswordRow{Email: "me@ex.com", Password: hashPassword(s.T(), "old-Password-99")}, nil)
+	s.mockQ.On("UpdateUserPassword", mock.Anything, mock.MatchedBy(func(p db.UpdateUserPasswordParams) bool {
+		ok, _, err := testHasher.Verify(p.Password, strongPassword)
+		return p.ID == 11 && ok && err == nil
+	})).Return(nil).Once()
+
+	current, err := s.authSvc.StartSession(s.ctx, 11, appauth.SessionMeta{})
+	require.NoError(s.T(), err)
+	other, err := s.authSvc.StartSession(s.ctx, 11, appauth.SessionMeta{})
+	require.NoError(s.T(), err)
+
+	w := s.do("/password/change", current.AccessToken, dto.ChangePasswordRequest{
+		CurrentPassword: "old-Password-99",
+		NewPassword:     strongPassword,
+	})
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	var resp dto.LoginResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotEmpty(s.T(), resp.RefreshToken)
+
+	// все прежние сессии завершены, новая выдана
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, other.AccessToken)
+	require.ErrorIs(s.T(), err, appauth.ErrTokenRevoked)
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, current.AccessToken)
+	require.ErrorIs(s.T(), err, appauth.ErrTokenRevoked)
+	uid, err := s.authSvc.ValidateAccessToken(s.ctx, resp.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int32(11), uid)
+
+	s.mockQ.AssertCalled(s.T(), "CreateSecurityEvent", mock.Anything, mock.MatchedBy(func(p db.CreateSecurityEventParams) bool {
+		return p.UserID == 11 && p.Event == "password_changed"
+	}))
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *PolicyTestSuite) TestChangePasswordRejects() {
+	s.mockQ.On("GetUserPassword", mock.Anything, int32(11)).
+		Return(db.GetUserPasswordRow{Email: "me@ex.com", Password: hashPassword(s.T(), "old-Password-99")}, nil)
+	tp, err := s.authSvc.StartSession(s.ctx, 11, appauth.SessionMeta{})
+	require.NoError(s.T(), err)
+
+	w := s.do("/password/change", tp.AccessToken, dto.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: strongPassword})
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+
+	w = s.do("/password/change", tp.AccessToken, dto.ChangePasswordRequest{CurrentPassword: "old-Password-99", NewPassword: "password123"})
+	require.Contains(s.T(), s.policyError(w), appauth.PasswordTooWeak)
+
+	w = s.do("/password/change", tp.AccessToken, dto.ChangePasswordRequest{CurrentPassword: "old-Password-99"})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	w = s.do("/password/change", "", dto.ChangePasswordRequest{CurrentPassword: "old-Password-99", NewPassword: strongPassword})
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+
+	s.mockQ.AssertNotCalled(s.T(), "UpdateUserPassword", mock.Anything, mock.Anything)
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, tp.AccessToken)
+	require.NoError(s.T(), err, "session survives rejected changes")
+}
+
+func TestPolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(PolicyTestSuite))
+}
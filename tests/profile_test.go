@@ -0,0 +1,288 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+// pngHeader — начало PNG-файла, по нему http.DetectContentType узнаёт формат
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+type ProfileTestSuite struct {
+	suite.Suite
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	mockQ   *mocks.MockQuerier
+	router  *chi.Mux
+	ctx     context.Context
+	userID  int32
+	token   string
+}
+
+func (s *ProfileTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.userID = 12
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+	s.mockQ = new(mocks.MockQuerier)
+
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+	s.token = tp.AccessToken
+
+	h := handlers.NewProfileHandler(s.mockQ)
+	tasks := handlers.NewTaskHandler(s.mockQ, new(mocks.MockPublisher))
+	s.router = chi.NewRouter()
+	s.router.Get("/users/{userID}/avatar", h.Avatar)
+	s.router.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(s.authSvc))
+		r.Get("/me", h.GetMe)
+		r.Patch("/me", h.UpdateMe)
+		r.Put("/me/avatar", h.UploadAvatar)
+		r.Delete("/me/avatar", h.DeleteAvatar)
+		r.Get("/boards/{boardID}/GetTasks", tasks.GetTasks)
+	})
+}
+
+func (s *ProfileTestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+func (s *ProfileTestSuite) do(method, path string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *ProfileTestSuite) patch(req dto.UpdateProfileRequest) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(req)
+	return s.do("PATCH", "/me", b)
+}
+
+func (s *ProfileTestSuite) profileRow() db.UpdateUserProfileRow {
+	return db.UpdateUserProfileRow{
+		ID:              s.userID,
+		Email:           "ann@ex.com",
+		EmailVerifiedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		DisplayName:     pgtype.Text{String: "Ann", Valid: true},
+		Timezone:        "Europe/Berlin",
+		Locale:          "de-DE",
+		CreatedAt:       pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}
+}
+
+func (s *ProfileTestSuite) TestGetMe() {
+	s.mockQ.On("GetUserProfile", mock.Anything, s.userID).
+		Return(db.GetUserProfileRow(s.profileRow()), nil)
+
+	w := s.do("GET", "/me", nil)
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var got dto.UserDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(s.T(), s.userID, got.ID)
+	require.Equal(s.T(), "ann@ex.com", got.Email)
+	require.True(s.T(), got.EmailVerified)
+	require.False(s.T(), got.MFAEnabled)
+	require.Equal(s.T(), "Ann", got.DisplayName)
+	require.Equal(s.T(), "Europe/Berlin", got.Timezone)
+	require.Equal(s.T(), "de-DE", got.Locale)
+	require.NotNil(s.T(), got.CreatedAt)
+}
+
+func (s *ProfileTestSuite) TestGetMeUnknownUser() {
+	s.mockQ.On("GetUserProfile", mock.Anything, s.userID).Return(db.GetUserProfileRow{}, pgx.ErrNoRows)
+
+	w := s.do("GET", "/me", nil)
+	require.Equal(s.T(), http.StatusNotFound, w.Code)
+}
+
+func (s *ProfileTestSuite) TestUpdateMeNormalizesFields() {
+	s.mockQ.On("UpdateUserProfile", mock.Anything, db.UpdateUserProfileParams{
+		DisplayName: pgtype.Text{String: "Ann Lee", Valid: true},
+		Timezone:    pgtype.Text{String: "America/New_York", Valid: true},
+		Locale:      pgtype.Text{String: "pt-BR", Valid: true},
+		ID:          s.userID,
+	}).Return(s.profileRow(), nil).Once()
+
+	w := s.patch(dto.UpdateProfileRequest{
+		DisplayName: ptrString("  Ann Lee "),
+		Timezone:    ptrString("America/New_York"),
+		Locale:      ptrString("pt_br"),
+	})
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	s.mockQ.AssertNotCalled(s.T(), "DeleteUserAvatar", mock.Anything, mock.Anything)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *ProfileTestSuite) TestUpdateMeRejectsInvalidFields() {
+	for name, req := range map[string]dto.UpdateProfileRequest{
+		"long name":       {DisplayName: ptrString(strings.Repeat("я", 65))},
+		"control char":    {DisplayName: ptrString("Ann\x00")},
+		"relative avatar": {AvatarURL: ptrString("/users/1/avatar")},
+		"avatar scheme":   {AvatarURL: ptrString("javascript:alert(1)")},
+		"avatar userinfo": {AvatarURL: ptrString("https://user:pw@img.example/a.png")},
+		"unknown tz":      {Timezone: ptrString("Mars/Olympus")},
+		"local tz":        {Timezone: ptrString("Local")},
+		"empty tz":        {Timezone: ptrString("")},
+		"bad locale":      {Locale: ptrString("english")},
+		"empty locale":    {Locale: ptrString("")},
+	} {
+		w := s.patch(req)
+		require.Equal(s.T(), http.StatusBadRequest, w.Code, name)
+	}
+	s.mockQ.AssertNotCalled(s.T(), "UpdateUserProfile", mock.Anything, mock.Anything)
+}
+
+func (s *ProfileTestSuite) TestExternalAvatarReplacesUpload() {
+	s.mockQ.On("DeleteUserAvatar", mock.Anything, s.userID).Return(int64(1), nil).Once()
+	s.mockQ.On("UpdateUserProfile", mock.Anything, db.UpdateUserProfileParams{
+		AvatarUrl: pgtype.Text{String: "https://img.example/ann.png", Valid: true},
+		ID:        s.userID,
+	}).Return(s.profileRow(), nil).Once()
+
+	w := s.patch(dto.UpdateProfileRequest{AvatarURL: ptrString("https://img.example/ann.png")})
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *ProfileTestSuite) TestUploadAvatar() {
+	s.mockQ.On("UpsertUserAvatar", mock.Anything, db.UpsertUserAvatarParams{
+		UserID:      s.userID,
+		ContentType: "image/png",
+		Data:        pngHeader,
+	}).Return(nil).Once()
+	s.mockQ.On("UpdateUserProfile", mock.Anything, mock.MatchedBy(func(p db.UpdateUserProfileParams) bool {
+		return p.ID == s.userID && strings.HasPrefix(p.AvatarUrl.String, "/users/12/avatar?v=") &&
+			!p.DisplayName.Valid && !p.Timezone.Valid && !p.Locale.Valid
+	})).Return(s.profileRow(), nil).Once()
+
+	w := s.do("PUT", "/me/avatar", pngHeader)
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *ProfileTestSuite) TestUploadAvatarRejectsNonImages() {
+	w := s.do("PUT", "/me/avatar", []byte("<svg onload=alert(1)></svg>"))
+	require.Equal(s.T(), http.StatusUnsupportedMediaType, w.Code)
+
+	big := append(append([]byte{}, pngHeader...), make([]byte, 1<<20)...)
+	w = s.do("PUT", "/me/avatar", big)
+	require.Equal(s.T(), http.StatusRequestEntityTooLarge, w.Code)
+
+	s.mockQ.AssertNotCalled(s.T(), "UpsertUserAvatar", mock.Anything, mock.Anything)
+}
+
+func (s *ProfileTestSuite) TestDeleteAvatar() {
+	s.mockQ.On("DeleteUserAvatar", mock.Anything, s.userID).Return(int64(1), nil).Once()
+	s.mockQ.On("UpdateUserProfile", mock.Anything, db.UpdateUserProfileParams{
+		AvatarUrl: pgtype.Text{String: "", Valid: true},
+		ID:        s.userID,
+	}).Return(s.profileRow(), nil).Once()
+
+	w := s.do("DELETE", "/me/avatar", nil)
+	require.Equal(s.T(), http.StatusNoContent, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *ProfileTestSuite) TestServeAvatar() {
+	s.mockQ.On("GetUserAvatar", mock.Anything, int32(12)).Return(db.UserAvatar{
+		UserID:      12,
+		ContentType: "image/png",
+		Data:        pngHeader,
+		UpdatedAt:   pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}, nil)
+	s.mockQ.On("GetUserAvatar", mock.Anything, int32(13)).Return(db.UserAvatar{}, pgx.ErrNoRows)
+
+	// без токена: картинку грузит <img>
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/users/12/avatar?v=1", nil))
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	require.Equal(s.T(), "image/png", w.Header().Get("Content-Type"))
+	require.Equal(s.T(), "nosniff", w.Header().Get("X-Content-Type-Options"))
+	require.Equal(s.T(), pngHeader, w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/users/13/avatar", nil))
+	require.Equal(s.T(), http.StatusNotFound, w.Code)
+}
+
+func (s *ProfileTestSuite) TestTasksIncludeUserSummaries() {
+	now := pgtype.Timestamp{Time: time.Now(), Valid: true}
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 5, UserID: s.userID}).
+		Return(authz.RoleViewer, nil)
+	s.mockQ.On("GetTasks", mock.Anything, mock.Anything).Return([]db.GetTasksRow{
+		{ID: 1, UserID: 12, BoardID: pgtype.Int4{Int32: 5, Valid: true}, AssigneeIds: []int32{12, 14}, CreatedAt: now, UpdatedAt: now},
+		{ID: 2, UserID: 14, BoardID: pgtype.Int4{Int32: 5, Valid: true}, CreatedAt: now, UpdatedAt: now},
+	}, nil)
+	s.mockQ.On("ListUserSummaries", mock.Anything, []int32{12, 14}).Return([]db.ListUserSummariesRow{
+		{ID: 12, Email: "ann@ex.com", DisplayName: pgtype.Text{String: "Ann", Valid: true},
+			AvatarUrl: pgtype.Text{String: "/users/12/avatar?v=1", Valid: true}},
+		{ID: 14, Email: "bob.smith@ex.com"},
+	}, nil).Once()
+
+	w := s.do("GET", "/boards/5/GetTasks", nil)
+	require.Equal(s.T(), http.StatusOK, w.Code, w.Body.String())
+
+	var resp []dto.TaskDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(s.T(), resp, 2)
+	require.Equal(s.T(), &dto.UserSummaryDTO{ID: 12, Name: "Ann", AvatarURL: "/users/12/avatar?v=1"}, resp[0].User)
+	require.Equal(s.T(), []dto.UserSummaryDTO{
+		{ID: 12, Name: "Ann", AvatarURL: "/users/12/avatar?v=1"},
+		{ID: 14, Name: "bob.smith"},
+	}, resp[0].Assignees)
+	require.Equal(s.T(), "bob.smith", resp[1].User.Name, "without a display name the email local part is shown")
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *ProfileTestSuite) TestTasksSurviveSummaryFailure() {
+	now := pgtype.Timestamp{Time: time.Now(), Valid: true}
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleViewer, nil)
+	s.mockQ.On("GetTasks", mock.Anything, mock.Anything).Return([]db.GetTasksRow{
+		{ID: 1, UserID: 12, BoardID: pgtype.Int4{Int32: 5, Valid: true}, CreatedAt: now, UpdatedAt: now},
+	}, nil)
+	s.mockQ.On("ListUserSummaries", mock.Anything, mock.Anything).Return([]db.ListUserSummariesRow{}, errors.New("db down"))
+
+	w := s.do("GET", "/boards/5/GetTasks", nil)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var resp []dto.TaskDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(s.T(), resp, 1)
+	require.Equal(s.T(), int32(12), resp[0].UserID)
+	require.Nil(s.T(), resp[0].User)
+}
+
+func TestProfileTestSuite(t *testing.T) {
+	suite.Run(t, new(ProfileTestSuite))
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sqszy/TaskTracker/internal/rank"
+)
+
+func TestRankBetween(t *testing.T) {
+	first, err := rank.Between("", "")
+	require.NoError(t, err)
+	second, err := rank.Between(first, "")
+	require.NoError(t, err)
+
+	// многократная вставка в начало, в конец и в одну и ту же щель сохраняет порядок
+	keys := []string{first, second}
+	for i := 0; i < 50; i++ {
+		head, err := rank.Between("", keys[0])
+		require.NoError(t, err)
+		tail, err := rank.Between(keys[len(keys)-1], "")
+		require.NoError(t, err)
+		mid, err := rank.Between(keys[0], keys[1])
+		require.NoError(t, err)
+
+		keys = append([]string{head}, keys...)
+		keys = append(keys, tail)
+		keys = append(keys, mid)
+		sort.Strings(keys)
+	}
+	for i := 1; i < len(keys); i++ {
+		require.Less(t, keys[i-1], keys[i])
+	}
+}
+
+func TestRankNoGap(t *testing.T) {
+	_, err := rank.Between("b", "a")
+	require.ErrorIs(t, err, rank.ErrNoGap)
+
+	_, err = rank.Between("a", "a")
+	require.ErrorIs(t, err, rank.ErrNoGap)
+
+	// вставки в одну щель рано или поздно упираются в предельную длину ключа
+	lower, upper := "a", "b"
+	for {
+		key, err := rank.Between(lower, upper)
+		if err != nil {
+			require.ErrorIs(t, err, rank.ErrNoGap)
+			break
+		}
+		require.LessOrEqual(t, len(key), rank.MaxLen)
+		upper = key
+	}
+}
+
+func TestRankSpread(t *testing.T) {
+	for _, n := range []int{1, 3, 1000, 50000} {
+		keys := rank.Spread(n)
+		require.Len(t, keys, n)
+		for i, k := range keys {
+			require.NotEmpty(t, k)
+			require.NotEqual(t, byte('0'), k[len(k)-1])
+			if i > 0 {
+				require.Less(t, keys[i-1], k)
+				// между соседями после перебалансировки остаётся место для вставки
+				_, err := rank.Between(keys[i-1], k)
+				require.NoError(t, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/ratelimit"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+	redis *miniredis.Miniredis
+	rdb   *redis.Client
+	mockQ *mocks.MockQuerier
+	now   time.Time
+	ctx   context.Context
+}
+
+func (s *RateLimitTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.mockQ = new(mocks.MockQuerier)
+	// окно считается по часам Redis
+	s.now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.redis.SetTime(s.now)
+}
+
+func (s *RateLimitTestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+func (s *RateLimitTestSuite) advance(d time.Duration) {
+	s.now = s.now.Add(d)
+	s.redis.SetTime(s.now)
+	s.redis.FastForward(d)
+}
+
+func (s *RateLimitTestSuite) login(router http.Handler, ip, email, password string) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(dto.LoginRequest{Email: email, Password: password})
+	req := httptest.NewRequest("POST", "/login", bytes.NewReader(b))
+	req.RemoteAddr = ip + ":40000"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// router — /login за лимитами по IP и email, как в main.go
+func (s *RateLimitTestSuite) router(h *handlers.AuthHandler, byIP, byEmail ratelimit.Limit) *chi.Mux {
+	r := chi.NewRouter()
+	r.With(
+		middleware.RateLimit(ratelimit.NewLimiter(s.rdb, "login_ip", byIP), middleware.ByIP),
+		middleware.RateLimit(ratelimit.NewLimiter(s.rdb, "login_email", byEmail), middleware.ByEmail),
+	).Post("/login", h.Login)
+	return r
+}
+
+func (s *RateLimitTestSuite) handler() *handlers.AuthHandler {
+	authSvc := appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+	return handlers.NewAuthHandler(s.mockQ, authSvc, new(mocks.MockMailer), "http://app.local").
+		WithLoginLockout(ratelimit.NewLockout(s.rdb, "login", ratelimit.DefaultLockoutPolicy)).
+		WithPasswordHasher(testHasher)
+}
+
+func (s *RateLimitTestSuite) user(email, password string) {
+	s.mockQ.On("GetUserByEmail", mock.Anything, email).Return(db.GetUserByEmailRow{
+		ID:       11,
+		Email:    email,
+		Password: hashPassword(s.T(), password),
+	}, nil)
+}
+
+func (s *RateLimitTestSuite) TestSlidingWindow() {
+	l := ratelimit.NewLimiter(s.rdb, "test", ratelimit.Limit{Requests: 3, Window: time.Minute})
+	for i := 0; i < 3; i++ {
+		res, err := l.Allow(s.ctx, "k")
+		require.NoError(s.T(), err)
+		require.True(s.T(), res.Allowed)
+		require.Equal(s.T(), 2-i, res.Remaining)
+		s.advance(10 * time.Second)
+	}
+
+	res, err := l.Allow(s.ctx, "k")
+	require.NoError(s.T(), err)
+	require.False(s.T(), res.Allowed)
+	// первый запрос выйдет из окна через 60 − 30 секунд
+	require.Equal(s.T(), 30*time.Second, res.RetryAfter)
+
+	other, err := l.Allow(s.ctx, "other")
+	require.NoError(s.T(), err)
+	require.True(s.T(), other.Allowed, "keys are limited independently")
+
+	s.advance(30 * time.Second)
+	res, err = l.Allow(s.ctx, "k")
+	require.NoError(s.T(), err)
+	require.True(s.T(), res.Allowed, "oldest request left the window")
+	res, err = l.Allow(s.ctx, "k")
+	require.NoError(s.T(), err)
+	require.False(s.T(), res.Allowed)
+	require.Equal(s.T(), 10*time.Second, res.RetryAfter)
+}
+
+func (s *RateLimitTestSuite) TestLimitByIP() {
+	s.user("me@ex.com", "secret-pass")
+	r := s.router(s.handler(), ratelimit.Limit{Requests: 2, Window: time.Minute}, ratelimit.Limit{Requests: 100, Window: time.Minute})
+
+	require.Equal(s.T(), http.StatusOK, s.login(r, "10.0.0.1", "me@ex.com", "secret-pass").Code)
+	require.Equal(s.T(), http.StatusOK, s.login(r, "10.0.0.1", "me@ex.com", "secret-pass").Code)
+	s.advance(15 * time.Second)
+	w := s.login(r, "10.0.0.1", "me@ex.com", "secret-pass")
+	require.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	require.Equal(s.T(), "45", w.Header().Get("Retry-After"))
+
+	require.Equal(s.T(), http.StatusOK, s.login(r, "10.0.0.2", "me@ex.com", "secret-pass").Code)
+}
+
+func (s *RateLimitTestSuite) TestLimitByEmail() {
+	s.user("me@ex.com", "secret-pass")
+	r := s.router(s.handler(), ratelimit.Limit{Requests: 100, Window: time.Minute}, ratelimit.Limit{Requests: 2, Window: time.Minute})
+
+	// разные IP, один аккаунт; регистр и пробелы в email не обходят лимит
+	require.Equal(s.T(), http.StatusOK, s.login(r, "10.0.0.1", "me@ex.com", "secret-pass").Code)
+	require.Equal(s.T(), http.StatusOK, s.login(r, "10.0.0.2", " ME@ex.com", "secret-pass").Code)
+	w := s.login(r, "10.0.0.3", "me@ex.com", "secret-pass")
+	require.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	require.Equal(s.T(), "60", w.Header().Get("Retry-After"))
+}
+
+func (s *RateLimitTestSuite) TestByEmailKeepsBody() {
+	var got string
+	h := middleware.RateLimit(ratelimit.NewLimiter(s.rdb, "t", ratelimit.Limit{Requests: 1, Window: time.Minute}), middleware.ByEmail)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			got = string(b)
+		}))
+	body := `{"email":"a@b.c","password":"secret"}`
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/login", strings.NewReader(body)))
+	require.Equal(s.T(), body, got)
+
+	// без email лимит не применяется
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/login", strings.NewReader(`not json`)))
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/login", strings.NewReader(`{"email":"A@b.c"}`)))
+	require.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+}
+
+func (s *RateLimitTestSuite) TestEmailHiddenInLongBody() {
+	r := s.router(s.handler(), ratelimit.Limit{Requests: 100, Window: time.Minute}, ratelimit.Limit{Requests: 1, Window: time.Minute})
+	b, _ := json.Marshal(dto.LoginRequest{Email: "me@ex.com", Password: strings.Repeat("x", 100<<10)})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/login", bytes.NewReader(b)))
+	// тело обрезано: пароль не сравнивается, а запрос отклоняется как невалидный
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "GetUserByEmail", mock.Anything, mock.Anything)
+}
+
+func (s *RateLimitTestSuite) TestRedisDownFailsOpen() {
+	down := redis.NewClient(&redis.Options{Addr: s.redis.Addr(), MaxRetries: -1, DialerRetries: 1})
+	defer down.Close()
+	h := middleware.RateLimit(ratelimit.NewLimiter(down, "t", ratelimit.Limit{Requests: 1, Window: time.Minute}), middleware.ByIP)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.redis.Close()
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		require.Equal(s.T(), http.StatusOK, w.Code)
+	}
+}
+
+func (s *RateLimitTestSuite) TestProgressiveLockout() {
+	s.user("me@ex.com", "secret-pass")
+	r := s.router(s.handler(), ratelimit.Limit{Requests: 1000, Window: time.Minute}, ratelimit.Limit{Requests: 1000, Window: time.Minute})
+
+	for i := 0; i < ratelimit.DefaultLockoutPolicy.Threshold; i++ {
+		require.Equal(s.T(), http.StatusUnauthorized, s.login(r, "10.0.0.1", "me@ex.com", "wrong").Code)
+	}
+	// даже верный пароль не принимается, пока аккаунт заблокирован
+	w := s.login(r, "10.0.0.2", "me@ex.com", "secret-pass")
+	require.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	require.Equal(s.T(), "60", w.Header().Get("Retry-After"))
+
+	// после блокировки каждая новая неудача удваивает срок
+	s.advance(time.Minute)
+	require.Equal(s.T(), http.StatusUnauthorized, s.login(r, "10.0.0.1", "me@ex.com", "wrong").Code)
+	w = s.login(r, "10.0.0.1", "me@ex.com", "wrong")
+	require.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	require.Equal(s.T(), "120", w.Header().Get("Retry-After"))
+
+	// успешный вход сбрасывает счётчик
+	s.advance(2 * time.Minute)
+	require.Equal(s.T(), http.StatusOK, s.login(r, "10.0.0.1", "me@ex.com", "secret-pass").Code)
+	require.Equal(s.T(), http.StatusUnauthorized, s.login(r, "10.0.0.1", "me@ex.com", "wrong").Code)
+	require.Equal(s.T(), http.StatusOK, s.login(r, "10.0.0.1", "me@ex.com", "secret-pass").Code)
+}
+
+func (s *RateLimitTestSuite) TestLockoutForUnknownEmail() {
+	s.mockQ.On("GetUserByEmail", mock.Anything, "ghost@ex.com").Return(db.GetUserByEmailRow{}, pgx.ErrNoRows)
+	r := s.router(s.handler(), ratelimit.Limit{Requests: 1000, Window: time.Minute}, ratelimit.Limit{Requests: 1000, Window: time.Minute})
+
+	for i := 0; i < ratelimit.DefaultLockoutPolicy.Threshold; i++ {
+		require.Equal(s.T(), http.StatusUnauthorized, s.login(r, "10.0.0.1", "ghost@ex.com", "x").Code)
+	}
+	// ответ тот же, что для существующего аккаунта, и база больше не спрашивается
+	require.Equal(s.T(), http.StatusTooManyRequests, s.login(r, "10.0.0.1", "ghost@ex.com", "x").Code)
+	s.mockQ.AssertNumberOfCalls(s.T(), "GetUserByEmail", ratelimit.DefaultLockoutPolicy.Threshold)
+}
+
+func (s *RateLimitTestSuite) TestLockoutCappedAndForgotten() {
+	l := ratelimit.NewLockout(s.rdb, "t", ratelimit.LockoutPolicy{
+		Threshold: 2,
+		Base:      time.Minute,
+		Max:       5 * time.Minute,
+		Memory:    time.Hour,
+	})
+	// каждая допущенная попытка после порога заранее выставляет следующую, более долгую блокировку
+	var got []time.Duration
+	for i := 0; i < 6; i++ {
+		d, err := l.Attempt(s.ctx, "k")
+		require.NoError(s.T(), err)
+		require.Zero(s.T(), d)
+		locked, err := l.Locked(s.ctx, "k")
+		require.NoError(s.T(), err)
+		got = append(got, locked)
+		s.advance(locked)
+	}
+	require.Equal(s.T(), []time.Duration{0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}, got)
+
+	d, err := l.Attempt(s.ctx, "k")
+	require.NoError(s.T(), err)
+	require.Zero(s.T(), d)
+	locked, err := l.Locked(s.ctx, "k")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 5*time.Minute, locked)
+
+	// неудачи забываются через Memory
+	s.advance(time.Hour)
+	locked, err = l.Locked(s.ctx, "k")
+	require.NoError(s.T(), err)
+	require.Zero(s.T(), locked)
+	_, err = l.Attempt(s.ctx, "k")
+	require.NoError(s.T(), err)
+	locked, err = l.Locked(s.ctx, "k")
+	require.NoError(s.T(), err)
+	require.Zero(s.T(), locked)
+}
+
+func (s *RateLimitTestSuite) TestLockoutAttemptUnderConcurrency() {
+	l := ratelimit.NewLockout(s.rdb, "t", ratelimit.DefaultLockoutPolicy)
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d, err := l.Attempt(s.ctx, "k")
+			if err == nil && d == 0 {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	// попытки учитываются до проверки, поэтому параллельные запросы не проскакивают порог
+	require.Equal(s.T(), int32(ratelimit.DefaultLockoutPolicy.Threshold), allowed.Load())
+
+	d, err := l.Locked(s.ctx, "k")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), time.Minute, d)
+
+	require.NoError(s.T(), l.Reset(s.ctx, "k"))
+	d, err = l.Attempt(s.ctx, "k")
+	require.NoError(s.T(), err)
+	require.Zero(s.T(), d)
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+)
+
+type RevocationTestSuite struct {
+	suite.Suite
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	ctx     context.Context
+}
+
+func (s *RevocationTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
+}
+
+func (s *RevocationTestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+func (s *RevocationTestSuite) login(userID int32) *appauth.TokenPair {
+	tp, err := s.authSvc.StartSession(s.ctx, userID, appauth.SessionMeta{UserAgent: "Firefox", IP: "10.0.0.1"})
+	require.NoError(s.T(), err)
+	return tp
+}
+
+// legacyToken — access-токен без sid, выпущенный до появления сессий
+func (s *RevocationTestSuite) legacyToken(userID int32, issuedAt time.Time) string {
+	str, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &appauth.Claims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(time.Hour)),
+		},
+	}).SignedString([]byte("acc"))
+	require.NoError(s.T(), err)
+	return str
+}
+
+func (s *RevocationTestSuite) TestAccessTokenCarriesJTI() {
+	first := s.login(5)
+	second := s.login(5)
+	a, err := s.authSvc.ParseAccessToken(s.ctx, first.AccessToken)
+	require.NoError(s.T(), err)
+	b, err := s.authSvc.ParseAccessToken(s.ctx, second.AccessToken)
+	require.NoError(s.T(), err)
+	require.NotEmpty(s.T(), a.ID)
+	require.NotEqual(s.T(), a.ID, b.ID)
+}
+
+func (s *RevocationTestSuite) TestLogoutRevokesAccessToken() {
+	tp := s.login(5)
+	_, err := s.authSvc.ValidateAccessToken(s.ctx, tp.AccessToken)
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), s.authSvc.Revoke(s.ctx, tp.RefreshToken))
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, tp.AccessToken)
+	require.ErrorIs(s.T(), err, appauth.ErrTokenRevoked)
+}
+
+func (s *RevocationTestSuite) TestRevokeSessionKeepsOtherSessions() {
+	laptop := s.login(5)
+	phone := s.login(5)
+	claims, err := s.authSvc.ParseAccessToken(s.ctx, laptop.AccessToken)
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), s.authSvc.RevokeSession(s.ctx, 5, claims.SessionID))
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, laptop.AccessToken)
+	require.ErrorIs(s.T(), err, appauth.ErrTokenRevoked)
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, phone.AccessToken)
+	require.NoError(s.T(), err)
+}
+
+func (s *RevocationTestSuite) TestRevokeAll() {
+	tp := s.login(5)
+	legacy := s.legacyToken(5, time.Now().Add(-10*time.Second))
+	other := s.login(6)
+	_, err := s.authSvc.ValidateAccessToken(s.ctx, legacy)
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), s.authSvc.RevokeAll(s.ctx, 5))
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, tp.AccessToken)
+	require.ErrorIs(s.T(), err, appauth.ErrTokenRevoked)
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, legacy)
+	require.ErrorIs(s.T(), err, appauth.ErrTokenRevoked)
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, other.AccessToken)
+	require.NoError(s.T(), err, "other users are not affected")
+
+	// новый вход после отзыва работает
+	fresh := s.login(5)
+	uid, err := s.authSvc.ValidateAccessToken(s.ctx, fresh.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int32(5), uid)
+}
+
+func (s *RevocationTestSuite) TestRevokeAllSameSecond() {
+	// токен без sid выдан в ту же секунду, что и отзыв
+	legacy := s.legacyToken(5, time.Now())
+	require.NoError(s.T(), s.authSvc.RevokeAll(s.ctx, 5))
+	_, err := s.authSvc.ValidateAccessToken(s.ctx, legacy)
+	require.ErrorIs(s.T(), err, appauth.ErrTokenRevoked)
+
+	// вход сразу после отзыва, в ту же секунду, даёт действующие токены
+	fresh := s.login(5)
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, fresh.AccessToken)
+	require.NoError(s.T(), err)
+	next, err := s.authSvc.Refresh(s.ctx, fresh.RefreshToken)
+	require.NoError(s.T(), err)
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, next.AccessToken)
+	require.NoError(s.T(), err)
+}
+
+func (s *RevocationTestSuite) TestRevocationRecordsExpireWithAccessTTL() {
+	tp := s.login(5)
+	require.NoError(s.T(), s.authSvc.RevokeAll(s.ctx, 5))
+	tok, _, err := jwt.NewParser().ParseUnverified(tp.AccessToken, &appauth.Claims{})
+	require.NoError(s.T(), err)
+	sid := tok.Claims.(*appauth.Claims).SessionID
+	// отметка времени отзывает и refresh-токены без sid, поэтому живёт refreshTTL
+	require.Equal(s.T(), time.Hour, s.redis.TTL("tokens_valid_after:5"))
+	require.Equal(s.T(), time.Minute, s.redis.TTL("revoked_session:"+sid))
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, tp.AccessToken)
+	require.Error(s.T(), err)
+
+	// отозванные токены к этому времени истекли сами
+	s.redis.FastForward(time.Minute)
+	require.False(s.T(), s.redis.Exists("revoked_session:"+sid))
+}
+
+func (s *RevocationTestSuite) TestCacheOnOtherInstance() {
+	tp := s.login(5)
+	cached := appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour).WithRevocationCache(time.Hour)
+	uncached := appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour).WithRevocationCache(0)
+	_, err := cached.ValidateAccessToken(s.ctx, tp.AccessToken)
+	require.NoError(s.T(), err)
+
+	// отзыв на первом экземпляре
+	require.NoError(s.T(), s.authSvc.Revoke(s.ctx, tp.RefreshToken))
+
+	// экземпляр без кеша видит отзыв сразу, с кешем — по истечении его времени жизни
+	_, err = uncached.ValidateAccessToken(s.ctx, tp.AccessToken)
+	require.ErrorIs(s.T(), err, appauth.ErrTokenRevoked)
+	_, err = cached.ValidateAccessToken(s.ctx, tp.AccessToken)
+	require.NoError(s.T(), err)
+
+	short := appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour).WithRevocationCache(20 * time.Millisecond)
+	next := s.login(6)
+	_, err = short.ValidateAccessToken(s.ctx, next.AccessToken)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.authSvc.RevokeAll(s.ctx, 6))
+	time.Sleep(30 * time.Millisecond)
+	_, err = short.ValidateAccessToken(s.ctx, next.AccessToken)
+	require.ErrorIs(s.T(), err, appauth.ErrTokenRevoked)
+}
+
+func TestRevocationSuite(t *testing.T) {
+	suite.Run(t, new(RevocationTestSuite))
+}
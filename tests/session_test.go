@@ -0,0 +1,359 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+type SessionTestSuite struct {
+	suite.Suite
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	auth    *handlers.AuthHandler
+	mockQ   *mocks.MockQuerier
+	router  *chi.Mux
+	ctx     context.Context
+}
+
+func (s *SessionTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	// короткое окно для параллельных обменов, чтобы тесты кражи не ждали DefaultRefreshGrace
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour).WithRefreshGrace(20 * time.Millisecond)
+	s.mockQ = new(mocks.MockQuerier)
+	s.auth = handlers.NewAuthHandler(s.mockQ, s.authSvc, new(mocks.MockMailer), "http://app.local")
+
+	h := handlers.NewSessionHandler(s.authSvc)
+	s.router = chi.NewRouter()
+	s.router.Post("/refresh", s.auth.Refresh)
+	s.router.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(s.authSvc))
+		r.Get("/sessions", h.ListSessions)
+		r.Delete("/sessions", h.RevokeAllSessions)
+		r.Delete("/sessions/{sessionID}", h.RevokeSession)
+	})
+}
+
+func (s *SessionTestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+func (s *SessionTestSuite) login(userID int32, ua, ip string) *appauth.TokenPair {
+	tp, err := s.authSvc.StartSession(s.ctx, userID, appauth.SessionMeta{UserAgent: ua, IP: ip})
+	require.NoError(s.T(), err)
+	return tp
+}
+
+func (s *SessionTestSuite) do(method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *SessionTestSuite) sessions(token string) []dto.SessionDTO {
+	w := s.do("GET", "/sessions", token)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var resp []dto.SessionDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func (s *SessionTestSuite) TestListSessions() {
+	laptop := s.login(5, "Firefox", "10.0.0.1")
+	s.redis.FastForward(time.Second)
+	phone := s.login(5, "iPhone", "10.0.0.2")
+	s.login(6, "Other user", "10.0.0.3")
+
+	list := s.sessions(laptop.AccessToken)
+	require.Len(s.T(), list, 2)
+	require.ElementsMatch(s.T(), []string{"Firefox", "iPhone"}, []string{list[0].UserAgent, list[1].UserAgent})
+	for _, sess := range list {
+		require.Equal(s.T(), sess.UserAgent == "Firefox", sess.Current)
+	}
+
+	list = s.sessions(phone.AccessToken)
+	for _, sess := range list {
+		require.Equal(s.T(), sess.UserAgent == "iPhone", sess.Current)
+	}
+}
+
+func (s *SessionTestSuite) refresh(token, ua, ip string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(dto.RefreshRequest{RefreshToken: token})
+	req := httptest.NewRequest("POST", "/refresh", bytes.NewReader(body))
+	req.Header.Set("User-Agent", ua)
+	req.RemoteAddr = ip + ":5555"
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *SessionTestSuite) TestRefreshKeepsSessionAndUpdatesDevice() {
+	tp := s.login(5, "Firefox", "10.0.0.1")
+	before := s.sessions(tp.AccessToken)
+	require.Len(s.T(), before, 1)
+
+	w := s.refresh(tp.RefreshToken, "Firefox 2", "10.0.0.9")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var next dto.LoginResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &next))
+	after := s.sessions(next.AccessToken)
+	require.Len(s.T(), after, 1)
+	require.Equal(s.T(), before[0].ID, after[0].ID)
+	require.Equal(s.T(), "Firefox 2", after[0].UserAgent)
+	require.Equal(s.T(), "10.0.0.9", after[0].IP)
+	require.True(s.T(), after[0].Current)
+}
+
+func (s *SessionTestSuite) TestRevokeSession() {
+	laptop := s.login(5, "Firefox", "10.0.0.1")
+	phone := s.login(5, "iPhone", "10.0.0.2")
+	other := s.login(6, "Other user", "10.0.0.3")
+
+	var phoneID string
+	for _, sess := range s.sessions(laptop.AccessToken) {
+		if !sess.Current {
+			phoneID = sess.ID
+		}
+	}
+	require.NotEmpty(s.T(), phoneID)
+
+	// чужую сессию нельзя ни увидеть, ни завершить
+	w := s.do("DELETE", "/sessions/"+phoneID, other.AccessToken)
+	require.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	w = s.do("DELETE", "/sessions/"+phoneID, laptop.AccessToken)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	_, err := s.authSvc.Refresh(s.ctx, phone.RefreshToken)
+	require.Error(s.T(), err)
+	_, err = s.authSvc.Refresh(s.ctx, laptop.RefreshToken)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), s.sessions(laptop.AccessToken), 1)
+}
+
+func (s *SessionTestSuite) TestLogoutEverywhere() {
+	laptop := s.login(5, "Firefox", "10.0.0.1")
+	phone := s.login(5, "iPhone", "10.0.0.2")
+	other := s.login(6, "Other user", "10.0.0.3")
+
+	w := s.do("DELETE", "/sessions", laptop.AccessToken)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	_, err := s.authSvc.Refresh(s.ctx, laptop.RefreshToken)
+	require.Error(s.T(), err)
+	_, err = s.authSvc.Refresh(s.ctx, phone.RefreshToken)
+	require.Error(s.T(), err)
+	_, err = s.authSvc.Refresh(s.ctx, other.RefreshToken)
+	require.NoError(s.T(), err)
+
+	// access-токены всех сессий перестают действовать сразу
+	require.Equal(s.T(), http.StatusUnauthorized, s.do("GET", "/sessions", laptop.AccessToken).Code)
+	require.Equal(s.T(), http.StatusUnauthorized, s.do("GET", "/sessions", phone.AccessToken).Code)
+	require.Equal(s.T(), http.StatusOK, s.do("GET", "/sessions", other.AccessToken).Code)
+	list, err := s.authSvc.ListSessions(s.ctx, 5)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), list)
+}
+
+// legacyRefreshToken — refresh-токен без sid, как до появления сессий
+func (s *SessionTestSuite) legacyRefreshToken(userID int32, issuedAt time.Time) string {
+	jti := uuid.NewString()
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &appauth.Claims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(time.Hour)),
+		},
+	}).SignedString([]byte("ref"))
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.rdb.Set(s.ctx, "refresh:"+jti, strconv.Itoa(int(userID)), time.Hour).Err())
+	return tok
+}
+
+func (s *SessionTestSuite) TestLogoutEverywhereRevokesLegacyRefreshTokens() {
+	legacy := s.legacyRefreshToken(5, time.Now().Add(-10*time.Second))
+	require.NoError(s.T(), s.authSvc.RevokeAll(s.ctx, 5))
+
+	// отметка отзыва переживает срок access-токенов: refresh-токен живёт дольше
+	s.redis.FastForward(2 * time.Minute)
+	_, err := s.authSvc.Refresh(s.ctx, legacy)
+	require.ErrorIs(s.T(), err, appauth.ErrRefreshRevoked)
+	list, err := s.authSvc.ListSessions(s.ctx, 5)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), list, "revoked legacy token must not start a new session")
+
+	// токен без sid, выданный после отзыва, по-прежнему обменивается
+	_, err = s.authSvc.Refresh(s.ctx, s.legacyRefreshToken(5, time.Now().Add(10*time.Second)))
+	require.NoError(s.T(), err)
+}
+
+func (s *SessionTestSuite) TestLogoutEndsSession() {
+	tp := s.login(5, "Firefox", "10.0.0.1")
+	require.NoError(s.T(), s.authSvc.Revoke(s.ctx, tp.RefreshToken))
+	require.Equal(s.T(), http.StatusUnauthorized, s.do("GET", "/sessions", tp.AccessToken).Code)
+	list, err := s.authSvc.ListSessions(s.ctx, 5)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), list)
+}
+
+func (s *SessionTestSuite) TestExpiredSessionsDropOut() {
+	tp := s.login(5, "Firefox", "10.0.0.1")
+	s.redis.FastForward(2 * time.Hour)
+
+	fresh := s.login(5, "iPhone", "10.0.0.2")
+	list := s.sessions(fresh.AccessToken)
+	require.Len(s.T(), list, 1)
+	require.Equal(s.T(), "iPhone", list[0].UserAgent)
+	_, err := s.authSvc.Refresh(s.ctx, tp.RefreshToken)
+	require.Error(s.T(), err)
+}
+
+func (s *SessionTestSuite) TestRefreshTokenReuseRevokesFamily() {
+	first := s.login(5, "Firefox", "10.0.0.1")
+	other := s.login(5, "iPhone", "10.0.0.2")
+
+	second, err := s.authSvc.Refresh(s.ctx, first.RefreshToken)
+	require.NoError(s.T(), err)
+	third, err := s.authSvc.Refresh(s.ctx, second.RefreshToken)
+	require.NoError(s.T(), err)
+
+	// повторно предъявлен токен из середины цепочки, когда окно для параллельных запросов прошло
+	time.Sleep(30 * time.Millisecond)
+	_, err = s.authSvc.Refresh(s.ctx, second.RefreshToken)
+	require.ErrorIs(s.T(), err, appauth.ErrRefreshTokenReused)
+	var reuse *appauth.ReuseError
+	require.ErrorAs(s.T(), err, &reuse)
+	require.Equal(s.T(), int32(5), reuse.UserID)
+
+	// последний выданный токен семейства тоже больше не действует
+	_, err = s.authSvc.Refresh(s.ctx, third.RefreshToken)
+	require.ErrorIs(s.T(), err, appauth.ErrRefreshRevoked)
+
+	// другая сессия того же пользователя не затронута
+	list := s.sessions(other.AccessToken)
+	require.Len(s.T(), list, 1)
+	require.Equal(s.T(), "iPhone", list[0].UserAgent)
+	_, err = s.authSvc.Refresh(s.ctx, other.RefreshToken)
+	require.NoError(s.T(), err)
+}
+
+func (s *SessionTestSuite) TestRefreshReuseRecordsSecurityEvent() {
+	tp := s.login(5, "Firefox", "10.0.0.1")
+	w := s.refresh(tp.RefreshToken, "Firefox", "10.0.0.1")
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	var next dto.LoginResponse
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &next))
+	sid := s.sessions(next.AccessToken)[0].ID
+
+	s.mockQ.On("CreateSecurityEvent", mock.Anything, mock.MatchedBy(func(p db.CreateSecurityEventParams) bool {
+		var details map[string]string
+		return p.UserID == 5 &&
+			p.Event == "refresh_token_reuse" &&
+			p.Ip.String == "10.0.0.66" &&
+			p.UserAgent.String == "curl" &&
+			json.Unmarshal(p.Details, &details) == nil &&
+			details["session_id"] == sid
+	})).Return(nil).Once()
+
+	time.Sleep(30 * time.Millisecond)
+	w = s.refresh(tp.RefreshToken, "curl", "10.0.0.66")
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+
+	w = s.refresh(next.RefreshToken, "Firefox", "10.0.0.1")
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	require.Equal(s.T(), http.StatusUnauthorized, s.do("GET", "/sessions", next.AccessToken).Code)
+}
+
+func (s *SessionTestSuite) TestConcurrentRefreshIsNotReuse() {
+	s.authSvc.WithRefreshGrace(appauth.DefaultRefreshGrace)
+	tp := s.login(5, "Firefox", "10.0.0.1")
+
+	// две вкладки одновременно обновляют один и тот же токен
+	const n = 8
+	results := make(chan error, n)
+	pairs := make(chan *appauth.TokenPair, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			next, err := s.authSvc.Refresh(s.ctx, tp.RefreshToken)
+			results <- err
+			if err == nil {
+				pairs <- next
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+	close(pairs)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(s.T(), err, appauth.ErrRefreshRevoked)
+		require.NotErrorIs(s.T(), err, appauth.ErrRefreshTokenReused)
+	}
+	require.Equal(s.T(), 1, succeeded)
+
+	// проигравший запрос повторяется уже после обмена — это тоже не кража
+	_, err := s.authSvc.Refresh(s.ctx, tp.RefreshToken)
+	require.ErrorIs(s.T(), err, appauth.ErrRefreshRevoked)
+
+	// сессия жива, выданный победителю токен действует
+	winner := <-pairs
+	_, err = s.authSvc.ValidateAccessToken(s.ctx, winner.AccessToken)
+	require.NoError(s.T(), err)
+	_, err = s.authSvc.Refresh(s.ctx, winner.RefreshToken)
+	require.NoError(s.T(), err)
+}
+
+func (s *SessionTestSuite) TestRevokedTokenIsNotReuse() {
+	tp := s.login(5, "Firefox", "10.0.0.1")
+	require.NoError(s.T(), s.authSvc.Revoke(s.ctx, tp.RefreshToken))
+
+	// после выхода токен просто недействителен, событие не пишется
+	_, err := s.authSvc.Refresh(s.ctx, tp.RefreshToken)
+	require.ErrorIs(s.T(), err, appauth.ErrRefreshRevoked)
+	w := s.refresh(tp.RefreshToken, "Firefox", "10.0.0.1")
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "CreateSecurityEvent", mock.Anything, mock.Anything)
+}
+
+func TestSessionSuite(t *testing.T) {
+	suite.Run(t, new(SessionTestSuite))
+}
@@ -16,8 +16,10 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
 	"github.com/sqszy/TaskTracker/internal/db"
 	"github.com/sqszy/TaskTracker/internal/dto"
 	"github.com/sqszy/TaskTracker/internal/handlers"
@@ -32,6 +34,7 @@ type TaskTestSuite struct {
 	rdb     *redis.Client
 	authSvc *appauth.Service
 	handler *handlers.TaskHandler
+	events  *mocks.MockPublisher
 	router  *chi.Mux
 	userID  int32
 	ctx     context.Context
@@ -49,13 +52,20 @@ func (s *TaskTestSuite) SetupTest() {
 	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour)
 
 	s.mockQ = new(mocks.MockQuerier)
-	s.handler = handlers.NewTaskHandler(s.mockQ)
+	// каждое событие журнала попадает и в очередь вебхуков
+	s.mockQ.On("EnqueueWebhookDeliveries", mock.Anything, mock.Anything).Return(nil).Maybe()
+	// имена и аватары в ответах проверяются в profile_test.go
+	s.mockQ.On("ListUserSummaries", mock.Anything, mock.Anything).Return([]db.ListUserSummariesRow{}, nil).Maybe()
+	s.events = new(mocks.MockPublisher)
+	s.handler = handlers.NewTaskHandler(s.mockQ, s.events)
 
 	s.router = chi.NewRouter()
 	s.router.Use(middleware.AuthMiddleware(s.authSvc))
 	s.router.Post("/boards/{boardID}/CreateTask", s.handler.CreateTask)
 	s.router.Get("/boards/{boardID}/GetTasks", s.handler.GetTasks)
+	s.router.Get("/tasks/assigned", s.handler.GetAssignedTasks)
 	s.router.Patch("/boards/{boardID}/tasks/{taskID}", s.handler.PatchTask)
+	s.router.Post("/boards/{boardID}/tasks/{taskID}/move", s.handler.MoveTask)
 	s.router.Delete("/boards/{boardID}/tasks/{taskID}", s.handler.DeleteTask)
 }
 
@@ -90,7 +100,14 @@ func (s *TaskTestSuite) TestCreateTask() {
 		UpdatedAt:   pgtype.Timestamp{Time: s.now, Valid: true},
 		Deadline:    pgtype.Timestamp{Valid: false},
 	}
-	s.mockQ.On("CreateTask", mock.Anything, mock.Anything).Return(createdRow, nil)
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 5, UserID: s.userID}).
+		Return(authz.RoleEditor, nil)
+	s.mockQ.On("GetFirstBoardColumn", mock.Anything, int32(5)).Return(db.BoardColumn{ID: 50, BoardID: 5, Name: "todo"}, nil)
+	s.mockQ.On("GetLastRank", mock.Anything, mock.Anything).Return("", pgx.ErrNoRows)
+	s.mockQ.On("CreateTask", mock.Anything, mock.MatchedBy(func(p db.CreateTaskParams) bool {
+		return p.Status.String == "todo" && p.ColumnID.Int32 == 50 && p.Rank == "i"
+	})).Return(createdRow, nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.Anything).Return(nil)
 
 	s.router.ServeHTTP(w, req)
 	require.Equal(s.T(), http.StatusOK, w.Code)
@@ -100,6 +117,9 @@ func (s *TaskTestSuite) TestCreateTask() {
 	require.Equal(s.T(), int32(101), got.ID)
 	require.Equal(s.T(), "Buy milk", got.Title)
 
+	require.Equal(s.T(), []string{"task.created"}, s.events.Types())
+	require.Equal(s.T(), "Buy milk", s.events.Events[0].Task.Title)
+
 	s.mockQ.AssertExpectations(s.T())
 }
 
@@ -124,6 +144,8 @@ func (s *TaskTestSuite) TestGetTasks() {
 		UpdatedAt:   pgtype.Timestamp{Time: s.now, Valid: true},
 	}
 
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, db.GetBoardMemberRoleParams{BoardID: 5, UserID: s.userID}).
+		Return(authz.RoleViewer, nil)
 	s.mockQ.On("GetTasks", mock.Anything, mock.Anything).Return([]db.GetTasksRow{row}, nil)
 
 	s.router.ServeHTTP(w, req)
@@ -158,8 +180,20 @@ func (s *TaskTestSuite) TestPatchTask() {
 		UpdatedAt:   pgtype.Timestamp{Time: s.now, Valid: true},
 	}
 
+	s.mockQ.On("GetTaskMemberRole", mock.Anything, db.GetTaskMemberRoleParams{TaskID: 77, BoardID: 5, UserID: s.userID}).
+		Return(authz.RoleOwner, nil)
+	before := mockTask
+	before.Title = "Old title"
+	s.mockQ.On("GetTaskForUpdate", mock.Anything, db.GetTaskForUpdateParams{ID: 77, BoardID: pgtype.Int4{Int32: 5, Valid: true}}).
+		Return(before, nil)
+	s.mockQ.On("ListTaskAssignees", mock.Anything, int32(77)).Return([]int32(nil), nil)
 	s.mockQ.On("UpdateTask", mock.Anything, mock.Anything).Return(mockTask, nil)
 
+	var event db.CreateActivityEventParams
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { event = args.Get(1).(db.CreateActivityEventParams) }).
+		Return(nil)
+
 	s.router.ServeHTTP(w, req)
 	require.Equal(s.T(), http.StatusOK, w.Code)
 
@@ -168,6 +202,10 @@ func (s *TaskTestSuite) TestPatchTask() {
 	require.Equal(s.T(), int32(77), got.ID)
 	require.Equal(s.T(), "New title", got.Title)
 
+	require.Equal(s.T(), pgtype.Int4{Int32: 77, Valid: true}, event.TaskID)
+	require.Equal(s.T(), "updated", event.Action)
+	require.JSONEq(s.T(), `{"title":{"before":"Old title","after":"New title"}}`, string(event.Changes))
+
 	s.mockQ.AssertExpectations(s.T())
 }
 
@@ -179,7 +217,13 @@ func (s *TaskTestSuite) TestDeleteTask() {
 	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
 	w := httptest.NewRecorder()
 
+	s.mockQ.On("GetTaskMemberRole", mock.Anything, db.GetTaskMemberRoleParams{TaskID: 9, BoardID: 5, UserID: s.userID}).
+		Return(authz.RoleEditor, nil)
+	s.mockQ.On("GetTaskForUpdate", mock.Anything, mock.Anything).Return(db.Task{ID: 9, Title: "Old"}, nil)
 	s.mockQ.On("DeleteTask", mock.Anything, mock.Anything).Return(int64(1), nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.MatchedBy(func(e db.CreateActivityEventParams) bool {
+		return e.EntityID == 9 && e.Action == "deleted"
+	})).Return(nil)
 
 	s.router.ServeHTTP(w, req)
 	require.Equal(s.T(), http.StatusOK, w.Code)
@@ -188,6 +232,363 @@ func (s *TaskTestSuite) TestDeleteTask() {
 	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &res))
 	require.True(s.T(), res["success"])
 
+	require.Equal(s.T(), []string{"task.deleted"}, s.events.Types())
+	require.Equal(s.T(), int32(9), s.events.Events[0].TaskID)
+	require.Nil(s.T(), s.events.Events[0].Task)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *TaskTestSuite) TestPatchTaskByViewerForbidden() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	update := dto.UpdateTaskRequest{Title: ptrString("New title")}
+	b, _ := json.Marshal(update)
+	req := httptest.NewRequest("PATCH", "/boards/5/tasks/77", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	s.mockQ.On("GetTaskMemberRole", mock.Anything, mock.Anything).Return(authz.RoleViewer, nil)
+
+	s.router.ServeHTTP(w, req)
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+
+	s.mockQ.AssertNotCalled(s.T(), "UpdateTask", mock.Anything, mock.Anything)
+}
+
+func (s *TaskTestSuite) TestCreateTaskWithAssignees() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	bodyReq := dto.CreateTaskRequest{Title: "Review", AssigneeIDs: []int32{7, 8, 7}}
+	b, _ := json.Marshal(bodyReq)
+	req := httptest.NewRequest("POST", "/boards/5/CreateTask", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleEditor, nil)
+	s.mockQ.On("GetFirstBoardColumn", mock.Anything, int32(5)).Return(db.BoardColumn{ID: 50, BoardID: 5, Name: "todo"}, nil)
+	s.mockQ.On("GetLastRank", mock.Anything, mock.Anything).Return("", pgx.ErrNoRows)
+	s.mockQ.On("CreateTask", mock.Anything, mock.Anything).
+		Return(db.CreateTaskRow{ID: 102, BoardID: pgtype.Int4{Int32: 5, Valid: true}, Title: "Review"}, nil)
+	s.mockQ.On("CountBoardMembersByIDs", mock.Anything, db.CountBoardMembersByIDsParams{BoardID: 5, UserIds: []int32{7, 8}}).
+		Return(int64(2), nil)
+	s.mockQ.On("SetTaskAssignees", mock.Anything, db.SetTaskAssigneesParams{TaskID: 102, UserIds: []int32{7, 8}}).
+		Return(nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.Anything).Return(nil)
+
+	s.router.ServeHTTP(w, req)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var got dto.TaskDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(s.T(), []int32{7, 8}, got.AssigneeIDs)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *TaskTestSuite) TestCreateTaskAssigneeNotMember() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	bodyReq := dto.CreateTaskRequest{Title: "Review", AssigneeIDs: []int32{7, 99}}
+	b, _ := json.Marshal(bodyReq)
+	req := httptest.NewRequest("POST", "/boards/5/CreateTask", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleEditor, nil)
+	s.mockQ.On("GetFirstBoardColumn", mock.Anything, int32(5)).Return(db.BoardColumn{ID: 50, BoardID: 5, Name: "todo"}, nil)
+	s.mockQ.On("GetLastRank", mock.Anything, mock.Anything).Return("", pgx.ErrNoRows)
+	s.mockQ.On("CreateTask", mock.Anything, mock.Anything).Return(db.CreateTaskRow{ID: 103}, nil)
+	s.mockQ.On("CountBoardMembersByIDs", mock.Anything, mock.Anything).Return(int64(1), nil)
+
+	s.router.ServeHTTP(w, req)
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	s.mockQ.AssertNotCalled(s.T(), "SetTaskAssignees", mock.Anything, mock.Anything)
+}
+
+func (s *TaskTestSuite) TestPatchTaskClearsAssignees() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest("PATCH", "/boards/5/tasks/77", bytes.NewBufferString(`{"assignee_ids":[]}`))
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	s.mockQ.On("GetTaskMemberRole", mock.Anything, mock.Anything).Return(authz.RoleEditor, nil)
+	s.mockQ.On("GetTaskForUpdate", mock.Anything, mock.Anything).Return(db.Task{ID: 77}, nil)
+	s.mockQ.On("ListTaskAssignees", mock.Anything, int32(77)).Return([]int32{4}, nil).Once()
+	s.mockQ.On("UpdateTask", mock.Anything, mock.Anything).Return(db.Task{ID: 77}, nil)
+	s.mockQ.On("SetTaskAssignees", mock.Anything, db.SetTaskAssigneesParams{TaskID: 77, UserIds: []int32{}}).Return(nil)
+	s.mockQ.On("ListTaskAssignees", mock.Anything, int32(77)).Return([]int32(nil), nil).Once()
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.MatchedBy(func(e db.CreateActivityEventParams) bool {
+		return string(e.Changes) == `{"assignee_ids":{"before":[4],"after":[]}}`
+	})).Return(nil)
+
+	s.router.ServeHTTP(w, req)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	require.Contains(s.T(), w.Body.String(), `"assignee_ids":[]`)
+
+	s.mockQ.AssertNotCalled(s.T(), "CountBoardMembersByIDs", mock.Anything, mock.Anything)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *TaskTestSuite) TestGetAssignedTasks() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest("GET", "/tasks/assigned?status=todo&deadline=with&sort_by=deadline&sort_dir=asc", nil)
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	s.mockQ.On("GetAssignedTasks", mock.Anything, db.GetAssignedTasksParams{
+		UserID:         s.userID,
+		Status:         "todo",
+		HasDeadlineSet: true,
+		HasDeadline:    true,
+		SortCode:       2,
+	}).Return([]db.GetAssignedTasksRow{
+		{ID: 1, BoardID: pgtype.Int4{Int32: 5, Valid: true}, Title: "A", AssigneeIds: []int32{s.userID}},
+		{ID: 2, BoardID: pgtype.Int4{Int32: 6, Valid: true}, Title: "B", AssigneeIds: []int32{s.userID, 40}},
+	}, nil)
+
+	s.router.ServeHTTP(w, req)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var resp []dto.TaskDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(s.T(), resp, 2)
+	require.Equal(s.T(), int32(6), resp[1].BoardID)
+	require.Equal(s.T(), []int32{s.userID, 40}, resp[1].AssigneeIDs)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *TaskTestSuite) TestCreateTaskUnknownStatus() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	b, _ := json.Marshal(dto.CreateTaskRequest{Title: "T", Status: "blocked"})
+	req := httptest.NewRequest("POST", "/boards/5/CreateTask", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleEditor, nil)
+	s.mockQ.On("GetBoardColumnByName", mock.Anything, db.GetBoardColumnByNameParams{BoardID: 5, Name: "blocked"}).
+		Return(db.BoardColumn{}, pgx.ErrNoRows)
+
+	s.router.ServeHTTP(w, req)
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	s.mockQ.AssertNotCalled(s.T(), "CreateTask", mock.Anything, mock.Anything)
+}
+
+func (s *TaskTestSuite) TestPatchTaskMovesToColumn() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest("PATCH", "/boards/5/tasks/77", bytes.NewBufferString(`{"column_id":52}`))
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	s.mockQ.On("GetTaskMemberRole", mock.Anything, mock.Anything).Return(authz.RoleEditor, nil)
+	s.mockQ.On("GetTaskForUpdate", mock.Anything, mock.Anything).
+		Return(db.Task{ID: 77, Status: pgtype.Text{String: "todo", Valid: true}}, nil)
+	s.mockQ.On("ListTaskAssignees", mock.Anything, int32(77)).Return([]int32(nil), nil)
+	s.mockQ.On("GetBoardColumn", mock.Anything, db.GetBoardColumnParams{ID: 52, BoardID: 5}).
+		Return(db.BoardColumn{ID: 52, BoardID: 5, Name: "review"}, nil)
+	// задача встаёт в конец новой колонки
+	s.mockQ.On("GetLastRank", mock.Anything, db.GetLastRankParams{ColumnID: pgtype.Int4{Int32: 52, Valid: true}, TaskID: 77}).
+		Return("m", nil)
+	s.mockQ.On("UpdateTask", mock.Anything, mock.MatchedBy(func(p db.UpdateTaskParams) bool {
+		return p.Status.String == "review" && p.ColumnID.Int32 == 52 && p.Rank.String == "t"
+	})).Return(db.Task{
+		ID:       77,
+		Status:   pgtype.Text{String: "review", Valid: true},
+		ColumnID: pgtype.Int4{Int32: 52, Valid: true},
+	}, nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.Anything).Return(nil)
+
+	s.router.ServeHTTP(w, req)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var got dto.TaskDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(s.T(), "review", got.Status)
+	require.Equal(s.T(), int32(52), got.ColumnID)
+
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *TaskTestSuite) move(body string) *httptest.ResponseRecorder {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest("POST", "/boards/5/tasks/77/move", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *TaskTestSuite) expectMoveBase(column db.BoardColumn) {
+	s.mockQ.On("GetTaskMemberRole", mock.Anything, mock.Anything).Return(authz.RoleEditor, nil)
+	s.mockQ.On("GetTaskForUpdate", mock.Anything, mock.Anything).Return(db.Task{
+		ID:       77,
+		BoardID:  pgtype.Int4{Int32: 5, Valid: true},
+		Status:   pgtype.Text{String: "todo", Valid: true},
+		ColumnID: pgtype.Int4{Int32: 50, Valid: true},
+		Rank:     "z",
+	}, nil)
+	s.mockQ.On("GetBoardColumn", mock.Anything, db.GetBoardColumnParams{ID: column.ID, BoardID: 5}).Return(column, nil)
+	s.mockQ.On("ListTaskAssignees", mock.Anything, int32(77)).Return([]int32(nil), nil)
+}
+
+func (s *TaskTestSuite) neighbour(id int32, columnID int32, rank string) {
+	s.mockQ.On("GetTaskRank", mock.Anything, db.GetTaskRankParams{ID: id, BoardID: pgtype.Int4{Int32: 5, Valid: true}}).
+		Return(db.GetTaskRankRow{ColumnID: pgtype.Int4{Int32: columnID, Valid: true}, Rank: rank}, nil)
+}
+
+func (s *TaskTestSuite) TestMoveTaskWithinColumn() {
+	s.expectMoveBase(db.BoardColumn{ID: 50, BoardID: 5, Name: "todo"})
+	s.neighbour(2, 50, "a")
+	s.neighbour(3, 50, "c")
+	s.mockQ.On("MoveTask", mock.Anything, db.MoveTaskParams{
+		ColumnID: pgtype.Int4{Int32: 50, Valid: true},
+		Status:   pgtype.Text{String: "todo", Valid: true},
+		Rank:     "b",
+		ID:       77,
+		BoardID:  pgtype.Int4{Int32: 5, Valid: true},
+	}).Return(db.Task{
+		ID:       77,
+		Status:   pgtype.Text{String: "todo", Valid: true},
+		ColumnID: pgtype.Int4{Int32: 50, Valid: true},
+		Rank:     "b",
+	}, nil)
+
+	w := s.move(`{"column_id":50,"prev_id":2,"next_id":3}`)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var got dto.TaskDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	require.Equal(s.T(), "b", got.Rank)
+
+	// обновляется только перемещённая задача, перестановка в журнал не пишется
+	s.mockQ.AssertNotCalled(s.T(), "UpdateTaskRanks", mock.Anything, mock.Anything)
+	s.mockQ.AssertNotCalled(s.T(), "CreateActivityEvent", mock.Anything, mock.Anything)
+	require.Equal(s.T(), []string{"task.moved"}, s.events.Types())
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *TaskTestSuite) TestMoveTaskToColumnTop() {
+	s.expectMoveBase(db.BoardColumn{ID: 52, BoardID: 5, Name: "review"})
+	s.neighbour(4, 52, "k")
+	s.mockQ.On("GetPrevRank", mock.Anything, db.GetPrevRankParams{
+		ColumnID: pgtype.Int4{Int32: 52, Valid: true},
+		TaskID:   77,
+		Rank:     "k",
+	}).Return("", pgx.ErrNoRows)
+	s.mockQ.On("MoveTask", mock.Anything, mock.MatchedBy(func(p db.MoveTaskParams) bool {
+		return p.Rank < "k" && p.Status.String == "review"
+	})).Return(db.Task{
+		ID:       77,
+		Status:   pgtype.Text{String: "review", Valid: true},
+		ColumnID: pgtype.Int4{Int32: 52, Valid: true},
+		Rank:     "a",
+	}, nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.MatchedBy(func(e db.CreateActivityEventParams) bool {
+		return string(e.Changes) == `{"status":{"before":"todo","after":"review"}}`
+	})).Return(nil)
+
+	w := s.move(`{"column_id":52,"next_id":4}`)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *TaskTestSuite) TestMoveTaskRebalancesWhenNoGap() {
+	s.expectMoveBase(db.BoardColumn{ID: 50, BoardID: 5, Name: "todo"})
+	// после конкурентных перемещений у соседей одинаковые ключи
+	s.neighbour(2, 50, "a")
+	s.neighbour(3, 50, "a")
+	s.mockQ.On("ListColumnRanks", mock.Anything, db.ListColumnRanksParams{
+		ColumnID: pgtype.Int4{Int32: 50, Valid: true},
+		TaskID:   77,
+	}).Return([]db.ListColumnRanksRow{{ID: 2, Rank: "a"}, {ID: 3, Rank: "a"}, {ID: 9, Rank: "q"}}, nil)
+
+	var ranks db.UpdateTaskRanksParams
+	s.mockQ.On("UpdateTaskRanks", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { ranks = args.Get(1).(db.UpdateTaskRanksParams) }).
+		Return(nil)
+	var moved db.MoveTaskParams
+	s.mockQ.On("MoveTask", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { moved = args.Get(1).(db.MoveTaskParams) }).
+		Return(db.Task{ID: 77, Status: pgtype.Text{String: "todo", Valid: true}}, nil)
+
+	w := s.move(`{"column_id":50,"prev_id":2,"next_id":3}`)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	require.Equal(s.T(), []int32{2, 3, 9}, ranks.Ids)
+	require.Less(s.T(), ranks.Ranks[0], moved.Rank)
+	require.Less(s.T(), moved.Rank, ranks.Ranks[1])
+	require.Less(s.T(), ranks.Ranks[1], ranks.Ranks[2])
+}
+
+func (s *TaskTestSuite) TestMoveTaskNeighbourFromOtherColumn() {
+	s.expectMoveBase(db.BoardColumn{ID: 50, BoardID: 5, Name: "todo"})
+	s.neighbour(2, 51, "a")
+
+	w := s.move(`{"column_id":50,"prev_id":2}`)
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "MoveTask", mock.Anything, mock.Anything)
+}
+
+func (s *TaskTestSuite) TestCreateTaskStrictWIPLimit() {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, s.userID)
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest("POST", "/boards/5/CreateTask", bytes.NewBufferString(`{"title":"T","column_id":51}`))
+	req.Header.Set("Authorization", "Bearer "+tp.AccessToken)
+	w := httptest.NewRecorder()
+
+	column := db.BoardColumn{ID: 51, BoardID: 5, Name: "in_progress", WipLimit: pgtype.Int4{Int32: 2, Valid: true}}
+	s.mockQ.On("GetBoardMemberRole", mock.Anything, mock.Anything).Return(authz.RoleEditor, nil)
+	s.mockQ.On("GetBoardColumn", mock.Anything, db.GetBoardColumnParams{ID: 51, BoardID: 5}).Return(column, nil)
+	s.mockQ.On("GetColumnWIP", mock.Anything, db.GetColumnWIPParams{TaskID: 0, ColumnID: 51}).
+		Return(db.GetColumnWIPRow{WipLimit: column.WipLimit, WipMode: "strict", TaskCount: 2}, nil)
+
+	s.router.ServeHTTP(w, req)
+	require.Equal(s.T(), http.StatusConflict, w.Code)
+	require.JSONEq(s.T(),
+		`{"error":"wip_limit_exceeded","column_id":51,"column":"in_progress","limit":2,"count":2}`,
+		w.Body.String())
+
+	s.mockQ.AssertNotCalled(s.T(), "CreateTask", mock.Anything, mock.Anything)
+	require.Empty(s.T(), s.events.Events)
+}
+
+func (s *TaskTestSuite) TestMoveTaskSoftWIPLimit() {
+	column := db.BoardColumn{ID: 52, BoardID: 5, Name: "review", WipLimit: pgtype.Int4{Int32: 1, Valid: true}}
+	s.expectMoveBase(column)
+	s.mockQ.On("GetColumnWIP", mock.Anything, db.GetColumnWIPParams{TaskID: 77, ColumnID: 52}).
+		Return(db.GetColumnWIPRow{WipLimit: column.WipLimit, WipMode: "soft", TaskCount: 1}, nil)
+	s.mockQ.On("GetLastRank", mock.Anything, mock.Anything).Return("k", nil)
+	s.mockQ.On("MoveTask", mock.Anything, mock.Anything).Return(db.Task{
+		ID:       77,
+		Status:   pgtype.Text{String: "review", Valid: true},
+		ColumnID: pgtype.Int4{Int32: 52, Valid: true},
+		Rank:     "s",
+	}, nil)
+	s.mockQ.On("CreateActivityEvent", mock.Anything, mock.Anything).Return(nil)
+
+	w := s.move(`{"column_id":52}`)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+
+	var got dto.TaskDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	require.True(s.T(), got.WIPLimitExceeded)
 	s.mockQ.AssertExpectations(s.T())
 }
 
@@ -0,0 +1,217 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+type TokenTestSuite struct {
+	suite.Suite
+	redis   *miniredis.Miniredis
+	rdb     *redis.Client
+	authSvc *appauth.Service
+	mockQ   *mocks.MockQuerier
+	router  *chi.Mux
+	ctx     context.Context
+}
+
+func (s *TokenTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.redis = miniredis.RunT(s.T())
+	s.rdb = redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.mockQ = new(mocks.MockQuerier)
+	s.authSvc = appauth.NewService(s.rdb, "acc", "ref", time.Minute, time.Hour).WithPersonalTokens(s.mockQ)
+
+	// ответ заглушки — ID пользователя из контекста
+	whoami := func(w http.ResponseWriter, r *http.Request) {
+		uid, _ := middleware.GetUserID(r)
+		_ = json.NewEncoder(w).Encode(map[string]int32{"user_id": uid})
+	}
+
+	h := handlers.NewTokenHandler(s.mockQ)
+	s.router = chi.NewRouter()
+	s.router.Group(func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(s.authSvc))
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.SessionOnly)
+			r.Get("/tokens", h.ListTokens)
+			r.Post("/tokens", h.CreateToken)
+			r.Delete("/tokens/{tokenID}", h.RevokeToken)
+		})
+		r.With(middleware.RequireScope("boards")).Get("/GetBoards", whoami)
+		r.With(middleware.RequireScope("boards")).Post("/CreateBoard", whoami)
+		r.With(middleware.RequireScope("tasks")).Get("/tasks/assigned", whoami)
+		r.With(middleware.RequireScope("tasks")).Post("/boards/{boardID}/CreateTask", whoami)
+	})
+}
+
+func (s *TokenTestSuite) TearDownTest() {
+	_ = s.rdb.Close()
+	s.redis.Close()
+}
+
+func (s *TokenTestSuite) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *TokenTestSuite) jwt(userID int32) string {
+	tp, err := s.authSvc.GenerateTokenPair(s.ctx, userID)
+	require.NoError(s.T(), err)
+	return tp.AccessToken
+}
+
+// pat регистрирует в моке действующий токен с заданными правами
+func (s *TokenTestSuite) pat(userID int32, scopes ...string) string {
+	token, hash, err := appauth.NewPersonalToken()
+	require.NoError(s.T(), err)
+	s.mockQ.On("GetPersonalAccessTokenByHash", mock.Anything, hash).Return(db.PersonalAccessToken{
+		ID:     10 + userID,
+		UserID: userID,
+		Scopes: scopes,
+	}, nil)
+	s.mockQ.On("TouchPersonalAccessToken", mock.Anything, 10+userID).Return(nil)
+	return token
+}
+
+func (s *TokenTestSuite) TestCreateToken() {
+	var hash string
+	s.mockQ.On("CreatePersonalAccessToken", mock.Anything, mock.MatchedBy(func(p db.CreatePersonalAccessTokenParams) bool {
+		hash = p.TokenHash
+		return p.UserID == 5 &&
+			p.Name == "CI" &&
+			p.ExpiresInDays == 90 &&
+			strings.HasPrefix(p.TokenPrefix, appauth.PersonalTokenPrefix) &&
+			len(p.Scopes) == 2 && p.Scopes[0] == "boards:read" && p.Scopes[1] == "tasks:write"
+	})).Return(db.PersonalAccessToken{
+		ID:          3,
+		UserID:      5,
+		Name:        "CI",
+		TokenPrefix: "ttp_abcdefgh",
+		Scopes:      []string{"boards:read", "tasks:write"},
+		ExpiresAt:   pgtype.Timestamp{Time: time.Now().Add(90 * 24 * time.Hour), Valid: true},
+		CreatedAt:   pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}, nil).Once()
+
+	w := s.do("POST", "/tokens", s.jwt(5), dto.CreatePersonalTokenRequest{
+		Name:   " CI ",
+		Scopes: []string{"tasks:write", "boards:read", "tasks:write"},
+	})
+	require.Equal(s.T(), http.StatusCreated, w.Code)
+	var resp dto.PersonalTokenDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	require.True(s.T(), appauth.IsPersonalToken(resp.Token))
+	require.Equal(s.T(), hash, appauth.HashToken(resp.Token), "only the hash is stored")
+	require.NotNil(s.T(), resp.ExpiresAt)
+	require.Nil(s.T(), resp.LastUsedAt)
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func (s *TokenTestSuite) TestCreateTokenValidation() {
+	w := s.do("POST", "/tokens", s.jwt(5), dto.CreatePersonalTokenRequest{Name: "CI", Scopes: []string{"admin"}})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	w = s.do("POST", "/tokens", s.jwt(5), dto.CreatePersonalTokenRequest{Name: "CI"})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	w = s.do("POST", "/tokens", s.jwt(5), dto.CreatePersonalTokenRequest{Name: "CI", Scopes: []string{"tasks:read"}, ExpiresInDays: 1000})
+	require.Equal(s.T(), http.StatusBadRequest, w.Code)
+	s.mockQ.AssertNotCalled(s.T(), "CreatePersonalAccessToken", mock.Anything, mock.Anything)
+}
+
+func (s *TokenTestSuite) TestPersonalTokenScopes() {
+	token := s.pat(5, "tasks:read")
+
+	w := s.do("GET", "/tasks/assigned", token, nil)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	require.JSONEq(s.T(), `{"user_id":5}`, w.Body.String())
+	s.mockQ.AssertCalled(s.T(), "TouchPersonalAccessToken", mock.Anything, int32(15))
+
+	w = s.do("POST", "/boards/1/CreateTask", token, nil)
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+	require.Contains(s.T(), w.Body.String(), "tasks:write")
+	w = s.do("GET", "/GetBoards", token, nil)
+	require.Equal(s.T(), http.StatusForbidden, w.Code)
+
+	// запись включает чтение
+	writer := s.pat(6, "boards:write")
+	require.Equal(s.T(), http.StatusOK, s.do("GET", "/GetBoards", writer, nil).Code)
+	require.Equal(s.T(), http.StatusOK, s.do("POST", "/CreateBoard", writer, nil).Code)
+}
+
+func (s *TokenTestSuite) TestPersonalTokenCannotManageAccount() {
+	token := s.pat(5, appauth.Scopes...)
+	require.Equal(s.T(), http.StatusForbidden, s.do("GET", "/tokens", token, nil).Code)
+	require.Equal(s.T(), http.StatusForbidden, s.do("POST", "/tokens", token, dto.CreatePersonalTokenRequest{
+		Name:   "escalate",
+		Scopes: []string{"boards:write"},
+	}).Code)
+	s.mockQ.AssertNotCalled(s.T(), "CreatePersonalAccessToken", mock.Anything, mock.Anything)
+}
+
+func (s *TokenTestSuite) TestUnknownOrExpiredToken() {
+	s.mockQ.On("GetPersonalAccessTokenByHash", mock.Anything, mock.Anything).Return(db.PersonalAccessToken{}, pgx.ErrNoRows)
+	w := s.do("GET", "/tasks/assigned", appauth.PersonalTokenPrefix+"nope", nil)
+	require.Equal(s.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (s *TokenTestSuite) TestJWTKeepsFullAccess() {
+	token := s.jwt(5)
+	require.Equal(s.T(), http.StatusOK, s.do("GET", "/GetBoards", token, nil).Code)
+	require.Equal(s.T(), http.StatusOK, s.do("POST", "/boards/1/CreateTask", token, nil).Code)
+	s.mockQ.AssertNotCalled(s.T(), "GetPersonalAccessTokenByHash", mock.Anything, mock.Anything)
+}
+
+func (s *TokenTestSuite) TestListAndRevoke() {
+	s.mockQ.On("ListPersonalAccessTokens", mock.Anything, int32(5)).Return([]db.PersonalAccessToken{{
+		ID:          3,
+		UserID:      5,
+		Name:        "CI",
+		TokenHash:   "secret-hash",
+		TokenPrefix: "ttp_abcdefgh",
+		Scopes:      []string{"tasks:read"},
+		LastUsedAt:  pgtype.Timestamp{Time: time.Now(), Valid: true},
+	}}, nil)
+	w := s.do("GET", "/tokens", s.jwt(5), nil)
+	require.Equal(s.T(), http.StatusOK, w.Code)
+	require.NotContains(s.T(), w.Body.String(), "secret-hash")
+	var list []dto.PersonalTokenDTO
+	require.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(s.T(), list, 1)
+	require.Equal(s.T(), "ttp_abcdefgh", list[0].Prefix)
+	require.Empty(s.T(), list[0].Token)
+	require.NotNil(s.T(), list[0].LastUsedAt)
+
+	s.mockQ.On("DeletePersonalAccessToken", mock.Anything, db.DeletePersonalAccessTokenParams{ID: 3, UserID: 5}).Return(int64(1), nil)
+	s.mockQ.On("DeletePersonalAccessToken", mock.Anything, db.DeletePersonalAccessTokenParams{ID: 3, UserID: 6}).Return(int64(0), nil)
+	require.Equal(s.T(), http.StatusOK, s.do("DELETE", "/tokens/3", s.jwt(5), nil).Code)
+	require.Equal(s.T(), http.StatusNotFound, s.do("DELETE", "/tokens/3", s.jwt(6), nil).Code)
+}
+
+func TestTokenSuite(t *testing.T) {
+	suite.Run(t, new(TokenTestSuite))
+}
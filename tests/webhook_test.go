@@ -0,0 +1,389 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sqszy/TaskTracker/internal/activity"
+	appauth "github.com/sqszy/TaskTracker/internal/auth"
+	"github.com/sqszy/TaskTracker/internal/authz"
+	"github.com/sqszy/TaskTracker/internal/db"
+	"github.com/sqszy/TaskTracker/internal/dto"
+	"github.com/sqszy/TaskTracker/internal/handlers"
+	"github.com/sqszy/TaskTracker/internal/middleware"
+	"github.com/sqszy/TaskTracker/internal/webhook"
+	"github.com/sqszy/TaskTracker/tests/mocks"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"task.created"}`)
+	sig := webhook.Sign("topsecret", "1700000000", body)
+
+	require.True(t, strings.HasPrefix(sig, "sha256="))
+	require.True(t, webhook.Verify("topsecret", "1700000000", body, sig))
+	require.False(t, webhook.Verify("topsecret", "1700000001", body, sig))
+	require.False(t, webhook.Verify("other", "1700000000", body, sig))
+	require.False(t, webhook.Verify("topsecret", "1700000000", []byte(`{"event":"task.deleted"}`), sig))
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	w := webhook.NewWorker(new(mocks.MockQuerier)).WithRetries(8, 30*time.Second, 5*time.Minute)
+
+	require.Equal(t, 30*time.Second, w.Backoff(1))
+	require.Equal(t, time.Minute, w.Backoff(2))
+	require.Equal(t, 2*time.Minute, w.Backoff(3))
+	require.Equal(t, 4*time.Minute, w.Backoff(4))
+	require.Equal(t, 5*time.Minute, w.Backoff(5))
+	require.Equal(t, 5*time.Minute, w.Backoff(7))
+}
+
+// claimDue отдаёт доставки по одной, как их забирает воркер, а затем пустую очередь
+func claimDue(m *mocks.MockQuerier, rows ...db.ClaimDueWebhookDeliveriesRow) {
+	params := db.ClaimDueWebhookDeliveriesParams{LeaseSeconds: 60, BatchSize: 1}
+	for _, row := range rows {
+		m.On("ClaimDueWebhookDeliveries", mock.Anything, params).Return([]db.ClaimDueWebhookDeliveriesRow{row}, nil).Once()
+	}
+	m.On("ClaimDueWebhookDeliveries", mock.Anything, params).Return([]db.ClaimDueWebhookDeliveriesRow{}, nil).Maybe()
+}
+
+func TestWebhookWorkerDeliversSignedPayload(t *testing.T) {
+	payload := []byte(`{"event":"task.created","board_id":1}`)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	m := new(mocks.MockQuerier)
+	claimDue(m, db.ClaimDueWebhookDeliveriesRow{
+		ID: 41, WebhookID: 7, Event: "task.created", Payload: payload, Url: receiver.URL, Secret: "whsec_test",
+	})
+	m.On("CreateWebhookAttempt", mock.Anything, mock.MatchedBy(func(a db.CreateWebhookAttemptParams) bool {
+		return a.DeliveryID == 41 && a.Attempt == 1 &&
+			a.StatusCode == pgtype.Int4{Int32: 204, Valid: true} && !a.Error.Valid
+	})).Return(nil)
+	m.On("UpdateWebhookDeliveryResult", mock.Anything, db.UpdateWebhookDeliveryResultParams{
+		Status:         webhook.StatusSucceeded,
+		Attempts:       1,
+		LastStatusCode: pgtype.Int4{Int32: 204, Valid: true},
+		ID:             41,
+	}).Return(nil)
+
+	n, err := webhook.NewWorker(m).WithClient(receiver.Client()).ProcessDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	req := <-got
+	require.Equal(t, payload, req.body)
+	require.Equal(t, "task.created", req.header.Get(webhook.HeaderEvent))
+	require.Equal(t, "41", req.header.Get(webhook.HeaderDelivery))
+	require.Equal(t, "application/json", req.header.Get("Content-Type"))
+	require.True(t, webhook.Verify("whsec_test", req.header.Get(webhook.HeaderTimestamp), req.body,
+		req.header.Get(webhook.HeaderSignature)))
+	m.AssertExpectations(t)
+}
+
+func TestWebhookWorkerSchedulesRetry(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	m := new(mocks.MockQuerier)
+	// две попытки уже были: третья неудача откладывает доставку на base·4
+	claimDue(m, db.ClaimDueWebhookDeliveriesRow{
+		ID: 41, WebhookID: 7, Event: "task.updated", Payload: []byte(`{}`), Attempts: 2, Url: receiver.URL, Secret: "s",
+	})
+	m.On("CreateWebhookAttempt", mock.Anything, mock.MatchedBy(func(a db.CreateWebhookAttemptParams) bool {
+		return a.DeliveryID == 41 && a.Attempt == 3 && a.StatusCode.Int32 == 502 && a.Error.Valid
+	})).Return(nil)
+	m.On("UpdateWebhookDeliveryResult", mock.Anything, mock.MatchedBy(func(p db.UpdateWebhookDeliveryResultParams) bool {
+		return p.ID == 41 && p.Status == webhook.StatusPending && p.Attempts == 3 &&
+			p.RetryIn == 120 && p.LastStatusCode.Int32 == 502 &&
+			p.LastError.String == "unexpected status 502 Bad Gateway"
+	})).Return(nil)
+
+	_, err := webhook.NewWorker(m).WithClient(receiver.Client()).ProcessDue(context.Background())
+	require.NoError(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestWebhookWorkerLeasesDeliveriesOneByOne(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	m := new(mocks.MockQuerier)
+	claimDue(m,
+		db.ClaimDueWebhookDeliveriesRow{ID: 41, Event: "task.created", Payload: []byte(`{}`), Url: receiver.URL, Secret: "s"},
+		db.ClaimDueWebhookDeliveriesRow{ID: 42, Event: "task.updated", Payload: []byte(`{}`), Url: receiver.URL, Secret: "s"},
+	)
+	m.On("CreateWebhookAttempt", mock.Anything, mock.Anything).Return(nil)
+	m.On("UpdateWebhookDeliveryResult", mock.Anything, mock.Anything).Return(nil)
+
+	n, err := webhook.NewWorker(m).WithClient(receiver.Client()).ProcessDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	// аренда берётся на каждую доставку, а не на пачку: третий запрос нашёл очередь пустой
+	m.AssertNumberOfCalls(t, "ClaimDueWebhookDeliveries", 3)
+	m.AssertNumberOfCalls(t, "UpdateWebhookDeliveryResult", 2)
+}
+
+func TestWebhookWorkerGivesUpAfterMaxAttempts(t *testing.T) {
+	m := new(mocks.MockQuerier)
+	// на закрытом порту соединение не устанавливается: ответа нет, код не записывается
+	claimDue(m, db.ClaimDueWebhookDeliveriesRow{
+		ID: 41, WebhookID: 7, Event: "task.updated", Payload: []byte(`{}`), Attempts: 2, Url: "http://127.0.0.1:1/hook", Secret: "s",
+	})
+	m.On("CreateWebhookAttempt", mock.Anything, mock.MatchedBy(func(a db.CreateWebhookAttemptParams) bool {
+		return a.Attempt == 3 && !a.StatusCode.Valid && a.Error.Valid
+	})).Return(nil)
+	m.On("UpdateWebhookDeliveryResult", mock.Anything, mock.MatchedBy(func(p db.UpdateWebhookDeliveryResultParams) bool {
+		return p.Status == webhook.StatusFailed && p.Attempts == 3 && p.RetryIn == 0
+	})).Return(nil)
+
+	_, err := webhook.NewWorker(m).WithRetries(3, time.Second, time.Minute).ProcessDue(context.Background())
+	require.NoError(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestWebhookClientRefusesInternalAddresses(t *testing.T) {
+	hit := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	defer internal.Close()
+
+	// имя, которое резолвится во внутренний адрес, не проходит проверку при соединении
+	_, port, _ := net.SplitHostPort(internal.Listener.Addr().String())
+	for _, target := range []string{internal.URL, "http://localhost:" + port} {
+		_, err := webhook.NewClient(time.Second).Get(target)
+		require.ErrorIs(t, err, webhook.ErrForbiddenAddress, target)
+	}
+	require.False(t, hit)
+
+	for addr, public := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+		"64:ff9b::a00:1":  false,
+	} {
+		require.Equal(t, public, webhook.PublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestWebhookWorkerDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { followed = true }))
+	defer target.Close()
+	receiver := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer receiver.Close()
+
+	// клиент теста пускает на 127.0.0.1, но редиректы воркер запрещает сам
+	client := receiver.Client()
+	client.CheckRedirect = webhook.NewClient(time.Second).CheckRedirect
+
+	m := new(mocks.MockQuerier)
+	claimDue(m, db.ClaimDueWebhookDeliveriesRow{
+		ID: 41, WebhookID: 7, Event: "task.updated", Payload: []byte(`{}`), Url: receiver.URL, Secret: "s",
+	})
+	m.On("CreateWebhookAttempt", mock.Anything, mock.MatchedBy(func(a db.CreateWebhookAttemptParams) bool {
+		return a.StatusCode.Int32 == http.StatusFound && a.Error.Valid
+	})).Return(nil)
+	m.On("UpdateWebhookDeliveryResult", mock.Anything, mock.MatchedBy(func(p db.UpdateWebhookDeliveryResultParams) bool {
+		return p.Status == webhook.StatusPending
+	})).Return(nil)
+
+	_, err := webhook.NewWorker(m).WithClient(client).ProcessDue(context.Background())
+	require.NoError(t, err)
+	require.False(t, followed)
+	m.AssertExpectations(t)
+}
+
+func TestActivityEnqueuesWebhookPayload(t *testing.T) {
+	m := new(mocks.MockQuerier)
+	m.On("CreateActivityEvent", mock.Anything, mock.Anything).Return(nil)
+	var enqueued db.EnqueueWebhookDeliveriesParams
+	m.On("EnqueueWebhookDeliveries", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { enqueued = args.Get(1).(db.EnqueueWebhookDeliveriesParams) }).
+		Return(nil)
+
+	err := activity.Record(context.Background(), m, activity.Event{
+		BoardID:  1,
+		TaskID:   2,
+		ActorID:  3,
+		Entity:   activity.EntityTask,
+		EntityID: 2,
+		Action:   activity.Updated,
+		Changes:  map[string]activity.Change{"status": {Before: "todo", After: "done"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "task.updated", enqueued.Event)
+	require.Equal(t, int32(1), enqueued.BoardID)
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(enqueued.Payload, &payload))
+	require.Equal(t, "task.updated", payload["event"])
+	require.Equal(t, float64(2), payload["task_id"])
+	require.Equal(t, map[string]any{"before": "todo", "after": "done"}, payload["changes"].(map[string]any)["status"])
+	require.NotEmpty(t, payload["occurred_at"])
+}
+
+func newWebhookRouter(t *testing.T, m *mocks.MockQuerier) (*chi.Mux, string) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	authSvc := appauth.NewService(rdb, "acc", "ref", time.Minute, time.Hour)
+	tp, err := authSvc.GenerateTokenPair(context.Background(), 3)
+	require.NoError(t, err)
+
+	h := handlers.NewWebhookHandler(m)
+	r := chi.NewRouter()
+	r.Use(middleware.AuthMiddleware(authSvc))
+	r.Post("/boards/{boardID}/webhooks", h.CreateWebhook)
+	r.Get("/boards/{boardID}/webhooks/{webhookID}/deliveries/{deliveryID}", h.GetDelivery)
+	r.Post("/boards/{boardID}/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", h.Redeliver)
+	return r, tp.AccessToken
+}
+
+func doWebhook(r *chi.Mux, token, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateWebhookGeneratesSecret(t *testing.T) {
+	m := new(mocks.MockQuerier)
+	stubRole(m, authz.RoleOwner)
+	m.On("CreateWebhook", mock.Anything, mock.MatchedBy(func(p db.CreateWebhookParams) bool {
+		return p.BoardID == 1 && p.Url == "https://ci.ex.com/hook" &&
+			strings.HasPrefix(p.Secret, "whsec_") && len(p.Secret) == len("whsec_")+64 &&
+			p.CreatedBy == pgtype.Int4{Int32: 3, Valid: true}
+	})).Return(db.Webhook{
+		ID: 7, BoardID: 1, Url: "https://ci.ex.com/hook", Secret: "whsec_generated",
+		Events: []string{"task.created", "task.updated"}, Active: true,
+	}, nil)
+	r, token := newWebhookRouter(t, m)
+
+	w := doWebhook(r, token, "POST", "/boards/1/webhooks", dto.CreateWebhookRequest{
+		URL:    " https://ci.ex.com/hook ",
+		Events: []string{"task.created", "task.updated", "task.created"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp dto.WebhookDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, "https://ci.ex.com/hook", resp.URL)
+	require.Equal(t, []string{"task.created", "task.updated"}, resp.Events)
+	require.Equal(t, "whsec_generated", resp.Secret)
+	m.AssertExpectations(t)
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	m := new(mocks.MockQuerier)
+	stubRole(m, authz.RoleOwner)
+	r, token := newWebhookRouter(t, m)
+
+	cases := []dto.CreateWebhookRequest{
+		{URL: "https://ci.ex.com/hook", Events: []string{"task.exploded"}},
+		{URL: "ftp://ci.ex.com/hook", Events: []string{"task.created"}},
+		{URL: "http://localhost:8080/hook", Events: []string{"task.created"}},
+		{URL: "http://169.254.169.254/latest/meta-data/", Events: []string{"task.created"}},
+		{URL: "http://10.0.0.5/hook", Events: []string{"task.created"}},
+		{URL: "http://[::1]/hook", Events: []string{"task.created"}},
+		{URL: "http://[::ffff:127.0.0.1]/hook", Events: []string{"task.created"}},
+		{URL: "https://ci.ex.com/hook", Events: nil},
+		{URL: "https://ci.ex.com/hook", Secret: "short", Events: []string{"task.created"}},
+	}
+	for _, req := range cases {
+		w := doWebhook(r, token, "POST", "/boards/1/webhooks", req)
+		require.Equal(t, http.StatusBadRequest, w.Code, "%+v", req)
+	}
+	m.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything)
+}
+
+func TestGetWebhookDeliveryWithAttempts(t *testing.T) {
+	m := new(mocks.MockQuerier)
+	stubRole(m, authz.RoleOwner)
+	m.On("GetWebhookDelivery", mock.Anything, db.GetWebhookDeliveryParams{ID: 41, WebhookID: 7, BoardID: 1}).
+		Return(db.WebhookDelivery{
+			ID: 41, WebhookID: 7, Event: "task.created", Payload: []byte(`{"event":"task.created"}`),
+			Status: webhook.StatusFailed, Attempts: 2,
+			LastStatusCode: pgtype.Int4{Int32: 500, Valid: true},
+		}, nil)
+	m.On("ListWebhookAttempts", mock.Anything, int32(41)).Return([]db.WebhookAttempt{
+		{DeliveryID: 41, Attempt: 1, Error: pgtype.Text{String: "connection refused", Valid: true}, DurationMs: 3},
+		{DeliveryID: 41, Attempt: 2, StatusCode: pgtype.Int4{Int32: 500, Valid: true}, DurationMs: 12},
+	}, nil)
+	r, token := newWebhookRouter(t, m)
+
+	w := doWebhook(r, token, "GET", "/boards/1/webhooks/7/deliveries/41", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp dto.WebhookDeliveryDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.JSONEq(t, `{"event":"task.created"}`, string(resp.Payload))
+	require.Nil(t, resp.NextAttemptAt)
+	require.Len(t, resp.History, 2)
+	require.Equal(t, "connection refused", *resp.History[0].Error)
+	require.Equal(t, int32(500), *resp.History[1].StatusCode)
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	m := new(mocks.MockQuerier)
+	stubRole(m, authz.RoleOwner)
+	m.On("RedeliverWebhookDelivery", mock.Anything, db.RedeliverWebhookDeliveryParams{ID: 41, WebhookID: 7, BoardID: 1}).
+		Return(db.WebhookDelivery{ID: 42, WebhookID: 7, Event: "task.created", Payload: []byte(`{}`), Status: webhook.StatusPending}, nil)
+	m.On("RedeliverWebhookDelivery", mock.Anything, db.RedeliverWebhookDeliveryParams{ID: 99, WebhookID: 7, BoardID: 1}).
+		Return(db.WebhookDelivery{}, pgx.ErrNoRows)
+	r, token := newWebhookRouter(t, m)
+
+	w := doWebhook(r, token, "POST", "/boards/1/webhooks/7/deliveries/41/redeliver", nil)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var resp dto.WebhookDeliveryDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, int32(42), resp.ID)
+	require.Equal(t, webhook.StatusPending, resp.Status)
+
+	w = doWebhook(r, token, "POST", "/boards/1/webhooks/7/deliveries/99/redeliver", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}